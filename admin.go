@@ -0,0 +1,534 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// adminCache is the admin API's representation of a cache.
+type adminCache struct {
+	Name     string     `json:"name"`
+	Kind     string     `json:"kind"`
+	Count    int        `json:"count"`
+	Stats    CacheStats `json:"stats"`
+	HitRatio float64    `json:"hitRatio"`
+}
+
+// adminItem is the admin API's representation of an item.
+type adminItem struct {
+	Key          string      `json:"key"`
+	CreatedOn    time.Time   `json:"createdOn"`
+	AccessedOn   time.Time   `json:"accessedOn"`
+	AccessCount  int64       `json:"accessCount"`
+	LifeSpan     string      `json:"lifeSpan,omitempty"`
+	TTLRemaining string      `json:"ttlRemaining,omitempty"`
+	Source       string      `json:"source"`
+	Value        interface{} `json:"value,omitempty"`
+}
+
+// adminItemsByAccess sorts items by their access count, most accessed first.
+type adminItemsByAccess []adminItem
+
+func (p adminItemsByAccess) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p adminItemsByAccess) Len() int           { return len(p) }
+func (p adminItemsByAccess) Less(i, j int) bool { return p[i].AccessCount > p[j].AccessCount }
+
+// AdminMaxBodySize is the maximum size of values added via the admin handler,
+// larger ones get rejected with 413 Request Entity Too Large.
+const AdminMaxBodySize = 1 << 20
+
+// AdminOp is the kind of operation an admin request performs.
+type AdminOp int
+
+const (
+	// AdminRead covers all lookups: statistics, caches, keys and values.
+	AdminRead AdminOp = iota
+	// AdminSet adds an item.
+	AdminSet
+	// AdminDelete deletes an item.
+	AdminDelete
+	// AdminFlush removes all items from a cache.
+	AdminFlush
+)
+
+// AdminAuthorizer decides whether a request may perform an operation. Cache
+// is empty for operations on all caches, and key is empty for operations on
+// a whole cache. Returning an error rejects the request.
+type AdminAuthorizer func(r *http.Request, op AdminOp, cache, key string) error
+
+// adminHandler serves the admin API.
+type adminHandler struct {
+	// Token required for API requests, empty if none.
+	token string
+	// Whether requests need a verified client certificate, and which ones
+	// get accepted, nil for all.
+	clientCerts     bool
+	allowClientCert func(cert *x509.Certificate) bool
+	// Decides which operations get authorized, nil for all.
+	authorize AdminAuthorizer
+	// Serves the web dashboard.
+	ui http.Handler
+}
+
+// AdminOption configures the admin handler.
+type AdminOption func(*adminHandler)
+
+// WithAdminToken requires all API requests, lookups included, to carry the
+// given token as an "Authorization: Bearer" header. Only the web dashboard's
+// static assets are served without it: they hold no cache data, and browsers
+// can't attach the header when navigating to the dashboard. The dashboard
+// asks for the token instead, and sends it along with its API requests.
+func WithAdminToken(token string) AdminOption {
+	return func(h *adminHandler) {
+		h.token = token
+	}
+}
+
+// WithAdminClientCert requires requests to come with a client certificate
+// verified by the TLS server, i.e. one configured with
+// tls.RequireAndVerifyClientCert. If allow isn't nil, it additionally has to
+// accept the certificate, e.g. by checking its subject.
+func WithAdminClientCert(allow func(cert *x509.Certificate) bool) AdminOption {
+	return func(h *adminHandler) {
+		h.clientCerts = true
+		h.allowClientCert = allow
+	}
+}
+
+// WithAdminAuthorizer lets authorize decide which operations a request may
+// perform on which caches, e.g. based on its client certificate or
+// credentials. Rejected requests get answered with 403 Forbidden.
+func WithAdminAuthorizer(authorize AdminAuthorizer) AdminOption {
+	return func(h *adminHandler) {
+		h.authorize = authorize
+	}
+}
+
+// NewAdminHandler returns an HTTP handler for administrating the caches in
+// the registry, e.g. with cmd/cache2go-cli. Mount it under a prefix of your
+// choice, like:
+//
+//	http.Handle("/debug/cache2go/", http.StripPrefix("/debug/cache2go", cache2go.NewAdminHandler()))
+//
+// It serves JSON on the following routes; keys are strings:
+//
+//	GET    /stats                       aggregate statistics of all caches
+//	GET    /caches                      list all caches
+//	GET    /caches/{cache}              a cache's size and statistics
+//	POST   /caches/{cache}/flush        remove all items from a cache
+//	GET    /caches/{cache}/debug        a cache's internal state, see
+//	                                    CacheTable.DebugState and
+//	                                    LFUCache.DebugState
+//	GET    /caches/{cache}/keys?limit=n the most accessed keys of a cache
+//	GET    /caches/{cache}/keys/{key}   an item and its value
+//	PUT    /caches/{cache}/keys/{key}   add an item, with the request body as
+//	                                    its value (JSON if the content type
+//	                                    says so) and the lifespan given by the
+//	                                    ttl query parameter
+//	DELETE /caches/{cache}/keys/{key}   delete an item
+//	GET    /ui/                         a web dashboard for the above,
+//	                                    when built with Go 1.16 or later
+//
+// Values pass through the table's export transformer, see
+// CacheTable.SetExportTransformer. Unless configured with WithAdminToken,
+// WithAdminClientCert or WithAdminAuthorizer, the handler doesn't
+// authenticate requests, so don't expose it publicly.
+func NewAdminHandler(opts ...AdminOption) http.Handler {
+	h := &adminHandler{}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	h.ui = http.StripPrefix("/ui", adminUIHandler())
+	return h
+}
+
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.verifiedClient(r) {
+		adminError(w, http.StatusUnauthorized, fmt.Errorf("Missing or rejected client certificate"))
+		return
+	}
+	if r.URL.Path == "/" {
+		// Relative to wherever the handler is mounted, which http.Redirect
+		// can't tell.
+		w.Header().Set("Location", "ui/")
+		w.WriteHeader(http.StatusFound)
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, "/ui/") {
+		// The dashboard's assets hold no data, and browsers can't send a
+		// token loading them. The dashboard passes it on to the API.
+		h.ui.ServeHTTP(w, r)
+		return
+	}
+	if !h.authorizedToken(r) {
+		adminError(w, http.StatusUnauthorized, fmt.Errorf("Missing or invalid token"))
+		return
+	}
+
+	var path []string
+	for _, segment := range strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/") {
+		s, err := url.PathUnescape(segment)
+		if err != nil {
+			adminError(w, http.StatusBadRequest, err)
+			return
+		}
+		path = append(path, s)
+	}
+	if err := h.authorized(r, path); err != nil {
+		adminError(w, http.StatusForbidden, err)
+		return
+	}
+
+	switch {
+	case len(path) == 1 && path[0] == "stats" && r.Method == "GET":
+		adminJSON(w, AggregateStats())
+	case len(path) == 1 && path[0] == "caches" && r.Method == "GET":
+		h.list(w)
+	case len(path) >= 2 && path[0] == "caches":
+		c, kind, ok := findCache(path[1])
+		if !ok {
+			adminError(w, http.StatusNotFound, fmt.Errorf("Cache %s not found", path[1]))
+			return
+		}
+		h.serveCache(w, r, path[1], kind, c, path[2:])
+	default:
+		adminError(w, http.StatusNotFound, fmt.Errorf("No such route: %s %s", r.Method, r.URL.Path))
+	}
+}
+
+// verifiedClient returns whether the request comes with an accepted client
+// certificate, if required.
+func (h *adminHandler) verifiedClient(r *http.Request) bool {
+	if !h.clientCerts {
+		return true
+	}
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return false
+	}
+	return h.allowClientCert == nil || h.allowClientCert(r.TLS.VerifiedChains[0][0])
+}
+
+// authorized asks the authorizer, if any, whether the request may perform
+// its operation on the given path.
+func (h *adminHandler) authorized(r *http.Request, path []string) error {
+	if h.authorize == nil {
+		return nil
+	}
+
+	var op AdminOp
+	switch r.Method {
+	case "GET":
+		op = AdminRead
+	case "PUT":
+		op = AdminSet
+	case "DELETE":
+		op = AdminDelete
+	case "POST":
+		op = AdminFlush
+	default:
+		// Not routed anywhere anyway.
+		return nil
+	}
+
+	var cache, key string
+	if len(path) >= 2 && path[0] == "caches" {
+		cache = path[1]
+		if len(path) == 4 && path[2] == "keys" {
+			key = path[3]
+		}
+	}
+	return h.authorize(r, op, cache, key)
+}
+
+// authorizedToken returns whether the request carries the required token.
+func (h *adminHandler) authorizedToken(r *http.Request) bool {
+	if h.token == "" {
+		return true
+	}
+	header := r.Header.Get("Authorization")
+	token := strings.TrimPrefix(header, "Bearer ")
+	if token == header {
+		// Raw tokens without the scheme don't count.
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+// serveCache serves the routes of a specific cache.
+func (h *adminHandler) serveCache(w http.ResponseWriter, r *http.Request, name, kind string, c Cacher, path []string) {
+	switch {
+	case len(path) == 0 && r.Method == "GET":
+		adminJSON(w, newAdminCache(name, kind, c))
+	case len(path) == 1 && path[0] == "flush" && r.Method == "POST":
+		if t, ok := c.(*CacheTable); ok {
+			t.FlushContext(adminContext(r))
+		} else {
+			c.Flush()
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case len(path) == 1 && path[0] == "debug" && r.Method == "GET":
+		switch c := c.(type) {
+		case *CacheTable:
+			adminJSON(w, c.DebugState())
+		case *LFUCache:
+			adminJSON(w, c.DebugState())
+		default:
+			adminError(w, http.StatusNotFound, fmt.Errorf("No debug state for %s caches", kind))
+		}
+	case len(path) == 1 && path[0] == "keys" && r.Method == "GET":
+		h.keys(w, r, c)
+	case len(path) == 2 && path[0] == "keys":
+		h.serveKey(w, r, c, path[1])
+	default:
+		adminError(w, http.StatusNotFound, fmt.Errorf("No such route: %s %s", r.Method, r.URL.Path))
+	}
+}
+
+// serveKey serves the routes of a specific key.
+func (h *adminHandler) serveKey(w http.ResponseWriter, r *http.Request, c Cacher, key string) {
+	switch r.Method {
+	case "GET":
+		item, ok := peek(c, key)
+		if !ok {
+			adminError(w, http.StatusNotFound, ErrKeyNotFound)
+			return
+		}
+		i := newAdminItem(item, time.Now())
+		i.Value = exportValue(c, key, item.Data())
+		adminJSON(w, i)
+	case "PUT":
+		var lifeSpan time.Duration
+		if ttl := r.URL.Query().Get("ttl"); ttl != "" {
+			var err error
+			if lifeSpan, err = time.ParseDuration(ttl); err != nil {
+				adminError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, AdminMaxBodySize))
+		if err != nil && len(body) >= AdminMaxBodySize {
+			adminError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("Value exceeds %d bytes", AdminMaxBodySize))
+			return
+		} else if err != nil {
+			adminError(w, http.StatusBadRequest, err)
+			return
+		}
+		var value interface{} = string(body)
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			if err := json.Unmarshal(body, &value); err != nil {
+				adminError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+		if t, ok := c.(*CacheTable); ok {
+			t.AddContext(adminContext(r), key, lifeSpan, value)
+		} else {
+			c.Add(key, lifeSpan, value)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case "DELETE":
+		var err error
+		if t, ok := c.(*CacheTable); ok {
+			_, err = t.DeleteContext(adminContext(r), key)
+		} else {
+			_, err = c.Delete(key)
+		}
+		if err != nil {
+			adminError(w, http.StatusNotFound, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		adminError(w, http.StatusMethodNotAllowed, fmt.Errorf("Method %s not allowed", r.Method))
+	}
+}
+
+// adminContext returns the request's context, with the subject of its client
+// certificate as audit actor, unless one was set already.
+func adminContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if AuditActor(ctx) == "" && r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		ctx = WithAuditActor(ctx, r.TLS.VerifiedChains[0][0].Subject.CommonName)
+	}
+	return ctx
+}
+
+// list serves all caches in the registry, sorted by name.
+func (h *adminHandler) list(w http.ResponseWriter) {
+	caches := []adminCache{}
+	ForeachCache(func(name string, c Cacher) {
+		caches = append(caches, newAdminCache(name, cacheKind(c), c))
+	})
+	sort.Sort(adminCachesByName(caches))
+
+	adminJSON(w, caches)
+}
+
+// keys serves the most accessed keys of a cache.
+func (h *adminHandler) keys(w http.ResponseWriter, r *http.Request, c Cacher) {
+	limit := 100
+	if l := r.URL.Query().Get("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil {
+			adminError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	now := time.Now()
+	items := []adminItem{}
+	c.Foreach(func(key interface{}, item *CacheItem) {
+		items = append(items, newAdminItem(item, now))
+	})
+	sort.Sort(adminItemsByAccess(items))
+	if limit >= 0 && len(items) > limit {
+		items = items[:limit]
+	}
+
+	adminJSON(w, items)
+}
+
+// adminCachesByName sorts caches by their name.
+type adminCachesByName []adminCache
+
+func (p adminCachesByName) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p adminCachesByName) Len() int      { return len(p) }
+func (p adminCachesByName) Less(i, j int) bool {
+	if p[i].Name == p[j].Name {
+		return p[i].Kind < p[j].Kind
+	}
+	return p[i].Name < p[j].Name
+}
+
+func newAdminCache(name, kind string, c Cacher) adminCache {
+	stats := c.Stats()
+	return adminCache{
+		Name:     name,
+		Kind:     kind,
+		Count:    c.Count(),
+		Stats:    stats,
+		HitRatio: stats.HitRatio(),
+	}
+}
+
+func newAdminItem(item *CacheItem, now time.Time) adminItem {
+	item.RLock()
+	defer item.RUnlock()
+
+	i := adminItem{
+		Key:         fmt.Sprint(item.key),
+		CreatedOn:   item.createdOn,
+		AccessedOn:  item.AccessedOn(),
+		AccessCount: item.AccessCount(),
+		Source:      item.source.String(),
+	}
+	if item.lifeSpan > 0 {
+		i.LifeSpan = item.lifeSpan.String()
+		remaining := item.lifeSpan - now.Sub(item.AccessedOn())
+		if remaining < 0 {
+			remaining = 0
+		}
+		i.TTLRemaining = remaining.String()
+	}
+	return i
+}
+
+// findCache looks up a cache in the registry, preferring tables over LFU
+// caches of the same name.
+func findCache(name string) (Cacher, string, bool) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return findCacheInternal(name)
+}
+
+// findCacheInternal looks up a cache in the registry, just like findCache.
+// Careful: do not run this function unless the registry-mutex is locked!
+func findCacheInternal(name string) (Cacher, string, bool) {
+	if t, ok := cache[name]; ok {
+		return t, cacheKind(t), true
+	}
+	if c, ok := lfuCaches[name]; ok {
+		return c, cacheKind(c), true
+	}
+	return nil, "", false
+}
+
+// cacheKind returns a short description of the cache's implementation.
+func cacheKind(c Cacher) string {
+	switch c.(type) {
+	case *CacheTable:
+		return "table"
+	case *LFUCache:
+		return "lfu"
+	case *BoundedCache:
+		return "bounded"
+	}
+	return fmt.Sprintf("%T", c)
+}
+
+// peek returns an item without counting it as an access, where possible.
+func peek(c Cacher, key interface{}) (*CacheItem, bool) {
+	if t, ok := c.(*CacheTable); ok {
+		t.RLock()
+		defer t.RUnlock()
+		item, ok := t.items[key]
+		return item, ok
+	}
+
+	if !c.Exists(key) {
+		return nil, false
+	}
+	item, err := c.Value(key)
+	return item, err == nil
+}
+
+// exportValue passes a value through the table's export transformer, if any.
+func exportValue(c Cacher, key, value interface{}) interface{} {
+	t, ok := c.(*CacheTable)
+	if !ok {
+		return value
+	}
+
+	t.RLock()
+	transform := t.exportTransformer
+	t.RUnlock()
+	if transform != nil {
+		return transform(key, value)
+	}
+	return value
+}
+
+func adminJSON(w http.ResponseWriter, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		adminError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(b, '\n'))
+}
+
+func adminError(w http.ResponseWriter, status int, err error) {
+	b, _ := json.Marshal(map[string]string{"error": err.Error()})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(b, '\n'))
+}
@@ -0,0 +1,207 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func adminRequest(t *testing.T, h http.Handler, method, path, body string, v interface{}) int {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if strings.HasPrefix(body, "{") {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	if v != nil && w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Error("Error decoding response", err)
+		}
+	}
+	return w.Code
+}
+
+func TestAdminHandler(t *testing.T) {
+	h := NewAdminHandler()
+	table, _ := CacheWithOptions("testAdmin table", WithStats())
+	table.SetExportTransformer(func(key interface{}, value interface{}) interface{} {
+		if key == "secret" {
+			return "***"
+		}
+		return value
+	})
+
+	if code := adminRequest(t, h, "PUT", "/caches/testAdmin%20table/keys/a%2Fb?ttl=1m", "value", nil); code != http.StatusNoContent {
+		t.Error("Error adding item, status", code)
+	}
+	adminRequest(t, h, "PUT", "/caches/testAdmin%20table/keys/json", `{"n":1}`, nil)
+	table.Add("secret", 0, "hunter2")
+
+	var i adminItem
+	if code := adminRequest(t, h, "GET", "/caches/testAdmin%20table/keys/a%2Fb", "", &i); code != http.StatusOK || i.Value != "value" || i.LifeSpan != "1m0s" {
+		t.Errorf("Unexpected item %+v, status %d", i, code)
+	}
+	adminRequest(t, h, "GET", "/caches/testAdmin%20table/keys/json", "", &i)
+	if m, ok := i.Value.(map[string]interface{}); !ok || m["n"] != float64(1) {
+		t.Errorf("JSON value should be decoded, got %+v", i)
+	}
+	adminRequest(t, h, "GET", "/caches/testAdmin%20table/keys/secret", "", &i)
+	if i.Value != "***" {
+		t.Error("Value should pass through the export transformer, got", i.Value)
+	}
+
+	// Peeking doesn't count as an access
+	if table.Stats().Hits != 0 {
+		t.Error("Admin lookups should not count as hits")
+	}
+	table.Value("secret")
+	var keys []adminItem
+	adminRequest(t, h, "GET", "/caches/testAdmin%20table/keys?limit=2", "", &keys)
+	if len(keys) != 2 || keys[0].Key != "secret" {
+		t.Errorf("Expected the most accessed keys, got %+v", keys)
+	}
+
+	var c adminCache
+	adminRequest(t, h, "GET", "/caches/testAdmin%20table", "", &c)
+	if c.Name != "testAdmin table" || c.Kind != "table" || c.Count != 3 || c.Stats.Hits != 1 {
+		t.Errorf("Unexpected cache %+v", c)
+	}
+	var caches []adminCache
+	adminRequest(t, h, "GET", "/caches", "", &caches)
+	found := false
+	for _, c := range caches {
+		found = found || c.Name == "testAdmin table"
+	}
+	if !found {
+		t.Error("Cache should be listed")
+	}
+
+	if code := adminRequest(t, h, "DELETE", "/caches/testAdmin%20table/keys/a%2Fb", "", nil); code != http.StatusNoContent || table.Exists("a/b") {
+		t.Error("Error deleting item, status", code)
+	}
+	if code := adminRequest(t, h, "POST", "/caches/testAdmin%20table/flush", "", nil); code != http.StatusNoContent || table.Count() != 0 {
+		t.Error("Error flushing cache, status", code)
+	}
+
+	if code := adminRequest(t, h, "GET", "/caches/missing", "", nil); code != http.StatusNotFound {
+		t.Error("Expected missing cache, status", code)
+	}
+	if code := adminRequest(t, h, "GET", "/caches/testAdmin%20table/keys/missing", "", nil); code != http.StatusNotFound {
+		t.Error("Expected missing key, status", code)
+	}
+	if code := adminRequest(t, h, "PUT", "/caches/testAdmin%20table/keys/a?ttl=soon", "", nil); code != http.StatusBadRequest {
+		t.Error("Expected invalid lifespan, status", code)
+	}
+	if code := adminRequest(t, h, "PUT", "/caches/testAdmin%20table/keys/big", strings.Repeat("x", AdminMaxBodySize+1), nil); code != http.StatusRequestEntityTooLarge || table.Exists("big") {
+		t.Error("Expected oversized value to be rejected, status", code)
+	}
+	var stats CacheStats
+	if code := adminRequest(t, h, "GET", "/stats", "", &stats); code != http.StatusOK || stats.Hits < 1 {
+		t.Error("Error retrieving aggregate stats", stats)
+	}
+}
+
+func TestAdminToken(t *testing.T) {
+	h := NewAdminHandler(WithAdminToken("s3cret"))
+	table := Cache("testAdminToken")
+	table.Add(k, 0, v)
+
+	if code := adminRequest(t, h, "DELETE", "/caches/testAdminToken/keys/"+k, "", nil); code != http.StatusUnauthorized {
+		t.Error("Changes without a token should be rejected, status", code)
+	}
+	if code := adminRequest(t, h, "GET", "/caches/testAdminToken/keys/"+k, "", nil); code != http.StatusUnauthorized {
+		t.Error("Lookups without a token should be rejected, status", code)
+	}
+	if code := adminRequest(t, h, "GET", "/ui/", "", nil); code == http.StatusUnauthorized {
+		t.Error("Dashboard should load without a token, status", code)
+	}
+
+	req := httptest.NewRequest("GET", "/caches/testAdminToken/keys/"+k, nil)
+	req.Header.Set("Authorization", "s3cret")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Error("Tokens without the Bearer scheme should be rejected, status", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/caches/testAdminToken/keys/"+k, nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Error("Lookups with the token should be accepted, status", w.Code)
+	}
+
+	req = httptest.NewRequest("DELETE", "/caches/testAdminToken/keys/"+k, nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent || table.Exists(k) {
+		t.Error("Changes with the token should be accepted, status", w.Code)
+	}
+}
+
+func TestAdminAuthorizer(t *testing.T) {
+	h := NewAdminHandler(WithAdminAuthorizer(func(r *http.Request, op AdminOp, cache, key string) error {
+		if op != AdminRead && (cache != "testAdminACL" || key == "") {
+			return errors.New("Read-only")
+		}
+		return nil
+	}))
+	table := Cache("testAdminACL")
+	table.Add(k, 0, v)
+
+	if code := adminRequest(t, h, "GET", "/stats", "", nil); code != http.StatusOK {
+		t.Error("Lookups should be authorized, status", code)
+	}
+	if code := adminRequest(t, h, "POST", "/caches/testAdminACL/flush", "", nil); code != http.StatusForbidden {
+		t.Error("Flushing should be forbidden, status", code)
+	}
+	if code := adminRequest(t, h, "DELETE", "/caches/testAdminACL/keys/"+k, "", nil); code != http.StatusNoContent {
+		t.Error("Deleting should be authorized, status", code)
+	}
+	if code := adminRequest(t, h, "PUT", "/caches/testAdminToken/keys/"+k, "", nil); code != http.StatusForbidden {
+		t.Error("Changing other caches should be forbidden, status", code)
+	}
+}
+
+func TestAdminClientCert(t *testing.T) {
+	h := NewAdminHandler(WithAdminClientCert(func(cert *x509.Certificate) bool {
+		return cert.Subject.CommonName == "ops"
+	}))
+
+	request := func(name string) int {
+		req := httptest.NewRequest("GET", "/stats", nil)
+		if name != "" {
+			cert := &x509.Certificate{Subject: pkix.Name{CommonName: name}}
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := request(""); code != http.StatusUnauthorized {
+		t.Error("Requests without a certificate should be rejected, status", code)
+	}
+	if code := request("intruder"); code != http.StatusUnauthorized {
+		t.Error("Requests with an unknown certificate should be rejected, status", code)
+	}
+	if code := request("ops"); code != http.StatusOK {
+		t.Error("Requests with an accepted certificate should be served, status", code)
+	}
+}
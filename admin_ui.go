@@ -0,0 +1,26 @@
+//go:build go1.16
+// +build go1.16
+
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed adminui
+var adminUI embed.FS
+
+// adminUIHandler serves the admin handler's web dashboard.
+func adminUIHandler() http.Handler {
+	ui, _ := fs.Sub(adminUI, "adminui")
+	return http.FileServer(http.FS(ui))
+}
@@ -0,0 +1,23 @@
+//go:build !go1.16
+// +build !go1.16
+
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"net/http"
+)
+
+// adminUIHandler serves the admin handler's web dashboard, which can't be
+// embedded before Go 1.16.
+func adminUIHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "The dashboard requires Go 1.16 or later", http.StatusNotFound)
+	})
+}
@@ -0,0 +1,39 @@
+//go:build go1.16
+// +build go1.16
+
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdminUI(t *testing.T) {
+	h := NewAdminHandler()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "ui/" {
+		t.Error("Expected a redirect to the dashboard, status", w.Code)
+	}
+
+	for _, path := range []string{"/ui/", "/ui/app.js", "/ui/style.css"} {
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK || w.Body.Len() == 0 {
+			t.Error("Error serving", path, "status", w.Code)
+		}
+	}
+	if !strings.Contains(w.Body.String(), "body") {
+		t.Error("Unexpected stylesheet")
+	}
+}
@@ -0,0 +1,181 @@
+'use strict';
+
+// The admin API lives one level above the UI.
+const base = location.pathname.replace(/\/ui\/.*$/, '');
+const interval = 2000;
+const history = 60;
+
+const state = {
+  selected: null,
+  previous: {},
+  samples: {},
+};
+
+const tokenInput = document.getElementById('token');
+tokenInput.value = localStorage.getItem('cache2go-token') || '';
+tokenInput.addEventListener('change', () => localStorage.setItem('cache2go-token', tokenInput.value));
+
+async function api(method, path) {
+  const headers = {};
+  if (tokenInput.value) {
+    headers['Authorization'] = 'Bearer ' + tokenInput.value;
+  }
+  const resp = await fetch(base + path, { method, headers });
+  if (!resp.ok) {
+    const body = await resp.json().catch(() => ({}));
+    throw new Error(body.error || resp.statusText);
+  }
+  return resp.status === 204 ? null : resp.json();
+}
+
+function showError(err) {
+  const el = document.getElementById('error');
+  el.hidden = !err;
+  el.textContent = err ? err.message : '';
+}
+
+function cachePath(name) {
+  return '/caches/' + encodeURIComponent(name);
+}
+
+function cell(row, text) {
+  const td = document.createElement('td');
+  td.textContent = text;
+  row.appendChild(td);
+  return td;
+}
+
+function record(cache) {
+  const prev = state.previous[cache.name];
+  state.previous[cache.name] = cache.stats;
+  const rates = { lookups: 0, evictions: 0, expirations: 0 };
+  if (prev) {
+    const seconds = interval / 1000;
+    rates.lookups = (cache.stats.Hits + cache.stats.Misses - prev.Hits - prev.Misses) / seconds;
+    rates.evictions = (cache.stats.Evicted - prev.Evicted) / seconds;
+    rates.expirations = (cache.stats.Expired - prev.Expired) / seconds;
+  }
+
+  const samples = state.samples[cache.name] = state.samples[cache.name] || [];
+  samples.push({ ratio: cache.hitRatio, lookups: rates.lookups });
+  if (samples.length > history) {
+    samples.shift();
+  }
+  return rates;
+}
+
+function renderCaches(caches) {
+  const tbody = document.querySelector('#caches tbody');
+  tbody.innerHTML = '';
+  for (const cache of caches) {
+    const rates = record(cache);
+    const row = document.createElement('tr');
+    row.classList.toggle('selected', cache.name === state.selected);
+    cell(row, cache.name);
+    cell(row, cache.kind);
+    cell(row, cache.count);
+    cell(row, (cache.hitRatio * 100).toFixed(1) + '%');
+    cell(row, rates.lookups.toFixed(1));
+    cell(row, rates.evictions.toFixed(1));
+    cell(row, rates.expirations.toFixed(1));
+    row.addEventListener('click', () => select(cache.name));
+    tbody.appendChild(row);
+  }
+}
+
+function chart(id, values, max) {
+  const canvas = document.getElementById(id);
+  const ctx = canvas.getContext('2d');
+  ctx.clearRect(0, 0, canvas.width, canvas.height);
+  max = max || Math.max(1, ...values);
+
+  ctx.strokeStyle = '#2b4a6f';
+  ctx.beginPath();
+  values.forEach((v, i) => {
+    const x = (i / (history - 1)) * canvas.width;
+    const y = canvas.height - (v / max) * (canvas.height - 10) - 5;
+    if (i === 0) {
+      ctx.moveTo(x, y);
+    } else {
+      ctx.lineTo(x, y);
+    }
+  });
+  ctx.stroke();
+}
+
+async function renderDetails() {
+  const name = state.selected;
+  document.getElementById('details').hidden = !name;
+  if (!name) {
+    return;
+  }
+
+  document.getElementById('title').textContent = name;
+  const samples = state.samples[name] || [];
+  chart('ratio', samples.map(s => s.ratio), 1);
+  chart('lookups', samples.map(s => s.lookups));
+
+  const keys = await api('GET', cachePath(name) + '/keys?limit=50');
+  const tbody = document.querySelector('#keys tbody');
+  tbody.innerHTML = '';
+  for (const item of keys) {
+    const row = document.createElement('tr');
+    cell(row, item.key);
+    cell(row, item.accessCount);
+    cell(row, item.lifeSpan || '-');
+    cell(row, item.ttlRemaining || '-');
+    const del = document.createElement('button');
+    del.textContent = 'Delete';
+    del.className = 'danger';
+    del.addEventListener('click', async (e) => {
+      e.stopPropagation();
+      if (confirm('Delete ' + item.key + '?')) {
+        await api('DELETE', cachePath(name) + '/keys/' + encodeURIComponent(item.key)).catch(showError);
+        renderDetails().catch(showError);
+      }
+    });
+    cell(row, '').appendChild(del);
+    row.addEventListener('click', () => showValue(name, item.key));
+    tbody.appendChild(row);
+  }
+}
+
+async function showValue(name, key) {
+  const el = document.getElementById('value');
+  try {
+    const item = await api('GET', cachePath(name) + '/keys/' + encodeURIComponent(key));
+    el.textContent = JSON.stringify(item.value, null, 2);
+    el.hidden = false;
+  } catch (err) {
+    showError(err);
+  }
+}
+
+function select(name) {
+  state.selected = name;
+  document.querySelectorAll('#caches tbody tr').forEach(row => {
+    row.classList.toggle('selected', row.firstChild.textContent === name);
+  });
+  document.getElementById('value').hidden = true;
+  renderDetails().catch(showError);
+}
+
+document.getElementById('flush').addEventListener('click', async () => {
+  if (confirm('Flush ' + state.selected + '?')) {
+    await api('POST', cachePath(state.selected) + '/flush').catch(showError);
+    renderDetails().catch(showError);
+  }
+});
+
+async function refresh() {
+  try {
+    renderCaches(await api('GET', '/caches'));
+    await renderDetails();
+    showError(null);
+  } catch (err) {
+    showError(err);
+  }
+}
+
+refresh();
+setInterval(refresh, interval);
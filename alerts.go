@@ -0,0 +1,175 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"time"
+)
+
+// Kinds of alerts.
+const (
+	AlertHitRatio  = "hitRatio"
+	AlertEvictions = "evictions"
+)
+
+// Number of samples the alerting window gets divided into.
+const alertSamples = 10
+
+// AlertConfig holds a table's alerting thresholds, see SetAlertThresholds.
+type AlertConfig struct {
+	// Alert when the hit ratio drops below this, 0 disables it.
+	HitRatioBelow float64
+	// Alert when more items than this get evicted per minute, 0 disables it.
+	EvictionsPerMinAbove float64
+	// The sliding window the thresholds apply to, a minute if 0.
+	Window time.Duration
+	// Lookups required within the window before the hit ratio counts, so a
+	// handful of misses doesn't raise an alert.
+	MinLookups int64
+}
+
+// Alert reports that a threshold was crossed.
+type Alert struct {
+	Time  time.Time
+	Table string
+	// AlertHitRatio or AlertEvictions.
+	Kind string
+	// The value within the window, and the threshold it crossed.
+	Value     float64
+	Threshold float64
+	// Whether the value is back within the threshold.
+	Resolved bool
+}
+
+// alertMonitor samples a table's statistics to check its thresholds.
+type alertMonitor struct {
+	config AlertConfig
+	alerts chan Alert
+	stop   chan struct{}
+
+	// Statistics of the last samples, oldest first.
+	samples []alertSample
+	// Whether each kind of alert is currently raised.
+	raised map[string]bool
+}
+
+type alertSample struct {
+	time  time.Time
+	stats CacheStats
+}
+
+// SetAlertThresholds starts checking the table's statistics against the
+// given thresholds over a sliding window, sending an alert on the returned
+// channel whenever a threshold gets crossed, and another one once the value
+// is back within it. Alerts get dropped if the channel's buffer is full.
+// The table needs to be created with WithStats. Calling it again replaces
+// the thresholds and closes the previous channel; an empty config stops
+// alerting.
+func (table *CacheTable) SetAlertThresholds(config AlertConfig) (<-chan Alert, error) {
+	if table.stats == nil {
+		return nil, ErrStatsDisabled
+	}
+	if config.Window <= 0 {
+		config.Window = time.Minute
+	}
+
+	var m *alertMonitor
+	if config.HitRatioBelow > 0 || config.EvictionsPerMinAbove > 0 {
+		m = &alertMonitor{
+			config: config,
+			alerts: make(chan Alert, 16),
+			stop:   make(chan struct{}),
+			raised: make(map[string]bool),
+		}
+	}
+
+	table.Lock()
+	previous := table.alertMonitor
+	table.alertMonitor = m
+	table.Unlock()
+
+	if previous != nil {
+		close(previous.stop)
+	}
+	if m == nil {
+		return nil, nil
+	}
+
+	m.sample(time.Now(), table.stats.snapshot())
+	go table.monitorAlerts(m)
+	return m.alerts, nil
+}
+
+// monitorAlerts samples the statistics until the monitor gets stopped.
+func (table *CacheTable) monitorAlerts(m *alertMonitor) {
+	defer close(m.alerts)
+
+	ticker := time.NewTicker(m.config.Window / alertSamples)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case now := <-ticker.C:
+			m.sample(now, table.stats.snapshot())
+			m.check(table.name, now)
+		}
+	}
+}
+
+// sample records the statistics, forgetting samples that left the window.
+func (m *alertMonitor) sample(now time.Time, stats CacheStats) {
+	m.samples = append(m.samples, alertSample{now, stats})
+	if len(m.samples) > alertSamples+1 {
+		m.samples = m.samples[1:]
+	}
+}
+
+// check compares the window's statistics to the thresholds.
+func (m *alertMonitor) check(table string, now time.Time) {
+	first, last := m.samples[0], m.samples[len(m.samples)-1]
+	elapsed := last.time.Sub(first.time)
+	if elapsed <= 0 {
+		return
+	}
+
+	if m.config.HitRatioBelow > 0 {
+		hits := last.stats.Hits - first.stats.Hits
+		lookups := hits + last.stats.Misses - first.stats.Misses
+		if lookups > 0 && lookups >= m.config.MinLookups {
+			ratio := float64(hits) / float64(lookups)
+			m.update(table, now, AlertHitRatio, ratio, m.config.HitRatioBelow, ratio < m.config.HitRatioBelow)
+		}
+	}
+	if m.config.EvictionsPerMinAbove > 0 {
+		perMin := float64(last.stats.Evicted-first.stats.Evicted) / elapsed.Minutes()
+		m.update(table, now, AlertEvictions, perMin, m.config.EvictionsPerMinAbove, perMin > m.config.EvictionsPerMinAbove)
+	}
+}
+
+// update sends an alert if the threshold got crossed in either direction.
+func (m *alertMonitor) update(table string, now time.Time, kind string, value, threshold float64, exceeded bool) {
+	if exceeded == m.raised[kind] {
+		return
+	}
+	m.raised[kind] = exceeded
+
+	alert := Alert{
+		Time:      now,
+		Table:     table,
+		Kind:      kind,
+		Value:     value,
+		Threshold: threshold,
+		Resolved:  !exceeded,
+	}
+	select {
+	case m.alerts <- alert:
+	default:
+	}
+}
@@ -0,0 +1,70 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+	"time"
+)
+
+func nextAlert(t *testing.T, alerts <-chan Alert) Alert {
+	t.Helper()
+	select {
+	case alert := <-alerts:
+		return alert
+	case <-time.After(time.Second):
+		t.Fatal("Expected an alert")
+	}
+	return Alert{}
+}
+
+func TestAlertThresholds(t *testing.T) {
+	if _, err := Cache("testAlertsDisabled").SetAlertThresholds(AlertConfig{HitRatioBelow: 0.5}); err != ErrStatsDisabled {
+		t.Error("Expected ErrStatsDisabled, got", err)
+	}
+
+	table, _ := CacheWithOptions("testAlertThresholds", WithStats(), WithCapacity(1))
+	alerts, err := table.SetAlertThresholds(AlertConfig{
+		HitRatioBelow:        0.5,
+		EvictionsPerMinAbove: 1000,
+		Window:               100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal("Error setting thresholds", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		table.Value("missing")
+	}
+	alert := nextAlert(t, alerts)
+	if alert.Kind != AlertHitRatio || alert.Resolved || alert.Value != 0 || alert.Table != "testAlertThresholds" {
+		t.Errorf("Unexpected alert %+v", alert)
+	}
+
+	table.Add(k, 0, v)
+	for i := 0; i < 100; i++ {
+		table.Value(k)
+	}
+	alert = nextAlert(t, alerts)
+	if alert.Kind != AlertHitRatio || !alert.Resolved {
+		t.Errorf("Expected resolved alert, got %+v", alert)
+	}
+
+	// 10 evictions within 100ms are 6000 per minute.
+	for i := 0; i < 10; i++ {
+		table.Add(i, 0, v)
+	}
+	alert = nextAlert(t, alerts)
+	if alert.Kind != AlertEvictions || alert.Resolved || alert.Value <= 1000 {
+		t.Errorf("Unexpected alert %+v", alert)
+	}
+
+	table.SetAlertThresholds(AlertConfig{})
+	for range alerts {
+	}
+}
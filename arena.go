@@ -0,0 +1,229 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"sync"
+)
+
+// ArenaStats describes the slabs holding a table's byte slice values, see
+// WithByteArena.
+type ArenaStats struct {
+	// Number of slabs allocated, and how large each of them is in bytes.
+	Slabs    int
+	SlabSize int
+	// Number of values stored in the slabs, and their total size in bytes.
+	Values    int
+	LiveBytes int64
+}
+
+// WithByteArena stores byte slice values in large slabs of slabSize bytes
+// each, instead of one object per value, so tables holding millions of them
+// don't burden the garbage collector. Values get copied into the slabs when
+// they're added, and copied out again by the items' Data method, so changing
+// either copy doesn't affect the cached value. Empty values, values larger
+// than a slab and values of other types are stored as usual.
+//
+// A slab gets reused once all of its values got removed from the table and
+// released by their readers, see Acquire, so tables whose values are
+// removed in about the order they were added waste the least space. Data of
+// items released already returns nil.
+func WithByteArena(slabSize int) Option {
+	return func(o *cacheOptions) {
+		o.arenaSlabSize = slabSize
+	}
+}
+
+// arenaSpan is where a value is stored in an arena.
+type arenaSpan struct {
+	slab   int
+	offset int
+	// Length of the value, -1 once it got freed.
+	length int
+	// Number of bytes taken up by the value, including its record header in
+	// mapped arenas.
+	size int
+}
+
+// byteArena allocates byte slice values from large slabs.
+type byteArena struct {
+	sync.Mutex
+
+	slabSize int
+	slabs    [][]byte
+	// Number of bytes in use per slab.
+	live []int
+	// Slabs without any values, ready for reuse.
+	free []int
+	// Slab values currently get appended to, -1 if none, and where.
+	current int
+	offset  int
+	// Number of values stored.
+	values int
+	// The file backing the slabs, nil if they're allocated on the heap. Its
+	// slabs are fixed and hold records, see OpenMappedArena.
+	mapping *MappedArena
+	// Whether the arena got detached from its table, which leaves its values
+	// alone from then on.
+	detached bool
+}
+
+// newByteArena returns an empty arena of slabs of the given size.
+func newByteArena(slabSize int) *byteArena {
+	return &byteArena{slabSize: slabSize, current: -1}
+}
+
+// store copies an item's byte slice value into the arena, unless it doesn't
+// fit into a slab, or a mapped arena is full.
+func (a *byteArena) store(item *CacheItem) {
+	data, ok := item.data.([]byte)
+	if !ok || len(data) == 0 {
+		return
+	}
+	size := len(data)
+	key, keyed := item.key.(string)
+	if a.mapping != nil {
+		size += arenaHeaderSize + len(key)
+	}
+	if size > a.slabSize {
+		return
+	}
+
+	a.Lock()
+	defer a.Unlock()
+
+	if a.detached {
+		return
+	}
+	if a.current < 0 || a.offset+size > a.slabSize {
+		if !a.nextSlab() {
+			return
+		}
+	}
+	slab := a.slabs[a.current]
+	offset := a.offset
+	if a.mapping != nil {
+		offset += writeArenaRecord(slab[offset:], key, keyed, item, len(data))
+	}
+	copy(slab[offset:], data)
+	item.span = arenaSpan{slab: a.current, offset: offset, length: len(data), size: size}
+	item.arena = a
+	item.data = nil
+
+	a.offset += size
+	a.live[a.current] += size
+	a.values++
+}
+
+// nextSlab switches to an empty slab, reusing one if possible, and returns
+// whether there was one.
+// Careful: do not run this method unless the arena-mutex is locked!
+func (a *byteArena) nextSlab() bool {
+	var next int
+	if n := len(a.free); n > 0 {
+		next = a.free[n-1]
+		a.free = a.free[:n-1]
+		if a.mapping != nil {
+			// Records of former values must not be recovered.
+			zeroBytes(a.slabs[next])
+		}
+	} else if a.mapping == nil {
+		a.slabs = append(a.slabs, make([]byte, a.slabSize))
+		a.live = append(a.live, 0)
+		next = len(a.slabs) - 1
+	} else {
+		return false
+	}
+
+	if a.current >= 0 && a.live[a.current] == 0 {
+		a.free = append(a.free, a.current)
+	}
+	a.current = next
+	a.offset = 0
+	return true
+}
+
+// read returns a copy of the value stored at span, or nil if it got freed.
+func (a *byteArena) read(span *arenaSpan) interface{} {
+	a.Lock()
+	defer a.Unlock()
+
+	if span.length < 0 || a.detached {
+		return nil
+	}
+	data := make([]byte, span.length)
+	copy(data, a.slabs[span.slab][span.offset:])
+	return data
+}
+
+// release frees the value stored at span.
+func (a *byteArena) release(span *arenaSpan) {
+	a.Lock()
+	defer a.Unlock()
+
+	if span.length < 0 || a.detached {
+		return
+	}
+	slab := a.slabs[span.slab]
+	if a.mapping != nil {
+		slab[span.offset+span.length-span.size] = arenaRecordDead
+	}
+	a.live[span.slab] -= span.size
+	a.values--
+	span.length = -1
+
+	if a.live[span.slab] == 0 {
+		if span.slab == a.current {
+			if a.mapping != nil {
+				zeroBytes(slab[:a.offset])
+			}
+			a.offset = 0
+		} else {
+			a.free = append(a.free, span.slab)
+		}
+	}
+}
+
+// detach makes the arena leave its values alone from now on, so closing its
+// table keeps them in a mapped arena's file.
+func (a *byteArena) detach() {
+	a.Lock()
+	defer a.Unlock()
+	a.detached = true
+}
+
+// zeroBytes sets all bytes of b to zero.
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// ArenaStats returns statistics of the slabs holding the table's values. The
+// second return value is false unless the table was created with
+// WithByteArena.
+func (table *CacheTable) ArenaStats() (ArenaStats, bool) {
+	table.RLock()
+	a := table.arena
+	table.RUnlock()
+	if a == nil {
+		return ArenaStats{}, false
+	}
+
+	a.Lock()
+	defer a.Unlock()
+	s := ArenaStats{
+		Slabs:    len(a.slabs),
+		SlabSize: a.slabSize,
+		Values:   a.values,
+	}
+	for _, n := range a.live {
+		s.LiveBytes += int64(n)
+	}
+	return s, true
+}
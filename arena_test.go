@@ -0,0 +1,66 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestByteArena(t *testing.T) {
+	table, _ := CacheWithOptions("testByteArena", WithByteArena(16))
+	table.Flush()
+
+	value := []byte("0123456789")
+	table.Add("a", 0, value)
+	value[0] = 'x'
+	table.Add("b", 0, []byte("abcdefghij"))
+	table.Add("large", 0, bytes.Repeat([]byte("x"), 32))
+	table.Add("string", 0, "not bytes")
+
+	p, _ := table.Value("a")
+	if data := p.Data().([]byte); string(data) != "0123456789" {
+		t.Error("Expected arena values to be copied when added, got", string(data))
+	} else {
+		data[0] = 'x'
+	}
+	if p, _ := table.Value("a"); string(p.Data().([]byte)) != "0123456789" {
+		t.Error("Expected arena values to be copied when read")
+	}
+	if p, _ := table.Value("large"); len(p.Data().([]byte)) != 32 {
+		t.Error("Expected values larger than a slab to be stored as usual")
+	}
+	if p, _ := table.Value("string"); p.Data() != "not bytes" {
+		t.Error("Expected other values to be stored as usual")
+	}
+
+	s, ok := table.ArenaStats()
+	if !ok || s.Slabs != 2 || s.Values != 2 || s.LiveBytes != 20 {
+		t.Error("Expected two values in two slabs, got", s)
+	}
+
+	item, release, _ := table.Acquire("a")
+	table.Delete("a")
+	table.Delete("b")
+	if string(item.Data().([]byte)) != "0123456789" {
+		t.Error("Expected acquired values to stay readable")
+	}
+	release()
+	if item.Data() != nil {
+		t.Error("Expected released values to be freed")
+	}
+
+	table.Add("c", 0, []byte("0123456789"))
+	if s, _ := table.ArenaStats(); s.Slabs != 2 || s.Values != 1 || s.LiveBytes != 10 {
+		t.Error("Expected empty slabs to be reused, got", s)
+	}
+
+	if _, ok := Cache("testByteArenaDisabled").ArenaStats(); ok {
+		t.Error("Expected no arena stats without an arena")
+	}
+}
@@ -0,0 +1,155 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Operations recorded in an audit log.
+const (
+	auditOpAdd    = "add"
+	auditOpDelete = "delete"
+	auditOpFlush  = "flush"
+)
+
+// AuditRecord is a line of an audit log.
+type AuditRecord struct {
+	Time  time.Time `json:"time"`
+	Table string    `json:"table"`
+	// One of "add", "delete" or "flush".
+	Op string `json:"op"`
+	// The item's key, empty for flushes.
+	Key string `json:"key,omitempty"`
+	// Who performed the operation, see WithAuditActor.
+	Actor string `json:"actor,omitempty"`
+}
+
+// auditLog writes a table's audit records.
+type auditLog struct {
+	sync.Mutex
+
+	// Where the records get written to, nil for the table's logger.
+	w io.Writer
+}
+
+// auditActorKey is the context key of the audit actor.
+type auditActorKey struct{}
+
+// WithAuditActor returns a context recording the given actor, e.g. a user
+// name, in the audit log when passed to AddContext, DeleteContext or
+// FlushContext.
+func WithAuditActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// AuditActor returns the actor stored in the context, if any.
+func AuditActor(ctx context.Context) string {
+	actor, _ := ctx.Value(auditActorKey{}).(string)
+	return actor
+}
+
+// EnableAuditLog records who added, deleted or flushed what and when, as JSON
+// lines written to w. If w is nil, the records get written through the
+// table's logger instead. Items expiring or getting evicted aren't recorded,
+// as nobody removed them.
+func (table *CacheTable) EnableAuditLog(w io.Writer) {
+	table.Lock()
+	defer table.Unlock()
+	table.auditLog = &auditLog{w: w}
+}
+
+// DisableAuditLog stops recording mutations.
+func (table *CacheTable) DisableAuditLog() {
+	table.Lock()
+	defer table.Unlock()
+	table.auditLog = nil
+}
+
+// AddContext adds a key/value pair to the cache, just like Add, recording the
+// context's actor in the audit log.
+func (table *CacheTable) AddContext(ctx context.Context, key interface{}, lifeSpan time.Duration, data interface{}) *CacheItem {
+	if h := table.loadOpHook(); h != nil {
+		defer h.observe(OpAdd, key, time.Now())
+	}
+	item := table.newItem(key, lifeSpan, data)
+
+	table.Lock()
+	table.addInternal(item)
+
+	table.audit(ctx, auditOpAdd, key)
+	return item
+}
+
+// DeleteContext deletes an item from the cache, just like Delete, recording
+// the context's actor in the audit log.
+func (table *CacheTable) DeleteContext(ctx context.Context, key interface{}) (*CacheItem, error) {
+	table.touch()
+	if h := table.loadOpHook(); h != nil {
+		defer h.observe(OpDelete, key, time.Now())
+	}
+	table.Lock()
+	r, err := table.deleteTombstoned(key, 0)
+	table.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	table.stats.delete()
+	table.audit(ctx, auditOpDelete, key)
+	return r, nil
+}
+
+// FlushContext deletes all items from the cache, just like Flush, recording
+// the context's actor in the audit log.
+func (table *CacheTable) FlushContext(ctx context.Context) {
+	table.touch()
+	table.flush()
+	table.audit(ctx, auditOpFlush, nil)
+}
+
+// audit records an operation in the audit log, if enabled.
+// Careful: do not run this method while holding the table-mutex!
+func (table *CacheTable) audit(ctx context.Context, op string, key interface{}) {
+	table.RLock()
+	a := table.auditLog
+	logger := table.logger
+	table.RUnlock()
+	if a == nil || (a.w == nil && logger == nil) {
+		return
+	}
+
+	record := AuditRecord{
+		Time:  time.Now(),
+		Table: table.name,
+		Op:    op,
+		Actor: AuditActor(ctx),
+	}
+	if key != nil {
+		record.Key = fmt.Sprint(key)
+	}
+	b, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+
+	if a.w == nil {
+		logger.Println(string(b))
+		return
+	}
+	a.Lock()
+	defer a.Unlock()
+	if _, err := a.w.Write(append(b, '\n')); err != nil {
+		table.log("Error writing audit log of table", table.name, ":", err)
+	}
+}
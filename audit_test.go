@@ -0,0 +1,80 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	var buf bytes.Buffer
+	table := Cache("testAuditLog")
+	table.EnableAuditLog(&buf)
+
+	ctx := WithAuditActor(context.Background(), "alice")
+	table.AddContext(ctx, k, 0, v)
+	table.Add("anonymous", 0, v)
+	table.DeleteContext(ctx, k)
+	if _, err := table.DeleteContext(ctx, "missing"); err != ErrKeyNotFound {
+		t.Error("Expected ErrKeyNotFound, got", err)
+	}
+	table.FlushContext(ctx)
+
+	var records []AuditRecord
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var r AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatal("Error decoding audit record", err)
+		}
+		records = append(records, r)
+	}
+
+	expected := []AuditRecord{
+		{Op: "add", Key: k, Actor: "alice"},
+		{Op: "add", Key: "anonymous"},
+		{Op: "delete", Key: k, Actor: "alice"},
+		{Op: "flush", Actor: "alice"},
+	}
+	if len(records) != len(expected) {
+		t.Fatal("Expected", len(expected), "audit records, got", records)
+	}
+	for i, r := range records {
+		e := expected[i]
+		if r.Op != e.Op || r.Key != e.Key || r.Actor != e.Actor || r.Table != "testAuditLog" || r.Time.IsZero() {
+			t.Errorf("Expected audit record %+v, got %+v", e, r)
+		}
+	}
+
+	table.DisableAuditLog()
+	buf.Reset()
+	table.Add(k, 0, v)
+	if buf.Len() != 0 {
+		t.Error("Disabled audit log should not record anything")
+	}
+}
+
+func TestAuditLogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	table := Cache("testAuditLogLogger")
+	table.EnableAuditLog(nil)
+	table.Add(k, 0, v)
+
+	table.SetLogger(log.New(&buf, "", 0))
+	table.EnableAuditLog(nil)
+	table.Delete(k)
+	if !strings.Contains(buf.String(), `"op":"delete"`) {
+		t.Error("Audit record should be written through the logger, got", buf.String())
+	}
+}
@@ -0,0 +1,267 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// SnapshotInfo describes a snapshot taken by EnableAutoSnapshot.
+type SnapshotInfo struct {
+	// Whether all items got saved, or just the changes since the last full
+	// snapshot.
+	Full bool
+	// Number of items, or changes, saved.
+	Items int
+	// Size of the snapshot in bytes.
+	Size int
+	// How long taking and saving the snapshot took.
+	Duration time.Duration
+	// The error that occurred while saving the snapshot, if any.
+	Err error
+}
+
+// autoSnapshot tracks the changes to a table since its last full snapshot.
+// Guarded by the table-mutex.
+type autoSnapshot struct {
+	sink SnapshotSink
+	// Keys changed since the last full snapshot.
+	dirty map[interface{}]struct{}
+	// Whether the table got flushed since the last full snapshot.
+	flushed bool
+	// Whether anything changed since the last snapshot.
+	changed bool
+	// Whether the next snapshot has to be a full one.
+	full bool
+
+	stop chan struct{}
+}
+
+// deltaName returns the name the changes since a table's last full snapshot
+// get saved under.
+func deltaName(name string) string {
+	return name + ".delta"
+}
+
+// EnableAutoSnapshot periodically saves snapshots of the table to the sink in
+// the background. To limit IO, only the first snapshot contains all items:
+// later ones only contain the changes since then, until enough items changed
+// to make a full snapshot worthwhile again. Nothing gets saved while the
+// table doesn't change. RestoreSnapshot restores both kinds of snapshots.
+func (table *CacheTable) EnableAutoSnapshot(interval time.Duration, sink SnapshotSink) {
+	table.DisableAutoSnapshot()
+
+	s := &autoSnapshot{
+		sink:    sink,
+		dirty:   make(map[interface{}]struct{}),
+		changed: true,
+		full:    true,
+		stop:    make(chan struct{}),
+	}
+	table.Lock()
+	table.autoSnapshot = s
+	table.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				table.snapshot(s)
+			}
+		}
+	}()
+}
+
+// DisableAutoSnapshot stops saving snapshots of the table.
+func (table *CacheTable) DisableAutoSnapshot() {
+	table.Lock()
+	s := table.autoSnapshot
+	table.autoSnapshot = nil
+	table.Unlock()
+
+	if s != nil {
+		close(s.stop)
+	}
+}
+
+// SetSnapshotCallback configures a callback, which will be called every time
+// a snapshot got saved by EnableAutoSnapshot.
+func (table *CacheTable) SetSnapshotCallback(f func(SnapshotInfo)) {
+	if len(table.snapshotted) > 0 {
+		table.RemoveSnapshotCallbacks()
+	}
+	table.Lock()
+	defer table.Unlock()
+	table.snapshotted = append(table.snapshotted, f)
+}
+
+// AddSnapshotCallback appends a new callback to the snapshotted queue
+func (table *CacheTable) AddSnapshotCallback(f func(SnapshotInfo)) {
+	table.Lock()
+	defer table.Unlock()
+	table.snapshotted = append(table.snapshotted, f)
+}
+
+// RemoveSnapshotCallbacks empties the snapshotted callback queue
+func (table *CacheTable) RemoveSnapshotCallbacks() {
+	table.Lock()
+	defer table.Unlock()
+	table.snapshotted = nil
+}
+
+// trackChange records a change to the given key for the next snapshot.
+// Careful: do not run this method unless the table-mutex is locked!
+func (table *CacheTable) trackChange(key interface{}) {
+	s := table.autoSnapshot
+	if s == nil {
+		return
+	}
+	s.changed = true
+	s.dirty[key] = struct{}{}
+}
+
+// trackFlush records a flush for the next snapshot.
+// Careful: do not run this method unless the table-mutex is locked!
+func (table *CacheTable) trackFlush() {
+	s := table.autoSnapshot
+	if s == nil {
+		return
+	}
+	s.changed = true
+	s.flushed = true
+	s.dirty = make(map[interface{}]struct{})
+}
+
+// snapshot saves a full or incremental snapshot, if anything changed.
+func (table *CacheTable) snapshot(s *autoSnapshot) {
+	start := time.Now()
+
+	table.Lock()
+	if table.autoSnapshot != s || !s.changed {
+		table.Unlock()
+		return
+	}
+	// Full snapshots get worthwhile once the changes make up a good share
+	// of the table.
+	full := s.full || len(s.dirty) > len(table.items)/2
+	var delta []logRecord
+	var err error
+	if !full {
+		if s.flushed {
+			delta = append(delta, logRecord{Op: logOpFlush})
+		}
+		for key := range s.dirty {
+			rec := logRecord{Op: logOpDelete}
+			if item, ok := table.items[key]; ok && item.Computed() {
+				rec.Op = logOpSet
+				rec.exportedItem, err = exportItem(item, table.exportTransformer)
+			} else {
+				rec.Key, err = json.Marshal(key)
+				rec.Value = json.RawMessage("null")
+			}
+			if err != nil {
+				break
+			}
+			delta = append(delta, rec)
+		}
+	}
+	s.changed = false
+	if full {
+		s.dirty = make(map[interface{}]struct{})
+		s.flushed = false
+		s.full = false
+	}
+	snapshotted := table.snapshotted
+	table.Unlock()
+
+	info := SnapshotInfo{Full: full}
+	var buf bytes.Buffer
+	if err == nil && full {
+		// Clear the changes first: a crash in between leaves a consistent,
+		// if older, snapshot behind.
+		if err = table.saveDelta(s.sink, nil, &buf); err == nil {
+			buf.Reset()
+			err = table.Export(&buf)
+			info.Items = bytes.Count(buf.Bytes(), []byte("\n")) - 1
+			info.Size = buf.Len()
+			if err == nil {
+				err = s.sink.Save(table.name, &buf)
+			}
+		}
+	} else if err == nil {
+		err = table.saveDelta(s.sink, delta, &buf)
+		info.Items = len(delta)
+		info.Size = buf.Len()
+	}
+
+	if err != nil {
+		// Start over, the saved state is unknown.
+		table.Lock()
+		s.full = true
+		s.changed = true
+		table.Unlock()
+		table.log("Error saving snapshot of table", table.name, ":", err)
+	}
+	info.Duration = time.Since(start)
+	info.Err = err
+
+	for _, callback := range snapshotted {
+		callback(info)
+	}
+}
+
+// saveDelta saves the changes since the last full snapshot.
+func (table *CacheTable) saveDelta(sink SnapshotSink, delta []logRecord, buf *bytes.Buffer) error {
+	enc := json.NewEncoder(buf)
+	if err := enc.Encode(exportHeader{Format: exportFormat, Version: exportVersion, Table: table.name, Delta: true}); err != nil {
+		return err
+	}
+	for _, rec := range delta {
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+
+	return sink.Save(deltaName(table.name), bytes.NewReader(buf.Bytes()))
+}
+
+// applyDelta applies the changes saved by saveDelta.
+func (table *CacheTable) applyDelta(r io.Reader, decode ImportDecoder) error {
+	if decode == nil {
+		decode = decodeJSON
+	}
+
+	dec := json.NewDecoder(r)
+	var header exportHeader
+	if err := dec.Decode(&header); err != nil {
+		return err
+	}
+	if header.Format != exportFormat || header.Version < 1 || header.Version > exportVersion || !header.Delta {
+		return ErrUnsupportedExport
+	}
+
+	now := time.Now()
+	for {
+		var rec logRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := table.applyRecord(rec, decode, now); err != nil {
+			return err
+		}
+	}
+}
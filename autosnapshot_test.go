@@ -0,0 +1,70 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAutoSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache2go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sink := NewFileSink(dir)
+
+	table := Cache("testAutoSnapshot")
+	infos := make(chan SnapshotInfo, 10)
+	table.SetSnapshotCallback(func(info SnapshotInfo) {
+		infos <- info
+	})
+	for i := 0; i < 10; i++ {
+		table.Add(i, 0, "value")
+	}
+	table.EnableAutoSnapshot(20*time.Millisecond, sink)
+
+	info := <-infos
+	if !info.Full || info.Items != 10 || info.Size == 0 || info.Err != nil {
+		t.Errorf("Expected a full snapshot of 10 items, got %+v", info)
+	}
+
+	// Small changes only get saved incrementally
+	table.Add(1, 0, "changed")
+	table.Delete(2)
+	info = <-infos
+	if info.Full || info.Items != 2 || info.Err != nil {
+		t.Errorf("Expected a delta of 2 changes, got %+v", info)
+	}
+
+	// Nothing gets saved without changes
+	select {
+	case info = <-infos:
+		t.Errorf("Unexpected snapshot %+v", info)
+	case <-time.After(60 * time.Millisecond):
+	}
+
+	table.DisableAutoSnapshot()
+	table.Flush()
+	if n, err := table.RestoreSnapshot(sink, func(key, value json.RawMessage) (interface{}, interface{}, error) {
+		k, v, err := decodeJSON(key, value)
+		return int(k.(float64)), v, err
+	}); err != nil || n != 10 {
+		t.Error("Error restoring snapshot", n, err)
+	}
+	if table.Count() != 9 || table.Exists(2) {
+		t.Error("Deleted item should be gone after restoring, items:", table.Count())
+	}
+	if p, err := table.Value(1); err != nil || p.Data() != "changed" {
+		t.Error("Changed item should be restored", p, err)
+	}
+}
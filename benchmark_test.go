@@ -8,6 +8,7 @@
 package cache2go
 
 import (
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -47,3 +48,100 @@ func BenchmarkNotFoundAdd(b *testing.B) {
 	finish.Wait()
 
 }
+
+// lockedValue looks up an item the way Value did before hits were served
+// with a read lock and atomics: the item's mutex guarded its access
+// metadata, every operation counted towards a shared counter, and hits
+// reported to the eviction policy under its mutex.
+func lockedValue(table *CacheTable, ops *uint64, key interface{}) (*CacheItem, error) {
+	atomic.AddUint64(ops, 1)
+	table.RLock()
+	r, ok := table.items[key]
+	table.RUnlock()
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	r.Lock()
+	r.accessedOn = time.Now().UnixNano()
+	r.accessCount++
+	r.Unlock()
+	if table.policy != nil {
+		table.policyMutex.Lock()
+		table.policyMutex.Unlock()
+	}
+	return r, nil
+}
+
+// benchmarkValueContention looks up keys from the given number of goroutines
+// at once. With hotKey, all goroutines look up the same key. With locked,
+// they look them up the way Value used to, see lockedValue.
+func benchmarkValueContention(b *testing.B, table *CacheTable, goroutines int, hotKey, locked bool) {
+	const keys = 1024
+	for i := 0; i < keys; i++ {
+		table.Add(i, 0, i)
+	}
+
+	var ops uint64
+	var finish sync.WaitGroup
+	finish.Add(goroutines)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for g := 0; g < goroutines; g++ {
+		go func(g int) {
+			defer finish.Done()
+			for i := g; i < b.N; i += goroutines {
+				key := i % keys
+				if hotKey {
+					key = 0
+				}
+				var err error
+				if locked {
+					_, err = lockedValue(table, &ops, key)
+				} else {
+					_, err = table.Value(key)
+				}
+				if err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		}(g)
+	}
+	finish.Wait()
+}
+
+// BenchmarkValueContention measures lookups contending for a table, both
+// via Value and the way Value used to look items up, see lockedValue. The
+// goroutines only run in parallel given enough CPUs, so compare the
+// "value" and "locked" results on a multi-core machine, e.g. with -cpu=32:
+// at 32 goroutines, Value is meant to reach at least three times the
+// throughput.
+func BenchmarkValueContention(b *testing.B) {
+	for _, shape := range []string{"value", "locked"} {
+		locked := shape == "locked"
+		for _, goroutines := range []int{1, 8, 32} {
+			goroutines := goroutines
+			name := shape + "/goroutines=" + strconv.Itoa(goroutines)
+			b.Run(name, func(b *testing.B) {
+				benchmarkValueContention(b, Cache("benchmarkValueContention"), goroutines, false, locked)
+			})
+			b.Run(name+"/hot", func(b *testing.B) {
+				benchmarkValueContention(b, Cache("benchmarkValueContention"), goroutines, true, locked)
+			})
+			b.Run(name+"/capacity", func(b *testing.B) {
+				table, _ := CacheWithOptions("benchmarkValueContentionCapacity", WithCapacity(2048))
+				benchmarkValueContention(b, table, goroutines, false, locked)
+			})
+		}
+	}
+}
+
+func BenchmarkKeepAlive(b *testing.B) {
+	item := NewCacheItem("key", time.Minute, "value")
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			item.KeepAlive()
+		}
+	})
+}
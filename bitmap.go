@@ -0,0 +1,199 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// BitOperation combines bitmaps, see CacheTable.BitOp.
+type BitOperation int
+
+const (
+	// BitAnd sets the bits set in all bitmaps.
+	BitAnd BitOperation = iota
+	// BitOr sets the bits set in any bitmap.
+	BitOr
+	// BitXor sets the bits set in an odd number of bitmaps.
+	BitXor
+)
+
+// bitmap is the data of a bitmap item. It grows as needed.
+type bitmap struct {
+	sync.RWMutex
+
+	words []uint64
+}
+
+// MarshalJSON exports a bitmap as the offsets of its set bits, e.g. for
+// Export.
+func (b *bitmap) MarshalJSON() ([]byte, error) {
+	b.RLock()
+	defer b.RUnlock()
+
+	offsets := []uint{}
+	for i, w := range b.words {
+		for bit := uint(0); w != 0; bit++ {
+			if w&1 != 0 {
+				offsets = append(offsets, uint(i)*64+bit)
+			}
+			w >>= 1
+		}
+	}
+	return json.Marshal(offsets)
+}
+
+// AddBitmap adds an empty bitmap to the cache, replacing any existing item
+// with the same key. Bitmaps are compact for dense offsets, e.g. user IDs
+// that were active today. Parameter lifeSpan works just like for Add; setting
+// bits counts as an access.
+//
+// Bitmaps get modified in place, so setting bits isn't recorded in mutation
+// logs and automatic snapshots.
+func (table *CacheTable) AddBitmap(key interface{}, lifeSpan time.Duration) *CacheItem {
+	return table.Add(key, lifeSpan, &bitmap{})
+}
+
+// SetBit sets or clears the bit at the given offset of a bitmap and returns
+// its previous value. If the key doesn't exist, a bitmap with the table's
+// default lifespan gets added, see AddBitmap.
+func (table *CacheTable) SetBit(key interface{}, offset uint, value bool) (bool, error) {
+	item := table.valueOrAdd(key, func() interface{} {
+		return &bitmap{}
+	})
+
+	b, ok := item.Data().(*bitmap)
+	if !ok {
+		return false, ErrNotABitmap
+	}
+
+	b.Lock()
+	defer b.Unlock()
+	word, mask := offset/64, uint64(1)<<(offset%64)
+	if word >= uint(len(b.words)) {
+		if !value {
+			return false, nil
+		}
+		words := make([]uint64, word+1)
+		copy(words, b.words)
+		b.words = words
+	}
+
+	previous := b.words[word]&mask != 0
+	if value {
+		b.words[word] |= mask
+	} else {
+		b.words[word] &^= mask
+	}
+	return previous, nil
+}
+
+// GetBit returns the bit at the given offset of a bitmap. Missing keys count
+// as empty bitmaps. The lookup works just like Value, including the
+// data-loader.
+func (table *CacheTable) GetBit(key interface{}, offset uint) (bool, error) {
+	words, err := table.bitmapWords(key)
+	if err == ErrKeyNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	word := offset / 64
+	return word < uint(len(words)) && words[word]&(1<<(offset%64)) != 0, nil
+}
+
+// BitCount returns the number of bits set in a bitmap. Missing keys count as
+// empty bitmaps. The lookup works just like Value, including the data-loader.
+func (table *CacheTable) BitCount(key interface{}) (int, error) {
+	words, err := table.bitmapWords(key)
+	if err == ErrKeyNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, w := range words {
+		for ; w != 0; w &= w - 1 {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// BitOp combines the bitmaps with the given keys and stores the result in
+// the bitmap with the destination key, which gets added like by SetBit if it
+// doesn't exist. Missing source keys count as empty bitmaps.
+func (table *CacheTable) BitOp(op BitOperation, dest interface{}, keys ...interface{}) error {
+	var result []uint64
+	for i, key := range keys {
+		words, err := table.bitmapWords(key)
+		if err != nil && err != ErrKeyNotFound {
+			return err
+		}
+		if len(words) > len(result) {
+			grown := make([]uint64, len(words))
+			copy(grown, result)
+			result = grown
+		}
+
+		for j := range result {
+			var w uint64
+			if j < len(words) {
+				w = words[j]
+			}
+			switch {
+			case i == 0:
+				result[j] = w
+			case op == BitAnd:
+				result[j] &= w
+			case op == BitOr:
+				result[j] |= w
+			case op == BitXor:
+				result[j] ^= w
+			}
+		}
+	}
+
+	item := table.valueOrAdd(dest, func() interface{} {
+		return &bitmap{}
+	})
+	b, ok := item.Data().(*bitmap)
+	if !ok {
+		return ErrNotABitmap
+	}
+
+	b.Lock()
+	defer b.Unlock()
+	b.words = result
+	return nil
+}
+
+// bitmapWords looks up a bitmap via Value and returns a copy of its words, so
+// combining bitmaps never needs to hold two bitmaps' mutexes.
+func (table *CacheTable) bitmapWords(key interface{}) ([]uint64, error) {
+	item, err := table.Value(key)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := item.Data().(*bitmap)
+	if !ok {
+		return nil, ErrNotABitmap
+	}
+
+	b.RLock()
+	defer b.RUnlock()
+	words := make([]uint64, len(b.words))
+	copy(words, b.words)
+	return words, nil
+}
@@ -0,0 +1,71 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBitmap(t *testing.T) {
+	table := Cache("testBitmap")
+
+	if previous, err := table.SetBit("monday", 3, true); previous || err != nil {
+		t.Error("Unexpected previous bit", err)
+	}
+	table.SetBit("monday", 100, true)
+	if previous, _ := table.SetBit("monday", 3, true); !previous {
+		t.Error("Expected previous bit to be set")
+	}
+	table.SetBit("monday", 1000, false)
+
+	if ok, _ := table.GetBit("monday", 100); !ok {
+		t.Error("Expected bit 100 to be set")
+	}
+	if ok, _ := table.GetBit("monday", 4); ok {
+		t.Error("Expected bit 4 not to be set")
+	}
+	if ok, err := table.GetBit("missing", 4); ok || err != nil {
+		t.Error("Missing bitmaps should be empty", err)
+	}
+	if n, _ := table.BitCount("monday"); n != 2 {
+		t.Error("Expected 2 bits, got", n)
+	}
+
+	table.AddBitmap("tuesday", 0)
+	table.SetBit("tuesday", 3, true)
+	table.SetBit("tuesday", 200, true)
+
+	for _, c := range []struct {
+		op       BitOperation
+		expected int
+	}{{BitAnd, 1}, {BitOr, 3}, {BitXor, 2}} {
+		if err := table.BitOp(c.op, "result", "monday", "tuesday"); err != nil {
+			t.Error("Error combining bitmaps", err)
+		}
+		if n, _ := table.BitCount("result"); n != c.expected {
+			t.Error("Expected", c.expected, "bits for operation", c.op, "got", n)
+		}
+	}
+	table.BitOp(BitAnd, "result", "monday", "missing")
+	if n, _ := table.BitCount("result"); n != 0 {
+		t.Error("Missing bitmaps should be empty, got", n)
+	}
+
+	var buf bytes.Buffer
+	table.Export(&buf)
+	if !strings.Contains(buf.String(), `[3,100]`) {
+		t.Error("Bitmaps should get exported as their set bits, got", buf.String())
+	}
+
+	table.Add(k, 0, v)
+	if _, err := table.SetBit(k, 1, true); err != ErrNotABitmap {
+		t.Error("Expected ErrNotABitmap, got", err)
+	}
+}
@@ -0,0 +1,358 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BoundedCache is a cache holding a limited number of items, which uses an
+// EvictionPolicy to decide which item to drop when it is full.
+type BoundedCache struct {
+	sync.RWMutex
+
+	// The cache's name.
+	name string
+	// Maximum capacity of the cache.
+	capacity int
+	// All cached items.
+	items map[interface{}]*CacheItem
+	// The policy choosing eviction victims.
+	policy EvictionPolicy
+
+	// Usage statistics.
+	stats statsCounter
+
+	// The logger used for this cache.
+	logger *log.Logger
+
+	// Callback method triggered when trying to load a non-existing key.
+	loadData func(key interface{}, args ...interface{}) *CacheItem
+	// Callback method triggered when adding a new item to the cache.
+	addedItem []func(item *CacheItem)
+	// Callback method triggered before deleting an item from the cache.
+	aboutToDeleteItem []func(item *CacheItem)
+}
+
+// NewBoundedCache creates a new cache with the specified capacity, evicting
+// items as decided by the given policy.
+func NewBoundedCache(name string, capacity int, policy EvictionPolicy) *BoundedCache {
+	return &BoundedCache{
+		name:     name,
+		capacity: capacity,
+		items:    make(map[interface{}]*CacheItem),
+		policy:   policy,
+	}
+}
+
+// evict removes items chosen by the policy until there's room for a new one.
+// Careful: do not run this method unless the cache-mutex is locked!
+func (cache *BoundedCache) evict() {
+	for len(cache.items) >= cache.capacity {
+		key, ok := cache.policy.Evict()
+		if !ok {
+			return
+		}
+		item, exists := cache.items[key]
+		if !exists {
+			continue
+		}
+
+		for _, callback := range cache.aboutToDeleteItem {
+			callback(item)
+		}
+		delete(cache.items, key)
+
+		cache.stats.evict()
+		cache.log("Evicted item with key", key, "from bounded cache", cache.name)
+	}
+}
+
+// insert stores a new item, evicting others if necessary.
+// Careful: do not run this method unless the cache-mutex is locked!
+func (cache *BoundedCache) insert(item *CacheItem) {
+	cache.evict()
+	cache.items[item.key] = item
+	cache.policy.Add(item)
+	cache.stats.add()
+
+	cache.log("Adding item with key", item.key, "to bounded cache", cache.name)
+
+	for _, callback := range cache.addedItem {
+		callback(item)
+	}
+}
+
+// Add adds a key/value pair to the cache. The item expires once it wasn't
+// accessed for the given lifespan, 0 meaning never. Adding an existing key
+// replaces its item and counts as an access.
+func (cache *BoundedCache) Add(key interface{}, lifeSpan time.Duration, data interface{}) *CacheItem {
+	item := NewCacheItem(key, lifeSpan, data)
+
+	cache.Lock()
+	defer cache.Unlock()
+
+	if _, exists := cache.items[key]; exists {
+		// Replace the item instead of changing it, as its data and lifespan
+		// get read without locking.
+		cache.items[key] = item
+		cache.policy.Access(item)
+		cache.stats.add()
+		return item
+	}
+	cache.insert(item)
+
+	return item
+}
+
+// expireInternal removes an item that exceeded its lifespan.
+// Careful: do not run this method unless the cache-mutex is locked!
+func (cache *BoundedCache) expireInternal(item *CacheItem) {
+	for _, callback := range cache.aboutToDeleteItem {
+		callback(item)
+	}
+	cache.policy.Remove(item)
+	delete(cache.items, item.key)
+
+	cache.stats.expire()
+	cache.log("Expired item with key", item.key, "from bounded cache", cache.name)
+}
+
+// Value returns an item from the cache and reports the access to the policy.
+// You can pass additional arguments to your DataLoader callback function.
+func (cache *BoundedCache) Value(key interface{}, args ...interface{}) (*CacheItem, error) {
+	cache.Lock()
+	defer cache.Unlock()
+
+	if item, exists := cache.items[key]; exists {
+		if !item.expired(time.Now()) {
+			item.KeepAlive()
+			cache.policy.Access(item)
+			cache.stats.hit()
+			return item, nil
+		}
+		cache.expireInternal(item)
+	}
+	cache.stats.miss()
+
+	if cache.loadData != nil {
+		loadData := cache.loadData
+		cache.Unlock()
+		item := loadData(key, args...)
+		cache.Lock()
+		if item == nil {
+			return nil, ErrKeyNotFoundOrLoadable
+		}
+
+		// Another caller may have added the key while we were unlocked.
+		if existing, exists := cache.items[key]; exists {
+			cache.policy.Access(existing)
+			return existing, nil
+		}
+
+		// Policies track items by their key, so make sure it's the one the
+		// item is stored under.
+		item.key = key
+		cache.insert(item)
+
+		return item, nil
+	}
+
+	return nil, ErrKeyNotFound
+}
+
+// Delete removes an item from the cache.
+func (cache *BoundedCache) Delete(key interface{}) (*CacheItem, error) {
+	cache.Lock()
+	defer cache.Unlock()
+
+	item, exists := cache.items[key]
+	if !exists {
+		return nil, ErrKeyNotFound
+	}
+
+	for _, callback := range cache.aboutToDeleteItem {
+		callback(item)
+	}
+	cache.policy.Remove(item)
+	delete(cache.items, key)
+
+	cache.stats.delete()
+	cache.log("Deleted item with key", key, "from bounded cache", cache.name)
+	return item, nil
+}
+
+// Exists returns whether an unexpired item exists in the cache, without
+// reporting an access to the policy.
+func (cache *BoundedCache) Exists(key interface{}) bool {
+	cache.RLock()
+	defer cache.RUnlock()
+	item, exists := cache.items[key]
+	return exists && !item.expired(time.Now())
+}
+
+// Count returns how many unexpired items are currently stored in the cache.
+func (cache *BoundedCache) Count() int {
+	cache.RLock()
+	defer cache.RUnlock()
+
+	now := time.Now()
+	count := 0
+	for _, item := range cache.items {
+		if !item.expired(now) {
+			count++
+		}
+	}
+	return count
+}
+
+// Stats returns the usage statistics of the cache.
+func (cache *BoundedCache) Stats() CacheStats {
+	return cache.stats.snapshot()
+}
+
+// Capacity returns the maximum capacity of the cache.
+func (cache *BoundedCache) Capacity() int {
+	return cache.capacity
+}
+
+// Flush removes all items from the cache.
+func (cache *BoundedCache) Flush() {
+	cache.Lock()
+	defer cache.Unlock()
+
+	cache.log("Flushing bounded cache", cache.name)
+
+	for _, item := range cache.items {
+		for _, callback := range cache.aboutToDeleteItem {
+			callback(item)
+		}
+	}
+
+	cache.items = make(map[interface{}]*CacheItem)
+	cache.policy.Reset()
+}
+
+// Foreach iterates over all unexpired items in the cache.
+func (cache *BoundedCache) Foreach(trans func(key interface{}, item *CacheItem)) {
+	cache.RLock()
+	defer cache.RUnlock()
+
+	now := time.Now()
+	for k, v := range cache.items {
+		if !v.expired(now) {
+			trans(k, v)
+		}
+	}
+}
+
+// cacheItemsByAccess sorts items by their last access, least recent first.
+type cacheItemsByAccess []*CacheItem
+
+func (p cacheItemsByAccess) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p cacheItemsByAccess) Len() int           { return len(p) }
+func (p cacheItemsByAccess) Less(i, j int) bool { return p[i].AccessedOn().Before(p[j].AccessedOn()) }
+
+// SetPolicy replaces the cache's eviction policy at runtime. All cached items
+// get migrated into the new policy's bookkeeping, least recently accessed
+// first, so recency-based policies start out with a sensible order.
+func (cache *BoundedCache) SetPolicy(policy EvictionPolicy) {
+	cache.Lock()
+	defer cache.Unlock()
+
+	items := make(cacheItemsByAccess, 0, len(cache.items))
+	for _, item := range cache.items {
+		items = append(items, item)
+	}
+	sort.Sort(items)
+
+	policy.Reset()
+	for _, item := range items {
+		policy.Add(item)
+	}
+	cache.policy = policy
+
+	cache.log("Switched eviction policy of bounded cache", cache.name, "with", len(items), "items")
+}
+
+// SetDataLoader configures a data-loader callback, which will be called when
+// trying to access a non-existing key.
+func (cache *BoundedCache) SetDataLoader(f func(interface{}, ...interface{}) *CacheItem) {
+	cache.Lock()
+	defer cache.Unlock()
+	cache.loadData = f
+}
+
+// SetAddedItemCallback configures a callback, which will be called every time
+// a new item is added to the cache.
+func (cache *BoundedCache) SetAddedItemCallback(f func(*CacheItem)) {
+	if len(cache.addedItem) > 0 {
+		cache.RemoveAddedItemCallbacks()
+	}
+	cache.Lock()
+	defer cache.Unlock()
+	cache.addedItem = append(cache.addedItem, f)
+}
+
+// AddAddedItemCallback appends a new callback to the addedItem queue
+func (cache *BoundedCache) AddAddedItemCallback(f func(*CacheItem)) {
+	cache.Lock()
+	defer cache.Unlock()
+	cache.addedItem = append(cache.addedItem, f)
+}
+
+// RemoveAddedItemCallbacks empties the added item callback queue
+func (cache *BoundedCache) RemoveAddedItemCallbacks() {
+	cache.Lock()
+	defer cache.Unlock()
+	cache.addedItem = nil
+}
+
+// SetAboutToDeleteItemCallback configures a callback, which will be called
+// every time an item is about to be removed from the cache.
+func (cache *BoundedCache) SetAboutToDeleteItemCallback(f func(*CacheItem)) {
+	if len(cache.aboutToDeleteItem) > 0 {
+		cache.RemoveAboutToDeleteItemCallback()
+	}
+	cache.Lock()
+	defer cache.Unlock()
+	cache.aboutToDeleteItem = append(cache.aboutToDeleteItem, f)
+}
+
+// AddAboutToDeleteItemCallback appends a new callback to the AboutToDeleteItem queue
+func (cache *BoundedCache) AddAboutToDeleteItemCallback(f func(*CacheItem)) {
+	cache.Lock()
+	defer cache.Unlock()
+	cache.aboutToDeleteItem = append(cache.aboutToDeleteItem, f)
+}
+
+// RemoveAboutToDeleteItemCallback empties the about to delete item callback queue
+func (cache *BoundedCache) RemoveAboutToDeleteItemCallback() {
+	cache.Lock()
+	defer cache.Unlock()
+	cache.aboutToDeleteItem = nil
+}
+
+// SetLogger sets the logger to be used by this cache.
+func (cache *BoundedCache) SetLogger(logger *log.Logger) {
+	cache.Lock()
+	defer cache.Unlock()
+	cache.logger = logger
+}
+
+// Internal logging method for convenience.
+func (cache *BoundedCache) log(v ...interface{}) {
+	if cache.logger == nil {
+		return
+	}
+
+	cache.logger.Println(v...)
+}
@@ -0,0 +1,153 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBoundedCacheBasicOperations(t *testing.T) {
+	cache := NewBoundedCache("testBounded", 2, NewSLRUPolicy(2, 0.5))
+
+	added := 0
+	deleted := 0
+	cache.SetAddedItemCallback(func(item *CacheItem) {
+		added++
+	})
+	cache.SetAboutToDeleteItemCallback(func(item *CacheItem) {
+		deleted++
+	})
+
+	cache.Add("key1", 0, "value1")
+	cache.Add("key2", 0, "value2")
+	cache.Add("key3", 0, "value3")
+
+	if cache.Count() != 2 {
+		t.Error("Cache should never hold more items than its capacity")
+	}
+	if added != 3 || deleted != 1 {
+		t.Error("Callbacks not triggered correctly", added, deleted)
+	}
+
+	p, err := cache.Value("key3")
+	if err != nil || p.Data().(string) != "value3" {
+		t.Error("Error retrieving data from bounded cache", err)
+	}
+
+	if _, err = cache.Delete("key3"); err != nil {
+		t.Error("Error deleting data from bounded cache", err)
+	}
+	if cache.Exists("key3") {
+		t.Error("Item should not exist after deletion")
+	}
+	if _, err = cache.Delete("key3"); err != ErrKeyNotFound {
+		t.Error("Expected ErrKeyNotFound deleting a missing key")
+	}
+
+	cache.Flush()
+	if cache.Count() != 0 {
+		t.Error("Cache should be empty after flush")
+	}
+}
+
+func TestBoundedCacheDataLoader(t *testing.T) {
+	cache := NewBoundedCache("testBoundedDataLoader", 1, NewSLRUPolicy(1, 0.5))
+	cache.SetDataLoader(func(key interface{}, args ...interface{}) *CacheItem {
+		if key.(string) == "loadable" {
+			return NewCacheItem(key, 0, "loaded_value")
+		}
+		return nil
+	})
+
+	p, err := cache.Value("loadable")
+	if err != nil || p.Data().(string) != "loaded_value" {
+		t.Error("Data loader should load the item")
+	}
+	if !cache.Exists("loadable") {
+		t.Error("Loaded item should be stored in the cache")
+	}
+
+	if _, err = cache.Value("non_loadable"); err != ErrKeyNotFoundOrLoadable {
+		t.Error("Should return ErrKeyNotFoundOrLoadable for non-loadable keys")
+	}
+}
+
+func TestBoundedCacheSetPolicy(t *testing.T) {
+	cache := NewBoundedCache("testBoundedSetPolicy", 3, NewRandomPolicy())
+
+	cache.Add("key1", 0, "value1")
+	cache.Add("key2", 0, "value2")
+	cache.Add("key3", 0, "value3")
+	time.Sleep(time.Millisecond)
+	cache.Value("key1")
+
+	// Switching to FIFO migrates the items by their last access
+	cache.SetPolicy(NewFIFOPolicy())
+	if cache.Count() != 3 {
+		t.Error("Switching policies should keep all items")
+	}
+
+	cache.Add("key4", 0, "value4")
+	if !cache.Exists("key1") {
+		t.Error("key1 was accessed last and should not be evicted first")
+	}
+	if cache.Count() != 3 {
+		t.Error("Cache should contain exactly 3 items")
+	}
+	if !cache.Exists("key4") {
+		t.Error("key4 should exist (newly added)")
+	}
+}
+
+func TestBoundedCacheLifeSpan(t *testing.T) {
+	cache := NewBoundedCache("testBoundedLifeSpan", 10, NewSLRUPolicy(10, 0.5))
+	expired := 0
+	cache.SetAboutToDeleteItemCallback(func(item *CacheItem) {
+		expired++
+	})
+
+	cache.Add("short", 20*time.Millisecond, "value")
+	cache.Add("forever", 0, "value")
+	time.Sleep(40 * time.Millisecond)
+
+	if cache.Exists("short") || cache.Count() != 1 {
+		t.Error("Items should expire after their lifespan")
+	}
+	if _, err := cache.Value("short"); err != ErrKeyNotFound {
+		t.Error("Expected ErrKeyNotFound for an expired item, got", err)
+	}
+	if expired != 1 || cache.Stats().Expired != 1 {
+		t.Error("Expired item should have been removed", expired)
+	}
+}
+
+func TestBoundedCacheReplace(t *testing.T) {
+	cache := NewBoundedCache("testBoundedReplace", 10, NewSLRUPolicy(10, 0.5))
+	old := cache.Add(k, 0, "old")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			old.Data()
+			old.LifeSpan()
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		cache.Add(k, time.Minute, "new")
+	}
+	<-done
+
+	if old.Data() != "old" {
+		t.Error("Replacing an item should leave the old one unchanged")
+	}
+	if p, err := cache.Value(k); err != nil || p.Data() != "new" || p.LifeSpan() != time.Minute {
+		t.Error("Error retrieving replaced item", err)
+	}
+}
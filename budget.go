@@ -0,0 +1,152 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"sync"
+)
+
+// budgetMember is a table's share of a SharedBudget.
+type budgetMember struct {
+	weight int
+	used   int64
+}
+
+// SharedBudget is a capacity limit shared by multiple cache tables, see
+// WithBudget. Once the tables together exceed the limit, items get evicted
+// from the table that exceeds its fair share the most, so many small caches
+// don't each need a hand-tuned capacity.
+type SharedBudget struct {
+	sync.Mutex
+
+	// The shared limit, in items or in the units returned by sizeOf.
+	limit int64
+	// Returns an item's size, nil counts every item as 1.
+	sizeOf func(item *CacheItem) int64
+
+	used    int64
+	members map[*CacheTable]*budgetMember
+}
+
+// NewSharedBudget creates a budget with the given limit. Parameter sizeOf
+// returns an item's size, e.g. its length in bytes; if it's nil, the limit
+// applies to the number of items instead.
+func NewSharedBudget(limit int64, sizeOf func(item *CacheItem) int64) *SharedBudget {
+	return &SharedBudget{
+		limit:   limit,
+		sizeOf:  sizeOf,
+		members: make(map[*CacheTable]*budgetMember),
+	}
+}
+
+// Used returns how much of the budget is currently in use.
+func (b *SharedBudget) Used() int64 {
+	b.Lock()
+	defer b.Unlock()
+	return b.used
+}
+
+// UsedBy returns how much of the budget is currently used by a table.
+func (b *SharedBudget) UsedBy(table *CacheTable) int64 {
+	b.Lock()
+	defer b.Unlock()
+	if m, ok := b.members[table]; ok {
+		return m.used
+	}
+	return 0
+}
+
+// join registers a table with the given weight.
+func (b *SharedBudget) join(table *CacheTable, weight int) {
+	if weight < 1 {
+		weight = 1
+	}
+
+	b.Lock()
+	defer b.Unlock()
+	b.members[table] = &budgetMember{weight: weight}
+}
+
+// size returns how much of the budget an item uses.
+func (b *SharedBudget) size(item *CacheItem) int64 {
+	if b.sizeOf == nil {
+		return 1
+	}
+	return b.sizeOf(item)
+}
+
+// charge accounts for an item added to a table.
+func (b *SharedBudget) charge(table *CacheTable, item *CacheItem) {
+	size := b.size(item)
+
+	b.Lock()
+	defer b.Unlock()
+	b.members[table].used += size
+	b.used += size
+}
+
+// release accounts for an item removed from a table.
+func (b *SharedBudget) release(table *CacheTable, item *CacheItem) {
+	size := b.size(item)
+
+	b.Lock()
+	defer b.Unlock()
+	b.members[table].used -= size
+	b.used -= size
+}
+
+// releaseAll accounts for a table getting flushed.
+func (b *SharedBudget) releaseAll(table *CacheTable) {
+	b.Lock()
+	defer b.Unlock()
+	b.used -= b.members[table].used
+	b.members[table].used = 0
+}
+
+// victim returns the table exceeding its fair share the most, if the budget
+// is exceeded.
+func (b *SharedBudget) victim() *CacheTable {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.used <= b.limit {
+		return nil
+	}
+
+	weights := 0
+	for _, m := range b.members {
+		weights += m.weight
+	}
+
+	var victim *CacheTable
+	var worst float64
+	for table, m := range b.members {
+		if m.used <= 0 {
+			continue
+		}
+		// Usage relative to the member's share of the budget.
+		ratio := float64(m.used) * float64(weights) / float64(m.weight)
+		if victim == nil || ratio > worst {
+			victim = table
+			worst = ratio
+		}
+	}
+
+	return victim
+}
+
+// enforce evicts items until the budget is no longer exceeded.
+// Careful: do not run this method while holding any table-mutex!
+func (b *SharedBudget) enforce() {
+	for {
+		table := b.victim()
+		if table == nil || !table.evictOne() {
+			return
+		}
+	}
+}
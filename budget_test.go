@@ -0,0 +1,69 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+)
+
+func TestSharedBudget(t *testing.T) {
+	budget := NewSharedBudget(10, nil)
+	big, _ := CacheWithOptions("testSharedBudgetBig", WithBudget(budget, 1))
+	small, _ := CacheWithOptions("testSharedBudgetSmall", WithBudget(budget, 1))
+
+	for i := 0; i < 10; i++ {
+		big.Add(i, 0, i)
+	}
+	if budget.Used() != 10 || big.Count() != 10 {
+		t.Error("The first table should be able to use the whole budget")
+	}
+
+	// Adding to the other table evicts from the one using more than its share
+	for i := 0; i < 3; i++ {
+		small.Add(i, 0, i)
+	}
+	if budget.Used() != 10 {
+		t.Error("Budget should not be exceeded, used:", budget.Used())
+	}
+	if big.Count() != 7 || small.Count() != 3 {
+		t.Error("Expected 7 and 3 items, got", big.Count(), small.Count())
+	}
+
+	// Deleting and flushing returns budget
+	small.Delete(0)
+	if budget.UsedBy(small) != 2 {
+		t.Error("Expected 2 used by the small table, got", budget.UsedBy(small))
+	}
+	big.Flush()
+	if budget.Used() != 2 {
+		t.Error("Expected 2 used after flushing, got", budget.Used())
+	}
+}
+
+func TestSharedBudgetWeights(t *testing.T) {
+	sizeOf := func(item *CacheItem) int64 {
+		return int64(len(item.Data().(string)))
+	}
+	budget := NewSharedBudget(300, sizeOf)
+	heavy, _ := CacheWithOptions("testSharedBudgetHeavy", WithBudget(budget, 2))
+	light, _ := CacheWithOptions("testSharedBudgetLight", WithBudget(budget, 1))
+
+	value := "0123456789"
+	for i := 0; i < 100; i++ {
+		heavy.Add(i, 0, value)
+		light.Add(i, 0, value)
+	}
+
+	// Both tables compete for the budget and settle at their weighted shares
+	if budget.Used() > 300 {
+		t.Error("Budget should not be exceeded, used:", budget.Used())
+	}
+	if budget.UsedBy(heavy) != 200 || budget.UsedBy(light) != 100 {
+		t.Error("Expected a 2:1 split, got", budget.UsedBy(heavy), budget.UsedBy(light))
+	}
+}
@@ -0,0 +1,51 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+// SetBypass turns the table's bypass mode on or off. While bypassed, lookups
+// always miss and get served by the data-loader, if any, and adding items is
+// a no-op. Statistics are still recorded as if the table was in use: lookups
+// of items that were cached before count as hits, and every add is counted.
+// This allows measuring the backend load with and without the cache, or
+// disabling the cache during incidents without touching the callers.
+// Items already in the table are kept, so turning bypass mode off again
+// restores the previous state of the table.
+func (table *CacheTable) SetBypass(bypass bool) {
+	table.Lock()
+	defer table.Unlock()
+	table.bypass = bypass
+	table.log("Setting bypass mode of table", table.name, "to", bypass)
+}
+
+// Bypass returns whether the table is currently bypassed.
+func (table *CacheTable) Bypass() bool {
+	table.RLock()
+	defer table.RUnlock()
+	return table.bypass
+}
+
+// bypassValue serves a lookup while the table is bypassed. Parameter cached
+// tells whether the table holds the item, which only affects the statistics.
+func (table *CacheTable) bypassValue(cached bool, req LoadRequest, loadData func(*LoadRequest) *CacheItem) (*CacheItem, error) {
+	if cached {
+		table.stats.hit()
+	} else {
+		table.stats.miss()
+	}
+
+	if loadData != nil {
+		item := loadData(&req)
+		if item != nil {
+			return item, nil
+		}
+
+		return nil, ErrKeyNotFoundOrLoadable
+	}
+
+	return nil, ErrKeyNotFound
+}
@@ -0,0 +1,66 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+)
+
+func TestBypass(t *testing.T) {
+	table, _ := CacheWithOptions("testBypass", WithStats())
+	table.Add("cached", 0, v)
+
+	loads := 0
+	table.SetDataLoader(func(key interface{}, args ...interface{}) *CacheItem {
+		loads++
+		return NewCacheItem(key, 0, "loaded")
+	})
+
+	table.SetBypass(true)
+	if !table.Bypass() {
+		t.Error("Table should be bypassed")
+	}
+
+	// Lookups always go to the data-loader
+	p, err := table.Value("cached")
+	if err != nil || p.Data() != "loaded" {
+		t.Error("Bypassed lookup should be served by the data-loader")
+	}
+	table.Value("missing")
+	if loads != 2 {
+		t.Error("Expected 2 loads, got", loads)
+	}
+	if table.Exists("missing") {
+		t.Error("Loaded item should not be cached while bypassed")
+	}
+
+	// Adding is a no-op
+	table.Add("added", 0, v)
+	if table.Exists("added") || table.Count() != 1 {
+		t.Error("Added item should not be cached while bypassed")
+	}
+
+	// Acquire works, but there's nothing to lease
+	p, release, err := table.Acquire("cached")
+	if err != nil || p.Data() != "loaded" {
+		t.Error("Bypassed acquire should be served by the data-loader")
+	}
+	release()
+
+	stats := table.Stats()
+	if stats.Hits != 2 || stats.Misses != 1 || stats.Added != 2 {
+		t.Error("Unexpected would-be stats:", stats)
+	}
+
+	// Turning bypass off restores the previous state
+	table.SetBypass(false)
+	p, err = table.Value("cached")
+	if err != nil || p.Data() != v {
+		t.Error("Previously cached item should be served again")
+	}
+}
@@ -10,19 +10,59 @@ package cache2go
 
 import (
 	"sync"
+	"time"
 )
 
 var (
 	cache = make(map[string]*CacheTable)
 	lfuCaches = make(map[string]*LFUCache)
 	mutex sync.RWMutex
+
+	// Options applied to every table created via Cache.
+	defaults []Option
 )
 
+// Cacher is the common interface of all cache implementations.
+type Cacher interface {
+	// Add adds a key/value pair to the cache.
+	Add(key interface{}, lifeSpan time.Duration, data interface{}) *CacheItem
+	// Value returns an item from the cache, loading it via the data-loader
+	// if necessary.
+	Value(key interface{}, args ...interface{}) (*CacheItem, error)
+	// Delete removes an item from the cache.
+	Delete(key interface{}) (*CacheItem, error)
+	// Exists returns whether an item exists in the cache.
+	Exists(key interface{}) bool
+	// Count returns how many items are currently stored in the cache.
+	Count() int
+	// Foreach iterates over all items in the cache.
+	Foreach(trans func(key interface{}, item *CacheItem))
+	// Flush removes all items from the cache.
+	Flush()
+	// Stats returns the cache's usage statistics.
+	Stats() CacheStats
+}
+
+// SetCacheDefaults configures the options every table created via Cache
+// inherits from now on, e.g. a default lifespan, capacity, statistics or a
+// logger. Already existing tables are not affected. Calling it again replaces
+// the previous defaults.
+func SetCacheDefaults(opts ...Option) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	defaults = opts
+}
+
 // Cache returns the existing cache table with given name or creates a new one
 // if the table does not exist yet.
 func Cache(table string) *CacheTable {
+	// Tables get touched while the registry is locked, so they can't get
+	// torn down for idleness in the meantime.
 	mutex.RLock()
 	t, ok := cache[table]
+	if ok {
+		t.touch()
+	}
 	mutex.RUnlock()
 
 	if !ok {
@@ -30,21 +70,52 @@ func Cache(table string) *CacheTable {
 		t, ok = cache[table]
 		// Double check whether the table exists or not.
 		if !ok {
-			t = &CacheTable{
-				name:  table,
-				items: make(map[interface{}]*CacheItem),
-			}
+			t = newCacheTable(table, newCacheOptions(defaults...))
 			cache[table] = t
 		}
+		t.touch()
 		mutex.Unlock()
 	}
 
 	return t
 }
 
-// LFUCache returns the existing LFU cache with given name or creates a new one
-// if the cache does not exist yet.
-func LFUCache(name string, capacity int) *LFUCache {
+// CacheWithOptions returns the existing cache table with given name or creates
+// a new one configured with the registry defaults and the given options.
+// If the table already exists but was configured differently,
+// ErrCacheOptionsMismatch is returned together with the existing table.
+func CacheWithOptions(table string, opts ...Option) (*CacheTable, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	o := newCacheOptions(append(append([]Option{}, defaults...), opts...)...)
+	t, ok := cache[table]
+	if !ok {
+		t = newCacheTable(table, o)
+		cache[table] = t
+		return t, nil
+	}
+	t.touch()
+
+	if !t.options.equal(o) {
+		return t, ErrCacheOptionsMismatch
+	}
+	return t, nil
+}
+
+// LFUCacheNamed returns the existing LFU cache with given name or creates a
+// new one if the cache does not exist yet. An existing cache is returned
+// regardless of its capacity, see LFUCacheWithCapacity.
+func LFUCacheNamed(name string, capacity int) *LFUCache {
+	c, _ := LFUCacheWithCapacity(name, capacity)
+	return c
+}
+
+// LFUCacheWithCapacity returns the existing LFU cache with given name or
+// creates a new one if the cache does not exist yet, just like LFUCacheNamed.
+// If the cache already exists with a different capacity,
+// ErrCacheOptionsMismatch is returned together with the existing cache.
+func LFUCacheWithCapacity(name string, capacity int) (*LFUCache, error) {
 	mutex.RLock()
 	c, ok := lfuCaches[name]
 	mutex.RUnlock()
@@ -60,5 +131,104 @@ func LFUCache(name string, capacity int) *LFUCache {
 		mutex.Unlock()
 	}
 
-	return c
-}
\ No newline at end of file
+	if ok && c.Capacity() != capacity {
+		return c, ErrCacheOptionsMismatch
+	}
+	return c, nil
+}
+
+// RenameCache renames a cache in the registry, so it gets returned by Cache
+// or LFUCacheNamed for the new name from now on. The cache itself keeps the name
+// it was created with, e.g. in logs, events and snapshots.
+func RenameCache(oldName, newName string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if _, _, ok := findCacheInternal(newName); ok {
+		return ErrCacheExists
+	}
+	if t, ok := cache[oldName]; ok {
+		delete(cache, oldName)
+		cache[newName] = t
+		return nil
+	}
+	if c, ok := lfuCaches[oldName]; ok {
+		delete(lfuCaches, oldName)
+		lfuCaches[newName] = c
+		return nil
+	}
+	return ErrCacheNotFound
+}
+
+// SwapCache atomically replaces the cache registered under the given name
+// with a fresh one, e.g. a table warmed up under a temporary name, so there's
+// no window in which the cache is missing or empty. If the fresh cache is
+// already registered under another name, it gets moved. The replaced cache
+// is returned, or nil if there was none; it's left as it is, so it can still
+// serve readers holding on to it, or get closed. Only tables and LFU caches
+// can be registered, other implementations return ErrUnsupportedCache.
+func SwapCache(name string, fresh Cacher) (Cacher, error) {
+	switch fresh.(type) {
+	case *CacheTable, *LFUCache:
+	default:
+		return nil, ErrUnsupportedCache
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for n, t := range cache {
+		if Cacher(t) == fresh {
+			delete(cache, n)
+		}
+	}
+	for n, c := range lfuCaches {
+		if Cacher(c) == fresh {
+			delete(lfuCaches, n)
+		}
+	}
+
+	old, _, _ := findCacheInternal(name)
+	delete(cache, name)
+	delete(lfuCaches, name)
+	switch c := fresh.(type) {
+	case *CacheTable:
+		cache[name] = c
+	case *LFUCache:
+		lfuCaches[name] = c
+	}
+	return old, nil
+}
+
+// ForeachCache calls trans for every cache table and LFU cache in the
+// registry. The registry isn't locked while trans runs, so it may create or
+// access caches itself.
+func ForeachCache(trans func(name string, c Cacher)) {
+	mutex.RLock()
+	tables := make(map[string]Cacher, len(cache))
+	for name, t := range cache {
+		tables[name] = t
+	}
+	lfus := make(map[string]Cacher, len(lfuCaches))
+	for name, c := range lfuCaches {
+		lfus[name] = c
+	}
+	mutex.RUnlock()
+
+	for name, c := range tables {
+		trans(name, c)
+	}
+	for name, c := range lfus {
+		trans(name, c)
+	}
+}
+
+// AggregateStats returns the sum of the usage statistics of all caches in the
+// registry.
+func AggregateStats() CacheStats {
+	var total CacheStats
+	ForeachCache(func(name string, c Cacher) {
+		total = total.add(c.Stats())
+	})
+	return total
+}
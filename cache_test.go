@@ -9,6 +9,7 @@ package cache2go
 
 import (
 	"bytes"
+	"context"
 	"log"
 	"strconv"
 	"sync"
@@ -83,6 +84,61 @@ func TestCacheExpire(t *testing.T) {
 	}
 }
 
+func TestExpiryBatch(t *testing.T) {
+	table, _ := CacheWithOptions("testExpiryBatch", WithExpiryBatch(10, time.Hour), WithStats())
+	table.Flush()
+	before := table.Stats().Expired
+	for i := 0; i < 100; i++ {
+		table.Add(i, 20*time.Millisecond, v)
+	}
+	table.Add("forever", 0, v)
+
+	time.Sleep(100 * time.Millisecond)
+	if n := table.Count(); n != 1 {
+		t.Error("Expected all expired items to get deleted in batches, got", n, "items")
+	}
+	if n := table.Stats().Expired - before; n != 100 {
+		t.Error("Expected 100 expired items, got", n)
+	}
+
+	// Items kept alive or deleted while unlocked between batches are left
+	// alone.
+	table.PauseExpiration()
+	defer table.ResumeExpiration()
+	table.Add("expired", time.Millisecond, v)
+	table.Add("alive", time.Minute, v)
+	time.Sleep(5 * time.Millisecond)
+	table.Lock()
+	table.deleteExpiredInternal([]interface{}{"expired", "alive", "missing"}, time.Now())
+	table.Unlock()
+	if table.Exists("expired") || !table.Exists("alive") {
+		t.Error("Expected only the expired item to get deleted")
+	}
+}
+
+func TestPauseExpiration(t *testing.T) {
+	table := Cache("testPauseExpiration")
+	table.Add(k, 50*time.Millisecond, v)
+
+	table.PauseExpiration()
+	table.Add(k+"_2", 50*time.Millisecond, v)
+	time.Sleep(100 * time.Millisecond)
+	if !table.Exists(k) || !table.Exists(k+"_2") {
+		t.Error("Items should not expire while expiration is paused")
+	}
+
+	table.ResumeExpiration()
+	if table.Exists(k) || table.Exists(k+"_2") {
+		t.Error("Items that expired while paused should be removed on resume")
+	}
+
+	table.Add(k, 50*time.Millisecond, v)
+	time.Sleep(100 * time.Millisecond)
+	if table.Exists(k) {
+		t.Error("Items should expire again after resuming")
+	}
+}
+
 func TestExists(t *testing.T) {
 	// add an expiring item
 	table := Cache("testExists")
@@ -192,6 +248,29 @@ func TestDelete(t *testing.T) {
 	}
 }
 
+func TestDeleteExpired(t *testing.T) {
+	table := Cache("testDeleteExpired")
+	expired := table.Add("expired", time.Hour, v)
+	table.Add("alive", time.Hour, v)
+	table.Add("forever", 0, v)
+
+	// Pretend the item hasn't been accessed for longer than its lifespan
+	expired.Lock()
+	expired.setAccessedOn(time.Now().Add(-2 * time.Hour))
+	expired.Unlock()
+
+	removed := table.DeleteExpired()
+	if len(removed) != 1 || removed[0] != expired {
+		t.Error("Expected the expired item to be returned, got", removed)
+	}
+	if table.Exists("expired") || table.Count() != 2 {
+		t.Error("Only the expired item should have been removed")
+	}
+	if removed = table.DeleteExpired(); len(removed) != 0 {
+		t.Error("Nothing should be left to remove, got", removed)
+	}
+}
+
 func TestFlush(t *testing.T) {
 	// add an item to the cache
 	table := Cache("testFlush")
@@ -472,3 +551,157 @@ func TestLogger(t *testing.T) {
 		t.Error("Logger is empty")
 	}
 }
+
+func TestValueCtx(t *testing.T) {
+	table := Cache("testValueCtx")
+	table.Add(k, 0, v)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := table.ValueCtx(ctx, k); err != context.Canceled {
+		t.Error("Expected context.Canceled for a done context, got", err)
+	}
+	if p, err := table.ValueCtx(context.Background(), k); err != nil || p.Data() != v {
+		t.Error("Error retrieving value via ValueCtx", err)
+	}
+
+	release := make(chan struct{})
+	table.SetDataLoader(func(key interface{}, args ...interface{}) *CacheItem {
+		<-release
+		return NewCacheItem(key, 0, "loaded")
+	})
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := table.ValueCtx(ctx, "slow"); err != context.DeadlineExceeded {
+		t.Error("Expected context.DeadlineExceeded, got", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("ValueCtx should return once the deadline is exceeded")
+	}
+
+	// The load completes in the background.
+	close(release)
+	for i := 0; i < 100 && !table.Exists("slow"); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !table.Exists("slow") {
+		t.Error("Abandoned load should still get cached")
+	}
+}
+
+func TestValueCtxSharedLoad(t *testing.T) {
+	table := newCacheTable("testValueCtxSharedLoad", newCacheOptions())
+	var loads int32
+	release := make(chan struct{})
+	table.SetDataLoader(func(key interface{}, args ...interface{}) *CacheItem {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return NewCacheItem(key, 0, args[0])
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if p, err := table.ValueCtx(ctx, "shared", "arg"); err != nil || p.Data() != "arg" {
+				t.Error("Error loading value via ValueCtx", err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := atomic.LoadInt32(&loads); n != 1 {
+		t.Error("Expected concurrent calls to share a load, got", n)
+	}
+
+	// Hits don't need to wait for anything.
+	if allocs := testing.AllocsPerRun(100, func() { table.ValueCtx(ctx, "shared") }); allocs > 2 {
+		t.Error("Expected hits to be served directly, got", allocs, "allocations")
+	}
+}
+
+func TestRenameCache(t *testing.T) {
+	table := Cache("testRenameCache")
+	table.Add("key", 0, "value")
+	Cache("testRenameCacheTaken")
+
+	if err := RenameCache("testRenameCache", "testRenameCacheTaken"); err != ErrCacheExists {
+		t.Error("Expected ErrCacheExists, got", err)
+	}
+	if err := RenameCache("testRenameCacheMissing", "testRenameCacheNew"); err != ErrCacheNotFound {
+		t.Error("Expected ErrCacheNotFound, got", err)
+	}
+	if err := RenameCache("testRenameCache", "testRenameCacheNew"); err != nil {
+		t.Fatal("Error renaming cache", err)
+	}
+	if _, _, ok := findCache("testRenameCache"); ok {
+		t.Error("Cache should be gone from its old name")
+	}
+	if Cache("testRenameCacheNew") != table || !table.Exists("key") {
+		t.Error("Cache should be registered under its new name")
+	}
+	RenameCache("testRenameCacheNew", "testRenameCache")
+}
+
+func TestSwapCache(t *testing.T) {
+	live := Cache("testSwapCache")
+	live.Add("key", 0, "old")
+	fresh := Cache("testSwapCacheNext")
+	fresh.Add("key", 0, "new")
+
+	old, err := SwapCache("testSwapCache", fresh)
+	if err != nil || old != Cacher(live) {
+		t.Error("Expected the live cache to get replaced, got", old, err)
+	}
+	if _, _, ok := findCache("testSwapCacheNext"); ok {
+		t.Error("Fresh cache should be moved from its temporary name")
+	}
+	item, err := Cache("testSwapCache").Value("key")
+	if err != nil || item.Data() != "new" {
+		t.Error("Expected the fresh cache to serve reads")
+	}
+
+	lfu := NewLFUCache("testSwapCacheLFU", 10)
+	if old, err = SwapCache("testSwapCache", lfu); err != nil || old != Cacher(fresh) {
+		t.Error("Expected the table to get replaced by an LFU cache, got", old, err)
+	}
+	if c, kind, ok := findCache("testSwapCache"); !ok || c != Cacher(lfu) || kind != "lfu" {
+		t.Error("Expected the LFU cache to be registered, got", c, kind)
+	}
+
+	if _, err = SwapCache("testSwapCache", NewBoundedCache("bounded", 10, NewFIFOPolicy())); err != ErrUnsupportedCache {
+		t.Error("Expected ErrUnsupportedCache, got", err)
+	}
+	SwapCache("testSwapCache", live)
+}
+
+func TestValueAllocs(t *testing.T) {
+	table, _ := CacheWithOptions("testValueAllocs", WithCapacity(10), WithStats())
+	table.Add("key", 0, "value")
+
+	hit := func() {
+		if _, err := table.Value("key"); err != nil {
+			t.Error("Error retrieving value from cache:", err)
+		}
+	}
+	if n := testing.AllocsPerRun(1000, hit); n != 0 {
+		t.Error("Expected cache hits not to allocate, got", n, "allocations")
+	}
+
+	// Neither checking whether the item is stale nor timing the lookup
+	// allocates.
+	table.SetDataLoader(func(key interface{}, args ...interface{}) *CacheItem {
+		return nil
+	})
+	table.SetOpHook(func(op Op, key interface{}, d time.Duration) {})
+	defer table.SetOpHook(nil)
+	if n := testing.AllocsPerRun(1000, hit); n != 0 {
+		t.Error("Expected cache hits with a data-loader and hook not to allocate, got", n, "allocations")
+	}
+}
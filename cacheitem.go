@@ -9,30 +9,85 @@ package cache2go
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// Releaser is implemented by values owning resources, like connections or
+// file handles, that need to be freed once they're no longer cached or used.
+type Releaser interface {
+	// Release frees the value's resources.
+	Release()
+}
+
 // CacheItem is an individual cache item
 // Parameter data contains the user-set value in the cache.
 type CacheItem struct {
+	// Last access timestamp in nanoseconds since the epoch, and how often
+	// the item was accessed. Accessed atomically, so cache hits don't need
+	// to lock the item, and they come first to be 64-bit aligned.
+	accessedOn  int64
+	accessCount int64
+	// Generation the item got stored with, see Generation. Accessed
+	// atomically, as stored items may be stored again.
+	generation uint64
+
 	sync.RWMutex
 
+	// Number of references to the item: one held by the cache while the item
+	// is stored in it, plus one per reader that retained it.
+	refs int32
+	// Whether a removal of the item from the cache claimed notifying its
+	// listener and dropping the cache's reference, see claimRemoval.
+	// Accessed atomically.
+	removing uint32
+
 	// The item's key.
 	key interface{}
 	// The item's data.
 	data interface{}
+	// The item's data computed on first use, nil if none, see AddLazy.
+	lazy *lazyValue
+	// The pool holding the item's key and data, nil if none, and whether it
+	// holds the data, see WithStringInterning.
+	interned     *InternPool
+	internedData bool
+	// The arena holding the item's data, nil if none, and where, see
+	// WithByteArena.
+	arena *byteArena
+	span  arenaSpan
 	// How long will the item live in the cache when not being accessed/kept alive.
 	lifeSpan time.Duration
+	// How long after its creation the item is considered stale.
+	softLifeSpan time.Duration
+	// Whether the item is currently being refreshed.
+	refreshing bool
+	// Where the item's value came from.
+	source ItemSource
+	// The value's sequence number, 0 if none, see CacheTable.AddSeq.
+	seq uint64
+	// How long the data-loader took to load the item, 0 if it wasn't loaded.
+	loadCost time.Duration
+	// Whether the item may replace a cached one in strict mode, and the
+	// hash of its value when it got added, 0 if none, see WithStrictMode.
+	replace   bool
+	valueHash uint64
 
 	// Creation timestamp.
 	createdOn time.Time
-	// Last access timestamp.
-	accessedOn time.Time
-	// How often the item was accessed.
-	accessCount int64
+
+	// Metadata used to revalidate the item once it expired.
+	validator Validator
+	// Whether the item is currently being revalidated.
+	revalidating bool
+	// Last access timestamp when the item was reported to expire soon.
+	expiringNotifiedOn time.Time
 
 	// Callback method triggered right before removing the item from the cache
 	aboutToExpire []func(key interface{})
+	// Callback method triggered when the item gets removed from the cache,
+	// set on creation.
+	removalListener func(item *CacheItem, reason RemovalReason)
 }
 
 // NewCacheItem returns a newly created CacheItem.
@@ -43,10 +98,11 @@ type CacheItem struct {
 func NewCacheItem(key interface{}, lifeSpan time.Duration, data interface{}) *CacheItem {
 	t := time.Now()
 	return &CacheItem{
+		refs:          1,
 		key:           key,
 		lifeSpan:      lifeSpan,
 		createdOn:     t,
-		accessedOn:    t,
+		accessedOn:    t.UnixNano(),
 		accessCount:   0,
 		aboutToExpire: nil,
 		data:          data,
@@ -55,10 +111,14 @@ func NewCacheItem(key interface{}, lifeSpan time.Duration, data interface{}) *Ca
 
 // KeepAlive marks an item to be kept for another expireDuration period.
 func (item *CacheItem) KeepAlive() {
-	item.Lock()
-	defer item.Unlock()
-	item.accessedOn = time.Now()
-	item.accessCount++
+	item.keepAliveAt(time.Now().UnixNano())
+}
+
+// keepAliveAt marks an item as accessed at the given time, in nanoseconds
+// since the epoch.
+func (item *CacheItem) keepAliveAt(nanos int64) {
+	atomic.StoreInt64(&item.accessedOn, nanos)
+	atomic.AddInt64(&item.accessCount, 1)
 }
 
 // LifeSpan returns this item's expiration duration.
@@ -69,9 +129,12 @@ func (item *CacheItem) LifeSpan() time.Duration {
 
 // AccessedOn returns when this item was last accessed.
 func (item *CacheItem) AccessedOn() time.Time {
-	item.RLock()
-	defer item.RUnlock()
-	return item.accessedOn
+	return time.Unix(0, atomic.LoadInt64(&item.accessedOn))
+}
+
+// setAccessedOn sets when the item was last accessed.
+func (item *CacheItem) setAccessedOn(t time.Time) {
+	atomic.StoreInt64(&item.accessedOn, t.UnixNano())
 }
 
 // CreatedOn returns when this item was added to the cache.
@@ -82,9 +145,7 @@ func (item *CacheItem) CreatedOn() time.Time {
 
 // AccessCount returns how often this item has been accessed.
 func (item *CacheItem) AccessCount() int64 {
-	item.RLock()
-	defer item.RUnlock()
-	return item.accessCount
+	return atomic.LoadInt64(&item.accessCount)
 }
 
 // Key returns the key of this cached item.
@@ -95,6 +156,13 @@ func (item *CacheItem) Key() interface{} {
 
 // Data returns the value of this cached item.
 func (item *CacheItem) Data() interface{} {
+	if item.lazy != nil {
+		data, _ := item.lazy.get()
+		return data
+	}
+	if item.arena != nil {
+		return item.arena.read(&item.span)
+	}
 	// immutable
 	return item.data
 }
@@ -123,3 +191,39 @@ func (item *CacheItem) RemoveAboutToExpireCallback() {
 	defer item.Unlock()
 	item.aboutToExpire = nil
 }
+
+// Retain registers a reader of the item, so the item's data doesn't get
+// released while it's still in use, even if the item gets removed from the
+// cache. Every successful call must be matched by a call to Release. Returns
+// false if the item was already released, in which case its data must not
+// be used anymore.
+func (item *CacheItem) Retain() bool {
+	for {
+		refs := atomic.LoadInt32(&item.refs)
+		if refs <= 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&item.refs, refs, refs+1) {
+			return true
+		}
+	}
+}
+
+// Release drops a reference to the item. Once the item was removed from the
+// cache and all readers released it, its data gets released if it implements
+// the Releaser interface.
+func (item *CacheItem) Release() {
+	if atomic.AddInt32(&item.refs, -1) != 0 {
+		return
+	}
+	if item.interned != nil {
+		item.releaseInterned()
+	}
+	if item.arena != nil {
+		item.arena.release(&item.span)
+		return
+	}
+	if r, ok := item.peek().(Releaser); ok {
+		r.Release()
+	}
+}
@@ -0,0 +1,153 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testResource struct {
+	released int32
+}
+
+func (r *testResource) Release() {
+	atomic.AddInt32(&r.released, 1)
+}
+
+func (r *testResource) Released() int32 {
+	return atomic.LoadInt32(&r.released)
+}
+
+func TestReleaserOnRemoval(t *testing.T) {
+	table := Cache("testReleaserOnRemoval")
+
+	deleted := &testResource{}
+	table.Add("deleted", 0, deleted)
+	table.Delete("deleted")
+	if deleted.Released() != 1 {
+		t.Error("Deleted value should be released")
+	}
+
+	expired := &testResource{}
+	table.Add("expired", 50*time.Millisecond, expired)
+	time.Sleep(100 * time.Millisecond)
+	if expired.Released() != 1 {
+		t.Error("Expired value should be released")
+	}
+
+	replaced := &testResource{}
+	table.Add("replaced", 0, replaced)
+	table.Add("replaced", 0, &testResource{})
+	if replaced.Released() != 1 {
+		t.Error("Replaced value should be released")
+	}
+
+	flushed := &testResource{}
+	table.Add("flushed", 0, flushed)
+	table.Flush()
+	if flushed.Released() != 1 {
+		t.Error("Flushed value should be released")
+	}
+}
+
+func TestReleaserWaitsForReaders(t *testing.T) {
+	table := Cache("testReleaserWaitsForReaders")
+
+	res := &testResource{}
+	table.Add("key", 0, res)
+
+	item, _ := table.Value("key")
+	if !item.Retain() {
+		t.Error("Cached item should be retainable")
+	}
+	table.Delete("key")
+	if res.Released() != 0 {
+		t.Error("Value should not be released while a reader holds it")
+	}
+
+	item.Release()
+	if res.Released() != 1 {
+		t.Error("Value should be released once the last reader is done")
+	}
+	if item.Retain() {
+		t.Error("Released item should not be retainable")
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	table := Cache("testAcquire")
+
+	res := &testResource{}
+	table.Add("key", 0, res)
+
+	item, release, err := table.Acquire("key")
+	if err != nil || item.Data() != res {
+		t.Error("Error acquiring item", err)
+	}
+	table.Delete("key")
+	if res.Released() != 0 {
+		t.Error("Value should not be released while leased")
+	}
+
+	// Releasing the lease twice must not drop another reference
+	release()
+	release()
+	if res.Released() != 1 {
+		t.Error("Value should be released exactly once after the lease ended")
+	}
+
+	if _, _, err = table.Acquire("key"); err != ErrKeyNotFound {
+		t.Error("Expected ErrKeyNotFound acquiring a missing key, got", err)
+	}
+
+	// Loaded items get leased from the cache
+	table.SetDataLoader(func(key interface{}, args ...interface{}) *CacheItem {
+		return NewCacheItem(key, 0, &testResource{})
+	})
+	item, release, err = table.Acquire("loaded")
+	if err != nil {
+		t.Error("Error acquiring loaded item", err)
+	}
+	stored, _ := table.Value("loaded")
+	if stored != item {
+		t.Error("Lease should be on the stored item")
+	}
+	release()
+}
+
+func TestKeepAliveConcurrent(t *testing.T) {
+	item := NewCacheItem("key", time.Minute, "value")
+	before := item.AccessedOn()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				item.KeepAlive()
+				item.AccessCount()
+				item.AccessedOn()
+			}
+		}()
+	}
+	// Holding the item's mutex doesn't block keeping it alive.
+	item.Lock()
+	wg.Wait()
+	item.Unlock()
+
+	if n := item.AccessCount(); n != 8000 {
+		t.Error("Expected 8000 accesses, got", n)
+	}
+	if item.AccessedOn().Before(before) {
+		t.Error("Expected the access timestamp to advance")
+	}
+}
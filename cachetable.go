@@ -8,9 +8,12 @@
 package cache2go
 
 import (
+	"context"
 	"log"
+	"math/rand"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -22,21 +25,188 @@ type CacheTable struct {
 	name string
 	// All cached items.
 	items map[interface{}]*CacheItem
+	// Generation assigned to the most recently stored item.
+	generation uint64
 
 	// Timer responsible for triggering cleanup.
 	cleanupTimer *time.Timer
 	// Current timer duration.
 	cleanupInterval time.Duration
+	// Whether expired items are currently left alone.
+	expirationPaused bool
+
+	// The options the table was created with.
+	options cacheOptions
+	// Lifespan used for items added with a lifespan of 0.
+	defaultLifeSpan time.Duration
+	// Soft lifespan used for items added without one.
+	defaultSoftLifeSpan time.Duration
+	// Maximum number of items, 0 means unlimited.
+	capacity int
+	// Policy choosing which item to evict once the table is full. It ranks
+	// items by their own access metadata, so hits don't need to report to
+	// it. Guarded by its own mutex.
+	policy      EvictionPolicy
+	policyMutex sync.Mutex
+	// Clock used to timestamp accesses, nil for time.Now, see
+	// WithCoarseClock.
+	clock *coarseClock
+	// Whether the table was used since the last idleness check, see
+	// SetCacheIdleTimeout. Accessed atomically.
+	active uint32
+	// Item limits of key prefixes, see LimitPrefix.
+	prefixLimits []*prefixLimit
+	// Segment new items start in, nil unless scan-resistant, see
+	// WithScanResistance.
+	probation *probation
+	// Stops auditing values, nil unless audited, see WithStrictMode.
+	stopAudit chan struct{}
+	// Whether items are being reclaimed, accessed atomically, see
+	// WithSoftReferences.
+	reclaiming uint32
+	// Source of random decisions, safe for concurrent use, see
+	// WithRandSource.
+	rand *rand.Rand
+	// Work deferred until the next Tick, see WithManualTicks.
+	pending []func()
+	// Usage statistics, nil if disabled.
+	stats *statsCounter
+	// Capacity limit shared with other tables, nil if none.
+	budget *SharedBudget
+	// Whether the table is bypassed, see SetBypass.
+	bypass bool
+	// Cache fed the same operations for comparison, nil if none.
+	shadow *BoundedCache
+	// Cache consulted on misses before the data-loader, nil if none, see
+	// SetParent.
+	parent Cacher
+	// Slabs holding byte slice values, nil if disabled, see WithByteArena.
+	arena *byteArena
+	// Keys of recently evicted items, nil if disabled, see
+	// EnableGhostCache.
+	ghost *ghostCache
+	// Faults injected for testing, nil if none.
+	faults *FaultInjector
+	// How to restore items' lifespans.
+	restorePolicy RestorePolicy
+	// Log of the table's mutations, nil if disabled.
+	mutationLog *mutationLog
+	// Changes tracked for automatic snapshots, nil if disabled.
+	autoSnapshot *autoSnapshot
+	// Log of who changed the table, nil if disabled.
+	auditLog *auditLog
+	// Queue of events for an event sink, nil if disabled.
+	eventExporter *eventExporter
+	// Checks the statistics against alerting thresholds, nil if disabled.
+	alertMonitor *alertMonitor
+	// When recently deleted keys got deleted, see WithTombstones, and how
+	// many were left after pruning them the last time.
+	tombstones       map[interface{}]tombstone
+	tombstonesPruned int
 
 	// The logger used for this table.
 	logger *log.Logger
 
 	// Callback method triggered when trying to load a non-existing key.
-	loadData func(key interface{}, args ...interface{}) *CacheItem
+	loadData func(req *LoadRequest) *CacheItem
+	// Loads in progress, shared by concurrent callers, see Load. Guarded by
+	// their own mutex.
+	loadCalls map[interface{}]*loadCall
+	loadMutex sync.Mutex
+	// Mutexes scoped to keys, see KeyLock.
+	keyLocks keyLocks
+	// Callback method triggered to revalidate an expired item.
+	revalidate func(key interface{}, validator Validator) (*CacheItem, bool)
 	// Callback method triggered when adding a new item to the cache.
 	addedItem []func(item *CacheItem)
 	// Callback method triggered before deleting an item from the cache.
 	aboutToDeleteItem []func(item *CacheItem)
+	// Callback method triggered before explicitly deleting an item, which
+	// may veto the delete.
+	deleteVeto []func(item *CacheItem) bool
+	// Callback method triggered after reclaiming an item.
+	reclaimedItem []func(item *CacheItem)
+	// Callback method triggered when adding an item with a tombstoned key.
+	resurrectedItem []func(item *CacheItem)
+	// Callback method triggered when strict mode catches a violation.
+	strictViolation []func(item *CacheItem, err error)
+	// Callback method timing operations, holding an *opHook. Not guarded by
+	// the table-mutex, so it can be loaded cheaply on every operation.
+	opHook atomic.Value
+	// Callback method returning the sequence number of an item being added.
+	sequencer func(item *CacheItem) uint64
+	// Rules determining the lifespans of items added without one, holding
+	// a *ttlRules. Not guarded by the table-mutex, as items get created
+	// while it's locked or not.
+	ttlRules atomic.Value
+	// Callback method triggered after saving an automatic snapshot.
+	snapshotted []func(info SnapshotInfo)
+	// Callback method transforming values before they leave the process.
+	exportTransformer func(key interface{}, value interface{}) interface{}
+	// Callback method triggered when an item is about to expire, and how
+	// long before.
+	expiringSoon   func(item *CacheItem)
+	expiringWithin time.Duration
+}
+
+// newCacheTable creates a table configured with the given options.
+func newCacheTable(name string, o cacheOptions) *CacheTable {
+	table := &CacheTable{
+		name:                name,
+		items:               make(map[interface{}]*CacheItem),
+		options:             o,
+		defaultLifeSpan:     o.lifeSpan,
+		defaultSoftLifeSpan: o.softLifeSpan,
+		capacity:            o.capacity,
+		logger:              o.logger,
+		faults:              o.faults,
+		restorePolicy:       o.restorePolicy,
+		rand:                newTableRand(o.randSource),
+	}
+	if o.capacity > 0 || o.budget != nil {
+		mode := SampleLRU
+		if o.costAware {
+			mode = SampleCostLFU
+		}
+		p := newItemSamplingPolicy(5, mode)
+		p.rand = table.rand
+		table.policy = p
+	}
+	if o.stats {
+		table.stats = &statsCounter{}
+	}
+	if o.clockResolution > 0 {
+		table.clock = coarseClockFor(o.clockResolution)
+	}
+	if o.manualTicks {
+		table.clock = &coarseClock{now: time.Now().UnixNano()}
+	}
+	if o.probationSize > 0 && o.capacity > 0 {
+		table.probation = newProbation(o.probationSize)
+	}
+	if o.strict && o.auditInterval > 0 {
+		table.stopAudit = make(chan struct{})
+		go table.auditValues(o.auditInterval, table.stopAudit)
+	}
+	if o.arenaSlabSize > 0 {
+		table.arena = newByteArena(o.arenaSlabSize)
+	}
+	if o.mappedArena != nil {
+		table.arena = o.mappedArena.arena
+		for _, item := range table.arena.recover() {
+			table.Lock()
+			table.addInternal(item)
+		}
+	}
+	if o.softHeapLimit > 0 {
+		watchSoftRefs(table)
+	}
+	if o.budget != nil {
+		table.budget = o.budget
+		table.budget.join(table, o.budgetWeight)
+	}
+
+	return table
 }
 
 // Count returns how many items are currently stored in the cache.
@@ -48,6 +218,7 @@ func (table *CacheTable) Count() int {
 
 // Foreach all items
 func (table *CacheTable) Foreach(trans func(key interface{}, item *CacheItem)) {
+	table.touch()
 	table.RLock()
 	defer table.RUnlock()
 
@@ -58,11 +229,22 @@ func (table *CacheTable) Foreach(trans func(key interface{}, item *CacheItem)) {
 
 // SetDataLoader configures a data-loader callback, which will be called when
 // trying to access a non-existing key. The key and 0...n additional arguments
-// are passed to the callback function.
+// are passed to the callback function. Prefer SetLoader, which passes the
+// lookup's context and typed options instead.
 func (table *CacheTable) SetDataLoader(f func(interface{}, ...interface{}) *CacheItem) {
 	table.Lock()
 	defer table.Unlock()
-	table.loadData = f
+	table.loadData = dataLoader(f)
+}
+
+// SetExportTransformer configures a callback, which transforms values before
+// they leave the process: in dumps, exports and snapshots. Use it to mask
+// secrets, e.g. by returning a redacted copy of the value. Note that
+// snapshots then restore the transformed values.
+func (table *CacheTable) SetExportTransformer(f func(key interface{}, value interface{}) interface{}) {
+	table.Lock()
+	defer table.Unlock()
+	table.exportTransformer = f
 }
 
 // SetAddedItemCallback configures a callback, which will be called every time
@@ -124,10 +306,25 @@ func (table *CacheTable) SetLogger(logger *log.Logger) {
 
 // Expiration check loop, triggered by a self-adjusting timer.
 func (table *CacheTable) expirationCheck() {
+	if table.options.manualTicks {
+		// Only Tick checks for expired items.
+		return
+	}
+	table.checkExpiry()
+}
+
+// checkExpiry removes expired items and schedules the next expiration check.
+func (table *CacheTable) checkExpiry() {
 	table.Lock()
 	if table.cleanupTimer != nil {
 		table.cleanupTimer.Stop()
 	}
+	if table.expirationPaused {
+		table.cleanupInterval = 0
+		table.log("Expiration check paused for table", table.name)
+		table.Unlock()
+		return
+	}
 	if table.cleanupInterval > 0 {
 		table.log("Expiration check triggered after", table.cleanupInterval, "for table", table.name)
 	} else {
@@ -136,79 +333,370 @@ func (table *CacheTable) expirationCheck() {
 
 	// To be more accurate with timers, we would need to update 'now' on every
 	// loop iteration. Not sure it's really efficient though.
-	now := time.Now()
+	now := table.now()
 	smallestDuration := 0 * time.Second
+	var expiring []*CacheItem
+	var expired []interface{}
 	for key, item := range table.items {
 		// Cache values so we don't keep blocking the mutex.
 		item.RLock()
 		lifeSpan := item.lifeSpan
-		accessedOn := item.accessedOn
+		accessedOn := item.AccessedOn()
+		hasValidator := !item.validator.isZero()
+		revalidating := item.revalidating
+		notifiedOn := item.expiringNotifiedOn
 		item.RUnlock()
 
-		if lifeSpan == 0 {
+		if lifeSpan == 0 || revalidating {
 			continue
 		}
 		if now.Sub(accessedOn) >= lifeSpan {
-			// Item has excessed its lifespan.
-			table.deleteInternal(key)
+			// Item has excessed its lifespan. Ask the revalidator whether it
+			// actually changed before dropping it.
+			if table.revalidate != nil && hasValidator {
+				item.Lock()
+				item.revalidating = true
+				item.Unlock()
+				key, item, revalidate := key, item, table.revalidate
+				table.spawnInternal(func() {
+					table.revalidateItem(key, item, revalidate)
+				})
+				continue
+			}
+			expired = append(expired, key)
 		} else {
+			remaining := lifeSpan - now.Sub(accessedOn)
+			// Notify about items about to expire, or wake up in time to.
+			if table.expiringSoon != nil && !notifiedOn.Equal(accessedOn) {
+				if remaining <= table.expiringWithin {
+					item.Lock()
+					item.expiringNotifiedOn = accessedOn
+					item.Unlock()
+					expiring = append(expiring, item)
+				} else if smallestDuration == 0 || remaining-table.expiringWithin < smallestDuration {
+					smallestDuration = remaining - table.expiringWithin
+				}
+			}
 			// Find the item chronologically closest to its end-of-lifespan.
-			if smallestDuration == 0 || lifeSpan-now.Sub(accessedOn) < smallestDuration {
-				smallestDuration = lifeSpan - now.Sub(accessedOn)
+			if smallestDuration == 0 || remaining < smallestDuration {
+				smallestDuration = remaining
 			}
 		}
 	}
 
+	table.deleteExpiredInternal(expired, now)
+
 	// Setup the interval for the next cleanup run.
 	table.cleanupInterval = smallestDuration
-	if smallestDuration > 0 {
+	if smallestDuration > 0 && !table.options.manualTicks {
 		table.cleanupTimer = time.AfterFunc(smallestDuration, func() {
 			go table.expirationCheck()
 		})
 	}
+	expiringSoon := table.expiringSoon
+	table.Unlock()
+
+	for _, item := range expiring {
+		expiringSoon(item)
+	}
+}
+
+// deleteExpiredInternal removes the given expired items in batches, so
+// the table-mutex doesn't stay locked for too long when many items expire at
+// once, see WithExpiryBatch. Items kept alive or replaced in the meantime are
+// left alone.
+// Careful: do not run this method unless the table-mutex is locked!
+func (table *CacheTable) deleteExpiredInternal(keys []interface{}, now time.Time) {
+	maxDeletions := table.options.expiryBatchSize
+	if maxDeletions <= 0 {
+		maxDeletions = defaultExpiryBatchSize
+	}
+	maxPause := table.options.expiryBatchPause
+	if maxPause <= 0 {
+		maxPause = defaultExpiryBatchPause
+	}
+
+	batchStart := time.Now()
+	deletions := 0
+	for _, key := range keys {
+		if deletions >= maxDeletions || time.Since(batchStart) >= maxPause {
+			// Let waiting readers and writers in before continuing.
+			table.Unlock()
+			table.Lock()
+			batchStart = time.Now()
+			deletions = 0
+		}
+
+		item, ok := table.items[key]
+		if !ok {
+			continue
+		}
+		item.RLock()
+		expired := item.lifeSpan > 0 && !item.revalidating && now.Sub(item.AccessedOn()) >= item.lifeSpan
+		item.RUnlock()
+		if !expired {
+			continue
+		}
+
+		if _, err := table.deleteInternal(key, RemovalExpired); err == nil {
+			table.stats.expire()
+		}
+		deletions++
+	}
+}
+
+// PauseExpiration stops removing expired items until ResumeExpiration gets
+// called, e.g. during maintenance or while restoring a snapshot. Expired
+// items stay in the table in the meantime, and get served by Value.
+func (table *CacheTable) PauseExpiration() {
+	table.Lock()
+	defer table.Unlock()
+	table.expirationPaused = true
+	if table.cleanupTimer != nil {
+		table.cleanupTimer.Stop()
+	}
+	table.cleanupInterval = 0
+}
+
+// ResumeExpiration resumes removing expired items, immediately removing the
+// ones that expired while paused.
+func (table *CacheTable) ResumeExpiration() {
+	table.Lock()
+	table.expirationPaused = false
 	table.Unlock()
+
+	table.expirationCheck()
 }
 
-func (table *CacheTable) addInternal(item *CacheItem) {
+func (table *CacheTable) addInternal(item *CacheItem) bool {
 	// Careful: do not run this method unless the table-mutex is locked!
 	// It will unlock it for the caller before running the callbacks and checks
-	table.log("Adding item with key", item.key, "and lifespan of", item.lifeSpan, "to table", table.name)
-	table.items[item.key] = item
+	// Returns whether the item got stored.
+	table.touch()
+	if item.seq == 0 && table.sequencer != nil {
+		item.seq = table.sequencer(item)
+	}
+	stale, resurrected := table.admit(item, item.seq)
+	if stale || (resurrected && table.options.rejectResurrections) {
+		resurrectedItem := table.resurrectedItem
+		table.Unlock()
+		table.log("Rejecting stale or tombstoned item with key", item.key, "in table", table.name)
+		for _, callback := range resurrectedItem {
+			callback(item)
+		}
+		return false
+	}
+	if table.rejectOverwriteInternal(item) {
+		strictViolation := table.strictViolation
+		table.Unlock()
+		table.log("Rejecting overwrite of key", item.key, "in table", table.name)
+		for _, callback := range strictViolation {
+			callback(item, ErrKeyExists)
+		}
+		return false
+	}
+	table.admitted(item)
+	if table.shadow != nil {
+		table.shadow.Add(item.key, item.lifeSpan, nil)
+	}
+	if table.bypass {
+		table.stats.addFrom(item.source)
+		table.Unlock()
+		table.log("Bypassing item with key", item.key, "in table", table.name)
+		return false
+	}
+	if _, ok := table.items[item.key]; !ok {
+		table.evictInternal(1)
+		table.evictPrefixesInternal(item.key)
+	}
+
+	old, replaced := table.storeInternal(item)
+	// A racing delete may have claimed the replaced item's removal already.
+	replaced = replaced && old.claimRemoval()
 
 	// Cache values so we don't keep blocking the mutex.
 	expDur := table.cleanupInterval
 	addedItem := table.addedItem
+	resurrectedItem := table.resurrectedItem
 	table.Unlock()
 
+	table.enforceLimits()
+
 	// Trigger callback after adding an item to cache.
 	if addedItem != nil {
 		for _, callback := range addedItem {
 			callback(item)
 		}
 	}
+	if resurrected {
+		for _, callback := range resurrectedItem {
+			callback(item)
+		}
+	}
+	if replaced {
+		if old.removalListener != nil {
+			old.removalListener(old, RemovalReplaced)
+		}
+		old.Release()
+	}
 
 	// If we haven't set up any expiration check timer or found a more imminent item.
 	if item.lifeSpan > 0 && (expDur == 0 || item.lifeSpan < expDur) {
 		table.expirationCheck()
 	}
+
+	return true
+}
+
+// storeInternal stores an item, replacing the one stored under its key, if
+// any, and returns the replaced item.
+// Careful: do not run this method unless the table-mutex is locked!
+func (table *CacheTable) storeInternal(item *CacheItem) (*CacheItem, bool) {
+	table.internInternal(item)
+	table.log("Adding item with key", item.key, "and lifespan of", item.lifeSpan, "to table", table.name)
+	old, replaced := table.items[item.key]
+	table.generation++
+	atomic.StoreUint64(&item.generation, table.generation)
+	if table.stopAudit != nil && item.lazy == nil && item.arena == nil {
+		item.valueHash, _ = valueHash(item.data)
+	}
+	if table.arena != nil && item.arena == nil {
+		table.arena.store(item)
+	}
+	table.items[item.key] = item
+	if !replaced {
+		table.trackPrefixesInternal(item.key, true)
+		table.probateInternal(item)
+	}
+	table.logMutation(logOpSet, item, nil)
+	table.emit(EventAdd, item.key, item)
+	table.trackChange(item.key)
+	table.stats.addFrom(item.source)
+	if table.policy != nil {
+		table.policyMutex.Lock()
+		if replaced {
+			table.policy.Remove(old)
+		}
+		table.policy.Add(item)
+		table.policyMutex.Unlock()
+	}
+	if table.budget != nil {
+		if replaced {
+			table.budget.release(table, old)
+		}
+		table.budget.charge(table, item)
+	}
+
+	return old, replaced
+}
+
+// enforceLimits makes room in the shared budget, possibly evicting from other
+// tables, and applies injected faults.
+// Careful: do not run this method while holding the table-mutex!
+func (table *CacheTable) enforceLimits() {
+	if table.budget != nil {
+		table.budget.enforce()
+	}
+	if table.faults != nil {
+		table.forceEvict()
+	}
+}
+
+// AddWithListener adds a key/value pair to the cache, just like Add. The
+// listener gets called once this specific item is removed from the cache,
+// together with the reason for its removal. This is handy to release
+// resources associated with the item, without having to register a
+// table-wide callback.
+func (table *CacheTable) AddWithListener(key interface{}, lifeSpan time.Duration, data interface{}, listener func(item *CacheItem, reason RemovalReason)) *CacheItem {
+	item := table.newItem(key, lifeSpan, data)
+	item.removalListener = listener
+
+	table.Lock()
+	table.addInternal(item)
+
+	table.audit(context.Background(), auditOpAdd, key)
+	return item
+}
+
+// newItem creates an item, applying the table's lifespan rules and default
+// lifespans.
+func (table *CacheTable) newItem(key interface{}, lifeSpan time.Duration, data interface{}) *CacheItem {
+	if lifeSpan == 0 {
+		if rules := table.loadTTLRules(); rules != nil {
+			lifeSpan = rules.lifeSpan(key)
+		}
+	}
+	if lifeSpan == 0 {
+		lifeSpan = table.defaultLifeSpan
+	}
+	item := NewCacheItem(key, table.jitter(lifeSpan), data)
+	table.stamp(item)
+	item.softLifeSpan = table.defaultSoftLifeSpan
+
+	return item
+}
+
+// evictInternal removes items chosen by the eviction policy until there's
+// room for the given number of new ones.
+// Careful: do not run this method unless the table-mutex is locked!
+func (table *CacheTable) evictInternal(room int) {
+	for table.capacity > 0 && len(table.items)+room > table.capacity {
+		key, ok := table.probationVictimInternal()
+		if !ok {
+			table.policyMutex.Lock()
+			key, ok = table.policy.Evict()
+			table.policyMutex.Unlock()
+		}
+		if !ok {
+			return
+		}
+
+		if _, err := table.deleteInternal(key, RemovalEvicted); err == nil {
+			table.stats.evict()
+		}
+	}
+}
+
+// evictOne removes a single item chosen by the eviction policy. Returns
+// whether an item was removed.
+func (table *CacheTable) evictOne() bool {
+	table.Lock()
+	defer table.Unlock()
+
+	for {
+		table.policyMutex.Lock()
+		key, ok := table.policy.Evict()
+		table.policyMutex.Unlock()
+		if !ok {
+			return false
+		}
+
+		if _, err := table.deleteInternal(key, RemovalEvicted); err == nil {
+			table.stats.evict()
+			return true
+		}
+	}
 }
 
 // Add adds a key/value pair to the cache.
 // Parameter key is the item's cache-key.
 // Parameter lifeSpan determines after which time period without an access the item
-// will get removed from the cache.
+// will get removed from the cache. A lifeSpan of 0 falls back to the table's
+// lifespan rules or default lifespan, if configured, see SetTTLRule.
 // Parameter data is the item's value.
 func (table *CacheTable) Add(key interface{}, lifeSpan time.Duration, data interface{}) *CacheItem {
-	item := NewCacheItem(key, lifeSpan, data)
-
-	// Add item to cache.
-	table.Lock()
-	table.addInternal(item)
+	return table.AddContext(context.Background(), key, lifeSpan, data)
+}
 
-	return item
+func (table *CacheTable) deleteInternal(key interface{}, reason RemovalReason) (*CacheItem, error) {
+	return table.deleteInternalFunc(key, reason, nil)
 }
 
-func (table *CacheTable) deleteInternal(key interface{}) (*CacheItem, error) {
+// deleteInternalFunc deletes an item just like deleteInternal, calling
+// beforeRemoval, if not nil, with the table-mutex locked once no veto callback
+// objected, before the removal callbacks run.
+// Careful: do not run this method unless the table-mutex is locked!
+func (table *CacheTable) deleteInternalFunc(key interface{}, reason RemovalReason, beforeRemoval func()) (*CacheItem, error) {
 	r, ok := table.items[key]
 	if !ok {
 		return nil, ErrKeyNotFound
@@ -216,42 +704,107 @@ func (table *CacheTable) deleteInternal(key interface{}) (*CacheItem, error) {
 
 	// Cache value so we don't keep blocking the mutex.
 	aboutToDeleteItem := table.aboutToDeleteItem
+	var deleteVeto []func(*CacheItem) bool
+	if reason == RemovalDeleted {
+		deleteVeto = table.deleteVeto
+	}
 	table.Unlock()
 
-	// Trigger callbacks before deleting an item from cache.
-	if aboutToDeleteItem != nil {
-		for _, callback := range aboutToDeleteItem {
-			callback(r)
+	// Only explicit deletes can be vetoed.
+	for _, callback := range deleteVeto {
+		if !callback(r) {
+			table.Lock()
+			table.log("Deleting item with key", key, "from table", table.name, "was vetoed")
+			return r, ErrDeleteVetoed
 		}
 	}
-
-	r.RLock()
-	defer r.RUnlock()
-	if r.aboutToExpire != nil {
-		for _, callback := range r.aboutToExpire {
-			callback(key)
-		}
+	table.Lock()
+	if !r.claimRemoval() {
+		// The item got deleted or replaced while running the vetoes.
+		return nil, ErrKeyNotFound
+	}
+	if beforeRemoval != nil {
+		beforeRemoval()
 	}
+	table.Unlock()
+
+	// Trigger callbacks before deleting an item from cache.
+	r.removed(reason, aboutToDeleteItem)
 
 	table.Lock()
-	table.log("Deleting item with key", key, "created on", r.createdOn, "and hit", r.accessCount, "times from table", table.name)
-	delete(table.items, key)
+	if table.items[key] != r {
+		// The key got re-added while running the callbacks, replacing the
+		// item already, so don't delete its successor. As the removal was
+		// claimed here, the replacement doesn't notify or release it again.
+		return r, nil
+	}
+	table.unlinkInternal(key, r, reason)
 
 	return r, nil
 }
 
+// unlinkInternal removes an item from the table, once its callbacks ran.
+// Careful: do not run this method unless the table-mutex is locked!
+func (table *CacheTable) unlinkInternal(key interface{}, r *CacheItem, reason RemovalReason) {
+	table.log("Deleting item with key", key, "created on", r.createdOn, "and hit", r.AccessCount(), "times from table", table.name)
+	delete(table.items, key)
+	table.trackPrefixesInternal(key, false)
+	table.logMutation(logOpDelete, nil, key)
+	table.emit(removalEvent(reason), key, r)
+	table.trackChange(key)
+	if table.policy != nil {
+		table.policyMutex.Lock()
+		table.policy.Remove(r)
+		table.policyMutex.Unlock()
+	}
+	if table.budget != nil {
+		table.budget.release(table, r)
+	}
+	// The shadow cache makes its own eviction decisions.
+	if table.shadow != nil && reason != RemovalEvicted {
+		table.shadow.Delete(key)
+	}
+	if table.ghost != nil {
+		table.ghost.remove(key, reason)
+	}
+}
+
 // Delete an item from the cache.
 func (table *CacheTable) Delete(key interface{}) (*CacheItem, error) {
+	return table.DeleteContext(context.Background(), key)
+}
+
+// DeleteExpired synchronously removes all items that exceeded their lifespan,
+// instead of waiting for the next expiration check, and returns them. Items
+// currently being revalidated are left alone.
+func (table *CacheTable) DeleteExpired() []*CacheItem {
 	table.Lock()
 	defer table.Unlock()
 
-	return table.deleteInternal(key)
+	now := table.now()
+	var expired []*CacheItem
+	for key, item := range table.items {
+		item.RLock()
+		ok := item.lifeSpan > 0 && !item.revalidating && now.Sub(item.AccessedOn()) >= item.lifeSpan
+		item.RUnlock()
+		if !ok {
+			continue
+		}
+
+		if r, err := table.deleteInternal(key, RemovalExpired); err == nil {
+			table.stats.expire()
+			expired = append(expired, r)
+		}
+	}
+
+	return expired
 }
 
 // Exists returns whether an item exists in the cache. Unlike the Value method
 // Exists neither tries to fetch data via the loadData callback nor does it
 // keep the item alive in the cache.
 func (table *CacheTable) Exists(key interface{}) bool {
+	table.touch()
 	table.RLock()
 	defer table.RUnlock()
 	_, ok := table.items[key]
@@ -269,52 +822,202 @@ func (table *CacheTable) NotFoundAdd(key interface{}, lifeSpan time.Duration, da
 		return false
 	}
 
-	item := NewCacheItem(key, lifeSpan, data)
+	item := table.newItem(key, lifeSpan, data)
 	table.addInternal(item)
 
+	table.audit(context.Background(), auditOpAdd, key)
 	return true
 }
 
 // Value returns an item from the cache and marks it to be kept alive. You can
 // pass additional arguments to your DataLoader callback function.
 func (table *CacheTable) Value(key interface{}, args ...interface{}) (*CacheItem, error) {
+	return table.value(LoadRequest{Key: key, Context: context.Background(), args: args}, false)
+}
+
+// value looks up an item, loading it via the data-loader if necessary.
+// Parameter shared tells whether the load may be shared with concurrent
+// callers, see Load.
+func (table *CacheTable) value(req LoadRequest, shared bool) (*CacheItem, error) {
+	key := req.Key
+	table.touch()
+	if h := table.loadOpHook(); h != nil {
+		defer h.observe(OpValue, key, time.Now())
+	}
+	table.faults.delay()
+
 	table.RLock()
 	r, ok := table.items[key]
-	loadData := table.loadData
+	loadData := table.faults.loader(table.loadData)
+	bypass := table.bypass
+	shadow := table.shadow
+	ghost := table.ghost
+	parent := table.parent
 	table.RUnlock()
 
+	if shadow != nil {
+		shadow.Value(key)
+	}
+
+	if bypass {
+		return table.bypassValue(ok, req, loadData)
+	}
 	if ok {
 		// Update access counter and timestamp.
-		r.KeepAlive()
+		table.keepAlive(r)
+		table.stats.hit()
+		// Serve stale items, but refresh them in the background.
+		if loadData != nil && table.isStale(r) {
+			table.refreshItem(r, req, loadData)
+		}
+		if r.lazy != nil {
+			if err := table.materialize(r); err != nil {
+				return nil, err
+			}
+		}
 		return r, nil
 	}
+	table.stats.miss()
+	if ghost != nil {
+		ghost.miss(key)
+	}
+
+	if parent != nil {
+		if r, err := table.valueFromParent(parent, req, shared); err == nil {
+			return r, nil
+		}
+	}
 
 	// Item doesn't exist in cache. Try and fetch it with a data-loader.
 	if loadData != nil {
-		item := loadData(key, args...)
-		if item != nil {
-			table.Add(key, item.lifeSpan, item.data)
-			return item, nil
+		if shared {
+			return table.loadShared(req, loadData)
 		}
-
-		return nil, ErrKeyNotFoundOrLoadable
+		return table.load(req, loadData)
 	}
 
 	return nil, ErrKeyNotFound
 }
 
+// ValueCtx returns an item from the cache, just like Value, but returns
+// ctx.Err() as soon as the context is done while waiting for the data-loader.
+// Cache hits get returned right away. Concurrent calls loading the same key
+// with equal arguments share a single load, just like Load: it keeps going
+// while any of them waits for it.
+func (table *CacheTable) ValueCtx(ctx context.Context, key interface{}, args ...interface{}) (*CacheItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return table.value(LoadRequest{Key: key, Context: ctx, args: args}, true)
+}
+
+// acquireInternal looks up an item and retains it.
+func (table *CacheTable) acquireInternal(key interface{}) (*CacheItem, bool) {
+	table.touch()
+	table.RLock()
+	defer table.RUnlock()
+
+	r, ok := table.items[key]
+	if !ok || !r.Retain() {
+		return nil, false
+	}
+	return r, true
+}
+
+// Acquire returns an item from the cache, just like Value, and leases it to
+// the caller: the item's data won't be released (see Releaser) before the
+// returned release function was called, even if the item gets removed from
+// the cache in the meantime.
+func (table *CacheTable) Acquire(key interface{}, args ...interface{}) (*CacheItem, func(), error) {
+	if table.Bypass() {
+		// Bypassed items aren't owned by the cache, there's nothing to lease.
+		r, err := table.Value(key, args...)
+		if err != nil {
+			return nil, nil, err
+		}
+		return r, func() {}, nil
+	}
+
+	r, ok := table.acquireInternal(key)
+	if !ok {
+		// Load the item via Value, then lease the stored item.
+		if _, err := table.Value(key, args...); err != nil {
+			return nil, nil, err
+		}
+		if r, ok = table.acquireInternal(key); !ok {
+			return nil, nil, ErrKeyNotFoundOrLoadable
+		}
+	} else {
+		table.shadowValue(key)
+		table.keepAlive(r)
+		table.stats.hit()
+	}
+
+	var once sync.Once
+	return r, func() {
+		once.Do(r.Release)
+	}, nil
+}
+
 // Flush deletes all items from this cache table.
 func (table *CacheTable) Flush() {
+	table.FlushContext(context.Background())
+}
+
+// flush deletes all items, without recording it in the audit log.
+func (table *CacheTable) flush() {
 	table.Lock()
-	defer table.Unlock()
 
 	table.log("Flushing table", table.name)
 
+	// Collect the items, so we can notify listeners and release them once
+	// we're unlocked.
+	flushed := make([]*CacheItem, 0, len(table.items))
+	for _, item := range table.items {
+		if item.claimRemoval() {
+			flushed = append(flushed, item)
+		}
+	}
+
 	table.items = make(map[interface{}]*CacheItem)
+	for _, l := range table.prefixLimits {
+		l.reset()
+	}
+	if table.probation != nil {
+		table.probation.items.Init()
+	}
+	table.logMutation(logOpFlush, nil, nil)
+	table.emit(EventFlush, nil, nil)
+	table.trackFlush()
+	if table.policy != nil {
+		table.policyMutex.Lock()
+		table.policy.Reset()
+		table.policyMutex.Unlock()
+	}
+	if table.budget != nil {
+		table.budget.releaseAll(table)
+	}
+	if table.shadow != nil {
+		table.shadow.Flush()
+	}
 	table.cleanupInterval = 0
 	if table.cleanupTimer != nil {
 		table.cleanupTimer.Stop()
 	}
+	table.Unlock()
+
+	for _, item := range flushed {
+		if item.removalListener != nil {
+			item.removalListener(item, RemovalFlushed)
+		}
+		item.Release()
+	}
+}
+
+// Stats returns the table's usage statistics. All counters are zero unless
+// the table was created with the WithStats option.
+func (table *CacheTable) Stats() CacheStats {
+	return table.stats.snapshot()
 }
 
 // CacheItemPair maps key to access counter
@@ -339,7 +1042,7 @@ func (table *CacheTable) MostAccessed(count int64) []*CacheItem {
 	p := make(CacheItemPairList, len(table.items))
 	i := 0
 	for k, v := range table.items {
-		p[i] = CacheItemPair{k, v.accessCount}
+		p[i] = CacheItemPair{k, v.AccessCount()}
 		i++
 	}
 	sort.Sort(p)
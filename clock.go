@@ -0,0 +1,116 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"container/list"
+)
+
+// clockEntry is a slot on the clock, carrying the key's reference bit.
+type clockEntry struct {
+	key        interface{}
+	referenced bool
+}
+
+// ClockPolicy implements the CLOCK (second-chance) eviction policy. Accesses
+// only set a reference bit; the clock hand sweeps over the items on eviction,
+// clearing reference bits until it finds an item that wasn't used since the
+// last sweep.
+type ClockPolicy struct {
+	// Items in insertion order, treated as a circular buffer.
+	clock *list.List
+	// The element the clock hand currently points to.
+	hand    *list.Element
+	entries map[interface{}]*list.Element
+}
+
+// NewClockPolicy creates a CLOCK eviction policy.
+func NewClockPolicy() *ClockPolicy {
+	return &ClockPolicy{
+		clock:   list.New(),
+		entries: make(map[interface{}]*list.Element),
+	}
+}
+
+// Add places a new item right behind the clock hand, so it's the last one to
+// be looked at during the next sweep.
+func (p *ClockPolicy) Add(item *CacheItem) {
+	if _, exists := p.entries[item.key]; exists {
+		p.Access(item)
+		return
+	}
+
+	entry := &clockEntry{key: item.key}
+	if p.hand == nil {
+		p.entries[item.key] = p.clock.PushBack(entry)
+		p.hand = p.entries[item.key]
+		return
+	}
+	p.entries[item.key] = p.clock.InsertBefore(entry, p.hand)
+}
+
+// Access sets the item's reference bit.
+func (p *ClockPolicy) Access(item *CacheItem) {
+	if element, exists := p.entries[item.key]; exists {
+		element.Value.(*clockEntry).referenced = true
+	}
+}
+
+// Remove takes an item off the clock.
+func (p *ClockPolicy) Remove(item *CacheItem) {
+	element, exists := p.entries[item.key]
+	if !exists {
+		return
+	}
+	p.unlink(element)
+}
+
+// Evict advances the clock hand, giving referenced items a second chance,
+// until it finds an unreferenced item.
+func (p *ClockPolicy) Evict() (interface{}, bool) {
+	if p.hand == nil {
+		return nil, false
+	}
+
+	for {
+		entry := p.hand.Value.(*clockEntry)
+		if !entry.referenced {
+			p.unlink(p.hand)
+			return entry.key, true
+		}
+		entry.referenced = false
+		p.advance()
+	}
+}
+
+// Reset drops all bookkeeping.
+func (p *ClockPolicy) Reset() {
+	p.clock.Init()
+	p.hand = nil
+	p.entries = make(map[interface{}]*list.Element)
+}
+
+// advance moves the clock hand to the next item, wrapping around at the end.
+func (p *ClockPolicy) advance() {
+	p.hand = p.hand.Next()
+	if p.hand == nil {
+		p.hand = p.clock.Front()
+	}
+}
+
+// unlink removes an element from the clock, moving the hand along if needed.
+func (p *ClockPolicy) unlink(element *list.Element) {
+	if element == p.hand {
+		p.advance()
+		if p.hand == element {
+			p.hand = nil
+		}
+	}
+	p.clock.Remove(element)
+	delete(p.entries, element.Value.(*clockEntry).key)
+}
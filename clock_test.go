@@ -0,0 +1,68 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+)
+
+func TestClockSecondChance(t *testing.T) {
+	cache := NewBoundedCache("testClock", 3, NewClockPolicy())
+
+	cache.Add("key1", 0, "value1")
+	cache.Add("key2", 0, "value2")
+	cache.Add("key3", 0, "value3")
+
+	// key1 and key3 get a second chance, key2 doesn't
+	cache.Value("key1")
+	cache.Value("key3")
+
+	cache.Add("key4", 0, "value4")
+	if cache.Exists("key2") {
+		t.Error("key2 should be evicted (not referenced)")
+	}
+	if !cache.Exists("key1") || !cache.Exists("key3") || !cache.Exists("key4") {
+		t.Error("Referenced and new items should still exist")
+	}
+
+	// key3 keeps its reference bit for one more sweep, while key1 already
+	// used up its second chance
+	cache.Add("key5", 0, "value5")
+	if cache.Exists("key1") {
+		t.Error("key1 should be evicted after losing its second chance")
+	}
+	if !cache.Exists("key3") {
+		t.Error("key3 should still exist (second chance)")
+	}
+	if cache.Count() != 3 {
+		t.Error("Cache should contain exactly 3 items")
+	}
+}
+
+func TestClockRemove(t *testing.T) {
+	p := NewClockPolicy()
+
+	a := NewCacheItem("a", 0, nil)
+	b := NewCacheItem("b", 0, nil)
+	p.Add(a)
+	p.Add(b)
+
+	// Removing the item under the hand must keep the clock consistent
+	p.Remove(a)
+	if key, ok := p.Evict(); !ok || key != "b" {
+		t.Error("Expected b to be evicted, got", key)
+	}
+	if _, ok := p.Evict(); ok {
+		t.Error("Empty policy should not return a victim")
+	}
+
+	p.Add(a)
+	if key, ok := p.Evict(); !ok || key != "a" {
+		t.Error("Expected a to be evicted, got", key)
+	}
+}
@@ -0,0 +1,162 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+// Command cache2go-cli administrates the caches of a process serving
+// cache2go's admin handler, see cache2go.NewAdminHandler.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/muesli/cache2go/internal/adminclient"
+)
+
+const usage = `Usage: cache2go-cli [-addr URL] [-token t] [-cert file -key file] <command> [arguments]
+
+Commands:
+  list                               list all caches
+  info <cache>                       show a cache's size and statistics
+  keys [-limit n] <cache>            list the most accessed keys of a cache
+  get <cache> <key>                  show an item and its value
+  set [-ttl d] [-json] <cache> <key> <value>
+                                     add an item
+  del <cache> <key>                  delete an item
+  flush <cache>                      remove all items from a cache
+  stats [-watch d] [cache]           show statistics, of all caches by default,
+                                     repeatedly if -watch is given
+`
+
+var conn = adminclient.RegisterFlags(flag.CommandLine)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	client, err := conn.Client()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cache2go-cli:", err)
+		os.Exit(1)
+	}
+	if err := run(client, flag.Arg(0), flag.Args()[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "cache2go-cli:", err)
+		os.Exit(1)
+	}
+}
+
+func run(client *adminclient.Client, command string, args []string) error {
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	limit := fs.Int("limit", 20, "maximum number of keys")
+	ttl := fs.Duration("ttl", 0, "lifespan of the item, 0 means forever")
+	isJSON := fs.Bool("json", false, "send the value as JSON")
+	watch := fs.Duration("watch", 0, "refresh interval")
+	fs.Parse(args)
+	args = fs.Args()
+
+	switch {
+	case command == "list" && len(args) == 0:
+		caches, err := client.Caches()
+		if err != nil {
+			return err
+		}
+		printCaches(caches)
+	case command == "info" && len(args) == 1:
+		c, err := client.Cache(args[0])
+		if err != nil {
+			return err
+		}
+		printCaches([]adminclient.Cache{c})
+	case command == "keys" && len(args) == 1:
+		items, err := client.Keys(args[0], *limit)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tACCESSES\tLIFESPAN\tREMAINING")
+		for _, i := range items {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", i.Key, i.AccessCount, orDash(i.LifeSpan), orDash(i.TTLRemaining))
+		}
+		w.Flush()
+	case command == "get" && len(args) == 2:
+		i, err := client.Get(args[0], args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("key:       %s\nsource:    %s\ncreated:   %s\naccessed:  %s\naccesses:  %d\nlifespan:  %s\nremaining: %s\nvalue:     %s\n",
+			i.Key, orDash(i.Source), i.CreatedOn.Format(time.RFC3339), i.AccessedOn.Format(time.RFC3339), i.AccessCount,
+			orDash(i.LifeSpan), orDash(i.TTLRemaining), orDash(string(i.Value)))
+	case command == "set" && len(args) == 3:
+		return client.Set(args[0], args[1], args[2], *ttl, *isJSON)
+	case command == "del" && len(args) == 2:
+		return client.Delete(args[0], args[1])
+	case command == "flush" && len(args) == 1:
+		return client.Flush(args[0])
+	case command == "stats" && len(args) <= 1:
+		for {
+			if err := printStats(client, args); err != nil {
+				return err
+			}
+			if *watch <= 0 {
+				return nil
+			}
+			time.Sleep(*watch)
+		}
+	default:
+		return fmt.Errorf("invalid command or arguments, see -help")
+	}
+
+	return nil
+}
+
+// printStats prints the statistics of a cache, or of all caches.
+func printStats(client *adminclient.Client, args []string) error {
+	var stats adminclient.Stats
+	if len(args) == 1 {
+		c, err := client.Cache(args[0])
+		if err != nil {
+			return err
+		}
+		stats = c.Stats
+	} else {
+		var err error
+		if stats, err = client.Stats(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("%s hits=%d misses=%d ratio=%.3f added=%d loaded=%d restored=%d deleted=%d expired=%d evicted=%d\n",
+		time.Now().Format("15:04:05"), stats.Hits, stats.Misses, stats.HitRatio(),
+		stats.Added, stats.Loaded, stats.Restored, stats.Deleted, stats.Expired, stats.Evicted)
+	return nil
+}
+
+func printCaches(caches []adminclient.Cache) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tKIND\tITEMS\tHITS\tMISSES\tRATIO\tEVICTED\tEXPIRED")
+	for _, c := range caches {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%.3f\t%d\t%d\n",
+			c.Name, c.Kind, c.Count, c.Stats.Hits, c.Stats.Misses, c.HitRatio, c.Stats.Evicted, c.Stats.Expired)
+	}
+	w.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
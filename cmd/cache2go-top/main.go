@@ -0,0 +1,133 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+// Command cache2go-top shows live statistics of the caches of a process
+// serving cache2go's admin handler, see cache2go.NewAdminHandler.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/muesli/cache2go/internal/adminclient"
+)
+
+var (
+	conn     = adminclient.RegisterFlags(flag.CommandLine)
+	interval = flag.Duration("interval", 2*time.Second, "refresh interval")
+	focus    = flag.String("cache", "", "cache to show the top keys of, the busiest one by default")
+	topKeys  = flag.Int("keys", 10, "number of top keys to show")
+)
+
+// key identifies a cache across refreshes.
+type key struct {
+	name, kind string
+}
+
+func main() {
+	flag.Parse()
+	client, err := conn.Client()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cache2go-top:", err)
+		os.Exit(1)
+	}
+
+	previous := make(map[key]adminclient.Stats)
+	last := time.Now()
+	for {
+		caches, err := client.Caches()
+		now := time.Now()
+		elapsed := now.Sub(last).Seconds()
+		last = now
+
+		var buf bytes.Buffer
+		// Clear the screen and move the cursor home.
+		buf.WriteString("\033[H\033[2J")
+		fmt.Fprintf(&buf, "cache2go-top - %s - %s - refreshing every %s\n\n", conn.Addr, now.Format("15:04:05"), *interval)
+		if err != nil {
+			fmt.Fprintln(&buf, "Error:", err)
+		} else {
+			busiest := render(&buf, caches, previous, elapsed)
+			name := *focus
+			if name == "" {
+				name = busiest
+			}
+			if name != "" {
+				renderKeys(&buf, client, name)
+			}
+		}
+		os.Stdout.Write(buf.Bytes())
+
+		time.Sleep(*interval)
+	}
+}
+
+// render writes the caches' statistics, returning the name of the cache with
+// the most lookups since the last refresh.
+func render(buf *bytes.Buffer, caches []adminclient.Cache, previous map[key]adminclient.Stats, elapsed float64) string {
+	w := tabwriter.NewWriter(buf, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "NAME\tKIND\tITEMS\tHIT RATIO\tLOOKUPS/S\tADDS/S\tEVICTIONS/S\tEXPIRATIONS/S\t")
+
+	busiest := ""
+	var mostLookups int64 = -1
+	for _, c := range caches {
+		k := key{c.Name, c.Kind}
+		prev, ok := previous[k]
+		if !ok {
+			// No rates before the second refresh.
+			prev = c.Stats
+		}
+		previous[k] = c.Stats
+
+		lookups := c.Stats.Hits + c.Stats.Misses - prev.Hits - prev.Misses
+		if lookups > mostLookups {
+			busiest = c.Name
+			mostLookups = lookups
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%.1f%%\t%.1f\t%.1f\t%.1f\t%.1f\t\n",
+			c.Name, c.Kind, c.Count, c.HitRatio*100,
+			rate(lookups, elapsed),
+			rate(c.Stats.Added-prev.Added, elapsed),
+			rate(c.Stats.Evicted-prev.Evicted, elapsed),
+			rate(c.Stats.Expired-prev.Expired, elapsed))
+	}
+	w.Flush()
+
+	return busiest
+}
+
+// renderKeys writes the cache's most accessed keys.
+func renderKeys(buf *bytes.Buffer, client *adminclient.Client, name string) {
+	fmt.Fprintf(buf, "\nTop keys of %s:\n\n", name)
+	items, err := client.Keys(name, *topKeys)
+	if err != nil {
+		fmt.Fprintln(buf, "Error:", err)
+		return
+	}
+
+	w := tabwriter.NewWriter(buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tACCESSES\tREMAINING")
+	for _, i := range items {
+		remaining := i.TTLRemaining
+		if remaining == "" {
+			remaining = "-"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\n", i.Key, i.AccessCount, remaining)
+	}
+	w.Flush()
+}
+
+func rate(delta int64, elapsed float64) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(delta) / elapsed
+}
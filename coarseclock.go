@@ -0,0 +1,79 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// coarseClock is a clock that only advances every so often, updated by a
+// single goroutine, so reading it is cheaper than calling time.Now.
+type coarseClock struct {
+	// The current time in nanoseconds since the epoch. Accessed atomically,
+	// so it comes first to be 64-bit aligned.
+	now int64
+}
+
+var (
+	// Coarse clocks by resolution. They're shared by all tables and run for
+	// the lifetime of the process.
+	coarseClocks     = make(map[time.Duration]*coarseClock)
+	coarseClockMutex sync.Mutex
+)
+
+// WithCoarseClock makes a table timestamp accesses with a clock that only
+// advances every resolution, instead of calling time.Now on every access,
+// which can be a noticeable cost in extremely hot caches. Items may expire
+// up to resolution earlier than they would otherwise, so it should be well
+// below the lifespans in use, e.g. 10ms. Tables with the same resolution
+// share a clock, updated by a single goroutine.
+func WithCoarseClock(resolution time.Duration) Option {
+	return func(o *cacheOptions) {
+		o.clockResolution = resolution
+	}
+}
+
+// coarseClockFor returns the shared coarse clock of the given resolution,
+// starting it if necessary.
+func coarseClockFor(resolution time.Duration) *coarseClock {
+	coarseClockMutex.Lock()
+	defer coarseClockMutex.Unlock()
+
+	c, ok := coarseClocks[resolution]
+	if !ok {
+		c = &coarseClock{now: time.Now().UnixNano()}
+		coarseClocks[resolution] = c
+		go c.run(resolution)
+	}
+	return c
+}
+
+// run advances the clock every resolution.
+func (c *coarseClock) run(resolution time.Duration) {
+	ticker := time.NewTicker(resolution)
+	defer ticker.Stop()
+	for t := range ticker.C {
+		atomic.StoreInt64(&c.now, t.UnixNano())
+	}
+}
+
+// nanos returns the clock's time in nanoseconds since the epoch. A nil clock
+// returns the precise time.
+func (c *coarseClock) nanos() int64 {
+	if c == nil {
+		return time.Now().UnixNano()
+	}
+	return atomic.LoadInt64(&c.now)
+}
+
+// keepAlive marks an item as accessed, using the table's clock.
+func (table *CacheTable) keepAlive(item *CacheItem) {
+	item.keepAliveAt(table.clock.nanos())
+}
@@ -0,0 +1,39 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCoarseClock(t *testing.T) {
+	// The clock won't advance during the test.
+	table, _ := CacheWithOptions("testCoarseClock", WithCoarseClock(time.Hour))
+	other, _ := CacheWithOptions("testCoarseClockOther", WithCoarseClock(time.Hour))
+	if table.clock == nil || table.clock != other.clock {
+		t.Fatal("Expected tables with the same resolution to share a clock")
+	}
+
+	table.Add("key", 0, v)
+	table.Value("key")
+	item, _ := table.Value("key")
+	accessedOn := item.AccessedOn()
+	time.Sleep(5 * time.Millisecond)
+	table.Value("key")
+	if !item.AccessedOn().Equal(accessedOn) || item.AccessCount() != 3 {
+		t.Error("Expected accesses to be timestamped by the coarse clock, got", item.AccessedOn(), accessedOn)
+	}
+
+	clock := coarseClockFor(5 * time.Millisecond)
+	start := clock.nanos()
+	time.Sleep(50 * time.Millisecond)
+	if clock.nanos() <= start {
+		t.Error("Expected the clock to advance")
+	}
+}
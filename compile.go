@@ -0,0 +1,96 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"regexp"
+	"sync"
+	"text/template"
+)
+
+// CompileCacheCapacity is the default number of compiled regular expressions
+// and templates cached by CachedRegexp and CachedTemplate, see
+// SetCompileCacheCapacity.
+const CompileCacheCapacity = 512
+
+var (
+	// Cache of compiled regular expressions and templates, evicting the
+	// least frequently used ones.
+	compileCache = NewLFUCache("compile", CompileCacheCapacity)
+	// Guards compileCache being replaced, see SetCompileCacheCapacity.
+	compileMutex sync.RWMutex
+)
+
+// regexpKey is the key of a compiled regular expression.
+type regexpKey string
+
+// templateKey is the key of a compiled template.
+type templateKey struct {
+	name, text string
+}
+
+// SetCompileCacheCapacity replaces the cache used by CachedRegexp and
+// CachedTemplate by an empty one with the given capacity.
+func SetCompileCacheCapacity(capacity int) {
+	compileMutex.Lock()
+	defer compileMutex.Unlock()
+	compileCache = NewLFUCache("compile", capacity)
+}
+
+// CachedRegexp returns the compiled regular expression for pattern, just like
+// regexp.Compile, compiling it only if it isn't cached yet. This makes
+// it cheap to use patterns that aren't known in advance, e.g. from user
+// input or configuration, in hot paths. Invalid patterns don't get cached.
+func CachedRegexp(pattern string) (*regexp.Regexp, error) {
+	v, err := compiled(regexpKey(pattern), func() (interface{}, error) {
+		return regexp.Compile(pattern)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*regexp.Regexp), nil
+}
+
+// CachedTemplate returns the template parsed from text with the given name,
+// just like template.New(name).Parse(text), parsing it only if it isn't cached
+// yet. The template is shared by all callers: executing it is
+// safe for concurrent use, but it must not be modified, e.g. by adding
+// functions or templates. Invalid templates don't get cached.
+func CachedTemplate(name, text string) (*template.Template, error) {
+	v, err := compiled(templateKey{name, text}, func() (interface{}, error) {
+		return template.New(name).Parse(text)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*template.Template), nil
+}
+
+// compiled returns the cached value for key, or the one compile returns.
+// Compilations of the same key are serialized, so a value doesn't get
+// compiled twice, while different keys compile concurrently.
+func compiled(key interface{}, compile func() (interface{}, error)) (interface{}, error) {
+	compileMutex.RLock()
+	cache := compileCache
+	compileMutex.RUnlock()
+	if item, err := cache.Value(key); err == nil {
+		return item.Data(), nil
+	}
+
+	unlock := cache.KeyLock(key)
+	defer unlock()
+	if item, err := cache.Value(key); err == nil {
+		return item.Data(), nil
+	}
+	v, err := compile()
+	if err != nil {
+		return nil, err
+	}
+	cache.Add(key, 0, v)
+	return v, nil
+}
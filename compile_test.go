@@ -0,0 +1,92 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func TestCachedRegexp(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			re, err := CachedRegexp("^[a-z]+$")
+			if err != nil || !re.MatchString("abc") {
+				t.Error("Error compiling regular expression", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	a, _ := CachedRegexp("^[a-z]+$")
+	b, _ := CachedRegexp("^[a-z]+$")
+	if a != b {
+		t.Error("Expected compiled regular expressions to be cached")
+	}
+	if _, err := CachedRegexp("("); err == nil {
+		t.Error("Expected invalid patterns to fail")
+	}
+}
+
+func TestCachedTemplate(t *testing.T) {
+	a, err := CachedTemplate("greeting", "Hello {{.}}!")
+	if err != nil {
+		t.Fatal("Error parsing template", err)
+	}
+	var buf bytes.Buffer
+	if err := a.Execute(&buf, "world"); err != nil || buf.String() != "Hello world!" {
+		t.Error("Unexpected output", buf.String(), err)
+	}
+
+	if b, _ := CachedTemplate("greeting", "Hello {{.}}!"); a != b {
+		t.Error("Expected parsed templates to be cached")
+	}
+	if b, _ := CachedTemplate("other", "Hello {{.}}!"); a == b {
+		t.Error("Expected templates to be cached by name and text")
+	}
+	if _, err := CachedTemplate("invalid", "{{"); err == nil {
+		t.Error("Expected invalid templates to fail")
+	}
+}
+
+func TestSetCompileCacheCapacity(t *testing.T) {
+	defer SetCompileCacheCapacity(CompileCacheCapacity)
+	SetCompileCacheCapacity(1)
+
+	a, _ := CachedRegexp("a")
+	CachedRegexp("b")
+	if b, _ := CachedRegexp("a"); a == b {
+		t.Error("Expected compiled regular expressions to be evicted")
+	}
+}
+
+func TestCompiledPerKey(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		compiled(regexpKey("testCompiledPerKey slow"), func() (interface{}, error) {
+			close(started)
+			<-release
+			return nil, nil
+		})
+	}()
+	<-started
+
+	// Compiling another key must not wait for the slow one.
+	if _, err := CachedRegexp("testCompiledPerKey"); err != nil {
+		t.Error("Error compiling regular expression", err)
+	}
+	close(release)
+	<-done
+}
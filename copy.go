@@ -0,0 +1,164 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"container/list"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// MergePolicy determines which item MergeFrom keeps for keys that exist in
+// both caches.
+type MergePolicy int
+
+const (
+	// MergeKeepExisting keeps the destination's items.
+	MergeKeepExisting MergePolicy = iota
+	// MergeOverwrite replaces the destination's items with the source's.
+	MergeOverwrite
+	// MergeNewest keeps whichever item was accessed more recently.
+	MergeNewest
+)
+
+// itemsByAccessCount sorts items by ascending access count.
+type itemsByAccessCount []*CacheItem
+
+func (p itemsByAccessCount) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p itemsByAccessCount) Len() int           { return len(p) }
+func (p itemsByAccessCount) Less(i, j int) bool { return p[i].AccessCount() < p[j].AccessCount() }
+
+// CopyTo copies the table's items accepted by filter, or all of them if
+// filter is nil, to another cache, e.g. to migrate them to an LFUCache. The
+// items keep their remaining lifespans and access counts; expired ones
+// don't get copied. Existing items in the destination get replaced. The
+// most accessed items get copied last, so they're the ones kept if the
+// destination can't hold all of them. Returns the number of copied items.
+// Values aren't copied but shared by both caches, so values implementing
+// Releaser get released as soon as either cache removes them.
+func (table *CacheTable) CopyTo(dst Cacher, filter func(key interface{}, item *CacheItem) bool) int {
+	var items []*CacheItem
+	table.Freeze().Foreach(func(key interface{}, item *CacheItem) {
+		if filter == nil || filter(key, item) {
+			items = append(items, item)
+		}
+	})
+	sort.Sort(itemsByAccessCount(items))
+
+	n := 0
+	now := time.Now()
+	for _, item := range items {
+		if item.expired(now) {
+			continue
+		}
+		addCopy(dst, item)
+		n++
+	}
+	return n
+}
+
+// MergeFrom copies all items of another cache into the table, keeping their
+// remaining lifespans and access counts, and resolving conflicts with the
+// given policy. Expired items don't get copied. Returns the number of copied
+// items. Just like with CopyTo, the most accessed items get copied last, and
+// values are shared by both caches.
+func (table *CacheTable) MergeFrom(src Cacher, policy MergePolicy) int {
+	var items []*CacheItem
+	src.Foreach(func(key interface{}, item *CacheItem) {
+		items = append(items, item.freeze())
+	})
+	sort.Sort(itemsByAccessCount(items))
+
+	n := 0
+	now := time.Now()
+	for _, item := range items {
+		if item.expired(now) {
+			continue
+		}
+
+		table.Lock()
+		if existing, ok := table.items[item.key]; ok {
+			if policy == MergeKeepExisting || (policy == MergeNewest && !existing.AccessedOn().Before(item.AccessedOn())) {
+				table.Unlock()
+				continue
+			}
+		}
+		table.addInternal(item)
+		n++
+	}
+	return n
+}
+
+// expired returns whether the item exceeded its lifespan.
+func (item *CacheItem) expired(now time.Time) bool {
+	item.RLock()
+	defer item.RUnlock()
+	return item.lifeSpan > 0 && now.Sub(item.AccessedOn()) >= item.lifeSpan
+}
+
+// addCopy adds a copy of an item to a cache, keeping its access metadata.
+func addCopy(dst Cacher, item *CacheItem) {
+	switch c := dst.(type) {
+	case *CacheTable:
+		c.Lock()
+		c.addInternal(item)
+	case *LFUCache:
+		c.addCopy(item)
+	default:
+		added := dst.Add(item.key, item.lifeSpan, item.Data())
+		added.Lock()
+		added.createdOn = item.createdOn
+		added.Unlock()
+		added.setAccessedOn(item.AccessedOn())
+		atomic.StoreInt64(&added.accessCount, item.AccessCount())
+	}
+}
+
+// addCopy adds a copy of an item, with a frequency matching its access
+// count.
+func (cache *LFUCache) addCopy(item *CacheItem) {
+	cache.Lock()
+	defer cache.Unlock()
+
+	key := item.key
+	if entry, exists := cache.items[key]; exists {
+		cache.removeEntry(key, entry)
+	} else if cache.size >= cache.capacity {
+		cache.evictLFU()
+	}
+
+	frequency := int(item.AccessCount()) + 1
+	if _, exists := cache.frequencies[frequency]; !exists {
+		cache.frequencies[frequency] = &LFUNode{
+			frequency: frequency,
+			items:     list.New(),
+		}
+	}
+	entry := &lfuEntry{
+		item:      item,
+		element:   cache.frequencies[frequency].items.PushFront(key),
+		frequency: frequency,
+		heapIndex: -1,
+	}
+	cache.items[key] = entry
+	cache.size++
+	cache.updateExpiry(entry)
+
+	// The minimum frequency may be higher than the item's, or may refer to
+	// a bucket that got emptied.
+	cache.minFrequency = frequency
+	for f, node := range cache.frequencies {
+		if f < cache.minFrequency && node.items.Len() > 0 {
+			cache.minFrequency = f
+		}
+	}
+	cache.stats.add()
+
+	cache.log("Adding copy of item with key", key, "to LFU cache", cache.name)
+}
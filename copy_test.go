@@ -0,0 +1,91 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCopyTo(t *testing.T) {
+	table := Cache("testCopyTo")
+	table.Flush()
+	table.Add("a", 0, "a")
+	table.Add("b", time.Hour, "b")
+	table.Add("skip", 0, "skip")
+	table.Add("expired", time.Millisecond, "expired")
+	for i := 0; i < 3; i++ {
+		table.Value("a")
+	}
+	time.Sleep(5 * time.Millisecond)
+	table.PauseExpiration()
+	defer table.ResumeExpiration()
+
+	lfu := NewLFUCache("testCopyToLFU", 10)
+	n := table.CopyTo(lfu, func(key interface{}, item *CacheItem) bool {
+		return key != "skip"
+	})
+	if n != 2 || lfu.Count() != 2 || lfu.Exists("skip") || lfu.Exists("expired") {
+		t.Error("Expected 2 items to get copied, got", n)
+	}
+	if f, _ := lfu.Frequency("a"); f != 4 {
+		t.Error("Expected the frequency to match the access count, got", f)
+	}
+	b, _ := table.Value("b")
+	copied, _ := lfu.Value("b")
+	if copied == b || copied.LifeSpan() != time.Hour || !copied.CreatedOn().Equal(b.CreatedOn()) {
+		t.Error("Expected a copy of the item with its lifespan")
+	}
+
+	// Once full, the least frequently used copies get evicted.
+	small := NewLFUCache("testCopyToSmall", 1)
+	table.CopyTo(small, nil)
+	if small.Count() != 1 || !small.Exists("a") {
+		t.Error("Expected the most frequently used item to be kept")
+	}
+
+	other := Cache("testCopyToTable")
+	other.Flush()
+	table.CopyTo(other, nil)
+	a, _ := other.Value("a")
+	if other.Count() != 3 || a.AccessCount() != 4 {
+		t.Error("Expected items to get copied with their access counts")
+	}
+}
+
+func TestMergeFrom(t *testing.T) {
+	src := NewLFUCache("testMergeFromLFU", 10)
+	src.Add("a", 0, "src")
+	src.Add("b", 0, "src")
+	src.Add("c", 0, "src")
+
+	merge := func(policy MergePolicy) *CacheTable {
+		table := Cache("testMergeFrom")
+		table.Flush()
+		table.Add("a", 0, "dst")
+		table.Add("b", 0, "dst")
+		// The source's "b" is accessed after the table's.
+		time.Sleep(time.Millisecond)
+		src.Value("b")
+		table.MergeFrom(src, policy)
+		return table
+	}
+	expect := func(table *CacheTable, a, b string) {
+		t.Helper()
+		itemA, _ := table.Value("a")
+		itemB, _ := table.Value("b")
+		itemC, _ := table.Value("c")
+		if itemA.Data() != a || itemB.Data() != b || itemC == nil || itemC.Data() != "src" {
+			t.Errorf("Expected a=%s, b=%s, c=src, got %v, %v, %v", a, b, itemA.Data(), itemB.Data(), itemC)
+		}
+	}
+
+	expect(merge(MergeKeepExisting), "dst", "dst")
+	expect(merge(MergeOverwrite), "src", "src")
+	expect(merge(MergeNewest), "dst", "src")
+}
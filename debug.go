@@ -0,0 +1,138 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Number of upcoming expiries included in debug states.
+const debugExpiries = 10
+
+// DebugExpiry is an upcoming expiry of an item.
+type DebugExpiry struct {
+	Key       string    `json:"key"`
+	ExpiresOn time.Time `json:"expiresOn"`
+}
+
+// debugExpiriesByTime sorts expiries by time, earliest first.
+type debugExpiriesByTime []DebugExpiry
+
+func (p debugExpiriesByTime) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p debugExpiriesByTime) Len() int           { return len(p) }
+func (p debugExpiriesByTime) Less(i, j int) bool { return p[i].ExpiresOn.Before(p[j].ExpiresOn) }
+
+// nextExpiries returns the earliest expiries.
+func nextExpiries(expiries []DebugExpiry) []DebugExpiry {
+	sort.Sort(debugExpiriesByTime(expiries))
+	if len(expiries) > debugExpiries {
+		expiries = expiries[:debugExpiries]
+	}
+	return expiries
+}
+
+// LFUBucket is the number of items with a specific access frequency.
+type LFUBucket struct {
+	Frequency int `json:"frequency"`
+	Size      int `json:"size"`
+}
+
+// LFUDebugState is a view of an LFU cache's internals, see
+// LFUCache.DebugState.
+type LFUDebugState struct {
+	Name         string `json:"name"`
+	Capacity     int    `json:"capacity"`
+	Size         int    `json:"size"`
+	MinFrequency int    `json:"minFrequency"`
+	// Non-empty frequency buckets, by ascending frequency.
+	Buckets []LFUBucket `json:"buckets"`
+	// Number of items with a lifespan, and the next ones to expire.
+	Expiring     int           `json:"expiring"`
+	NextExpiries []DebugExpiry `json:"nextExpiries"`
+}
+
+// lfuBucketsByFrequency sorts buckets by ascending frequency.
+type lfuBucketsByFrequency []LFUBucket
+
+func (p lfuBucketsByFrequency) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p lfuBucketsByFrequency) Len() int           { return len(p) }
+func (p lfuBucketsByFrequency) Less(i, j int) bool { return p[i].Frequency < p[j].Frequency }
+
+// DebugState returns a serializable view of the cache's internals, e.g. to
+// diagnose unexpected evictions. It's also served by the admin handler.
+func (cache *LFUCache) DebugState() LFUDebugState {
+	cache.RLock()
+	defer cache.RUnlock()
+
+	state := LFUDebugState{
+		Name:         cache.name,
+		Capacity:     cache.capacity,
+		Size:         cache.size,
+		MinFrequency: cache.minFrequency,
+		Buckets:      []LFUBucket{},
+		Expiring:     len(cache.expiries),
+	}
+	for frequency, node := range cache.frequencies {
+		if node.items.Len() > 0 {
+			state.Buckets = append(state.Buckets, LFUBucket{Frequency: frequency, Size: node.items.Len()})
+		}
+	}
+	sort.Sort(lfuBucketsByFrequency(state.Buckets))
+
+	expiries := make([]DebugExpiry, 0, len(cache.expiries))
+	for _, entry := range cache.expiries {
+		expiries = append(expiries, DebugExpiry{Key: fmt.Sprint(entry.item.key), ExpiresOn: entry.expiresOn})
+	}
+	state.NextExpiries = nextExpiries(expiries)
+
+	return state
+}
+
+// TableDebugState is a view of a table's internals, see
+// CacheTable.DebugState.
+type TableDebugState struct {
+	Name     string `json:"name"`
+	Capacity int    `json:"capacity"`
+	Count    int    `json:"count"`
+	// Interval of the pending expiration check, 0 if none is scheduled.
+	CleanupInterval  time.Duration `json:"cleanupInterval"`
+	ExpirationPaused bool          `json:"expirationPaused"`
+	// Number of items with a lifespan, and the next ones to expire.
+	Expiring     int           `json:"expiring"`
+	NextExpiries []DebugExpiry `json:"nextExpiries"`
+}
+
+// DebugState returns a serializable view of the table's internals, in
+// particular its expiration schedule. It's also served by the admin handler.
+func (table *CacheTable) DebugState() TableDebugState {
+	table.RLock()
+	defer table.RUnlock()
+
+	state := TableDebugState{
+		Name:             table.name,
+		Capacity:         table.capacity,
+		Count:            len(table.items),
+		CleanupInterval:  table.cleanupInterval,
+		ExpirationPaused: table.expirationPaused,
+	}
+
+	expiries := []DebugExpiry{}
+	for key, item := range table.items {
+		item.RLock()
+		if item.lifeSpan > 0 {
+			expiries = append(expiries, DebugExpiry{Key: fmt.Sprint(key), ExpiresOn: item.AccessedOn().Add(item.lifeSpan)})
+		}
+		item.RUnlock()
+	}
+	state.Expiring = len(expiries)
+	state.NextExpiries = nextExpiries(expiries)
+
+	return state
+}
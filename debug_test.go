@@ -0,0 +1,89 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLFUDebugState(t *testing.T) {
+	cache := NewLFUCache("testLFUDebugState", 10)
+	cache.Add("a", 0, 1)
+	cache.Add("b", time.Hour, 2)
+	cache.Add("c", time.Minute, 3)
+	cache.Value("a")
+	cache.Value("a")
+	cache.Value("b")
+
+	state := cache.DebugState()
+	if state.Name != "testLFUDebugState" || state.Capacity != 10 || state.Size != 3 {
+		t.Errorf("Unexpected debug state %+v", state)
+	}
+	if state.MinFrequency != 1 {
+		t.Error("Expected min frequency 1, got", state.MinFrequency)
+	}
+	expected := []LFUBucket{{1, 1}, {2, 1}, {3, 1}}
+	if len(state.Buckets) != len(expected) {
+		t.Fatalf("Expected buckets %v, got %v", expected, state.Buckets)
+	}
+	for i, b := range expected {
+		if state.Buckets[i] != b {
+			t.Errorf("Expected buckets %v, got %v", expected, state.Buckets)
+		}
+	}
+	if state.Expiring != 2 || len(state.NextExpiries) != 2 || state.NextExpiries[0].Key != "c" || state.NextExpiries[1].Key != "b" {
+		t.Errorf("Unexpected expiries %+v", state)
+	}
+}
+
+func TestTableDebugState(t *testing.T) {
+	table := Cache("testTableDebugState")
+	table.Flush()
+	for i := 0; i < 20; i++ {
+		table.Add(i, time.Duration(20-i)*time.Minute, i)
+	}
+	table.Add("forever", 0, true)
+
+	state := table.DebugState()
+	if state.Count != 21 || state.Expiring != 20 {
+		t.Errorf("Unexpected debug state %+v", state)
+	}
+	if len(state.NextExpiries) != debugExpiries {
+		t.Fatal("Expected", debugExpiries, "upcoming expiries, got", len(state.NextExpiries))
+	}
+	for i, e := range state.NextExpiries {
+		if e.Key != strconv.Itoa(19-i) {
+			t.Errorf("Expected key %d to expire next, got %s", 19-i, e.Key)
+		}
+	}
+	if state.CleanupInterval <= 0 || state.CleanupInterval > time.Minute {
+		t.Error("Expected expiration check within a minute, got", state.CleanupInterval)
+	}
+}
+
+func TestAdminDebugState(t *testing.T) {
+	h := NewAdminHandler()
+	Cache("testAdminDebugState").Add("a", time.Minute, 1)
+	lfu := NewLFUCache("testAdminDebugStateLFU", 5)
+	mutex.Lock()
+	lfuCaches["testAdminDebugStateLFU"] = lfu
+	mutex.Unlock()
+	lfu.Add("a", 0, 1)
+
+	var tableState TableDebugState
+	if code := adminRequest(t, h, "GET", "/caches/testAdminDebugState/debug", "", &tableState); code != http.StatusOK || tableState.Expiring != 1 {
+		t.Errorf("Unexpected table debug state %+v, status %d", tableState, code)
+	}
+	var lfuState LFUDebugState
+	if code := adminRequest(t, h, "GET", "/caches/testAdminDebugStateLFU/debug", "", &lfuState); code != http.StatusOK || lfuState.Size != 1 || len(lfuState.Buckets) != 1 {
+		t.Errorf("Unexpected LFU debug state %+v, status %d", lfuState, code)
+	}
+}
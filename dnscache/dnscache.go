@@ -0,0 +1,191 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+// Package dnscache provides a caching DNS resolver built on a cache2go table.
+// It has the lookup methods of net.Resolver, so it can replace one e.g. in a
+// dialer's resolve step, and caches answers for their TTL and unknown hosts
+// for a shorter negative TTL.
+package dnscache
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/muesli/cache2go"
+)
+
+// LookupFunc resolves a host to its addresses, and returns how long the
+// answer may be cached, 0 for the resolver's default TTL.
+type LookupFunc func(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error)
+
+// Resolver resolves hosts, caching the answers in a table, see New.
+type Resolver struct {
+	table       *cache2go.CacheTable
+	lookup      LookupFunc
+	ttl         time.Duration
+	negativeTTL time.Duration
+}
+
+// Option configures a Resolver.
+type Option func(*Resolver)
+
+// WithLookup makes the resolver resolve hosts via the given function, e.g.
+// one querying a DNS server directly to learn the records' TTLs. Without it,
+// hosts get resolved via net.DefaultResolver, whose answers don't carry
+// TTLs, so the default TTL applies to all of them.
+func WithLookup(lookup LookupFunc) Option {
+	return func(r *Resolver) {
+		r.lookup = lookup
+	}
+}
+
+// WithTTL sets how long answers without a TTL get cached. Defaults to one
+// minute.
+func WithTTL(ttl time.Duration) Option {
+	return func(r *Resolver) {
+		r.ttl = ttl
+	}
+}
+
+// WithNegativeTTL sets how long unknown hosts, i.e. NXDOMAIN answers, get
+// cached. Defaults to ten seconds, 0 disables negative caching.
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(r *Resolver) {
+		r.negativeTTL = ttl
+	}
+}
+
+// entry is a cached answer: the host's addresses, or the error for unknown
+// hosts, and when it expires.
+type entry struct {
+	addrs   []net.IPAddr
+	err     error
+	expires time.Time
+}
+
+// New returns a resolver caching its answers in the given table, keyed by
+// host names.
+func New(table *cache2go.CacheTable, opts ...Option) *Resolver {
+	r := &Resolver{
+		table:       table,
+		lookup:      defaultLookup,
+		ttl:         time.Minute,
+		negativeTTL: 10 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// defaultLookup resolves a host via net.DefaultResolver.
+func defaultLookup(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	return addrs, 0, err
+}
+
+// LookupIPAddr looks up a host's IP addresses, just like
+// net.Resolver.LookupIPAddr, serving them from the cache if possible.
+// Concurrent lookups of the same host share a single query, waiting for it
+// until ctx is done. The addresses returned are the caller's to modify.
+func (r *Resolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if e, ok := r.cached(host); ok {
+		return copyAddrs(e.addrs), e.err
+	}
+
+	// Let the first lookup resolve the host while the others wait.
+	unlock, err := r.table.KeyLockContext(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if e, ok := r.cached(host); ok {
+		return copyAddrs(e.addrs), e.err
+	}
+
+	addrs, ttl, err := r.lookup(ctx, host)
+	if err != nil {
+		if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound && r.negativeTTL > 0 {
+			r.store(host, entry{err: err}, r.negativeTTL)
+		}
+		return nil, err
+	}
+	if ttl <= 0 {
+		ttl = r.ttl
+	}
+	r.store(host, entry{addrs: copyAddrs(addrs)}, ttl)
+	return addrs, nil
+}
+
+// LookupHost looks up a host's addresses, just like net.Resolver.LookupHost.
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	addrs, err := r.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := make([]string, len(addrs))
+	for i, addr := range addrs {
+		hosts[i] = addr.String()
+	}
+	return hosts, nil
+}
+
+// LookupIP looks up a host's addresses of the given network, "ip", "ip4" or
+// "ip6", just like net.Resolver.LookupIP.
+func (r *Resolver) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	addrs, err := r.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var ips []net.IP
+	for _, addr := range addrs {
+		v4 := addr.IP.To4() != nil
+		if network == "ip" || (network == "ip4" && v4) || (network == "ip6" && !v4) {
+			ips = append(ips, addr.IP)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, &net.DNSError{Err: "no suitable address found", Name: host}
+	}
+	return ips, nil
+}
+
+// copyAddrs returns a deep copy of addresses, so callers modifying them don't
+// change the cached ones.
+func copyAddrs(addrs []net.IPAddr) []net.IPAddr {
+	if addrs == nil {
+		return nil
+	}
+	c := make([]net.IPAddr, len(addrs))
+	for i, addr := range addrs {
+		c[i] = net.IPAddr{IP: append(net.IP(nil), addr.IP...), Zone: addr.Zone}
+	}
+	return c
+}
+
+// cached returns the cached answer for a host, unless it expired.
+func (r *Resolver) cached(host string) (entry, bool) {
+	item, err := r.table.Value(host)
+	if err != nil {
+		return entry{}, false
+	}
+	e, ok := item.Data().(entry)
+	if !ok || !time.Now().Before(e.expires) {
+		// Table lifespans get extended by accesses, TTLs don't.
+		return entry{}, false
+	}
+	return e, true
+}
+
+// store caches the answer for a host for the given TTL.
+func (r *Resolver) store(host string, e entry, ttl time.Duration) {
+	e.expires = time.Now().Add(ttl)
+	r.table.Add(host, ttl, e)
+}
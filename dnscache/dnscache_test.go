@@ -0,0 +1,123 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package dnscache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/muesli/cache2go"
+)
+
+func TestResolver(t *testing.T) {
+	var queries int32
+	lookup := func(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error) {
+		atomic.AddInt32(&queries, 1)
+		time.Sleep(10 * time.Millisecond)
+		switch host {
+		case "example.com":
+			return []net.IPAddr{{IP: net.ParseIP("192.0.2.1")}, {IP: net.ParseIP("2001:db8::1")}}, 50 * time.Millisecond, nil
+		case "flaky.example.com":
+			return nil, 0, errors.New("timeout")
+		}
+		return nil, 0, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	table := cache2go.Cache("testDNSCacheResolver")
+	table.Flush()
+	r := New(table, WithLookup(lookup), WithNegativeTTL(time.Hour))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if hosts, err := r.LookupHost(context.Background(), "example.com"); err != nil || len(hosts) != 2 || hosts[0] != "192.0.2.1" {
+				t.Error("Unexpected answer", hosts, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&queries); n != 1 {
+		t.Error("Expected concurrent lookups to share a query, got", n)
+	}
+
+	if ips, err := r.LookupIP(context.Background(), "ip6", "example.com"); err != nil || len(ips) != 1 || !ips[0].Equal(net.ParseIP("2001:db8::1")) {
+		t.Error("Expected only IPv6 addresses", ips, err)
+	}
+
+	// Answers expire after their TTL, even if they get accessed.
+	time.Sleep(60 * time.Millisecond)
+	r.LookupHost(context.Background(), "example.com")
+	if n := atomic.LoadInt32(&queries); n != 2 {
+		t.Error("Expected expired answers to be resolved again, got", n, "queries")
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.LookupHost(context.Background(), "unknown.example.com"); err == nil {
+			t.Error("Expected unknown hosts to fail")
+		}
+		if _, err := r.LookupHost(context.Background(), "flaky.example.com"); err == nil {
+			t.Error("Expected failing lookups to fail")
+		}
+	}
+	if n := atomic.LoadInt32(&queries); n != 5 {
+		t.Error("Expected only unknown hosts to be cached, got", n, "queries")
+	}
+}
+
+func TestResolverCopies(t *testing.T) {
+	lookup := func(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error) {
+		return []net.IPAddr{{IP: net.ParseIP("192.0.2.1")}}, 0, nil
+	}
+	table := cache2go.Cache("testDNSCacheResolverCopies")
+	table.Flush()
+	r := New(table, WithLookup(lookup))
+
+	addrs, _ := r.LookupIPAddr(context.Background(), "example.com")
+	addrs[0].IP[len(addrs[0].IP)-1] = 2
+	ips, _ := r.LookupIP(context.Background(), "ip", "example.com")
+	ips[0][len(ips[0])-1] = 3
+	if addrs, _ := r.LookupIPAddr(context.Background(), "example.com"); !addrs[0].IP.Equal(net.ParseIP("192.0.2.1")) {
+		t.Error("Modifying returned addresses changed the cached ones", addrs)
+	}
+}
+
+func TestResolverWaitContext(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	lookup := func(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error) {
+		close(started)
+		<-release
+		return []net.IPAddr{{IP: net.ParseIP("192.0.2.1")}}, 0, nil
+	}
+	table := cache2go.Cache("testDNSCacheResolverWaitContext")
+	table.Flush()
+	r := New(table, WithLookup(lookup))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.LookupIPAddr(context.Background(), "example.com")
+	}()
+	<-started
+
+	// Waiting for the slow lookup gets abandoned once ctx is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := r.LookupIPAddr(ctx, "example.com"); err != context.DeadlineExceeded {
+		t.Error("Expected waiting to time out, got", err)
+	}
+	close(release)
+	<-done
+}
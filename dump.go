@@ -0,0 +1,117 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// DumpOptions configures DumpJSON.
+type DumpOptions struct {
+	// Whether to include the items' values, which must be marshalable to
+	// JSON.
+	IncludeValues bool
+	// Returns the representation of a key in the dump, e.g. to redact
+	// personal data. If nil, keys get formatted with fmt.
+	RedactKey func(key interface{}) string
+	// Returns the value to be dumped in place of an item's value, e.g. to
+	// redact secrets. Only used if IncludeValues is set, and applied after
+	// the table's export transformer.
+	RedactValue func(key interface{}, value interface{}) interface{}
+}
+
+// dumpedItem is the JSON representation of an item.
+type dumpedItem struct {
+	Key          string      `json:"key"`
+	CreatedOn    time.Time   `json:"createdOn"`
+	AccessedOn   time.Time   `json:"accessedOn"`
+	AccessCount  int64       `json:"accessCount"`
+	LifeSpan     string      `json:"lifeSpan,omitempty"`
+	TTLRemaining string      `json:"ttlRemaining,omitempty"`
+	Stale        bool        `json:"stale,omitempty"`
+	Value        interface{} `json:"value,omitempty"`
+}
+
+// dumpedItemsByKey sorts dumped items by their key.
+type dumpedItemsByKey []dumpedItem
+
+func (p dumpedItemsByKey) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p dumpedItemsByKey) Len() int           { return len(p) }
+func (p dumpedItemsByKey) Less(i, j int) bool { return p[i].Key < p[j].Key }
+
+// dumpedTable is the JSON representation of a table.
+type dumpedTable struct {
+	Name  string       `json:"name"`
+	Count int          `json:"count"`
+	Items []dumpedItem `json:"items"`
+}
+
+// DumpJSON writes the table's items and their metadata to w as JSON, sorted
+// by key. It's meant for debugging and support, not for persistence: keys
+// get formatted as strings and values are only included on request.
+func (table *CacheTable) DumpJSON(w io.Writer, opts DumpOptions) error {
+	table.RLock()
+	items := make([]*CacheItem, 0, len(table.items))
+	for _, item := range table.items {
+		items = append(items, item)
+	}
+	transform := table.exportTransformer
+	table.RUnlock()
+
+	now := time.Now()
+	dump := dumpedTable{
+		Name:  table.name,
+		Count: len(items),
+		Items: make([]dumpedItem, 0, len(items)),
+	}
+	for _, item := range items {
+		item.RLock()
+		d := dumpedItem{
+			CreatedOn:   item.createdOn,
+			AccessedOn:  item.AccessedOn(),
+			AccessCount: item.AccessCount(),
+		}
+		if item.lifeSpan > 0 {
+			d.LifeSpan = item.lifeSpan.String()
+			remaining := item.lifeSpan - now.Sub(item.AccessedOn())
+			if remaining < 0 {
+				remaining = 0
+			}
+			d.TTLRemaining = remaining.String()
+		}
+		data := item.peek()
+		item.RUnlock()
+		d.Stale = item.IsStale()
+
+		if opts.RedactKey != nil {
+			d.Key = opts.RedactKey(item.key)
+		} else {
+			d.Key = fmt.Sprint(item.key)
+		}
+		if opts.IncludeValues {
+			if transform != nil {
+				data = transform(item.key, data)
+			}
+			if opts.RedactValue != nil {
+				data = opts.RedactValue(item.key, data)
+			}
+			d.Value = data
+		}
+
+		dump.Items = append(dump.Items, d)
+	}
+	sort.Sort(dumpedItemsByKey(dump.Items))
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(dump)
+}
@@ -0,0 +1,60 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDumpJSON(t *testing.T) {
+	table := Cache("testDumpJSON")
+	table.Add("b", time.Minute, "secret")
+	table.Add("a", 0, "public")
+	table.Value("a")
+
+	var buf bytes.Buffer
+	if err := table.DumpJSON(&buf, DumpOptions{}); err != nil {
+		t.Error("Error dumping table", err)
+	}
+	var dump dumpedTable
+	if err := json.Unmarshal(buf.Bytes(), &dump); err != nil {
+		t.Error("Error parsing dump", err)
+	}
+	if dump.Name != "testDumpJSON" || dump.Count != 2 || len(dump.Items) != 2 {
+		t.Error("Unexpected dump", buf.String())
+		return
+	}
+	a, b := dump.Items[0], dump.Items[1]
+	if a.Key != "a" || a.AccessCount != 1 || a.LifeSpan != "" || a.Value != nil {
+		t.Error("Unexpected metadata for item a", a)
+	}
+	if b.Key != "b" || b.LifeSpan != "1m0s" || b.TTLRemaining == "" {
+		t.Error("Unexpected metadata for item b", b)
+	}
+
+	buf.Reset()
+	table.DumpJSON(&buf, DumpOptions{
+		IncludeValues: true,
+		RedactKey: func(key interface{}) string {
+			return "key-" + key.(string)
+		},
+		RedactValue: func(key interface{}, value interface{}) interface{} {
+			if key == "b" {
+				return "REDACTED"
+			}
+			return value
+		},
+	})
+	json.Unmarshal(buf.Bytes(), &dump)
+	if dump.Items[0].Key != "key-a" || dump.Items[0].Value != "public" || dump.Items[1].Value != "REDACTED" {
+		t.Error("Unexpected redacted dump", buf.String())
+	}
+}
@@ -0,0 +1,314 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+// KeyProvider provides the AES keys used to encrypt snapshots and mutation
+// logs. Implementations can fetch keys from a KMS, and rotate them: data
+// gets encrypted with the current key, and remembers the key's id, so it can
+// still be decrypted after rotating.
+type KeyProvider interface {
+	// CurrentKey returns the id of the key to encrypt new data with, and the
+	// key itself, which must be 16, 24 or 32 bytes long.
+	CurrentKey() (id string, key []byte, err error)
+	// Key returns the key with the given id.
+	Key(id string) ([]byte, error)
+}
+
+// staticKey is a KeyProvider for a single key.
+type staticKey []byte
+
+// StaticKey returns a KeyProvider always using the given key, which must be
+// 16, 24 or 32 bytes long.
+func StaticKey(key []byte) KeyProvider {
+	return staticKey(key)
+}
+
+func (k staticKey) CurrentKey() (string, []byte, error) {
+	return "", k, nil
+}
+
+func (k staticKey) Key(id string) ([]byte, error) {
+	return k, nil
+}
+
+// newGCM creates an AES-GCM cipher for the key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypt encrypts data with AES-GCM using the current key, authenticating
+// additionalData along with it. The result holds the key's id, the nonce and
+// the sealed data.
+func encrypt(keys KeyProvider, data, additionalData []byte) ([]byte, error) {
+	id, key, err := keys.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+	if len(id) > 255 {
+		return nil, ErrInvalidCiphertext
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 1+len(id)+gcm.NonceSize(), 1+len(id)+gcm.NonceSize()+len(data)+gcm.Overhead())
+	b[0] = byte(len(id))
+	copy(b[1:], id)
+	nonce := b[1+len(id):]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(b, nonce, data, additionalData), nil
+}
+
+// decrypt decrypts data encrypted by encrypt with the same additionalData.
+func decrypt(keys KeyProvider, b, additionalData []byte) ([]byte, error) {
+	if len(b) < 1 || len(b) < 1+int(b[0]) {
+		return nil, ErrInvalidCiphertext
+	}
+	id := string(b[1 : 1+b[0]])
+	b = b[1+len(id):]
+
+	key, err := keys.Key(id)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < gcm.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+
+	data, err := gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], additionalData)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+	return data, nil
+}
+
+// logCipher encrypts the records of a mutation log segment, i.e. of the
+// records appended since the log file got created, reopened or compacted.
+// Segments have random ids, which the keys of their records are derived
+// from, so records get sealed with counter nonces that never repeat under
+// the same key. The segment id and the record's offset in the file get
+// authenticated along with the record, so records can't be reordered,
+// replayed or spliced between logs unnoticed.
+type logCipher struct {
+	keys    KeyProvider
+	segment []byte
+	// Records sealed so far.
+	sealed uint64
+	// Ciphers with the segment's keys, by key id.
+	gcms map[string]cipher.AEAD
+}
+
+// newLogCipher returns a cipher for the segment with the given id.
+func newLogCipher(keys KeyProvider, segment []byte) *logCipher {
+	return &logCipher{keys: keys, segment: segment, gcms: make(map[string]cipher.AEAD)}
+}
+
+// newLogSegment returns a cipher for a new segment with a random id.
+func newLogSegment(keys KeyProvider) (*logCipher, error) {
+	segment := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, segment); err != nil {
+		return nil, err
+	}
+	return newLogCipher(keys, segment), nil
+}
+
+// gcm returns the cipher with the segment's key derived from the given key.
+func (c *logCipher) gcm(id string, key []byte) (cipher.AEAD, error) {
+	if gcm, ok := c.gcms[id]; ok {
+		return gcm, nil
+	}
+	if _, err := aes.NewCipher(key); err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("cache2go mutation log segment"))
+	mac.Write(c.segment)
+	gcm, err := newGCM(mac.Sum(nil)[:len(key)])
+	if err != nil {
+		return nil, err
+	}
+	c.gcms[id] = gcm
+	return gcm, nil
+}
+
+// additionalData returns the data authenticated along with the record at the
+// given offset.
+func (c *logCipher) additionalData(offset int64) []byte {
+	b := make([]byte, len(c.segment)+8)
+	copy(b, c.segment)
+	binary.BigEndian.PutUint64(b[len(c.segment):], uint64(offset))
+	return b
+}
+
+// seal encrypts the record written at the given offset using the current
+// key. The result holds the key's id, the nonce and the sealed record.
+func (c *logCipher) seal(data []byte, offset int64) ([]byte, error) {
+	id, key, err := c.keys.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+	if len(id) > 255 {
+		return nil, ErrInvalidCiphertext
+	}
+	gcm, err := c.gcm(id, key)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 1+len(id)+gcm.NonceSize(), 1+len(id)+gcm.NonceSize()+len(data)+gcm.Overhead())
+	b[0] = byte(len(id))
+	copy(b[1:], id)
+	nonce := b[1+len(id):]
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], c.sealed)
+	c.sealed++
+
+	return gcm.Seal(b, nonce, data, c.additionalData(offset)), nil
+}
+
+// open decrypts a record sealed at the given offset.
+func (c *logCipher) open(b []byte, offset int64) ([]byte, error) {
+	if len(b) < 1 || len(b) < 1+int(b[0]) {
+		return nil, ErrInvalidCiphertext
+	}
+	id := string(b[1 : 1+b[0]])
+	b = b[1+len(id):]
+
+	gcm, ok := c.gcms[id]
+	if !ok {
+		key, err := c.keys.Key(id)
+		if err != nil {
+			return nil, err
+		}
+		if gcm, err = c.gcm(id, key); err != nil {
+			return nil, err
+		}
+	}
+	if len(b) < gcm.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+
+	data, err := gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], c.additionalData(offset))
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+	return data, nil
+}
+
+// encryptedSink encrypts the snapshots stored in another sink.
+type encryptedSink struct {
+	sink SnapshotSink
+	keys KeyProvider
+
+	// Latest epoch saved or loaded, by snapshot name.
+	mutex  sync.Mutex
+	epochs map[string]uint64
+}
+
+// NewEncryptedSink returns a SnapshotSink encrypting snapshots with AES-GCM
+// before storing them in the given sink, and decrypting them when loading.
+// Snapshots are bound to their name and stamped with an epoch, so loading
+// fails with ErrInvalidCiphertext if a snapshot got stored under another
+// name, and with ErrStaleSnapshot if it's older than one the sink already
+// saved or loaded under its name.
+func NewEncryptedSink(sink SnapshotSink, keys KeyProvider) SnapshotSink {
+	return &encryptedSink{sink: sink, keys: keys, epochs: make(map[string]uint64)}
+}
+
+// snapshotAdditionalData returns the data authenticated along with a
+// snapshot: its name and epoch.
+func snapshotAdditionalData(name string, epoch uint64) []byte {
+	prefix := "cache2go snapshot"
+	b := make([]byte, len(prefix)+8+len(name))
+	copy(b, prefix)
+	binary.BigEndian.PutUint64(b[len(prefix):], epoch)
+	copy(b[len(prefix)+8:], name)
+	return b
+}
+
+func (s *encryptedSink) Save(name string, r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	// Epochs are timestamps, so they keep growing across restarts, but
+	// strictly increasing ones even if the clock doesn't.
+	s.mutex.Lock()
+	epoch := uint64(time.Now().UnixNano())
+	if epoch <= s.epochs[name] {
+		epoch = s.epochs[name] + 1
+	}
+	s.epochs[name] = epoch
+	s.mutex.Unlock()
+
+	b, err := encrypt(s.keys, data, snapshotAdditionalData(name, epoch))
+	if err != nil {
+		return err
+	}
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint64(header, epoch)
+
+	return s.sink.Save(name, io.MultiReader(bytes.NewReader(header), bytes.NewReader(b)))
+}
+
+func (s *encryptedSink) Load(name string) (io.ReadCloser, error) {
+	r, err := s.sink.Load(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 8 {
+		return nil, ErrInvalidCiphertext
+	}
+	epoch := binary.BigEndian.Uint64(b)
+	data, err := decrypt(s.keys, b[8:], snapshotAdditionalData(name, epoch))
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if epoch < s.epochs[name] {
+		return nil, ErrStaleSnapshot
+	}
+	s.epochs[name] = epoch
+
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
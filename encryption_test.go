@@ -0,0 +1,188 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// rotatingKeys is a KeyProvider supporting key rotation.
+type rotatingKeys struct {
+	current string
+	keys    map[string][]byte
+}
+
+func (k *rotatingKeys) CurrentKey() (string, []byte, error) {
+	return k.current, k.keys[k.current], nil
+}
+
+func (k *rotatingKeys) Key(id string) ([]byte, error) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, errors.New("unknown key")
+	}
+	return key, nil
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	keys := &rotatingKeys{current: "v1", keys: map[string][]byte{
+		"v1": bytes.Repeat([]byte{1}, 32),
+		"v2": bytes.Repeat([]byte{2}, 32),
+	}}
+
+	b, err := encrypt(keys, []byte("secret"), []byte("ad"))
+	if err != nil || bytes.Contains(b, []byte("secret")) {
+		t.Error("Error encrypting data", err)
+	}
+
+	// Data encrypted with an older key can still be decrypted
+	keys.current = "v2"
+	if data, err := decrypt(keys, b, []byte("ad")); err != nil || string(data) != "secret" {
+		t.Error("Error decrypting data", err)
+	}
+
+	if _, err := decrypt(keys, b, []byte("other")); err != ErrInvalidCiphertext {
+		t.Error("Data should not decrypt with other additional data, got", err)
+	}
+	b[len(b)-1] ^= 1
+	if _, err := decrypt(keys, b, []byte("ad")); err != ErrInvalidCiphertext {
+		t.Error("Tampered data should not decrypt, got", err)
+	}
+}
+
+func TestEncryptedSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache2go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sink := NewEncryptedSink(NewFileSink(dir), StaticKey(bytes.Repeat([]byte{1}, 16)))
+
+	table := Cache("testEncryptedSink")
+	table.Add(k, 0, "secret")
+	if err := table.SaveSnapshot(sink); err != nil {
+		t.Error("Error saving snapshot", err)
+	}
+	b, _ := ioutil.ReadFile(filepath.Join(dir, "testEncryptedSink.snapshot"))
+	if len(b) == 0 || bytes.Contains(b, []byte("secret")) {
+		t.Error("Snapshot should be encrypted")
+	}
+
+	table.Flush()
+	if n, err := table.RestoreSnapshot(sink, nil); err != nil || n != 1 {
+		t.Error("Error restoring encrypted snapshot", n, err)
+	}
+}
+
+func TestEncryptedSinkSwappedSnapshots(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache2go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sink := NewEncryptedSink(NewFileSink(dir), StaticKey(bytes.Repeat([]byte{1}, 16)))
+	file := func(name string) string {
+		return filepath.Join(dir, name+".snapshot")
+	}
+
+	sink.Save("a", strings.NewReader("first"))
+	old, _ := ioutil.ReadFile(file("a"))
+	sink.Save("a", strings.NewReader("second"))
+	sink.Save("b", strings.NewReader("other"))
+
+	// A snapshot stored under another name doesn't decrypt.
+	renamed, _ := ioutil.ReadFile(file("b"))
+	ioutil.WriteFile(file("a"), renamed, 0600)
+	if _, err := sink.Load("a"); err != ErrInvalidCiphertext {
+		t.Error("Expected a renamed snapshot to fail, got", err)
+	}
+
+	// Neither does an older snapshot stored in place of a newer one.
+	ioutil.WriteFile(file("a"), old, 0600)
+	if _, err := sink.Load("a"); err != ErrStaleSnapshot {
+		t.Error("Expected an older snapshot to fail, got", err)
+	}
+}
+
+func TestEncryptedMutationLog(t *testing.T) {
+	path, cleanup := tempLogPath(t)
+	defer cleanup()
+	opts := MutationLogOptions{Keys: StaticKey(bytes.Repeat([]byte{1}, 16))}
+
+	table := Cache("testEncryptedMutationLog")
+	table.EnableMutationLog(path, opts)
+	table.Add(k, 0, "secret")
+	table.DisableMutationLog()
+	b, _ := ioutil.ReadFile(path)
+	if len(b) == 0 || bytes.Contains(b, []byte("secret")) {
+		t.Error("Mutation log should be encrypted")
+	}
+
+	restored := Cache("testEncryptedMutationLogRestored")
+	if err := restored.EnableMutationLog(path, opts); err != nil {
+		t.Error("Error replaying encrypted mutation log", err)
+	}
+	defer restored.DisableMutationLog()
+	if p, err := restored.Value(k); err != nil || p.Data() != "secret" {
+		t.Error("Error retrieving replayed item", p, err)
+	}
+}
+
+func TestEncryptedMutationLogTampering(t *testing.T) {
+	path, cleanup := tempLogPath(t)
+	defer cleanup()
+	otherPath := path + ".other"
+	opts := MutationLogOptions{Keys: StaticKey(bytes.Repeat([]byte{1}, 16))}
+
+	write := func(name, path string, keys ...string) [][]byte {
+		table := Cache(name)
+		table.Flush()
+		table.EnableMutationLog(path, opts)
+		for _, key := range keys {
+			table.Add(key, 0, "secret")
+		}
+		table.DisableMutationLog()
+		b, _ := ioutil.ReadFile(path)
+		return bytes.SplitAfter(b, []byte("\n"))
+	}
+	replay := func(lines [][]byte) error {
+		ioutil.WriteFile(path, bytes.Join(lines, nil), 0600)
+		table := Cache("testEncryptedMutationLogTamperingReplay")
+		table.Flush()
+		defer table.DisableMutationLog()
+		return table.EnableMutationLog(path, opts)
+	}
+
+	// A segment header and two records; reopening adds another segment.
+	lines := write("testEncryptedMutationLogTampering", path, "a", "b")
+	if err := replay(lines); err != nil {
+		t.Error("Error replaying untampered log", err)
+	}
+	lines = lines[:3]
+
+	if err := replay([][]byte{lines[0], lines[2], lines[1]}); err != ErrInvalidCiphertext {
+		t.Error("Reordered records should not replay, got", err)
+	}
+	if err := replay([][]byte{lines[0], lines[1], lines[1]}); err != ErrInvalidCiphertext {
+		t.Error("Replayed records should not replay, got", err)
+	}
+	other := write("testEncryptedMutationLogTamperingOther", otherPath, "c")
+	// At the same offset, so only the segment tells them apart.
+	if err := replay([][]byte{lines[0], other[1]}); err != ErrInvalidCiphertext {
+		t.Error("Records spliced from another log should not replay, got", err)
+	}
+	if err := replay(lines[1:]); err != ErrInvalidCiphertext {
+		t.Error("Records without a segment should not replay, got", err)
+	}
+}
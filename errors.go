@@ -17,4 +17,75 @@ var (
 	// ErrKeyNotFoundOrLoadable gets returned when a specific key couldn't be
 	// found and loading via the data-loader callback also failed
 	ErrKeyNotFoundOrLoadable = errors.New("Key not found and could not be loaded into cache")
+	// ErrCacheOptionsMismatch gets returned when requesting an existing cache
+	// with options that differ from the ones it was created with
+	ErrCacheOptionsMismatch = errors.New("Cache already exists with different options")
+	// ErrUnsupportedExport gets returned when importing data that isn't a
+	// cache export, or one of an unsupported version
+	ErrUnsupportedExport = errors.New("Unsupported cache export format")
+	// ErrSnapshotNotFound gets returned when a snapshot sink doesn't hold the
+	// requested snapshot
+	ErrSnapshotNotFound = errors.New("Snapshot not found")
+	// ErrInvalidCiphertext gets returned when decrypting data that wasn't
+	// encrypted by this library, or got corrupted
+	ErrInvalidCiphertext = errors.New("Invalid encrypted data")
+	// ErrStaleSnapshot gets returned when loading an encrypted snapshot older
+	// than one already saved or loaded under its name
+	ErrStaleSnapshot = errors.New("Snapshot is older than expected")
+	// ErrNotAList gets returned when using list operations on an item that
+	// isn't a list
+	ErrNotAList = errors.New("Item is not a list")
+	// ErrEmptyList gets returned when popping from an empty list
+	ErrEmptyList = errors.New("List is empty")
+	// ErrNotASet gets returned when using set operations on an item that
+	// isn't a set
+	ErrNotASet = errors.New("Item is not a set")
+	// ErrNotAHyperLogLog gets returned when using HyperLogLog operations on
+	// an item that isn't a HyperLogLog
+	ErrNotAHyperLogLog = errors.New("Item is not a HyperLogLog")
+	// ErrNotABitmap gets returned when using bitmap operations on an item
+	// that isn't a bitmap
+	ErrNotABitmap = errors.New("Item is not a bitmap")
+	// ErrStatsDisabled gets returned when using features relying on the
+	// statistics of a table created without WithStats
+	ErrStatsDisabled = errors.New("Statistics are disabled for this cache")
+	// ErrCacheNotFound gets returned when a cache couldn't be found in the
+	// registry
+	ErrCacheNotFound = errors.New("Cache not found")
+	// ErrCacheExists gets returned when a cache name is already taken in the
+	// registry
+	ErrCacheExists = errors.New("Cache already exists")
+	// ErrUnsupportedCache gets returned when registering a cache
+	// implementation the registry can't hold
+	ErrUnsupportedCache = errors.New("Unsupported cache implementation")
+	// ErrGenerationMismatch gets returned when the item stored under a key
+	// isn't of the expected generation anymore
+	ErrGenerationMismatch = errors.New("Item was replaced")
+	// ErrTxRejected gets returned when a transaction got discarded because
+	// of a stale or tombstoned item
+	ErrTxRejected = errors.New("Transaction rejected stale or tombstoned item")
+	// ErrConflict gets returned when an optimistic transaction read a key
+	// that changed before the transaction got applied
+	ErrConflict = errors.New("Transaction conflicts with a concurrent change")
+	// ErrUnexpectedType gets returned when a cached value isn't of the type
+	// requested
+	ErrUnexpectedType = errors.New("Cached value has an unexpected type")
+	// ErrKeyExists gets returned when strict mode rejects overwriting a
+	// cached item
+	ErrKeyExists = errors.New("Key already exists in cache")
+	// ErrValueMutated gets reported when strict mode detects a cached value
+	// that was modified in place
+	ErrValueMutated = errors.New("Cached value was modified in place")
+	// ErrDeleteVetoed gets returned when a delete veto callback refused to
+	// delete an item
+	ErrDeleteVetoed = errors.New("Deleting item was vetoed")
+	// ErrArenaMismatch gets returned when opening a mapped arena file that
+	// wasn't created with the same layout
+	ErrArenaMismatch = errors.New("Mapped arena file has a different layout")
+	// ErrMmapUnsupported gets returned when mapping files into memory isn't
+	// supported on the platform
+	ErrMmapUnsupported = errors.New("Memory-mapped files are not supported")
+	// ErrParentCycle gets returned when setting a parent cache that would
+	// make a table its own ancestor
+	ErrParentCycle = errors.New("Parent cache would form a cycle")
 )
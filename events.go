@@ -0,0 +1,133 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"time"
+)
+
+// Types of events.
+const (
+	EventAdd    = "add"
+	EventDelete = "delete"
+	EventExpire = "expire"
+	EventEvict  = "evict"
+	EventFlush  = "flush"
+)
+
+// Event describes a change to a cache table, see EnableEvents.
+type Event struct {
+	Time  time.Time `json:"time"`
+	Table string    `json:"table"`
+	// One of EventAdd, EventDelete, EventExpire, EventEvict or EventFlush.
+	Type string `json:"type"`
+	// The item's key, nil for flushes.
+	Key interface{} `json:"key,omitempty"`
+	// The item's lifespan and where its value came from.
+	LifeSpan time.Duration `json:"lifeSpan,omitempty"`
+	Source   string        `json:"source,omitempty"`
+	// The item's generation, see CacheItem.Generation.
+	Generation uint64 `json:"generation,omitempty"`
+}
+
+// EventSink receives a table's events, e.g. to publish them to a message
+// broker so other systems can mirror or audit the cache's activity. See
+// NATSSink; for Kafka and other brokers, implement it with their client.
+type EventSink interface {
+	// Publish sends an event. It gets called from a single goroutine per
+	// table, in the order the events occurred.
+	Publish(e Event) error
+}
+
+// eventExporter queues a table's events for its sink.
+type eventExporter struct {
+	sink   EventSink
+	events chan Event
+	done   chan struct{}
+}
+
+// EnableEvents publishes every addition, deletion, expiry, eviction and
+// flush to the given sink. Events get queued, so a slow sink doesn't slow
+// down the table; once more than buffer events are waiting, further events
+// get dropped.
+func (table *CacheTable) EnableEvents(sink EventSink, buffer int) {
+	table.DisableEvents()
+
+	e := &eventExporter{
+		sink:   sink,
+		events: make(chan Event, buffer),
+		done:   make(chan struct{}),
+	}
+	go table.exportEvents(e)
+
+	table.Lock()
+	table.eventExporter = e
+	table.Unlock()
+}
+
+// DisableEvents stops publishing events, after publishing the queued ones.
+func (table *CacheTable) DisableEvents() {
+	table.Lock()
+	e := table.eventExporter
+	table.eventExporter = nil
+	table.Unlock()
+
+	if e == nil {
+		return
+	}
+	close(e.events)
+	<-e.done
+}
+
+// exportEvents publishes queued events until the queue gets closed.
+func (table *CacheTable) exportEvents(e *eventExporter) {
+	defer close(e.done)
+	for event := range e.events {
+		if err := e.sink.Publish(event); err != nil {
+			table.log("Error publishing event of table", table.name, ":", err)
+		}
+	}
+}
+
+// emit queues an event about the given key and item, which may be nil.
+// Careful: do not run this method unless the table-mutex is locked!
+func (table *CacheTable) emit(eventType string, key interface{}, item *CacheItem) {
+	e := table.eventExporter
+	if e == nil {
+		return
+	}
+
+	event := Event{
+		Time:  time.Now(),
+		Table: table.name,
+		Type:  eventType,
+		Key:   key,
+	}
+	if item != nil {
+		event.LifeSpan = item.lifeSpan
+		event.Source = item.source.String()
+		event.Generation = item.Generation()
+	}
+
+	select {
+	case e.events <- event:
+	default:
+		table.log("Dropping event for key", key, "of table", table.name)
+	}
+}
+
+// removalEvent returns the event type of a removal.
+func removalEvent(reason RemovalReason) string {
+	switch reason {
+	case RemovalExpired:
+		return EventExpire
+	case RemovalEvicted, RemovalReclaimed:
+		return EventEvict
+	}
+	return EventDelete
+}
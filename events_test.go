@@ -0,0 +1,94 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+	"time"
+)
+
+type chanSink chan Event
+
+func (s chanSink) Publish(e Event) error {
+	s <- e
+	return nil
+}
+
+func TestEvents(t *testing.T) {
+	table, _ := CacheWithOptions("testEvents", WithCapacity(1))
+	table.Flush()
+	sink := make(chanSink, 10)
+	table.EnableEvents(sink, 10)
+
+	table.Add("a", 50*time.Millisecond, v)
+	table.Add("b", 0, v)
+	table.Delete("b")
+	table.Add("c", 50*time.Millisecond, v)
+	time.Sleep(150 * time.Millisecond)
+	table.Flush()
+	table.DisableEvents()
+	table.Add("d", 0, v)
+	close(sink)
+
+	expected := []struct {
+		eventType string
+		key       interface{}
+	}{
+		{EventAdd, "a"},
+		{EventEvict, "a"},
+		{EventAdd, "b"},
+		{EventDelete, "b"},
+		{EventAdd, "c"},
+		{EventExpire, "c"},
+		{EventFlush, nil},
+	}
+	var events []Event
+	for e := range sink {
+		events = append(events, e)
+	}
+	if len(events) != len(expected) {
+		t.Fatal("Expected", len(expected), "events, got", events)
+	}
+	for i, e := range events {
+		if e.Type != expected[i].eventType || e.Key != expected[i].key || e.Table != "testEvents" {
+			t.Errorf("Expected %s event for %v, got %+v", expected[i].eventType, expected[i].key, e)
+		}
+	}
+	if events[0].LifeSpan != 50*time.Millisecond || events[0].Source != "add" {
+		t.Errorf("Events should carry the item's metadata, got %+v", events[0])
+	}
+}
+
+type blockingSink chan struct{}
+
+func (s blockingSink) Publish(e Event) error {
+	<-s
+	return nil
+}
+
+func TestEventsDropped(t *testing.T) {
+	table := Cache("testEventsDropped")
+	sink := make(blockingSink)
+	table.EnableEvents(sink, 1)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			table.Add(i, 0, v)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("A slow sink should not block the table")
+	}
+
+	close(sink)
+	table.DisableEvents()
+}
@@ -0,0 +1,28 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"time"
+)
+
+// NotifyExpiringWithin configures a callback, which will be called once an
+// item is about to expire within the given duration. This allows renewing
+// upstream leases or refreshing data just in time. Accessing the item
+// extends its lifespan, after which the callback gets called again before
+// the item expires. Only one callback can be configured per table; passing
+// nil removes it.
+func (table *CacheTable) NotifyExpiringWithin(d time.Duration, f func(item *CacheItem)) {
+	table.Lock()
+	table.expiringWithin = d
+	table.expiringSoon = f
+	table.Unlock()
+
+	// Reschedule the expiration check to wake up in time.
+	table.expirationCheck()
+}
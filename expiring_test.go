@@ -0,0 +1,53 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNotifyExpiringWithin(t *testing.T) {
+	table := Cache("testNotifyExpiringWithin")
+	notified := make(chan time.Time, 10)
+	table.NotifyExpiringWithin(100*time.Millisecond, func(item *CacheItem) {
+		if item.Key() == k {
+			notified <- time.Now()
+		}
+	})
+
+	start := time.Now()
+	table.Add(k, 200*time.Millisecond, v)
+	table.Add("forever", 0, v)
+
+	select {
+	case at := <-notified:
+		if elapsed := at.Sub(start); elapsed < 80*time.Millisecond || elapsed > 180*time.Millisecond {
+			t.Error("Notification should arrive about 100ms before the expiry, after", elapsed)
+		}
+		if !table.Exists(k) {
+			t.Error("Item should not have expired yet")
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected a notification before the item expires")
+	}
+
+	// Accessing the item extends its lifespan and rearms the notification
+	table.Value(k)
+	select {
+	case <-notified:
+	case <-time.After(time.Second):
+		t.Error("Expected another notification after accessing the item")
+	}
+
+	select {
+	case <-notified:
+		t.Error("Item should only be notified once per lifespan")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
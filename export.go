@@ -0,0 +1,222 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+const (
+	// exportFormat identifies cache exports.
+	exportFormat = "cache2go"
+	// exportVersion is the version of the export format written by Export.
+	// Import supports all versions up to this one.
+	exportVersion = 1
+)
+
+// exportHeader is the first line of an export.
+type exportHeader struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	Table   string `json:"table"`
+	// Whether the export holds the changes since a snapshot, see
+	// EnableAutoSnapshot.
+	Delta bool `json:"delta,omitempty"`
+}
+
+// exportedItem is a line of an export, holding a single item.
+type exportedItem struct {
+	Key          json.RawMessage `json:"key"`
+	Value        json.RawMessage `json:"value"`
+	LifeSpan     time.Duration   `json:"lifeSpan,omitempty"`
+	SoftLifeSpan time.Duration   `json:"softLifeSpan,omitempty"`
+	CreatedOn    time.Time       `json:"createdOn"`
+	AccessedOn   time.Time       `json:"accessedOn"`
+	AccessCount  int64           `json:"accessCount"`
+	// When the item expires unless accessed, zero if it never does.
+	ExpiresOn time.Time `json:"expiresOn,omitempty"`
+}
+
+// exportItem returns the exported representation of an item, with its value
+// passed through transform, unless that's nil.
+func exportItem(item *CacheItem, transform func(key, value interface{}) interface{}) (exportedItem, error) {
+	key, err := json.Marshal(item.key)
+	if err != nil {
+		return exportedItem{}, err
+	}
+
+	item.RLock()
+	defer item.RUnlock()
+	e := exportedItem{
+		Key:          key,
+		LifeSpan:     item.lifeSpan,
+		SoftLifeSpan: item.softLifeSpan,
+		CreatedOn:    item.createdOn,
+		AccessedOn:   item.AccessedOn(),
+		AccessCount:  item.AccessCount(),
+	}
+	if item.lifeSpan > 0 {
+		e.ExpiresOn = item.AccessedOn().Add(item.lifeSpan)
+	}
+	data := item.peek()
+	if transform != nil {
+		data = transform(item.key, data)
+	}
+	e.Value, err = json.Marshal(data)
+	return e, err
+}
+
+// expiresOn returns when the exported item expires.
+func (e exportedItem) expiresOn() time.Time {
+	if !e.ExpiresOn.IsZero() {
+		return e.ExpiresOn
+	}
+	// Exports of older versions only hold the last access.
+	return e.AccessedOn.Add(e.LifeSpan)
+}
+
+// expired returns whether the exported item's lifespan was exceeded.
+func (e exportedItem) expired(now time.Time) bool {
+	return e.LifeSpan > 0 && !now.Before(e.expiresOn())
+}
+
+// dropped returns whether the exported item gets dropped when restoring it
+// with the given policy.
+func (e exportedItem) dropped(policy RestorePolicy, now time.Time) bool {
+	return policy == RestoreDrop && e.expired(now)
+}
+
+// restore returns a new item holding the exported item, with its remaining
+// lifespan determined by the given policy.
+func (e exportedItem) restore(decode ImportDecoder, policy RestorePolicy, now time.Time) (*CacheItem, error) {
+	key, data, err := decode(e.Key, e.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	item := NewCacheItem(key, e.LifeSpan, data)
+	item.softLifeSpan = e.SoftLifeSpan
+	item.createdOn = e.CreatedOn
+	item.accessedOn = e.AccessedOn.UnixNano()
+	item.accessCount = e.AccessCount
+	item.source = SourceRestore
+	if e.LifeSpan > 0 {
+		if policy == RestoreKeep || (policy == RestoreExtend && e.expired(now)) {
+			// Start over with the full lifespan.
+			item.accessedOn = now.UnixNano()
+		} else {
+			// Keep the absolute expiry.
+			item.accessedOn = e.expiresOn().Add(-e.LifeSpan).UnixNano()
+		}
+	}
+
+	return item, nil
+}
+
+// ImportDecoder turns the JSON representations of an exported key and value
+// back into the key and value to be cached.
+type ImportDecoder func(key, value json.RawMessage) (interface{}, interface{}, error)
+
+// Export writes the table's items to w in a portable format, so they can be
+// imported into another table, e.g. in another environment or by a later
+// version of this library. The format is line-delimited JSON: a header line
+// followed by one line per item, holding the key and value marshaled to JSON
+// along with the item's lifespans and access metadata. Values get passed
+// through the table's export transformer, if any.
+func (table *CacheTable) Export(w io.Writer) error {
+	table.RLock()
+	items := make([]*CacheItem, 0, len(table.items))
+	for _, item := range table.items {
+		if item.Computed() {
+			items = append(items, item)
+		}
+	}
+	transform := table.exportTransformer
+	table.RUnlock()
+
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(exportHeader{Format: exportFormat, Version: exportVersion, Table: table.name}); err != nil {
+		return err
+	}
+	for _, item := range items {
+		e, err := exportItem(item, transform)
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Import adds the items exported by Export to the table, returning how many
+// items got imported. Keys and values get unmarshaled into generic JSON types,
+// e.g. numbers become float64; use ImportWith to restore the original types.
+// The items' remaining lifespans are determined by the table's restore
+// policy, see WithRestorePolicy: by default they keep expiring at the same
+// time, and items that expired in the meantime are skipped.
+func (table *CacheTable) Import(r io.Reader) (int, error) {
+	return table.ImportWith(r, nil)
+}
+
+// ImportWith imports items just like Import, but decodes keys and values
+// with the given decoder.
+func (table *CacheTable) ImportWith(r io.Reader, decode ImportDecoder) (int, error) {
+	if decode == nil {
+		decode = decodeJSON
+	}
+
+	dec := json.NewDecoder(bufio.NewReader(r))
+	var header exportHeader
+	if err := dec.Decode(&header); err != nil {
+		return 0, err
+	}
+	if header.Format != exportFormat || header.Version < 1 || header.Version > exportVersion || header.Delta {
+		return 0, ErrUnsupportedExport
+	}
+
+	n := 0
+	now := time.Now()
+	for {
+		var e exportedItem
+		if err := dec.Decode(&e); err == io.EOF {
+			return n, nil
+		} else if err != nil {
+			return n, err
+		}
+		if e.dropped(table.restorePolicy, now) {
+			continue
+		}
+
+		item, err := e.restore(decode, table.restorePolicy, now)
+		if err != nil {
+			return n, err
+		}
+
+		table.Lock()
+		table.addInternal(item)
+		n++
+	}
+}
+
+// decodeJSON unmarshals keys and values into generic JSON types.
+func decodeJSON(key, value json.RawMessage) (interface{}, interface{}, error) {
+	var k, v interface{}
+	if err := json.Unmarshal(key, &k); err != nil {
+		return nil, nil, err
+	}
+	if err := json.Unmarshal(value, &v); err != nil {
+		return nil, nil, err
+	}
+	return k, v, nil
+}
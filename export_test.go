@@ -0,0 +1,147 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportImport(t *testing.T) {
+	table := Cache("testExport")
+	table.Add("a", 0, "value")
+	table.Add("b", time.Minute, 42)
+	table.Value("a")
+
+	var buf bytes.Buffer
+	if err := table.Export(&buf); err != nil {
+		t.Error("Error exporting table", err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 3 {
+		t.Error("Expected a header and 2 item lines, got", lines)
+	}
+
+	imported := Cache("testImport")
+	n, err := imported.Import(bytes.NewReader(buf.Bytes()))
+	if err != nil || n != 2 {
+		t.Error("Error importing table", n, err)
+	}
+	p, err := imported.Value("a")
+	if err != nil || p.Data() != "value" || p.AccessCount() != 2 {
+		t.Error("Error retrieving imported item", p, err)
+	}
+	// Numbers are float64 unless decoded otherwise
+	p, err = imported.Value("b")
+	if err != nil || p.Data() != float64(42) || p.LifeSpan() != time.Minute {
+		t.Error("Error retrieving imported item with lifespan", p, err)
+	}
+
+	typed := Cache("testImportWith")
+	typed.ImportWith(bytes.NewReader(buf.Bytes()), func(key, value json.RawMessage) (interface{}, interface{}, error) {
+		var k string
+		json.Unmarshal(key, &k)
+		if k == "b" {
+			var v int
+			err := json.Unmarshal(value, &v)
+			return k, v, err
+		}
+		var v string
+		err := json.Unmarshal(value, &v)
+		return k, v, err
+	})
+	if p, err := typed.Value("b"); err != nil || p.Data() != 42 {
+		t.Error("Error retrieving item imported with decoder", p, err)
+	}
+}
+
+func TestImportSkipsExpired(t *testing.T) {
+	table := Cache("testImportExpiredSource")
+	table.Add(k, 50*time.Millisecond, v)
+	var buf bytes.Buffer
+	table.Export(&buf)
+
+	time.Sleep(100 * time.Millisecond)
+	imported := Cache("testImportExpired")
+	if n, err := imported.Import(&buf); err != nil || n != 0 {
+		t.Error("Expired item should not be imported", n, err)
+	}
+}
+
+func TestImportUnsupported(t *testing.T) {
+	table := Cache("testImportUnsupported")
+	if _, err := table.Import(strings.NewReader(`{"format":"cache2go","version":99}`)); err != ErrUnsupportedExport {
+		t.Error("Expected unsupported export error, got", err)
+	}
+}
+
+func TestExportTransformer(t *testing.T) {
+	table := Cache("testExportTransformer")
+	table.Add("password", 0, "hunter2")
+	table.Add("user", 0, "alice")
+	table.SetExportTransformer(func(key interface{}, value interface{}) interface{} {
+		if key == "password" {
+			return "***"
+		}
+		return value
+	})
+
+	var buf bytes.Buffer
+	table.Export(&buf)
+	table.DumpJSON(&buf, DumpOptions{IncludeValues: true})
+	if strings.Contains(buf.String(), "hunter2") || !strings.Contains(buf.String(), "alice") {
+		t.Error("Secret values should be masked in exports and dumps:", buf.String())
+	}
+
+	// The cached value is unaffected
+	if p, err := table.Value("password"); err != nil || p.Data() != "hunter2" {
+		t.Error("Transformer should not affect the cached value")
+	}
+}
+
+func TestRestorePolicy(t *testing.T) {
+	source := Cache("testRestorePolicySource")
+	source.Add("short", 50*time.Millisecond, v)
+	source.Add("long", time.Hour, v)
+	var buf bytes.Buffer
+	source.Export(&buf)
+	time.Sleep(100 * time.Millisecond)
+
+	// By default, items keep expiring at the same time
+	table := Cache("testRestoreDrop")
+	if n, _ := table.Import(bytes.NewReader(buf.Bytes())); n != 1 || table.Exists("short") {
+		t.Error("Expired item should be dropped")
+	}
+	// Peek at the items, as accessing them would extend their lifespan
+	p := table.items["long"]
+	if remaining := p.LifeSpan() - time.Since(p.AccessedOn()); remaining > time.Hour-100*time.Millisecond {
+		t.Error("Remaining lifespan should be recomputed, got", remaining)
+	}
+
+	table, _ = CacheWithOptions("testRestoreKeep", WithRestorePolicy(RestoreKeep))
+	table.PauseExpiration()
+	if n, _ := table.Import(bytes.NewReader(buf.Bytes())); n != 2 {
+		t.Error("All items should be kept")
+	}
+	p = table.items["long"]
+	if remaining := p.LifeSpan() - time.Since(p.AccessedOn()); remaining < time.Hour-50*time.Millisecond {
+		t.Error("Lifespan should restart, got", remaining)
+	}
+
+	table, _ = CacheWithOptions("testRestoreExtend", WithRestorePolicy(RestoreExtend))
+	table.PauseExpiration()
+	if n, _ := table.Import(bytes.NewReader(buf.Bytes())); n != 2 {
+		t.Error("Expired items should be extended")
+	}
+	short, long := table.items["short"], table.items["long"]
+	if time.Since(short.AccessedOn()) > 50*time.Millisecond || time.Since(long.AccessedOn()) < 100*time.Millisecond {
+		t.Error("Only expired items should restart their lifespan")
+	}
+}
@@ -0,0 +1,99 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// FaultInjector makes a table misbehave on purpose, see WithFaultInjector.
+// It's meant for tests only: it allows services to verify they cope with
+// failing data-loaders, slow lookups and items vanishing early. Configure it
+// before handing it to a table.
+type FaultInjector struct {
+	// Share of data-loader calls that fail, between 0 and 1.
+	LoaderFailureRate float64
+	// Artificial latency added to every lookup.
+	Latency time.Duration
+	// Share of additions that evict another item, between 0 and 1.
+	EvictionRate float64
+
+	mutex sync.Mutex
+	rand  *rand.Rand
+}
+
+// NewFaultInjector creates a fault injector with all faults disabled. The
+// same seed results in the same sequence of faults.
+func NewFaultInjector(seed int64) *FaultInjector {
+	return &FaultInjector{
+		rand: rand.New(rand.NewSource(seed)),
+	}
+}
+
+// WithFaultInjector makes a table inject the faults configured in f. Never
+// use this outside of tests!
+func WithFaultInjector(f *FaultInjector) Option {
+	return func(o *cacheOptions) {
+		o.faults = f
+	}
+}
+
+// chance returns true with the given probability.
+func (f *FaultInjector) chance(rate float64) bool {
+	if f == nil || rate <= 0 {
+		return false
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.rand.Float64() < rate
+}
+
+// delay sleeps for the configured latency.
+func (f *FaultInjector) delay() {
+	if f != nil && f.Latency > 0 {
+		time.Sleep(f.Latency)
+	}
+}
+
+// loader wraps a data-loader so it randomly fails.
+func (f *FaultInjector) loader(loadData func(*LoadRequest) *CacheItem) func(*LoadRequest) *CacheItem {
+	if f == nil || f.LoaderFailureRate <= 0 || loadData == nil {
+		return loadData
+	}
+
+	return func(req *LoadRequest) *CacheItem {
+		if f.chance(f.LoaderFailureRate) {
+			return nil
+		}
+		return loadData(req)
+	}
+}
+
+// forceEvict evicts an item, unless the fault injector decides otherwise.
+// Careful: do not run this method while holding the table-mutex!
+func (table *CacheTable) forceEvict() {
+	if !table.faults.chance(table.faults.EvictionRate) {
+		return
+	}
+	if table.policy != nil {
+		table.evictOne()
+		return
+	}
+
+	table.Lock()
+	defer table.Unlock()
+	for key := range table.items {
+		if _, err := table.deleteInternal(key, RemovalEvicted); err == nil {
+			table.stats.evict()
+		}
+		return
+	}
+}
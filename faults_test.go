@@ -0,0 +1,64 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFaultInjectorLoader(t *testing.T) {
+	faults := NewFaultInjector(1)
+	faults.LoaderFailureRate = 0.5
+	table, _ := CacheWithOptions("testFaultInjectorLoader", WithFaultInjector(faults))
+	table.SetDataLoader(func(key interface{}, args ...interface{}) *CacheItem {
+		return NewCacheItem(key, 0, v)
+	})
+
+	failures := 0
+	for i := 0; i < 100; i++ {
+		if _, err := table.Value(i); err == ErrKeyNotFoundOrLoadable {
+			failures++
+		}
+	}
+	if failures < 25 || failures > 75 {
+		t.Error("Expected about half of the loads to fail, got", failures)
+	}
+	if table.Count() != 100-failures {
+		t.Error("Only successfully loaded items should be cached")
+	}
+}
+
+func TestFaultInjectorLatency(t *testing.T) {
+	faults := NewFaultInjector(1)
+	faults.Latency = 50 * time.Millisecond
+	table, _ := CacheWithOptions("testFaultInjectorLatency", WithFaultInjector(faults))
+	table.Add(k, 0, v)
+
+	start := time.Now()
+	table.Value(k)
+	if time.Since(start) < faults.Latency {
+		t.Error("Lookup should have been delayed")
+	}
+}
+
+func TestFaultInjectorEviction(t *testing.T) {
+	faults := NewFaultInjector(1)
+	faults.EvictionRate = 1
+	table, _ := CacheWithOptions("testFaultInjectorEviction", WithFaultInjector(faults), WithStats())
+
+	for i := 0; i < 10; i++ {
+		table.Add(i, 0, i)
+	}
+	if table.Count() != 0 {
+		t.Error("Every addition should have evicted an item, items left:", table.Count())
+	}
+	if table.Stats().Evicted != 10 {
+		t.Error("Forced evictions should be counted:", table.Stats())
+	}
+}
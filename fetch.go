@@ -0,0 +1,139 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// FetchOption configures a call to Fetch.
+type FetchOption func(*fetchOptions)
+
+// fetchOptions holds the configuration assembled from FetchOptions.
+type fetchOptions struct {
+	// How long errors returned by the fetch function get cached, 0 if not
+	// at all.
+	errorLifeSpan time.Duration
+}
+
+// WithErrorCaching makes Fetch cache errors returned by the fetch function
+// for the given lifespan, returning them without calling the function again
+// in the meantime, so a failing backend doesn't get hammered by retries.
+// Errors of canceled contexts are never cached.
+func WithErrorCaching(lifeSpan time.Duration) FetchOption {
+	return func(o *fetchOptions) {
+		o.errorLifeSpan = lifeSpan
+	}
+}
+
+// fetchError is an error cached by Fetch.
+type fetchError struct {
+	err error
+}
+
+// fetchKey identifies a key of a cache being fetched.
+type fetchKey struct {
+	cache Cacher
+	key   interface{}
+}
+
+// fetchCall is a fetch in progress, shared by all callers fetching the same
+// key at the same time.
+type fetchCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+var (
+	fetchCalls = make(map[fetchKey]*fetchCall)
+	fetchMutex sync.Mutex
+)
+
+// Fetch returns the value cached under the given key, or calls fn to fetch it
+// and caches the result for the given lifespan. Concurrent calls for the same
+// key of the same cache share a single call of fn; callers waiting for it
+// return early with the context's error if it gets done. A cached value that
+// isn't of type T results in ErrUnexpectedType.
+func Fetch[T any](ctx context.Context, c Cacher, key interface{}, lifeSpan time.Duration, fn func(ctx context.Context) (T, error), opts ...FetchOption) (T, error) {
+	if item, err := c.Value(key); err == nil {
+		return fetchResult[T](item.Data())
+	}
+
+	k := fetchKey{cache: c, key: key}
+	fetchMutex.Lock()
+	call, ok := fetchCalls[k]
+	if ok {
+		fetchMutex.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+	} else {
+		call = &fetchCall{done: make(chan struct{})}
+		fetchCalls[k] = call
+		fetchMutex.Unlock()
+		fetchInto(ctx, call, k, lifeSpan, fn, opts)
+	}
+
+	if call.err != nil {
+		var zero T
+		return zero, call.err
+	}
+	return fetchResult[T](call.value)
+}
+
+// fetchInto calls fn, caches its result and shares it with the callers
+// waiting for the call.
+func fetchInto[T any](ctx context.Context, call *fetchCall, k fetchKey, lifeSpan time.Duration, fn func(ctx context.Context) (T, error), opts []FetchOption) {
+	defer func() {
+		fetchMutex.Lock()
+		delete(fetchCalls, k)
+		fetchMutex.Unlock()
+		close(call.done)
+	}()
+
+	var o fetchOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	v, err := fn(ctx)
+	if err != nil {
+		call.err = err
+		if o.errorLifeSpan > 0 && ctx.Err() == nil {
+			k.cache.Add(k.key, o.errorLifeSpan, fetchError{err: err})
+		}
+		return
+	}
+	call.value = v
+	k.cache.Add(k.key, lifeSpan, v)
+}
+
+// fetchResult returns a cached value as type T.
+func fetchResult[T any](data interface{}) (T, error) {
+	var zero T
+	if e, ok := data.(fetchError); ok {
+		return zero, e.err
+	}
+	if data == nil {
+		return zero, nil
+	}
+	v, ok := data.(T)
+	if !ok {
+		return zero, ErrUnexpectedType
+	}
+	return v, nil
+}
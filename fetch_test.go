@@ -0,0 +1,117 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetch(t *testing.T) {
+	table := Cache("testFetch")
+	ctx := context.Background()
+
+	calls := 0
+	fn := func(ctx context.Context) (int, error) {
+		calls++
+		return 42, nil
+	}
+	for i := 0; i < 2; i++ {
+		v, err := Fetch(ctx, table, k, 0, fn)
+		if err != nil || v != 42 {
+			t.Error("Expected 42, got", v, err)
+		}
+	}
+	if calls != 1 {
+		t.Error("Expected the fetch function to be called once, got", calls)
+	}
+
+	if _, err := Fetch(ctx, table, k, 0, func(ctx context.Context) (string, error) {
+		return v, nil
+	}); err != ErrUnexpectedType {
+		t.Error("Expected ErrUnexpectedType, got", err)
+	}
+}
+
+func TestFetchSingleflight(t *testing.T) {
+	table := Cache("testFetchSingleflight")
+	ctx := context.Background()
+
+	var calls int32
+	release := make(chan struct{})
+	fn := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return v, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if r, err := Fetch(ctx, table, k, 0, fn); err != nil || r != v {
+				t.Error("Expected", v, "got", r, err)
+			}
+		}()
+	}
+
+	// Wait for the first caller to start fetching.
+	for atomic.LoadInt32(&calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := Fetch(canceled, table, k, 0, fn); err != context.Canceled {
+		t.Error("Expected waiting with a canceled context to fail, got", err)
+	}
+
+	close(release)
+	wg.Wait()
+	if calls != 1 {
+		t.Error("Expected the fetch function to be called once, got", calls)
+	}
+}
+
+func TestFetchErrorCaching(t *testing.T) {
+	table := Cache("testFetchErrorCaching")
+	ctx := context.Background()
+
+	errBackend := errors.New("backend down")
+	calls := 0
+	fn := func(ctx context.Context) (int, error) {
+		calls++
+		return 0, errBackend
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := Fetch(ctx, table, "uncached", 0, fn); err != errBackend {
+			t.Error("Expected the fetch function's error, got", err)
+		}
+	}
+	if calls != 2 {
+		t.Error("Expected errors not to be cached by default, got", calls, "calls")
+	}
+
+	calls = 0
+	for i := 0; i < 2; i++ {
+		if _, err := Fetch(ctx, table, "cached", 0, fn, WithErrorCaching(time.Minute)); err != errBackend {
+			t.Error("Expected the cached error, got", err)
+		}
+	}
+	if calls != 1 {
+		t.Error("Expected the error to be cached, got", calls, "calls")
+	}
+}
@@ -0,0 +1,64 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"container/list"
+)
+
+// FIFOPolicy evicts items in the order they were added, ignoring accesses.
+type FIFOPolicy struct {
+	// Keys in insertion order, oldest at the front.
+	queue   *list.List
+	entries map[interface{}]*list.Element
+}
+
+// NewFIFOPolicy creates a first-in, first-out eviction policy.
+func NewFIFOPolicy() *FIFOPolicy {
+	return &FIFOPolicy{
+		queue:   list.New(),
+		entries: make(map[interface{}]*list.Element),
+	}
+}
+
+// Add appends a new item to the queue.
+func (p *FIFOPolicy) Add(item *CacheItem) {
+	if _, exists := p.entries[item.key]; exists {
+		return
+	}
+	p.entries[item.key] = p.queue.PushBack(item.key)
+}
+
+// Access does nothing: FIFO doesn't care about accesses.
+func (p *FIFOPolicy) Access(item *CacheItem) {}
+
+// Remove takes an item out of the queue.
+func (p *FIFOPolicy) Remove(item *CacheItem) {
+	if element, exists := p.entries[item.key]; exists {
+		p.queue.Remove(element)
+		delete(p.entries, item.key)
+	}
+}
+
+// Evict drops the oldest item.
+func (p *FIFOPolicy) Evict() (interface{}, bool) {
+	front := p.queue.Front()
+	if front == nil {
+		return nil, false
+	}
+
+	key := p.queue.Remove(front)
+	delete(p.entries, key)
+	return key, true
+}
+
+// Reset drops all bookkeeping.
+func (p *FIFOPolicy) Reset() {
+	p.queue.Init()
+	p.entries = make(map[interface{}]*list.Element)
+}
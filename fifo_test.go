@@ -0,0 +1,35 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+)
+
+func TestFIFOEviction(t *testing.T) {
+	cache := NewBoundedCache("testFIFO", 2, NewFIFOPolicy())
+
+	cache.Add("key1", 0, "value1")
+	cache.Add("key2", 0, "value2")
+
+	// Accesses don't matter for FIFO
+	cache.Value("key1")
+	cache.Value("key1")
+
+	cache.Add("key3", 0, "value3")
+	if cache.Exists("key1") {
+		t.Error("key1 should be evicted (added first)")
+	}
+
+	cache.Delete("key2")
+	cache.Add("key4", 0, "value4")
+	cache.Add("key5", 0, "value5")
+	if cache.Exists("key3") || !cache.Exists("key4") || !cache.Exists("key5") {
+		t.Error("key3 should be evicted after key2 got deleted")
+	}
+}
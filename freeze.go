@@ -0,0 +1,129 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// ReadOnlyCache is an immutable point-in-time view of a cache, see
+// CacheTable.Freeze.
+type ReadOnlyCache interface {
+	// Name returns the name of the cache the view was taken of.
+	Name() string
+	// FrozenOn returns when the view was taken.
+	FrozenOn() time.Time
+	// Value returns an item of the view, without counting it as an access.
+	Value(key interface{}) (*CacheItem, error)
+	// Exists returns whether an item exists in the view.
+	Exists(key interface{}) bool
+	// Count returns how many items are in the view.
+	Count() int
+	// Foreach iterates over all items in the view.
+	Foreach(trans func(key interface{}, item *CacheItem))
+	// MostAccessed returns the most accessed items in the view.
+	MostAccessed(count int64) []*CacheItem
+}
+
+// frozenCache is a ReadOnlyCache. It never changes, so it needs no locking.
+type frozenCache struct {
+	name     string
+	frozenOn time.Time
+	items    map[interface{}]*CacheItem
+}
+
+// Freeze returns an immutable point-in-time view of the table. The view
+// shares no locks with the table, so expensive analytics like finding the
+// top keys can run on it without slowing down the table's users. The items
+// in the view are copies, but their values are shared with the table: don't
+// modify them, and note that values implementing Releaser may get released
+// once they're removed from the table.
+func (table *CacheTable) Freeze() ReadOnlyCache {
+	table.RLock()
+	defer table.RUnlock()
+
+	c := &frozenCache{
+		name:     table.name,
+		frozenOn: time.Now(),
+		items:    make(map[interface{}]*CacheItem, len(table.items)),
+	}
+	for key, item := range table.items {
+		c.items[key] = item.freeze()
+	}
+	return c
+}
+
+// freeze returns a copy of the item, without its callbacks.
+func (item *CacheItem) freeze() *CacheItem {
+	item.RLock()
+	defer item.RUnlock()
+
+	data := item.data
+	if item.arena != nil {
+		data = item.arena.read(&item.span)
+	}
+	return &CacheItem{
+		refs:         1,
+		key:          item.key,
+		data:         data,
+		lazy:         item.lazy,
+		lifeSpan:     item.lifeSpan,
+		softLifeSpan: item.softLifeSpan,
+		source:       item.source,
+		seq:          item.seq,
+		createdOn:    item.createdOn,
+		accessedOn:   atomic.LoadInt64(&item.accessedOn),
+		accessCount:  atomic.LoadInt64(&item.accessCount),
+	}
+}
+
+func (c *frozenCache) Name() string {
+	return c.name
+}
+
+func (c *frozenCache) FrozenOn() time.Time {
+	return c.frozenOn
+}
+
+func (c *frozenCache) Value(key interface{}) (*CacheItem, error) {
+	if item, ok := c.items[key]; ok {
+		return item, nil
+	}
+	return nil, ErrKeyNotFound
+}
+
+func (c *frozenCache) Exists(key interface{}) bool {
+	_, ok := c.items[key]
+	return ok
+}
+
+func (c *frozenCache) Count() int {
+	return len(c.items)
+}
+
+func (c *frozenCache) Foreach(trans func(key interface{}, item *CacheItem)) {
+	for key, item := range c.items {
+		trans(key, item)
+	}
+}
+
+func (c *frozenCache) MostAccessed(count int64) []*CacheItem {
+	p := make(CacheItemPairList, 0, len(c.items))
+	for k, v := range c.items {
+		p = append(p, CacheItemPair{k, v.accessCount})
+	}
+	sort.Sort(p)
+
+	var r []*CacheItem
+	for i := int64(0); i < count && i < int64(len(p)); i++ {
+		r = append(r, c.items[p[i].Key])
+	}
+	return r
+}
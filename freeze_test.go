@@ -0,0 +1,63 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestFreeze(t *testing.T) {
+	table := Cache("testFreeze")
+	table.Flush()
+	for i := 0; i < 10; i++ {
+		table.Add(i, 0, "value"+strconv.Itoa(i))
+		for j := 0; j < i; j++ {
+			table.Value(i)
+		}
+	}
+
+	view := table.Freeze()
+	table.Add(10, 0, "added later")
+	table.Delete(0)
+	table.Value(1)
+
+	if view.Name() != "testFreeze" || view.Count() != 10 {
+		t.Error("Expected a view of 10 items, got", view.Count())
+	}
+	if view.Exists(10) || !view.Exists(0) {
+		t.Error("The view shouldn't reflect later changes")
+	}
+	item, err := view.Value(1)
+	if err != nil || item.Data() != "value1" || item.AccessCount() != 1 {
+		t.Error("Expected the item as of freezing, got", item, err)
+	}
+	view.Value(1)
+	if item.AccessCount() != 1 {
+		t.Error("Reading from the view shouldn't count as an access")
+	}
+	if _, err := view.Value(10); err != ErrKeyNotFound {
+		t.Error("Expected ErrKeyNotFound, got", err)
+	}
+
+	top := view.MostAccessed(3)
+	if len(top) != 3 || top[0].Key() != 9 || top[1].Key() != 8 || top[2].Key() != 7 {
+		t.Error("Unexpected most accessed items", top)
+	}
+	if len(view.MostAccessed(20)) != 10 {
+		t.Error("Expected all items to be returned")
+	}
+
+	n := 0
+	view.Foreach(func(key interface{}, item *CacheItem) {
+		n++
+	})
+	if n != 10 {
+		t.Error("Expected to iterate over 10 items, got", n)
+	}
+}
@@ -0,0 +1,62 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Generation returns the generation the item got stored with, 0 if it never
+// got stored. Every item stored in a table gets a new, higher generation than
+// the ones stored before it, so callbacks, watchers and other asynchronous
+// work can tell whether the key they act on still holds the same item, rather
+// than a different value added under the same key in the meantime.
+func (item *CacheItem) Generation() uint64 {
+	return atomic.LoadUint64(&item.generation)
+}
+
+// Generation returns the generation of the item currently stored under the
+// given key, without counting as an access. The second return value is false
+// if there is no such item.
+func (table *CacheTable) Generation(key interface{}) (uint64, bool) {
+	table.RLock()
+	defer table.RUnlock()
+
+	r, ok := table.items[key]
+	if !ok {
+		return 0, false
+	}
+	return r.Generation(), true
+}
+
+// DeleteGeneration deletes an item from the cache, just like Delete, unless
+// the key got re-added since the given generation was stored, in which case
+// ErrGenerationMismatch is returned and the newer item is kept.
+func (table *CacheTable) DeleteGeneration(key interface{}, generation uint64) (*CacheItem, error) {
+	table.touch()
+	table.Lock()
+	r, ok := table.items[key]
+	if !ok {
+		table.Unlock()
+		return nil, ErrKeyNotFound
+	}
+	if r.Generation() != generation {
+		table.Unlock()
+		return nil, ErrGenerationMismatch
+	}
+	r, err := table.deleteTombstoned(key, 0)
+	table.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	table.stats.delete()
+	table.audit(context.Background(), auditOpDelete, key)
+	return r, nil
+}
@@ -0,0 +1,69 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+)
+
+func TestGeneration(t *testing.T) {
+	table := Cache("testGeneration")
+
+	a := table.Add(k, 0, v)
+	if a.Generation() == 0 {
+		t.Error("Stored item has no generation")
+	}
+	gen, ok := table.Generation(k)
+	if !ok || gen != a.Generation() {
+		t.Error("Expected generation", a.Generation(), "got", gen)
+	}
+
+	b := table.Add(k, 0, v+"b")
+	if b.Generation() <= a.Generation() {
+		t.Error("Re-added item didn't get a newer generation")
+	}
+	if _, err := table.DeleteGeneration(k, a.Generation()); err != ErrGenerationMismatch {
+		t.Error("Expected ErrGenerationMismatch, got", err)
+	}
+	if !table.Exists(k) {
+		t.Error("Deleting an old generation removed its successor")
+	}
+	if _, err := table.DeleteGeneration(k, b.Generation()); err != nil {
+		t.Error("Error deleting current generation", err)
+	}
+	if _, ok := table.Generation(k); ok {
+		t.Error("Deleted item still has a generation")
+	}
+	if _, err := table.DeleteGeneration(k, b.Generation()); err != ErrKeyNotFound {
+		t.Error("Expected ErrKeyNotFound, got", err)
+	}
+}
+
+func TestGenerationReAddDuringDelete(t *testing.T) {
+	table := Cache("testGenerationReAddDuringDelete")
+
+	table.Add(k, 0, v)
+	var fresh *CacheItem
+	table.SetAboutToDeleteItemCallback(func(item *CacheItem) {
+		if fresh == nil {
+			// Re-add the key while its old item is being deleted.
+			fresh = table.Add(k, 0, v+"fresh")
+		}
+	})
+	if _, err := table.Delete(k); err != nil {
+		t.Error("Error deleting item", err)
+	}
+
+	r, err := table.Value(k)
+	if err != nil || r != fresh {
+		t.Error("Item re-added during deletion got deleted")
+	}
+	if gen, _ := table.Generation(k); gen != fresh.Generation() {
+		t.Error("Expected generation", fresh.Generation(), "got", gen)
+	}
+}
@@ -0,0 +1,161 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"container/list"
+	"sync"
+)
+
+// GhostStats estimates how a larger capacity would affect a table's hit
+// ratio, see EnableGhostCache.
+type GhostStats struct {
+	// Misses since the ghost cache got enabled.
+	Misses int64
+	// Misses broken down by why the key wasn't cached: never seen or long
+	// forgotten, expired, evicted to make room, or deleted, e.g. by
+	// invalidations. These tell whether to raise lifespans, raise the
+	// capacity or fix the invalidation logic.
+	ColdMisses        int64
+	ExpiredMisses     int64
+	EvictedMisses     int64
+	InvalidatedMisses int64
+	// ExtraHits[n] counts the misses of evicted keys that would have been
+	// hits with n+1 more items of capacity.
+	ExtraHits []int64
+}
+
+// WouldHit returns how many of the misses would have been hits with the given
+// additional capacity.
+func (s GhostStats) WouldHit(extra int) int64 {
+	var hits int64
+	for i := 0; i < extra && i < len(s.ExtraHits); i++ {
+		hits += s.ExtraHits[i]
+	}
+	return hits
+}
+
+// ghostEntry is a removed key remembered by a ghost cache.
+type ghostEntry struct {
+	key    interface{}
+	reason RemovalReason
+	// Number of keys evicted before this one.
+	evicted int64
+}
+
+// ghostCache remembers the keys of recently removed items.
+type ghostCache struct {
+	sync.Mutex
+
+	size    int
+	evicted int64
+	hits    []int64
+	// Misses by reason, see GhostStats.
+	misses, cold, expired, evictedMisses, invalidated int64
+	// Removed keys, most recently removed last.
+	entries  *list.List
+	elements map[interface{}]*list.Element
+}
+
+// EnableGhostCache makes the table remember the keys, never the data, of up
+// to size recently evicted, expired or deleted items. Misses of evicted keys
+// would have been hits with a larger capacity, and GhostStats tells how much
+// larger, giving a concrete idea of how much memory would help. It also
+// classifies all misses by the reason their keys weren't cached. Calling it
+// again resets the statistics.
+func (table *CacheTable) EnableGhostCache(size int) {
+	g := &ghostCache{
+		size:     size,
+		hits:     make([]int64, size),
+		entries:  list.New(),
+		elements: make(map[interface{}]*list.Element),
+	}
+
+	table.Lock()
+	defer table.Unlock()
+	table.ghost = g
+}
+
+// DisableGhostCache stops remembering evicted keys.
+func (table *CacheTable) DisableGhostCache() {
+	table.Lock()
+	defer table.Unlock()
+	table.ghost = nil
+}
+
+// GhostStats returns the statistics of the table's ghost cache. The second
+// return value is false if the ghost cache isn't enabled.
+func (table *CacheTable) GhostStats() (GhostStats, bool) {
+	table.RLock()
+	g := table.ghost
+	table.RUnlock()
+	if g == nil {
+		return GhostStats{}, false
+	}
+
+	g.Lock()
+	defer g.Unlock()
+	return GhostStats{
+		Misses:            g.misses,
+		ColdMisses:        g.cold,
+		ExpiredMisses:     g.expired,
+		EvictedMisses:     g.evictedMisses,
+		InvalidatedMisses: g.invalidated,
+		ExtraHits:         append([]int64(nil), g.hits...),
+	}, true
+}
+
+// remove remembers a removed key.
+func (g *ghostCache) remove(key interface{}, reason RemovalReason) {
+	g.Lock()
+	defer g.Unlock()
+
+	if e, ok := g.elements[key]; ok {
+		g.entries.Remove(e)
+	}
+	g.elements[key] = g.entries.PushBack(&ghostEntry{key: key, reason: reason, evicted: g.evicted})
+	if reason == RemovalEvicted {
+		g.evicted++
+	}
+	for g.entries.Len() > g.size {
+		e := g.entries.Front()
+		g.entries.Remove(e)
+		delete(g.elements, e.Value.(*ghostEntry).key)
+	}
+}
+
+// miss records a miss, why its key wasn't cached, and whether it was evicted
+// recently enough to have been a hit with more capacity.
+func (g *ghostCache) miss(key interface{}) {
+	g.Lock()
+	defer g.Unlock()
+
+	g.misses++
+	e, ok := g.elements[key]
+	if !ok {
+		g.cold++
+		return
+	}
+	g.entries.Remove(e)
+	delete(g.elements, key)
+
+	entry := e.Value.(*ghostEntry)
+	switch entry.reason {
+	case RemovalExpired:
+		g.expired++
+	case RemovalDeleted:
+		g.invalidated++
+	case RemovalEvicted:
+		g.evictedMisses++
+		// The key would have survived with room for every key evicted
+		// after it.
+		if n := g.evicted - entry.evicted - 1; n < int64(len(g.hits)) {
+			g.hits[n]++
+		}
+	}
+}
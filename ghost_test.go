@@ -0,0 +1,100 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGhostCache(t *testing.T) {
+	table, _ := CacheWithOptions("testGhostCache", WithCapacity(2))
+	table.DisableGhostCache()
+	table.Flush()
+	if _, ok := table.GhostStats(); ok {
+		t.Error("Ghost cache is enabled by default")
+	}
+	table.EnableGhostCache(10)
+
+	for i := 0; i < 6; i++ {
+		table.Add(i, 0, v)
+	}
+	// 4 keys got evicted, all of which would have been hits with 4 more
+	// items of capacity.
+	for i := 0; i < 6; i++ {
+		table.Value(i)
+	}
+	table.Value("unknown")
+
+	stats, ok := table.GhostStats()
+	if !ok {
+		t.Error("Ghost cache isn't enabled")
+		return
+	}
+	if stats.Misses != 5 {
+		t.Error("Expected 5 misses, got", stats.Misses)
+	}
+	if stats.WouldHit(4) != 4 {
+		t.Error("Unexpected extra hits", stats.ExtraHits)
+	}
+
+	table.DisableGhostCache()
+	if _, ok := table.GhostStats(); ok {
+		t.Error("Ghost cache is still enabled")
+	}
+}
+
+func TestGhostCacheDistance(t *testing.T) {
+	table := Cache("testGhostCacheDistance")
+	table.EnableGhostCache(3)
+	g := table.ghost
+
+	for i := 0; i < 4; i++ {
+		g.remove(i, RemovalEvicted)
+	}
+	// Key 0 dropped out of the ghost cache.
+	for i := 0; i < 4; i++ {
+		g.miss(i)
+	}
+
+	stats, _ := table.GhostStats()
+	if stats.Misses != 4 {
+		t.Error("Expected 4 misses, got", stats.Misses)
+	}
+	if stats.WouldHit(1) != 1 || stats.WouldHit(2) != 2 || stats.WouldHit(3) != 3 {
+		t.Error("Unexpected extra hits", stats.ExtraHits)
+	}
+}
+
+func TestGhostCacheMissReasons(t *testing.T) {
+	table, _ := CacheWithOptions("testGhostCacheMissReasons", WithCapacity(2))
+	table.DisableGhostCache()
+	table.Flush()
+	table.EnableGhostCache(10)
+
+	table.Add("expired", 10*time.Millisecond, v)
+	time.Sleep(50 * time.Millisecond)
+	table.DeleteExpired()
+	table.Add("deleted", 0, v)
+	table.Delete("deleted")
+	for i := 0; i < 3; i++ {
+		table.Add(i, 0, v)
+	}
+
+	table.Value("cold")
+	table.Value("expired")
+	table.Value("deleted")
+	for i := 0; i < 3; i++ {
+		table.Value(i)
+	}
+
+	stats, _ := table.GhostStats()
+	if stats.Misses != 4 || stats.ColdMisses != 1 || stats.ExpiredMisses != 1 || stats.InvalidatedMisses != 1 || stats.EvictedMisses != 1 {
+		t.Error("Unexpected miss breakdown", stats)
+	}
+}
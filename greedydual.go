@@ -0,0 +1,182 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"container/heap"
+)
+
+// agingEntry is a key's priority record in an aging policy.
+type agingEntry struct {
+	key       interface{}
+	frequency int64
+	size      int64
+	priority  float64
+	// Tie-breaker: entries touched longer ago get evicted first.
+	touched uint64
+	index   int
+}
+
+// agingHeap orders entries by ascending priority.
+type agingHeap []*agingEntry
+
+func (h agingHeap) Len() int { return len(h) }
+func (h agingHeap) Less(i, j int) bool {
+	if h[i].priority == h[j].priority {
+		return h[i].touched < h[j].touched
+	}
+	return h[i].priority < h[j].priority
+}
+func (h agingHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *agingHeap) Push(x interface{}) {
+	entry := x.(*agingEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *agingHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return entry
+}
+
+// agingPolicy evicts the item with the lowest priority, where an item's
+// priority is the cache's age at its last access plus a weight. The age gets
+// raised to the priority of every evicted item, so items that were popular a
+// long time ago eventually become eligible for eviction.
+type agingPolicy struct {
+	// Computes an entry's weight, which gets added to the cache's age.
+	weight func(entry *agingEntry) float64
+	// Returns the size of an item, if the weight depends on it.
+	sizeOf func(item *CacheItem) int64
+
+	age     float64
+	clock   uint64
+	queue   agingHeap
+	entries map[interface{}]*agingEntry
+}
+
+func newAgingPolicy(weight func(*agingEntry) float64, sizeOf func(*CacheItem) int64) agingPolicy {
+	return agingPolicy{
+		weight:  weight,
+		sizeOf:  sizeOf,
+		entries: make(map[interface{}]*agingEntry),
+	}
+}
+
+// touch updates an entry's priority after it was added or accessed.
+func (p *agingPolicy) touch(entry *agingEntry, item *CacheItem) {
+	if p.sizeOf != nil {
+		entry.size = p.sizeOf(item)
+		if entry.size < 1 {
+			entry.size = 1
+		}
+	}
+	p.clock++
+	entry.touched = p.clock
+	entry.priority = p.age + p.weight(entry)
+}
+
+// Add starts tracking a new item with a frequency of 1.
+func (p *agingPolicy) Add(item *CacheItem) {
+	if _, exists := p.entries[item.key]; exists {
+		p.Access(item)
+		return
+	}
+
+	entry := &agingEntry{key: item.key, frequency: 1}
+	p.touch(entry, item)
+	p.entries[item.key] = entry
+	heap.Push(&p.queue, entry)
+}
+
+// Access bumps an item's frequency and recomputes its priority.
+func (p *agingPolicy) Access(item *CacheItem) {
+	entry, exists := p.entries[item.key]
+	if !exists {
+		return
+	}
+
+	entry.frequency++
+	p.touch(entry, item)
+	heap.Fix(&p.queue, entry.index)
+}
+
+// Remove stops tracking an item.
+func (p *agingPolicy) Remove(item *CacheItem) {
+	entry, exists := p.entries[item.key]
+	if !exists {
+		return
+	}
+
+	heap.Remove(&p.queue, entry.index)
+	delete(p.entries, item.key)
+}
+
+// Evict drops the item with the lowest priority and ages the cache.
+func (p *agingPolicy) Evict() (interface{}, bool) {
+	if len(p.queue) == 0 {
+		return nil, false
+	}
+
+	entry := heap.Pop(&p.queue).(*agingEntry)
+	delete(p.entries, entry.key)
+	p.age = entry.priority
+	return entry.key, true
+}
+
+// Reset drops all bookkeeping and resets the cache's age.
+func (p *agingPolicy) Reset() {
+	p.age = 0
+	p.queue = nil
+	p.entries = make(map[interface{}]*agingEntry)
+}
+
+// LFUDAPolicy implements LFU with Dynamic Aging: items are ranked by their
+// access frequency plus the cache's age at their last access, which keeps
+// formerly popular items from staying in the cache forever.
+type LFUDAPolicy struct {
+	agingPolicy
+}
+
+// NewLFUDAPolicy creates an LFU with Dynamic Aging eviction policy.
+func NewLFUDAPolicy() *LFUDAPolicy {
+	return &LFUDAPolicy{
+		agingPolicy: newAgingPolicy(func(entry *agingEntry) float64 {
+			return float64(entry.frequency)
+		}, nil),
+	}
+}
+
+// GDSFPolicy implements GreedyDual-Size-Frequency: items are ranked by their
+// access frequency divided by their size, plus the cache's age at their last
+// access. Small, popular objects are preferred over large ones, which
+// maximizes the hit ratio when object sizes vary a lot, e.g. for HTTP
+// responses.
+type GDSFPolicy struct {
+	agingPolicy
+}
+
+// NewGDSFPolicy creates a GreedyDual-Size-Frequency eviction policy.
+// Parameter sizeOf returns the size of an item, e.g. its length in bytes;
+// sizes below 1 are treated as 1.
+func NewGDSFPolicy(sizeOf func(item *CacheItem) int64) *GDSFPolicy {
+	return &GDSFPolicy{
+		agingPolicy: newAgingPolicy(func(entry *agingEntry) float64 {
+			return float64(entry.frequency) / float64(entry.size)
+		}, sizeOf),
+	}
+}
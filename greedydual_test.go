@@ -0,0 +1,63 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+)
+
+func TestGDSFPrefersSmallItems(t *testing.T) {
+	sizeOf := func(item *CacheItem) int64 {
+		return int64(len(item.Data().([]byte)))
+	}
+	cache := NewBoundedCache("testGDSF", 2, NewGDSFPolicy(sizeOf))
+
+	cache.Add("small", 0, make([]byte, 1024))
+	cache.Add("large", 0, make([]byte, 10*1024*1024))
+
+	// Even with a few more hits, the large object is worth less per byte
+	cache.Value("large")
+	cache.Value("large")
+
+	cache.Add("other", 0, make([]byte, 2048))
+	if cache.Exists("large") {
+		t.Error("large should be evicted (lowest frequency per byte)")
+	}
+	if !cache.Exists("small") || !cache.Exists("other") {
+		t.Error("Small items should still exist")
+	}
+}
+
+func TestLFUDAAging(t *testing.T) {
+	p := NewLFUDAPolicy()
+
+	old := NewCacheItem("old", 0, nil)
+	p.Add(old)
+	for i := 0; i < 3; i++ {
+		p.Access(old)
+	}
+
+	// Churn through items with a frequency of 1 to age the cache
+	for i := 0; i < 3; i++ {
+		p.Add(NewCacheItem(i, 0, nil))
+		if key, _ := p.Evict(); key != i {
+			t.Error("Expected the fresh item to be evicted, got", key)
+		}
+	}
+
+	// A new item now catches up with the formerly popular one
+	p.Add(NewCacheItem("new", 0, nil))
+	if key, _ := p.Evict(); key != "old" {
+		t.Error("Expected aged item to be evicted, got", key)
+	}
+
+	p.Reset()
+	if _, ok := p.Evict(); ok {
+		t.Error("Empty policy should not return a victim")
+	}
+}
@@ -0,0 +1,172 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"container/list"
+	"sync"
+)
+
+var (
+	groups     = make(map[string]*TableGroup)
+	groupMutex sync.Mutex
+)
+
+// TableGroup creates cache tables on demand, e.g. one per tenant, and limits
+// how many of them exist at a time, see CacheGroup.
+type TableGroup struct {
+	sync.Mutex
+
+	// Prefix of the tables' names.
+	prefix string
+	// Options the tables get created with.
+	opts []Option
+	// Maximum number of tables, 0 means unlimited.
+	maxCaches int
+
+	// The group's tables, most recently used first.
+	tables *list.List
+	// Map from key to the table's list element.
+	elements map[string]*list.Element
+}
+
+// groupEntry is a table of a group.
+type groupEntry struct {
+	key   string
+	table *CacheTable
+}
+
+// CacheGroup returns the existing group of tables with the given name prefix
+// or creates a new one, whose tables get configured with the given options.
+// Its tables are part of the registry, named prefix+key, e.g. with the prefix
+// "tenant:" the table of key "acme" is called "tenant:acme".
+func CacheGroup(prefix string, opts ...Option) *TableGroup {
+	groupMutex.Lock()
+	defer groupMutex.Unlock()
+
+	g, ok := groups[prefix]
+	if !ok {
+		g = &TableGroup{
+			prefix:   prefix,
+			opts:     opts,
+			tables:   list.New(),
+			elements: make(map[string]*list.Element),
+		}
+		groups[prefix] = g
+	}
+	return g
+}
+
+// SetMaxCaches limits the number of tables in the group. Once there are more,
+// the least recently used tables get removed from the registry and closed,
+// see CacheTable.Close. A limit of 0 means unlimited.
+func (g *TableGroup) SetMaxCaches(max int) {
+	g.Lock()
+	g.maxCaches = max
+	evicted := g.evictInternal()
+	g.Unlock()
+
+	closeTables(evicted)
+}
+
+// Cache returns the group's table for the given key, creating it if
+// necessary, and marks it as most recently used. Look tables up every time
+// you use them: holding on to a table doesn't keep it from getting evicted.
+func (g *TableGroup) Cache(key string) *CacheTable {
+	name := g.prefix + key
+
+	g.Lock()
+	// The table may have been torn down in the meantime, see
+	// SetCacheIdleTimeout, so always look it up in the registry.
+	t, _ := CacheWithOptions(name, g.opts...)
+	if e, ok := g.elements[key]; ok {
+		e.Value.(*groupEntry).table = t
+		g.tables.MoveToFront(e)
+	} else {
+		g.elements[key] = g.tables.PushFront(&groupEntry{key: key, table: t})
+	}
+	evicted := g.evictInternal()
+	g.Unlock()
+
+	closeTables(evicted)
+	return t
+}
+
+// Remove removes the group's table for the given key from the registry and
+// closes it. Returns whether the table existed.
+func (g *TableGroup) Remove(key string) bool {
+	g.Lock()
+	e, ok := g.elements[key]
+	if ok {
+		g.removeInternal(e)
+	}
+	g.Unlock()
+
+	if ok {
+		closeTables([]*CacheTable{e.Value.(*groupEntry).table})
+	}
+	return ok
+}
+
+// Len returns the number of tables in the group.
+func (g *TableGroup) Len() int {
+	g.Lock()
+	defer g.Unlock()
+	return g.tables.Len()
+}
+
+// Keys returns the keys of the group's tables, most recently used first.
+func (g *TableGroup) Keys() []string {
+	g.Lock()
+	defer g.Unlock()
+
+	keys := make([]string, 0, g.tables.Len())
+	for e := g.tables.Front(); e != nil; e = e.Next() {
+		keys = append(keys, e.Value.(*groupEntry).key)
+	}
+	return keys
+}
+
+// evictInternal removes the least recently used tables until the group is
+// within its limit, and returns them.
+// Careful: do not run this method unless the group-mutex is locked!
+func (g *TableGroup) evictInternal() []*CacheTable {
+	var evicted []*CacheTable
+	for g.maxCaches > 0 && g.tables.Len() > g.maxCaches {
+		e := g.tables.Back()
+		g.removeInternal(e)
+		evicted = append(evicted, e.Value.(*groupEntry).table)
+	}
+	return evicted
+}
+
+// removeInternal removes a table from the group and the registry.
+// Careful: do not run this method unless the group-mutex is locked!
+func (g *TableGroup) removeInternal(e *list.Element) {
+	entry := e.Value.(*groupEntry)
+	g.tables.Remove(e)
+	delete(g.elements, entry.key)
+
+	mutex.Lock()
+	// Only remove the group's own table, not one created since it got torn
+	// down for idleness.
+	if cache[g.prefix+entry.key] == entry.table {
+		delete(cache, g.prefix+entry.key)
+	}
+	mutex.Unlock()
+}
+
+// closeTables closes tables removed from the registry.
+func closeTables(tables []*CacheTable) {
+	for _, t := range tables {
+		t.log("Closing table", t.name, "removed from its group")
+		if err := t.Close(); err != nil {
+			t.log("Error closing table", t.name, ":", err)
+		}
+	}
+}
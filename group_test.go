@@ -0,0 +1,58 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCacheGroup(t *testing.T) {
+	g := CacheGroup("testCacheGroup:", WithStats())
+	if CacheGroup("testCacheGroup:") != g {
+		t.Error("Expected the existing group")
+	}
+	g.SetMaxCaches(2)
+
+	acme := g.Cache("acme")
+	acme.Add("key", 0, "value")
+	if Cache("testCacheGroup:acme") != acme {
+		t.Error("Group tables should be part of the registry")
+	}
+	if acme.stats == nil {
+		t.Error("Group tables should get created with the group's options")
+	}
+	if g.Cache("acme") != acme {
+		t.Error("Expected the existing table")
+	}
+
+	g.Cache("globex")
+	g.Cache("acme")
+	g.Cache("initech")
+	if keys := g.Keys(); !reflect.DeepEqual(keys, []string{"initech", "acme"}) {
+		t.Error("Expected the least recently used table to get evicted, got", keys)
+	}
+	if _, _, ok := findCache("testCacheGroup:globex"); ok {
+		t.Error("Evicted tables should get removed from the registry")
+	}
+
+	g.SetMaxCaches(1)
+	if g.Len() != 1 || acme.Count() != 0 {
+		t.Error("Lowering the limit should evict and close tables")
+	}
+	if _, _, ok := findCache("testCacheGroup:acme"); ok {
+		t.Error("Evicted tables should get removed from the registry")
+	}
+
+	if !g.Remove("initech") || g.Remove("initech") || g.Len() != 0 {
+		t.Error("Expected the table to get removed once")
+	}
+	if _, _, ok := findCache("testCacheGroup:initech"); ok {
+		t.Error("Removed tables should get removed from the registry")
+	}
+}
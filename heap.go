@@ -0,0 +1,30 @@
+//go:build go1.16
+// +build go1.16
+
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"runtime/metrics"
+)
+
+// heapObjectsMetric holds the bytes occupied by heap objects, live or not
+// yet swept, just like MemStats.HeapAlloc.
+const heapObjectsMetric = "/memory/classes/heap/objects:bytes"
+
+// heapAlloc returns the bytes allocated on the heap, without stopping the
+// world like runtime.ReadMemStats.
+func heapAlloc() uint64 {
+	sample := []metrics.Sample{{Name: heapObjectsMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
@@ -0,0 +1,23 @@
+//go:build !go1.16
+// +build !go1.16
+
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"runtime"
+)
+
+// heapAlloc returns the bytes allocated on the heap. Without runtime/metrics,
+// this stops the world.
+func heapAlloc() uint64 {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
@@ -0,0 +1,140 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// HTTPLoader loads items by requesting them from an HTTP endpoint, see
+// NewHTTPLoader.
+type HTTPLoader struct {
+	client *http.Client
+	url    func(req *LoadRequest) string
+	decode func(resp *http.Response) (interface{}, error)
+	// Callback method triggered when a request fails.
+	failed func(key interface{}, err error)
+}
+
+// HTTPLoaderOption configures an HTTPLoader.
+type HTTPLoaderOption func(*HTTPLoader)
+
+// WithHTTPClient makes the loader send its requests with the given client,
+// e.g. one with a timeout or custom transport, instead of
+// http.DefaultClient.
+func WithHTTPClient(client *http.Client) HTTPLoaderOption {
+	return func(l *HTTPLoader) {
+		l.client = client
+	}
+}
+
+// WithHTTPErrorCallback configures a callback, which will be called every
+// time a request fails, other than with 404 Not Found, e.g. to log it. The
+// lookup fails with ErrKeyNotFoundOrLoadable either way.
+func WithHTTPErrorCallback(f func(key interface{}, err error)) HTTPLoaderOption {
+	return func(l *HTTPLoader) {
+		l.failed = f
+	}
+}
+
+// NewHTTPLoader returns a loader requesting the keys being looked up from an
+// HTTP endpoint, for use with SetLoader, turning the table into a
+// read-through cache of another service. Parameter url returns the URL to GET
+// for a request, e.g. "http://users/" plus the key. Parameter decode turns
+// successful responses into the value to cache, e.g. by decoding their JSON
+// body. Responses with 404 Not Found fail to load, just like any other than
+// 2xx. Requests run with the lookup's context, see Load, and can be hedged
+// via Hedge.
+func NewHTTPLoader(url func(req *LoadRequest) string, decode func(resp *http.Response) (interface{}, error), opts ...HTTPLoaderOption) *HTTPLoader {
+	l := &HTTPLoader{
+		client: http.DefaultClient,
+		url:    url,
+		decode: decode,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Load requests the item from the endpoint, returning nil if that fails. Pass
+// it to SetLoader.
+func (l *HTTPLoader) Load(req *LoadRequest) *CacheItem {
+	r, err := http.NewRequest(http.MethodGet, l.url(req), nil)
+	if err != nil {
+		l.fail(req.Key, err)
+		return nil
+	}
+	resp, err := l.client.Do(r.WithContext(req.Context))
+	if err != nil {
+		l.fail(req.Key, err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if resp.StatusCode != http.StatusNotFound {
+			l.fail(req.Key, fmt.Errorf("Unexpected status: %s", resp.Status))
+		}
+		return nil
+	}
+	v, err := l.decode(resp)
+	if err != nil {
+		l.fail(req.Key, err)
+		return nil
+	}
+	return NewCacheItem(req.Key, 0, v)
+}
+
+// fail reports a failed request.
+func (l *HTTPLoader) fail(key interface{}, err error) {
+	if l.failed != nil {
+		l.failed(key, err)
+	}
+}
+
+// Hedge returns a loader calling loadData, and calling it once more if the
+// first call didn't return within the given latency threshold. Whichever call
+// loads the item first wins, and the other one's context gets canceled. This
+// cuts tail latencies of remote loaders, e.g. an HTTPLoader or a wrapped gRPC
+// method, at the cost of some duplicate requests: set the threshold to about
+// the 95th percentile of their latency.
+func Hedge(loadData func(req *LoadRequest) *CacheItem, after time.Duration) func(req *LoadRequest) *CacheItem {
+	return func(req *LoadRequest) *CacheItem {
+		ctx, cancel := context.WithCancel(req.Context)
+		defer cancel()
+
+		// Buffered, so the losing call can finish after we returned.
+		results := make(chan *CacheItem, 2)
+		attempt := func() {
+			r := *req
+			r.Context = ctx
+			results <- loadData(&r)
+		}
+		go attempt()
+
+		timer := time.NewTimer(after)
+		defer timer.Stop()
+		pending := 1
+		for {
+			select {
+			case item := <-results:
+				pending--
+				if item != nil || pending == 0 {
+					return item
+				}
+			case <-timer.C:
+				pending++
+				go attempt()
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPLoader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/1":
+			fmt.Fprint(w, "alice")
+		case "/users/2":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	var failures int32
+	loader := NewHTTPLoader(func(req *LoadRequest) string {
+		return fmt.Sprint(server.URL, "/users/", req.Key)
+	}, func(resp *http.Response) (interface{}, error) {
+		b, err := ioutil.ReadAll(resp.Body)
+		return string(b), err
+	}, WithHTTPErrorCallback(func(key interface{}, err error) {
+		atomic.AddInt32(&failures, 1)
+	}))
+
+	table, _ := CacheWithOptions("testHTTPLoader")
+	table.Flush()
+	table.SetLoader(loader.Load)
+
+	if p, err := table.Value(1); err != nil || p.Data() != "alice" {
+		t.Error("Error loading user 1", err)
+	}
+	if _, err := table.Value(3); err != ErrKeyNotFoundOrLoadable {
+		t.Error("Expected ErrKeyNotFoundOrLoadable for missing users, got", err)
+	}
+	if n := atomic.LoadInt32(&failures); n != 0 {
+		t.Error("Expected missing users not to be reported as failures, got", n)
+	}
+	if _, err := table.Value(2); err != ErrKeyNotFoundOrLoadable {
+		t.Error("Expected ErrKeyNotFoundOrLoadable for server errors, got", err)
+	}
+	if n := atomic.LoadInt32(&failures); n != 1 {
+		t.Error("Expected server errors to be reported as failures, got", n)
+	}
+}
+
+func TestHedge(t *testing.T) {
+	var calls, canceled int32
+	slowFirst := func(req *LoadRequest) *CacheItem {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			// The first call hangs until it gets canceled.
+			<-req.Context.Done()
+			atomic.AddInt32(&canceled, 1)
+			return nil
+		}
+		return NewCacheItem(req.Key, 0, v)
+	}
+
+	table, _ := CacheWithOptions("testHedge")
+	table.Flush()
+	table.SetLoader(Hedge(slowFirst, 10*time.Millisecond))
+
+	if p, err := table.Value(k); err != nil || p.Data() != v {
+		t.Error("Expected the hedged call to load the item", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Error("Expected 2 calls, got", n)
+	}
+	for start := time.Now(); atomic.LoadInt32(&canceled) == 0 && time.Since(start) < time.Second; {
+		time.Sleep(time.Millisecond)
+	}
+	if atomic.LoadInt32(&canceled) != 1 {
+		t.Error("Expected the losing call to be canceled")
+	}
+
+	// Fast calls don't get hedged.
+	atomic.StoreInt32(&calls, 1)
+	if _, err := table.Value("fast"); err != nil {
+		t.Error("Error loading item", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Error("Expected fast calls not to be hedged, got", n-1, "calls")
+	}
+}
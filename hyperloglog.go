@@ -0,0 +1,212 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"encoding/json"
+	"math"
+	"sync"
+	"time"
+)
+
+// The number of index bits of a HyperLogLog, resulting in 2^14 registers and
+// a standard error of about 0.8%.
+const hllPrecision = 14
+
+// hyperLogLog is the data of a HyperLogLog item, estimating the number of
+// distinct values added to it.
+type hyperLogLog struct {
+	sync.RWMutex
+
+	registers []uint8
+}
+
+func newHyperLogLog() *hyperLogLog {
+	return &hyperLogLog{registers: make([]uint8, 1<<hllPrecision)}
+}
+
+// MarshalJSON exports a HyperLogLog as its registers, e.g. for Export.
+func (h *hyperLogLog) MarshalJSON() ([]byte, error) {
+	h.RLock()
+	defer h.RUnlock()
+	return json.Marshal(h.registers)
+}
+
+// add adds a value and returns whether a register changed.
+// Careful: do not run this method unless the HyperLogLog-mutex is locked!
+func (h *hyperLogLog) add(v interface{}) bool {
+	hash := mix64(Key(v).Hash())
+	index := hash >> (64 - hllPrecision)
+
+	// The position of the first set bit in the remaining bits. The sentinel
+	// bit caps it if they're all zero.
+	w := hash<<hllPrecision | 1<<(hllPrecision-1)
+	rank := uint8(1)
+	for w&(1<<63) == 0 {
+		rank++
+		w <<= 1
+	}
+
+	if rank <= h.registers[index] {
+		return false
+	}
+	h.registers[index] = rank
+	return true
+}
+
+// merge folds another HyperLogLog's registers into this one.
+// Careful: do not run this method unless the HyperLogLog-mutex is locked!
+func (h *hyperLogLog) merge(registers []uint8) {
+	for i, r := range registers {
+		if r > h.registers[i] {
+			h.registers[i] = r
+		}
+	}
+}
+
+// estimate returns the estimated number of distinct values.
+// Careful: do not run this method unless the HyperLogLog-mutex is locked!
+func (h *hyperLogLog) estimate() uint64 {
+	m := float64(len(h.registers))
+	sum := 0.0
+	zeros := 0
+	for _, r := range h.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	alpha := 0.7213 / (1 + 1.079/m)
+	e := alpha * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		// Linear counting is more accurate for small cardinalities.
+		e = m * math.Log(m/float64(zeros))
+	}
+	return uint64(e + 0.5)
+}
+
+// mix64 scrambles a hash's bits, as HyperLogLog relies on all of them being
+// evenly distributed (the finalizer of MurmurHash3).
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb93fe53e63b9
+	h ^= h >> 33
+	return h
+}
+
+// AddHyperLogLog adds an empty HyperLogLog to the cache, replacing any
+// existing item with the same key. A HyperLogLog estimates the number of
+// distinct values added to it, e.g. unique visitors, within about 1% using
+// 16 KiB of memory. Parameter lifeSpan works just like for Add; adding values
+// counts as an access.
+//
+// HyperLogLogs get modified in place, so adding values isn't recorded in
+// mutation logs and automatic snapshots.
+func (table *CacheTable) AddHyperLogLog(key interface{}, lifeSpan time.Duration) *CacheItem {
+	return table.Add(key, lifeSpan, newHyperLogLog())
+}
+
+// PFAdd adds values to a HyperLogLog and returns whether its estimate
+// changed. Values are hashed like the parts of a Key. If the key doesn't
+// exist, a HyperLogLog with the table's default lifespan gets added, see
+// AddHyperLogLog.
+func (table *CacheTable) PFAdd(key interface{}, values ...interface{}) (bool, error) {
+	item := table.valueOrAdd(key, func() interface{} {
+		return newHyperLogLog()
+	})
+
+	h, ok := item.Data().(*hyperLogLog)
+	if !ok {
+		return false, ErrNotAHyperLogLog
+	}
+
+	h.Lock()
+	defer h.Unlock()
+	changed := false
+	for _, v := range values {
+		if h.add(v) {
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+// PFCount returns the estimated number of distinct values added to the
+// HyperLogLogs with the given keys, i.e. of their union. Missing keys count
+// as empty HyperLogLogs. The lookups work just like Value, including the
+// data-loader.
+func (table *CacheTable) PFCount(keys ...interface{}) (uint64, error) {
+	union := newHyperLogLog()
+	for _, key := range keys {
+		registers, err := table.hyperLogLogRegisters(key)
+		if err == ErrKeyNotFound {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		union.merge(registers)
+	}
+
+	return union.estimate(), nil
+}
+
+// PFMerge merges the HyperLogLogs with the given source keys into the one
+// with the destination key, so it estimates the number of distinct values of
+// all of them. Missing source keys count as empty HyperLogLogs; a missing
+// destination gets added like by PFAdd.
+func (table *CacheTable) PFMerge(dest interface{}, sources ...interface{}) error {
+	var merged [][]uint8
+	for _, key := range sources {
+		registers, err := table.hyperLogLogRegisters(key)
+		if err == ErrKeyNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		merged = append(merged, registers)
+	}
+
+	item := table.valueOrAdd(dest, func() interface{} {
+		return newHyperLogLog()
+	})
+	h, ok := item.Data().(*hyperLogLog)
+	if !ok {
+		return ErrNotAHyperLogLog
+	}
+
+	h.Lock()
+	defer h.Unlock()
+	for _, registers := range merged {
+		h.merge(registers)
+	}
+	return nil
+}
+
+// hyperLogLogRegisters looks up a HyperLogLog via Value and returns a copy of
+// its registers, so merging never needs to hold two HyperLogLogs' mutexes.
+func (table *CacheTable) hyperLogLogRegisters(key interface{}) ([]uint8, error) {
+	item, err := table.Value(key)
+	if err != nil {
+		return nil, err
+	}
+	h, ok := item.Data().(*hyperLogLog)
+	if !ok {
+		return nil, ErrNotAHyperLogLog
+	}
+
+	h.RLock()
+	defer h.RUnlock()
+	registers := make([]uint8, len(h.registers))
+	copy(registers, h.registers)
+	return registers, nil
+}
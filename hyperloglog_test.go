@@ -0,0 +1,60 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	table := Cache("testHyperLogLog")
+
+	for i := 0; i < 100000; i++ {
+		table.PFAdd("visitors", i%50000)
+	}
+	if changed, _ := table.PFAdd("visitors", 42); changed {
+		t.Error("Adding a known value should not change the estimate")
+	}
+
+	n, err := table.PFCount("visitors")
+	if err != nil || math.Abs(float64(n)-50000) > 50000*0.03 {
+		t.Error("Estimate too far off, expected about 50000, got", n, err)
+	}
+
+	for i := 0; i < 10; i++ {
+		table.PFAdd("small", i)
+	}
+	if n, _ := table.PFCount("small"); n != 10 {
+		t.Error("Small cardinalities should be exact, got", n)
+	}
+
+	table.AddHyperLogLog("other", 0)
+	for i := 40000; i < 60000; i++ {
+		table.PFAdd("other", i)
+	}
+	union, _ := table.PFCount("visitors", "other", "missing")
+	if math.Abs(float64(union)-60000) > 60000*0.03 {
+		t.Error("Union estimate too far off, expected about 60000, got", union)
+	}
+
+	if err := table.PFMerge("merged", "visitors", "other"); err != nil {
+		t.Error("Error merging", err)
+	}
+	if n, _ := table.PFCount("merged"); n != union {
+		t.Error("Merged estimate should equal the union's, got", n, union)
+	}
+
+	table.Add(k, 0, v)
+	if _, err := table.PFAdd(k, 1); err != ErrNotAHyperLogLog {
+		t.Error("Expected ErrNotAHyperLogLog, got", err)
+	}
+	if _, err := table.PFCount(k); err != ErrNotAHyperLogLog {
+		t.Error("Expected ErrNotAHyperLogLog, got", err)
+	}
+}
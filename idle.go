@@ -0,0 +1,145 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Number of idleness checks per idle timeout.
+const idleChecks = 4
+
+// idleReaper tears down tables of the registry that have been idle for too
+// long, see SetCacheIdleTimeout.
+type idleReaper struct {
+	timeout time.Duration
+	stop    chan struct{}
+
+	// Since when each table has been idle.
+	seen map[*CacheTable]time.Time
+}
+
+// The running reaper, nil if none. Guarded by the registry mutex.
+var reaper *idleReaper
+
+// SetCacheIdleTimeout makes the registry close and remove tables that have
+// had no operations for the given timeout, e.g. dynamically named
+// per-tenant tables that are no longer used, so their items and background
+// goroutines don't leak. Looking a table up via Cache or CacheWithOptions
+// counts as an operation; looking at its statistics doesn't. Removed tables
+// get closed, see CacheTable.Close, and are created anew on their next
+// lookup. Don't keep references to tables in the registry across idle
+// periods. A timeout of 0 stops tearing down tables.
+func SetCacheIdleTimeout(timeout time.Duration) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if reaper != nil {
+		close(reaper.stop)
+		reaper = nil
+	}
+	if timeout <= 0 {
+		return
+	}
+
+	reaper = &idleReaper{
+		timeout: timeout,
+		stop:    make(chan struct{}),
+		seen:    make(map[*CacheTable]time.Time),
+	}
+	go reaper.run()
+}
+
+// run checks the tables for idleness until the reaper gets stopped.
+func (r *idleReaper) run() {
+	ticker := time.NewTicker(r.timeout / idleChecks)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case now := <-ticker.C:
+			for _, t := range r.check(now) {
+				t.log("Tearing down idle table", t.name)
+				if err := t.Close(); err != nil {
+					t.log("Error closing table", t.name, ":", err)
+				}
+			}
+		}
+	}
+}
+
+// check removes the tables that have been idle for too long from the
+// registry and returns them.
+func (r *idleReaper) check(now time.Time) []*CacheTable {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	var idle []*CacheTable
+	seen := make(map[*CacheTable]time.Time, len(cache))
+	for name, t := range cache {
+		since, ok := r.seen[t]
+		if atomic.SwapUint32(&t.active, 0) == 1 || !ok {
+			since = now
+		}
+		if now.Sub(since) >= r.timeout {
+			delete(cache, name)
+			idle = append(idle, t)
+			continue
+		}
+		seen[t] = since
+	}
+	r.seen = seen
+
+	return idle
+}
+
+// touch records an operation on the table, see SetCacheIdleTimeout. Only
+// the first operation after each check writes to the flag, so busy tables'
+// operations don't contend for it.
+func (table *CacheTable) touch() {
+	if atomic.LoadUint32(&table.active) == 0 {
+		atomic.StoreUint32(&table.active, 1)
+	}
+}
+
+// Close stops all of the table's background work, i.e. automatic
+// snapshots, the mutation log, event publishing, alerting, value audits and
+// expiration checks, and removes its items. Items in a mapped arena are kept
+// in its file though, which gets closed, see WithMappedArena. The table
+// remains usable as a plain cache.
+func (table *CacheTable) Close() error {
+	table.DisableAutoSnapshot()
+	err := table.DisableMutationLog()
+	table.DisableEvents()
+	table.Lock()
+	if table.stopAudit != nil {
+		close(table.stopAudit)
+		table.stopAudit = nil
+	}
+	arena := table.arena
+	table.Unlock()
+	unwatchSoftRefs(table)
+	if table.stats != nil {
+		table.SetAlertThresholds(AlertConfig{})
+	}
+	if arena != nil && arena.mapping != nil {
+		// Keep the items in the file, for the next process to recover.
+		arena.detach()
+	}
+	table.flush()
+	if arena != nil && arena.mapping != nil {
+		if cerr := arena.mapping.Close(); err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,46 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCacheIdleTimeout(t *testing.T) {
+	idle := Cache("testCacheIdleTimeout")
+	idle.Add("key", 0, "value")
+	events := make(chanSink, 16)
+	idle.EnableEvents(events, 16)
+
+	SetCacheIdleTimeout(100 * time.Millisecond)
+	defer SetCacheIdleTimeout(0)
+
+	busy := Cache("testCacheIdleTimeoutBusy")
+	for i := 0; i < 30; i++ {
+		busy.Add("key", 0, i)
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, _, ok := findCache("testCacheIdleTimeoutBusy"); !ok {
+		t.Error("Busy table shouldn't get torn down")
+	}
+
+	if _, _, ok := findCache("testCacheIdleTimeout"); ok {
+		t.Fatal("Idle table should get removed from the registry")
+	}
+	idle.RLock()
+	closed := idle.eventExporter == nil
+	idle.RUnlock()
+	if !closed || idle.Count() != 0 {
+		t.Error("Idle table should get closed")
+	}
+
+	if Cache("testCacheIdleTimeout") == idle {
+		t.Error("Idle table should get created anew on its next lookup")
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"sync"
+)
+
+// InternPool deduplicates identical strings stored by cache tables, see
+// WithStringInterning. It may be shared by several tables. Strings get
+// dropped from the pool once no stored item uses them anymore.
+type InternPool struct {
+	sync.Mutex
+	strings map[string]*internedString
+}
+
+// internedString is a string in a pool, and how many items use it.
+type internedString struct {
+	s    string
+	refs int
+}
+
+// NewInternPool returns an empty pool of strings.
+func NewInternPool() *InternPool {
+	return &InternPool{
+		strings: make(map[string]*internedString),
+	}
+}
+
+// Len returns how many distinct strings the pool holds.
+func (p *InternPool) Len() int {
+	p.Lock()
+	defer p.Unlock()
+	return len(p.strings)
+}
+
+// intern returns the pool's copy of s, adding s unless it's held already.
+// Careful: do not run this method unless the pool-mutex is locked!
+func (p *InternPool) intern(s string) string {
+	e, ok := p.strings[s]
+	if !ok {
+		e = &internedString{s: s}
+		p.strings[s] = e
+	}
+	e.refs++
+	return e.s
+}
+
+// release drops a reference to a string of the pool.
+// Careful: do not run this method unless the pool-mutex is locked!
+func (p *InternPool) release(s string) {
+	e, ok := p.strings[s]
+	if !ok {
+		return
+	}
+	e.refs--
+	if e.refs == 0 {
+		delete(p.strings, s)
+	}
+}
+
+// WithStringInterning makes the table store string keys, and string values
+// of up to maxValueLen bytes, via the given pool, so identical strings share
+// their memory, e.g. in catalogs whose entries repeat the same attributes.
+// Longer values are rarely identical, so they aren't worth the lookup.
+func WithStringInterning(pool *InternPool, maxValueLen int) Option {
+	return func(o *cacheOptions) {
+		o.internPool = pool
+		o.internMaxValueLen = maxValueLen
+	}
+}
+
+// internInternal replaces an item's string key and value by the pool's
+// copies, before the item gets stored.
+// Careful: do not run this method unless the table-mutex is locked!
+func (table *CacheTable) internInternal(item *CacheItem) {
+	pool := table.options.internPool
+	if pool == nil || item.interned != nil {
+		return
+	}
+
+	pool.Lock()
+	defer pool.Unlock()
+	if key, ok := item.key.(string); ok {
+		item.key = pool.intern(key)
+	}
+	if data, ok := item.data.(string); ok && len(data) <= table.options.internMaxValueLen {
+		item.data = pool.intern(data)
+		item.internedData = true
+	}
+	item.interned = pool
+}
+
+// releaseInterned drops the item's references to the strings of its pool.
+func (item *CacheItem) releaseInterned() {
+	pool := item.interned
+	pool.Lock()
+	defer pool.Unlock()
+	if key, ok := item.key.(string); ok {
+		pool.release(key)
+	}
+	if item.internedData {
+		pool.release(item.data.(string))
+	}
+}
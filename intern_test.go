@@ -0,0 +1,59 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestStringInterning(t *testing.T) {
+	pool := NewInternPool()
+	products := newCacheTable("testStringInterningProducts", newCacheOptions(WithStringInterning(pool, 8)))
+	orders := newCacheTable("testStringInterningOrders", newCacheOptions(WithStringInterning(pool, 8)))
+
+	// Build the strings at runtime, so they don't share constant memory.
+	color := func() string { return strings.Repeat("re", 1) + "d" }
+	a := products.Add("p1", 0, color())
+	b := products.Add("p2", 0, color())
+	c := orders.Add("p1", 0, color())
+	products.Add("p3", 0, strings.Repeat("x", 9))
+
+	if a.Data().(string) != "red" {
+		t.Error("Expected interned values to be unchanged, got", a.Data())
+	}
+	if !sameString(a.Data().(string), b.Data().(string)) || !sameString(a.Data().(string), c.Data().(string)) {
+		t.Error("Expected identical values to share their memory")
+	}
+	if !sameString(a.Key().(string), c.Key().(string)) {
+		t.Error("Expected identical keys to share their memory")
+	}
+	// p1, p2, p3 and red, but not the long value.
+	if n := pool.Len(); n != 4 {
+		t.Error("Expected 4 strings in the pool, got", n)
+	}
+
+	products.Delete("p1")
+	if n := pool.Len(); n != 4 {
+		t.Error("Expected strings still in use to be kept, got", n)
+	}
+	products.Flush()
+	orders.Flush()
+	if n := pool.Len(); n != 0 {
+		t.Error("Expected unused strings to be dropped, got", n)
+	}
+}
+
+// sameString returns whether two strings share their memory.
+func sameString(a, b string) bool {
+	ha := (*reflect.StringHeader)(unsafe.Pointer(&a))
+	hb := (*reflect.StringHeader)(unsafe.Pointer(&b))
+	return ha.Data == hb.Data && ha.Len == hb.Len
+}
@@ -0,0 +1,172 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+// Package adminclient is a client for cache2go's admin handler, shared by
+// the command line tools.
+package adminclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Stats mirrors cache2go.CacheStats.
+type Stats struct {
+	Hits, Misses, Added, Loaded, Restored, Deleted, Expired, Evicted int64
+}
+
+// HitRatio returns the share of lookups that found an item in the cache.
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// Cache is the admin handler's representation of a cache.
+type Cache struct {
+	Name     string
+	Kind     string
+	Count    int
+	Stats    Stats
+	HitRatio float64
+}
+
+// Item is the admin handler's representation of an item.
+type Item struct {
+	Key          string
+	CreatedOn    time.Time
+	AccessedOn   time.Time
+	AccessCount  int64
+	LifeSpan     string
+	TTLRemaining string
+	Source       string
+	Value        json.RawMessage
+}
+
+// Client talks to an admin handler.
+type Client struct {
+	// The URL the admin handler is mounted at.
+	BaseURL string
+	// Token sent as "Authorization: Bearer" header, empty if none.
+	Token string
+	// The client used to send the requests, http.DefaultClient if nil.
+	HTTPClient *http.Client
+}
+
+// Caches lists all caches.
+func (c *Client) Caches() ([]Cache, error) {
+	var caches []Cache
+	err := c.do("GET", "/caches", nil, "", &caches)
+	return caches, err
+}
+
+// Cache returns a cache's size and statistics.
+func (c *Client) Cache(name string) (Cache, error) {
+	var cache Cache
+	err := c.do("GET", cachePath(name), nil, "", &cache)
+	return cache, err
+}
+
+// Keys returns up to limit of the cache's most accessed keys.
+func (c *Client) Keys(name string, limit int) ([]Item, error) {
+	var items []Item
+	err := c.do("GET", fmt.Sprintf("%s/keys?limit=%d", cachePath(name), limit), nil, "", &items)
+	return items, err
+}
+
+// Get returns an item.
+func (c *Client) Get(name, key string) (Item, error) {
+	var item Item
+	err := c.do("GET", keyPath(name, key), nil, "", &item)
+	return item, err
+}
+
+// Set adds an item. If isJSON is set, the value is stored decoded from JSON,
+// otherwise as a string.
+func (c *Client) Set(name, key, value string, ttl time.Duration, isJSON bool) error {
+	contentType := "text/plain"
+	if isJSON {
+		contentType = "application/json"
+	}
+	return c.do("PUT", keyPath(name, key)+"?ttl="+ttl.String(), strings.NewReader(value), contentType, nil)
+}
+
+// Delete deletes an item.
+func (c *Client) Delete(name, key string) error {
+	return c.do("DELETE", keyPath(name, key), nil, "", nil)
+}
+
+// Flush removes all items from a cache.
+func (c *Client) Flush(name string) error {
+	return c.do("POST", cachePath(name)+"/flush", nil, "", nil)
+}
+
+// Stats returns the aggregate statistics of all caches.
+func (c *Client) Stats() (Stats, error) {
+	var stats Stats
+	err := c.do("GET", "/stats", nil, "", &stats)
+	return stats, err
+}
+
+func cachePath(name string) string {
+	return "/caches/" + url.PathEscape(name)
+}
+
+func keyPath(name, key string) string {
+	return cachePath(name) + "/keys/" + url.PathEscape(key)
+}
+
+// do sends a request to the admin handler and decodes the response into v,
+// unless it's nil.
+func (c *Client) do(method, path string, body io.Reader, contentType string, v interface{}) error {
+	req, err := http.NewRequest(method, strings.TrimRight(c.BaseURL, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		var e struct{ Error string }
+		if json.Unmarshal(b, &e) == nil && e.Error != "" {
+			return errors.New(e.Error)
+		}
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(b))
+	}
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,71 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package adminclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"os"
+)
+
+// Flags holds the command line flags for connecting to an admin handler.
+type Flags struct {
+	Addr   string
+	Token  string
+	Cert   string
+	Key    string
+	CACert string
+}
+
+// RegisterFlags registers the connection flags with a flag set.
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{}
+	fs.StringVar(&f.Addr, "addr", "http://localhost:6060/debug/cache2go", "base URL of the admin handler")
+	fs.StringVar(&f.Token, "token", os.Getenv("CACHE2GO_TOKEN"), "token for the admin API, $CACHE2GO_TOKEN by default")
+	fs.StringVar(&f.Cert, "cert", "", "client certificate file, for handlers requiring one")
+	fs.StringVar(&f.Key, "key", "", "client certificate's key file")
+	fs.StringVar(&f.CACert, "cacert", "", "CA certificate file to verify the server with, instead of the system's")
+	return f
+}
+
+// Client returns a client configured by the flags.
+func (f *Flags) Client() (*Client, error) {
+	c := &Client{BaseURL: f.Addr, Token: f.Token}
+	if f.Cert == "" && f.Key == "" && f.CACert == "" {
+		return c, nil
+	}
+
+	config := &tls.Config{}
+	if f.Cert != "" || f.Key != "" {
+		cert, err := tls.LoadX509KeyPair(f.Cert, f.Key)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if f.CACert != "" {
+		pem, err := ioutil.ReadFile(f.CACert)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("No certificates found in " + f.CACert)
+		}
+	}
+
+	c.HTTPClient = &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: config,
+	}}
+	return c, nil
+}
@@ -0,0 +1,41 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package invariants
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muesli/cache2go"
+)
+
+func FuzzPolicies(f *testing.F) {
+	f.Add([]byte{0, 1, 0, 0, 2, 0, 0, 3, 0, 1, 1, 0, 2, 2, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for name, newPolicy := range policies {
+			if err := CheckPolicy(newPolicy(4), 4, Decode(data)); err != nil {
+				t.Error("Policy", name, ":", err)
+			}
+		}
+	})
+}
+
+func FuzzTable(f *testing.F) {
+	f.Add([]byte{0, 1, 1, 0, 2, 0, 3, 0, 2, 1, 1, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		table, _ := cache2go.CacheWithOptions("invariantsFuzz", cache2go.WithCapacity(4), cache2go.WithManualTicks())
+		table.Flush()
+		start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		if err := Run(table, 4, table.Tick, start, Decode(data)); err != nil {
+			t.Error(err)
+		}
+	})
+}
@@ -0,0 +1,161 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+// Package invariants checks that caches and eviction policies uphold
+// cache2go's guarantees while being driven by arbitrary sequences of
+// operations, e.g. generated by Go's fuzzing engine. This allows validating
+// all policies uniformly, including third-party ones.
+package invariants
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/muesli/cache2go"
+)
+
+// Cache is a cache whose invariants can be checked, e.g. a
+// *cache2go.CacheTable or a *cache2go.BoundedCache.
+type Cache interface {
+	cache2go.Cacher
+	SetAboutToDeleteItemCallback(f func(*cache2go.CacheItem))
+}
+
+// OpKind is the kind of an operation applied to a cache.
+type OpKind int
+
+const (
+	// OpAdd adds an item.
+	OpAdd OpKind = iota
+	// OpValue looks up an item.
+	OpValue
+	// OpDelete deletes an item.
+	OpDelete
+	// OpTick advances the cache's time, see Run.
+	OpTick
+)
+
+// Op is an operation applied to a cache.
+type Op struct {
+	Kind OpKind
+	Key  int
+	// Lifespan of added items, or how far ticks advance the time.
+	Duration time.Duration
+}
+
+// String returns a human readable description of the operation.
+func (op Op) String() string {
+	switch op.Kind {
+	case OpAdd:
+		return fmt.Sprintf("add %d for %v", op.Key, op.Duration)
+	case OpValue:
+		return fmt.Sprintf("value %d", op.Key)
+	case OpDelete:
+		return fmt.Sprintf("delete %d", op.Key)
+	case OpTick:
+		return fmt.Sprintf("tick %v", op.Duration)
+	}
+	return "unknown"
+}
+
+// Decode turns arbitrary bytes, e.g. a fuzzer's input, into operations on a
+// small set of keys, so they collide often. Every 3 bytes make an operation.
+func Decode(data []byte) []Op {
+	ops := make([]Op, 0, len(data)/3)
+	for ; len(data) >= 3; data = data[3:] {
+		ops = append(ops, Op{
+			Kind:     OpKind(data[0] % 4),
+			Key:      int(data[1] % 16),
+			Duration: time.Duration(data[2]%8) * time.Second,
+		})
+	}
+	return ops
+}
+
+// Run applies operations to a cache and returns the first violated
+// invariant, if any:
+//   - the cache never holds more than capacity items, unless capacity is 0,
+//   - every item removed from the cache triggers the about to delete
+//     callbacks exactly once, replaced items none, and
+//   - once the time advanced, no item that expired by then is still cached.
+//
+// Run installs its own about to delete callback. Parameter tick advances
+// the cache's time, e.g. CacheTable.Tick of a table created with
+// WithManualTicks, starting at start. If it's nil, ticks are skipped and
+// expirations aren't checked.
+func Run(c Cache, capacity int, tick func(now time.Time), start time.Time, ops []Op) error {
+	removals := make(map[*cache2go.CacheItem]int)
+	c.SetAboutToDeleteItemCallback(func(item *cache2go.CacheItem) {
+		removals[item]++
+	})
+
+	now := start
+	if tick != nil {
+		tick(now)
+	}
+	// Items that should be cached, and items that got replaced.
+	cached := make(map[interface{}]*cache2go.CacheItem)
+	replaced := make(map[*cache2go.CacheItem]bool)
+	for i, op := range ops {
+		switch op.Kind {
+		case OpAdd:
+			item := c.Add(op.Key, op.Duration, i)
+			if old, ok := cached[op.Key]; ok && old != item && removals[old] == 0 {
+				replaced[old] = true
+			}
+		case OpValue:
+			c.Value(op.Key)
+		case OpDelete:
+			c.Delete(op.Key)
+		case OpTick:
+			if tick == nil {
+				continue
+			}
+			now = now.Add(op.Duration)
+			tick(now)
+		}
+
+		current := make(map[interface{}]*cache2go.CacheItem)
+		c.Foreach(func(key interface{}, item *cache2go.CacheItem) {
+			current[key] = item
+		})
+
+		if capacity > 0 && len(current) > capacity {
+			return fmt.Errorf("Cache holds %d items, exceeding its capacity of %d, after op %d (%v)", len(current), capacity, i, op)
+		}
+		for key, item := range current {
+			if n := removals[item]; n > 0 {
+				return fmt.Errorf("Removal callbacks fired %d times for cached key %v after op %d (%v)", n, key, i, op)
+			}
+			if op.Kind == OpTick && tick != nil && item.LifeSpan() > 0 && now.Sub(item.AccessedOn()) >= item.LifeSpan() {
+				return fmt.Errorf("Key %v is still cached after expiring, after op %d (%v)", key, i, op)
+			}
+		}
+		for key, item := range cached {
+			if current[key] == item {
+				continue
+			}
+			want := 1
+			if replaced[item] {
+				want = 0
+			}
+			if n := removals[item]; n != want {
+				return fmt.Errorf("Removal callbacks fired %d times instead of %d for key %v after op %d (%v)", n, want, key, i, op)
+			}
+		}
+		cached = current
+	}
+
+	return nil
+}
+
+// CheckPolicy applies operations to a cache2go.BoundedCache using the given
+// policy, and returns the first violated invariant, if any, see Run.
+func CheckPolicy(policy cache2go.EvictionPolicy, capacity int, ops []Op) error {
+	cache := cache2go.NewBoundedCache("invariants", capacity, policy)
+	return Run(cache, capacity, nil, time.Time{}, ops)
+}
@@ -0,0 +1,78 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package invariants
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/muesli/cache2go"
+)
+
+var policies = map[string]func(capacity int) cache2go.EvictionPolicy{
+	"sampling": func(int) cache2go.EvictionPolicy { return cache2go.NewSamplingPolicy(5, cache2go.SampleLRU) },
+	"random":   func(int) cache2go.EvictionPolicy { return cache2go.NewRandomPolicy() },
+	"clock":    func(int) cache2go.EvictionPolicy { return cache2go.NewClockPolicy() },
+	"fifo":     func(int) cache2go.EvictionPolicy { return cache2go.NewFIFOPolicy() },
+	"lfuda":    func(int) cache2go.EvictionPolicy { return cache2go.NewLFUDAPolicy() },
+	"slru": func(capacity int) cache2go.EvictionPolicy {
+		return cache2go.NewSLRUPolicy(capacity, 0.8)
+	},
+	"twoqueue": func(capacity int) cache2go.EvictionPolicy {
+		return cache2go.NewTwoQueuePolicy(capacity, 0.25, 0.5)
+	},
+	"lirs": func(capacity int) cache2go.EvictionPolicy {
+		return cache2go.NewLIRSPolicy(capacity, 0.1)
+	},
+}
+
+// randomOps returns reproducible random operations.
+func randomOps(seed int64, n int) []Op {
+	data := make([]byte, 3*n)
+	rand.New(rand.NewSource(seed)).Read(data)
+	return Decode(data)
+}
+
+func TestPolicies(t *testing.T) {
+	for name, newPolicy := range policies {
+		for seed := int64(0); seed < 20; seed++ {
+			if err := CheckPolicy(newPolicy(4), 4, randomOps(seed, 200)); err != nil {
+				t.Error("Policy", name, "with seed", seed, ":", err)
+			}
+		}
+	}
+}
+
+func TestTable(t *testing.T) {
+	for seed := int64(0); seed < 20; seed++ {
+		table, _ := cache2go.CacheWithOptions("invariants", cache2go.WithCapacity(4), cache2go.WithManualTicks())
+		table.Flush()
+		start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		if err := Run(table, 4, table.Tick, start, randomOps(seed, 200)); err != nil {
+			t.Error("Table with seed", seed, ":", err)
+		}
+	}
+}
+
+func TestRunDetectsViolations(t *testing.T) {
+	// A policy that never evicts lets the cache exceed its capacity.
+	ops := []Op{{Kind: OpAdd, Key: 1}, {Kind: OpAdd, Key: 2}, {Kind: OpAdd, Key: 3}}
+	if err := CheckPolicy(nopPolicy{}, 2, ops); err == nil {
+		t.Error("Exceeding the capacity went unnoticed")
+	}
+}
+
+// nopPolicy never evicts anything.
+type nopPolicy struct{}
+
+func (nopPolicy) Add(item *cache2go.CacheItem)    {}
+func (nopPolicy) Access(item *cache2go.CacheItem) {}
+func (nopPolicy) Remove(item *cache2go.CacheItem) {}
+func (nopPolicy) Evict() (interface{}, bool)      { return nil, false }
+func (nopPolicy) Reset()                          {}
@@ -0,0 +1,129 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// CompositeKey is a cache key made up of several parts, see Key. Keys made of
+// equal parts are equal, so they can be used as map keys and compared with ==.
+type CompositeKey string
+
+// Key creates a composite key from the given parts, e.g. Key("user", 42,
+// "lang", "en"). Unlike keys built with fmt.Sprintf, the parts can't run into
+// each other: Key("a:b", "c") and Key("a", "b:c") differ. Integers are
+// compared by value, regardless of their size, so Key(1) equals Key(int64(1)).
+// Parts of types other than strings, byte slices, booleans and numbers are
+// formatted with fmt.
+func Key(parts ...interface{}) CompositeKey {
+	var b []byte
+	for _, part := range parts {
+		switch p := part.(type) {
+		case string:
+			b = appendKeyPart(b, 's', []byte(p))
+		case []byte:
+			b = appendKeyPart(b, 'b', p)
+		case bool:
+			b = appendKeyPart(b, 't', strconv.AppendBool(nil, p))
+		case int:
+			b = appendKeyPart(b, 'i', varintBytes(int64(p)))
+		case int8:
+			b = appendKeyPart(b, 'i', varintBytes(int64(p)))
+		case int16:
+			b = appendKeyPart(b, 'i', varintBytes(int64(p)))
+		case int32:
+			b = appendKeyPart(b, 'i', varintBytes(int64(p)))
+		case int64:
+			b = appendKeyPart(b, 'i', varintBytes(p))
+		case uint:
+			b = appendKeyPart(b, 'u', uvarintBytes(uint64(p)))
+		case uint8:
+			b = appendKeyPart(b, 'u', uvarintBytes(uint64(p)))
+		case uint16:
+			b = appendKeyPart(b, 'u', uvarintBytes(uint64(p)))
+		case uint32:
+			b = appendKeyPart(b, 'u', uvarintBytes(uint64(p)))
+		case uint64:
+			b = appendKeyPart(b, 'u', uvarintBytes(p))
+		case float32:
+			b = appendKeyPart(b, 'f', uvarintBytes(math.Float64bits(float64(p))))
+		case float64:
+			b = appendKeyPart(b, 'f', uvarintBytes(math.Float64bits(p)))
+		default:
+			b = appendKeyPart(b, 'v', []byte(fmt.Sprintf("%T:%v", p, p)))
+		}
+	}
+
+	return CompositeKey(b)
+}
+
+// appendKeyPart appends a type tag, the length of the encoded value and the
+// value itself.
+func appendKeyPart(b []byte, tag byte, value []byte) []byte {
+	b = append(b, tag)
+	b = append(b, uvarintBytes(uint64(len(value)))...)
+	return append(b, value...)
+}
+
+func varintBytes(i int64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return buf[:binary.PutVarint(buf, i)]
+}
+
+func uvarintBytes(u uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return buf[:binary.PutUvarint(buf, u)]
+}
+
+// Hash returns a 64-bit hash of the key. It only depends on the key's parts,
+// so unlike Go's map hashing it's stable across processes and can be used
+// to persist or shard keys.
+func (k CompositeKey) Hash() uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(k))
+	return h.Sum64()
+}
+
+// String returns a human-readable representation of the key, with its parts
+// separated by colons.
+func (k CompositeKey) String() string {
+	var parts []string
+	b := []byte(k)
+	for len(b) > 0 {
+		tag := b[0]
+		l, n := binary.Uvarint(b[1:])
+		if n <= 0 || uint64(len(b)-1-n) < l {
+			// Not a key created by Key.
+			return string(k)
+		}
+		value := b[1+n : 1+n+int(l)]
+		b = b[1+n+int(l):]
+
+		switch tag {
+		case 'i':
+			i, _ := binary.Varint(value)
+			parts = append(parts, strconv.FormatInt(i, 10))
+		case 'u':
+			u, _ := binary.Uvarint(value)
+			parts = append(parts, strconv.FormatUint(u, 10))
+		case 'f':
+			u, _ := binary.Uvarint(value)
+			parts = append(parts, strconv.FormatFloat(math.Float64frombits(u), 'g', -1, 64))
+		default:
+			parts = append(parts, string(value))
+		}
+	}
+
+	return strings.Join(parts, ":")
+}
@@ -0,0 +1,38 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+)
+
+func TestKey(t *testing.T) {
+	if Key("user", 42, "lang", "en") != Key("user", int64(42), "lang", "en") {
+		t.Error("Keys of equal parts should be equal")
+	}
+	if Key("a:b", "c") == Key("a", "b:c") {
+		t.Error("Parts should not run into each other")
+	}
+	if Key("1") == Key(1) || Key(1) == Key(uint(1)) || Key(1) == Key(1.0) {
+		t.Error("Parts of different kinds should differ")
+	}
+	if Key("user", 42).Hash() != Key("user", 42).Hash() || Key("user", 42).Hash() == Key("user", 43).Hash() {
+		t.Error("Hashes should only depend on the parts")
+	}
+
+	s := Key("user", 42, -1, uint8(7), 1.5, true, []byte("raw")).String()
+	if s != "user:42:-1:7:1.5:true:raw" {
+		t.Error("Unexpected string representation:", s)
+	}
+
+	table := Cache("testKey")
+	table.Add(Key("user", 42), 0, v)
+	if _, err := table.Value(Key("user", 42)); err != nil {
+		t.Error("Error retrieving item by composite key", err)
+	}
+}
@@ -0,0 +1,92 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"context"
+	"sync"
+)
+
+// keyLock is a mutex scoped to a key, and how many callers hold or wait for
+// it. Guarded by the keyLocks' mutex. It's held while its channel is full,
+// so waiting for it can be abandoned.
+type keyLock struct {
+	ch   chan struct{}
+	refs int
+}
+
+// keyLocks hands out mutexes scoped to keys, dropping them once nobody holds
+// or waits for them.
+type keyLocks struct {
+	sync.Mutex
+	locks map[interface{}]*keyLock
+}
+
+// lock locks the mutex scoped to key, blocking until it's available or ctx
+// is done, and returns the function unlocking it again.
+func (k *keyLocks) lock(ctx context.Context, key interface{}) (unlock func(), err error) {
+	k.Lock()
+	l, ok := k.locks[key]
+	if !ok {
+		l = &keyLock{ch: make(chan struct{}, 1)}
+		if k.locks == nil {
+			k.locks = make(map[interface{}]*keyLock)
+		}
+		k.locks[key] = l
+	}
+	l.refs++
+	k.Unlock()
+
+	release := func() {
+		k.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.Unlock()
+	}
+
+	select {
+	case l.ch <- struct{}{}:
+	case <-ctx.Done():
+		release()
+		return nil, ctx.Err()
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-l.ch
+			release()
+		})
+	}, nil
+}
+
+// KeyLock locks a mutex scoped to the given key, blocking until it's
+// available, and returns the function unlocking it again. It lets callers
+// serialize work per key, e.g. writing an item back to its backend, without
+// maintaining their own map of mutexes. Keys don't need to be cached, and
+// locking a key doesn't block any of the table's operations. Mutexes get
+// dropped once nobody holds or waits for them. Calling the returned function
+// more than once has no further effect.
+func (table *CacheTable) KeyLock(key interface{}) (unlock func()) {
+	unlock, _ = table.keyLocks.lock(context.Background(), key)
+	return unlock
+}
+
+// KeyLockContext locks a mutex scoped to the given key just like KeyLock,
+// but stops waiting for it once ctx is done, returning ctx's error.
+func (table *CacheTable) KeyLockContext(ctx context.Context, key interface{}) (unlock func(), err error) {
+	return table.keyLocks.lock(ctx, key)
+}
+
+// KeyLock locks a mutex scoped to the given key, just like CacheTable.KeyLock.
+func (cache *LFUCache) KeyLock(key interface{}) (unlock func()) {
+	unlock, _ = cache.keyLocks.lock(context.Background(), key)
+	return unlock
+}
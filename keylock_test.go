@@ -0,0 +1,101 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKeyLock(t *testing.T) {
+	table, _ := CacheWithOptions("testKeyLock")
+
+	var wg sync.WaitGroup
+	counters := map[string]int{}
+	var countersMutex sync.Mutex
+	for i := 0; i < 50; i++ {
+		for _, key := range []string{"a", "b"} {
+			wg.Add(1)
+			go func(key string) {
+				defer wg.Done()
+				unlock := table.KeyLock(key)
+				defer unlock()
+
+				countersMutex.Lock()
+				n := counters[key]
+				countersMutex.Unlock()
+				// Unless serialized, concurrent increments get lost.
+				countersMutex.Lock()
+				counters[key] = n + 1
+				countersMutex.Unlock()
+			}(key)
+		}
+	}
+	wg.Wait()
+
+	if counters["a"] != 50 || counters["b"] != 50 {
+		t.Error("Expected work on each key to be serialized, got", counters)
+	}
+
+	table.keyLocks.Lock()
+	n := len(table.keyLocks.locks)
+	table.keyLocks.Unlock()
+	if n != 0 {
+		t.Error("Expected unused key mutexes to be dropped, got", n)
+	}
+}
+
+func TestKeyLockIndependent(t *testing.T) {
+	table, _ := CacheWithOptions("testKeyLockIndependent")
+
+	unlockA := table.KeyLock("a")
+	unlockB := table.KeyLock("b")
+	unlockB()
+	unlockB()
+
+	locked := make(chan struct{})
+	go func() {
+		unlock := table.KeyLock("a")
+		close(locked)
+		unlock()
+	}()
+	select {
+	case <-locked:
+		t.Error("Expected a locked key to block")
+	default:
+	}
+	unlockA()
+	<-locked
+}
+
+func TestKeyLockContext(t *testing.T) {
+	table, _ := CacheWithOptions("testKeyLockContext")
+
+	unlock := table.KeyLock("a")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := table.KeyLockContext(ctx, "a"); err != context.DeadlineExceeded {
+		t.Error("Expected waiting for a locked key to time out, got", err)
+	}
+	unlock()
+
+	unlock, err := table.KeyLockContext(context.Background(), "a")
+	if err != nil {
+		t.Fatal("Error locking an unlocked key", err)
+	}
+	unlock()
+
+	table.keyLocks.Lock()
+	n := len(table.keyLocks.locks)
+	table.keyLocks.Unlock()
+	if n != 0 {
+		t.Error("Expected abandoned key mutexes to be dropped, got", n)
+	}
+}
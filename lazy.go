@@ -0,0 +1,96 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// lazyValue is a value computed on first use, see AddLazy.
+type lazyValue struct {
+	once sync.Once
+	f    func() (interface{}, error)
+	// Whether the value got computed already. Accessed atomically.
+	done  uint32
+	value interface{}
+	err   error
+}
+
+// get computes the value unless that happened already, and returns it.
+// Concurrent callers wait for the same computation.
+func (l *lazyValue) get() (interface{}, error) {
+	l.once.Do(func() {
+		l.value, l.err = l.f()
+		l.f = nil
+		atomic.StoreUint32(&l.done, 1)
+	})
+	return l.value, l.err
+}
+
+// computed returns whether the value got computed already.
+func (l *lazyValue) computed() bool {
+	return atomic.LoadUint32(&l.done) == 1
+}
+
+// AddLazy adds a key to the cache, just like Add, but its value only gets
+// computed by calling f once it's first read, e.g. when producers enumerate
+// many keys while consumers read few of them. Reads block while the value
+// is being computed, sharing a single call of f. If f fails, Value returns
+// its error and deletes the item, so a data-loader gets a chance to load it
+// on the next lookup, while the item's Data returns nil. Items that weren't
+// read yet are left out of exports and snapshots.
+func (table *CacheTable) AddLazy(key interface{}, lifeSpan time.Duration, f func() (interface{}, error)) *CacheItem {
+	if h := table.loadOpHook(); h != nil {
+		defer h.observe(OpAdd, key, time.Now())
+	}
+	item := table.newItem(key, lifeSpan, nil)
+	item.lazy = &lazyValue{f: f}
+
+	table.Lock()
+	table.addInternal(item)
+
+	table.audit(context.Background(), auditOpAdd, key)
+	return item
+}
+
+// Computed returns whether the item's value is available without computing
+// it, which is only the case once items added via AddLazy got read.
+func (item *CacheItem) Computed() bool {
+	return item.lazy == nil || item.lazy.computed()
+}
+
+// peek returns the item's value, or nil if it wasn't computed yet.
+func (item *CacheItem) peek() interface{} {
+	if item.lazy != nil {
+		if !item.lazy.computed() {
+			return nil
+		}
+		return item.lazy.value
+	}
+	if item.arena != nil {
+		return item.arena.read(&item.span)
+	}
+	return item.data
+}
+
+// materialize computes a lazy item's value, deleting the item if that fails.
+func (table *CacheTable) materialize(item *CacheItem) error {
+	if _, err := item.lazy.get(); err != nil {
+		table.log("Computing value of key", item.key, "in table", table.name, "failed:", err)
+		table.Lock()
+		if table.items[item.key] == item {
+			table.deleteInternal(item.key, RemovalDeleted)
+		}
+		table.Unlock()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAddLazy(t *testing.T) {
+	table, _ := CacheWithOptions("testAddLazy")
+	table.Flush()
+
+	var calls int32
+	for i := 0; i < 10; i++ {
+		i := i
+		table.AddLazy(i, 0, func() (interface{}, error) {
+			atomic.AddInt32(&calls, 1)
+			return i * 2, nil
+		})
+	}
+	if n := atomic.LoadInt32(&calls); n != 0 {
+		t.Error("Expected lazy values not to be computed before being read, got", n, "calls")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if p, err := table.Value(3); err != nil || p.Data() != 6 {
+				t.Error("Error reading lazy value", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Error("Expected lazy value to be computed once, got", n, "calls")
+	}
+
+	p, _ := table.Value(3)
+	if !p.Computed() {
+		t.Error("Read lazy values should be computed")
+	}
+	var buf bytes.Buffer
+	if err := table.Export(&buf); err != nil {
+		t.Error("Error exporting table", err)
+	}
+	if n := bytes.Count(buf.Bytes(), []byte("\n")) - 1; n != 1 {
+		t.Error("Expected exports to leave out lazy values not read yet, got", n, "items")
+	}
+}
+
+func TestAddLazyError(t *testing.T) {
+	table, _ := CacheWithOptions("testAddLazyError")
+	table.Flush()
+
+	errFailed := errors.New("failed")
+	table.AddLazy(k, 0, func() (interface{}, error) {
+		return nil, errFailed
+	})
+
+	if _, err := table.Value(k); err != errFailed {
+		t.Error("Expected the lazy value's error, got", err)
+	}
+	if table.Exists(k) {
+		t.Error("Expected failed lazy values to be deleted")
+	}
+
+	table.SetDataLoader(func(key interface{}, args ...interface{}) *CacheItem {
+		return NewCacheItem(key, 0, v)
+	})
+	table.AddLazy(k, 0, func() (interface{}, error) {
+		return nil, errFailed
+	})
+	if _, err := table.Value(k); err != errFailed {
+		t.Error("Expected the lazy value's error, got", err)
+	}
+	if p, err := table.Value(k); err != nil || p.Data() != v {
+		t.Error("Expected failed lazy values to be loaded next", err)
+	}
+}
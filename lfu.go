@@ -8,6 +8,7 @@
 package cache2go
 
 import (
+	"container/heap"
 	"container/list"
 	"log"
 	"sync"
@@ -20,6 +21,47 @@ type LFUNode struct {
 	items     *list.List // List of cache items with this frequency
 }
 
+// lfuEntry is the LFU's own record for a cached key. The frequency is kept
+// here rather than derived from the item's access count, so external calls
+// to KeepAlive can't desynchronize the frequency lists.
+type lfuEntry struct {
+	item      *CacheItem
+	element   *list.Element
+	frequency int
+
+	// When the item is expected to expire, and its position in the expiry
+	// heap (-1 if the item never expires).
+	expiresOn time.Time
+	heapIndex int
+}
+
+// lfuExpiryHeap orders entries with a lifespan by their expected expiry.
+type lfuExpiryHeap []*lfuEntry
+
+func (h lfuExpiryHeap) Len() int           { return len(h) }
+func (h lfuExpiryHeap) Less(i, j int) bool { return h[i].expiresOn.Before(h[j].expiresOn) }
+func (h lfuExpiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex = i
+	h[j].heapIndex = j
+}
+
+func (h *lfuExpiryHeap) Push(x interface{}) {
+	entry := x.(*lfuEntry)
+	entry.heapIndex = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *lfuExpiryHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = nil
+	entry.heapIndex = -1
+	*h = old[:n-1]
+	return entry
+}
+
 // LFUCache implements Least Frequently Used cache algorithm
 type LFUCache struct {
 	sync.RWMutex
@@ -31,14 +73,19 @@ type LFUCache struct {
 	// Current size
 	size int
 
-	// Map from key to cache item
-	items map[interface{}]*CacheItem
-	// Map from key to list element (for O(1) access)
-	keyToListElement map[interface{}]*list.Element
+	// Map from key to the LFU's bookkeeping record for that key
+	items map[interface{}]*lfuEntry
 	// Map from frequency to LFU node
 	frequencies map[int]*LFUNode
 	// Minimum frequency in the cache
 	minFrequency int
+	// Entries with a lifespan, ordered by expected expiry
+	expiries lfuExpiryHeap
+
+	// Usage statistics
+	stats statsCounter
+	// Mutexes scoped to keys, see KeyLock.
+	keyLocks keyLocks
 
 	// The logger used for this cache
 	logger *log.Logger
@@ -54,26 +101,24 @@ type LFUCache struct {
 // NewLFUCache creates a new LFU cache with the specified capacity
 func NewLFUCache(name string, capacity int) *LFUCache {
 	return &LFUCache{
-		name:             name,
-		capacity:         capacity,
-		size:             0,
-		items:            make(map[interface{}]*CacheItem),
-		keyToListElement: make(map[interface{}]*list.Element),
-		frequencies:      make(map[int]*LFUNode),
-		minFrequency:     0,
+		name:         name,
+		capacity:     capacity,
+		size:         0,
+		items:        make(map[interface{}]*lfuEntry),
+		frequencies:  make(map[int]*LFUNode),
+		minFrequency: 0,
 	}
 }
 
 // updateFrequency updates the frequency of an item
-func (cache *LFUCache) updateFrequency(key interface{}) {
-	item := cache.items[key]
-	element := cache.keyToListElement[key]
-	oldFreq := int(item.AccessCount())
+func (cache *LFUCache) updateFrequency(entry *lfuEntry) {
+	key := entry.element.Value
+	oldFreq := entry.frequency
 	newFreq := oldFreq + 1
 
 	// Remove from old frequency list
 	if oldNode, exists := cache.frequencies[oldFreq]; exists {
-		oldNode.items.Remove(element)
+		oldNode.items.Remove(entry.element)
 		if oldNode.items.Len() == 0 && oldFreq == cache.minFrequency {
 			cache.minFrequency++
 		}
@@ -86,16 +131,121 @@ func (cache *LFUCache) updateFrequency(key interface{}) {
 			items:     list.New(),
 		}
 	}
-	newElement := cache.frequencies[newFreq].items.PushFront(key)
-	cache.keyToListElement[key] = newElement
+	entry.element = cache.frequencies[newFreq].items.PushFront(key)
+	entry.frequency = newFreq
+}
+
+// insertEntry stores a new item with a frequency of 1
+func (cache *LFUCache) insertEntry(key interface{}, item *CacheItem) {
+	if _, exists := cache.frequencies[1]; !exists {
+		cache.frequencies[1] = &LFUNode{
+			frequency: 1,
+			items:     list.New(),
+		}
+	}
+	entry := &lfuEntry{
+		item:      item,
+		element:   cache.frequencies[1].items.PushFront(key),
+		frequency: 1,
+		heapIndex: -1,
+	}
+	cache.items[key] = entry
+	cache.size++
+	cache.minFrequency = 1
+	cache.updateExpiry(entry)
 }
 
-// evictLFU removes the least frequently used item
+// updateExpiry recomputes when an entry is expected to expire and keeps the
+// expiry heap in sync with it
+func (cache *LFUCache) updateExpiry(entry *lfuEntry) {
+	entry.item.RLock()
+	lifeSpan := entry.item.lifeSpan
+	accessedOn := entry.item.AccessedOn()
+	entry.item.RUnlock()
+
+	if lifeSpan == 0 {
+		if entry.heapIndex >= 0 {
+			heap.Remove(&cache.expiries, entry.heapIndex)
+		}
+		return
+	}
+
+	entry.expiresOn = accessedOn.Add(lifeSpan)
+	if entry.heapIndex >= 0 {
+		heap.Fix(&cache.expiries, entry.heapIndex)
+	} else {
+		heap.Push(&cache.expiries, entry)
+	}
+}
+
+// removeEntry unlinks an entry from the frequency lists and the expiry heap
+func (cache *LFUCache) removeEntry(key interface{}, entry *lfuEntry) {
+	if node, exists := cache.frequencies[entry.frequency]; exists {
+		node.items.Remove(entry.element)
+		if node.items.Len() == 0 && entry.frequency == cache.minFrequency {
+			cache.minFrequency++
+		}
+	}
+	if entry.heapIndex >= 0 {
+		heap.Remove(&cache.expiries, entry.heapIndex)
+	}
+
+	delete(cache.items, key)
+	cache.size--
+}
+
+// evictExpired removes the item closest to its end-of-lifespan if it has
+// already expired. Returns whether an item was removed.
+func (cache *LFUCache) evictExpired() bool {
+	now := time.Now()
+	for len(cache.expiries) > 0 {
+		entry := cache.expiries[0]
+		if entry.expiresOn.After(now) {
+			return false
+		}
+
+		// The item may have been kept alive without us noticing, so check
+		// its actual expiry before evicting it.
+		cache.updateExpiry(entry)
+		if entry.heapIndex < 0 || entry.expiresOn.After(now) {
+			continue
+		}
+
+		key := entry.element.Value
+		cache.removeEntry(key, entry)
+
+		if cache.aboutToDeleteItem != nil {
+			for _, callback := range cache.aboutToDeleteItem {
+				callback(entry.item)
+			}
+		}
+
+		entry.item.RLock()
+		for _, callback := range entry.item.aboutToExpire {
+			callback(key)
+		}
+		entry.item.RUnlock()
+
+		cache.stats.expire()
+		cache.log("Evicted expired item with key", key, "from LFU cache", cache.name)
+		return true
+	}
+
+	return false
+}
+
+// evictLFU removes an expired item if there is one, and the least
+// frequently used item otherwise
 func (cache *LFUCache) evictLFU() {
 	if cache.size == 0 {
 		return
 	}
 
+	// Prefer items that have already outlived their lifespan
+	if cache.evictExpired() {
+		return
+	}
+
 	// Find the LFU item
 	minNode := cache.frequencies[cache.minFrequency]
 	if minNode == nil || minNode.items.Len() == 0 {
@@ -105,13 +255,14 @@ func (cache *LFUCache) evictLFU() {
 	// Get the least recently used item among items with minimum frequency
 	element := minNode.items.Back()
 	key := element.Value
-	
-	// Remove from frequency list
-	minNode.items.Remove(element)
+	frequency := cache.minFrequency
 	
 	// Get the item before deletion for callbacks
-	item := cache.items[key]
+	item := cache.items[key].item
 	
+	// Remove from frequency list and cache
+	cache.removeEntry(key, cache.items[key])
+
 	// Trigger callbacks before deleting
 	if cache.aboutToDeleteItem != nil {
 		for _, callback := range cache.aboutToDeleteItem {
@@ -119,12 +270,8 @@ func (cache *LFUCache) evictLFU() {
 		}
 	}
 
-	// Remove from cache
-	delete(cache.items, key)
-	delete(cache.keyToListElement, key)
-	cache.size--
-
-	cache.log("Evicted LFU item with key", key, "frequency", cache.minFrequency)
+	cache.stats.evict()
+	cache.log("Evicted LFU item with key", key, "frequency", frequency)
 }
 
 // Add adds a key/value pair to the LFU cache
@@ -133,16 +280,18 @@ func (cache *LFUCache) Add(key interface{}, lifeSpan time.Duration, data interfa
 	defer cache.Unlock()
 
 	// Check if item already exists
-	if existingItem, exists := cache.items[key]; exists {
+	if entry, exists := cache.items[key]; exists {
 		// Update existing item
+		existingItem := entry.item
 		existingItem.Lock()
 		existingItem.data = data
 		existingItem.lifeSpan = lifeSpan
-		existingItem.accessedOn = time.Now()
-		existingItem.accessCount++
 		existingItem.Unlock()
+		existingItem.KeepAlive()
 		
-		cache.updateFrequency(key)
+		cache.updateFrequency(entry)
+		cache.updateExpiry(entry)
+		cache.stats.add()
 		return existingItem
 	}
 
@@ -153,19 +302,8 @@ func (cache *LFUCache) Add(key interface{}, lifeSpan time.Duration, data interfa
 
 	// Create new item
 	item := NewCacheItem(key, lifeSpan, data)
-	cache.items[key] = item
-	cache.size++
-
-	// Add to frequency 1 list
-	if _, exists := cache.frequencies[1]; !exists {
-		cache.frequencies[1] = &LFUNode{
-			frequency: 1,
-			items:     list.New(),
-		}
-	}
-	element := cache.frequencies[1].items.PushFront(key)
-	cache.keyToListElement[key] = element
-	cache.minFrequency = 1
+	cache.insertEntry(key, item)
+	cache.stats.add()
 
 	cache.log("Adding item with key", key, "to LFU cache", cache.name)
 
@@ -184,12 +322,15 @@ func (cache *LFUCache) Value(key interface{}, args ...interface{}) (*CacheItem,
 	cache.Lock()
 	defer cache.Unlock()
 
-	if item, exists := cache.items[key]; exists {
+	if entry, exists := cache.items[key]; exists {
 		// Update access info
-		item.KeepAlive()
-		cache.updateFrequency(key)
-		return item, nil
+		entry.item.KeepAlive()
+		cache.updateFrequency(entry)
+		cache.updateExpiry(entry)
+		cache.stats.hit()
+		return entry.item, nil
 	}
+	cache.stats.miss()
 
 	// Try data loader if available
 	if cache.loadData != nil {
@@ -197,23 +338,18 @@ func (cache *LFUCache) Value(key interface{}, args ...interface{}) (*CacheItem,
 		item := cache.loadData(key, args...)
 		cache.Lock()
 		if item != nil {
+			// Another caller may have added the key while we were unlocked
+			if entry, exists := cache.items[key]; exists {
+				cache.updateFrequency(entry)
+				return entry.item, nil
+			}
+
 			// Add the loaded item to cache
 			if cache.size >= cache.capacity {
 				cache.evictLFU()
 			}
-			cache.items[key] = item
-			cache.size++
-
-			// Add to frequency 1 list
-			if _, exists := cache.frequencies[1]; !exists {
-				cache.frequencies[1] = &LFUNode{
-					frequency: 1,
-					items:     list.New(),
-				}
-			}
-			element := cache.frequencies[1].items.PushFront(key)
-			cache.keyToListElement[key] = element
-			cache.minFrequency = 1
+			cache.insertEntry(key, item)
+			cache.stats.add()
 
 			return item, nil
 		}
@@ -228,20 +364,14 @@ func (cache *LFUCache) Delete(key interface{}) (*CacheItem, error) {
 	cache.Lock()
 	defer cache.Unlock()
 
-	item, exists := cache.items[key]
+	entry, exists := cache.items[key]
 	if !exists {
 		return nil, ErrKeyNotFound
 	}
+	item := entry.item
 
-	// Remove from frequency list
-	element := cache.keyToListElement[key]
-	freq := int(item.AccessCount())
-	if node, exists := cache.frequencies[freq]; exists {
-		node.items.Remove(element)
-		if node.items.Len() == 0 && freq == cache.minFrequency {
-			cache.minFrequency++
-		}
-	}
+	// Remove from frequency list and cache
+	cache.removeEntry(key, entry)
 
 	// Trigger callbacks
 	if cache.aboutToDeleteItem != nil {
@@ -250,11 +380,7 @@ func (cache *LFUCache) Delete(key interface{}) (*CacheItem, error) {
 		}
 	}
 
-	// Remove from cache
-	delete(cache.items, key)
-	delete(cache.keyToListElement, key)
-	cache.size--
-
+	cache.stats.delete()
 	cache.log("Deleted item with key", key, "from LFU cache", cache.name)
 	return item, nil
 }
@@ -267,6 +393,17 @@ func (cache *LFUCache) Exists(key interface{}) bool {
 	return exists
 }
 
+// Frequency returns the LFU frequency recorded for the given key
+func (cache *LFUCache) Frequency(key interface{}) (int, error) {
+	cache.RLock()
+	defer cache.RUnlock()
+	entry, exists := cache.items[key]
+	if !exists {
+		return 0, ErrKeyNotFound
+	}
+	return entry.frequency, nil
+}
+
 // Count returns the number of items in the LFU cache
 func (cache *LFUCache) Count() int {
 	cache.RLock()
@@ -274,6 +411,11 @@ func (cache *LFUCache) Count() int {
 	return cache.size
 }
 
+// Stats returns the usage statistics of the LFU cache
+func (cache *LFUCache) Stats() CacheStats {
+	return cache.stats.snapshot()
+}
+
 // Capacity returns the maximum capacity of the LFU cache
 func (cache *LFUCache) Capacity() int {
 	return cache.capacity
@@ -288,15 +430,15 @@ func (cache *LFUCache) Flush() {
 
 	// Trigger callbacks for all items
 	if cache.aboutToDeleteItem != nil {
-		for _, item := range cache.items {
+		for _, entry := range cache.items {
 			for _, callback := range cache.aboutToDeleteItem {
-				callback(item)
+				callback(entry.item)
 			}
 		}
 	}
 
-	cache.items = make(map[interface{}]*CacheItem)
-	cache.keyToListElement = make(map[interface{}]*list.Element)
+	cache.items = make(map[interface{}]*lfuEntry)
+	cache.expiries = nil
 	cache.frequencies = make(map[int]*LFUNode)
 	cache.size = 0
 	cache.minFrequency = 0
@@ -385,8 +527,8 @@ func (cache *LFUCache) MostAccessed(count int64) []*CacheItem {
 		if node, exists := cache.frequencies[freq]; exists {
 			for element := node.items.Front(); element != nil && collected < count; element = element.Next() {
 				key := element.Value
-				if item, exists := cache.items[key]; exists {
-					result = append(result, item)
+				if entry, exists := cache.items[key]; exists {
+					result = append(result, entry.item)
 					collected++
 				}
 			}
@@ -402,6 +544,6 @@ func (cache *LFUCache) Foreach(trans func(key interface{}, item *CacheItem)) {
 	defer cache.RUnlock()
 
 	for k, v := range cache.items {
-		trans(k, v)
+		trans(k, v.item)
 	}
 }
\ No newline at end of file
@@ -223,4 +223,101 @@ func TestLFUDataLoader(t *testing.T) {
 	if err != ErrKeyNotFoundOrLoadable {
 		t.Error("Should return ErrKeyNotFoundOrLoadable for non-loadable keys")
 	}
-}
\ No newline at end of file
+}
+
+func TestLFUFrequencyIndependentOfKeepAlive(t *testing.T) {
+	cache := NewLFUCache("testLFUFrequencyKeepAlive", 2)
+
+	item1 := cache.Add("key1", 0, "value1")
+	cache.Add("key2", 0, "value2")
+
+	// External access-count changes must not affect LFU bookkeeping
+	for i := 0; i < 5; i++ {
+		item1.KeepAlive()
+	}
+	cache.Value("key2")
+	cache.Value("key2")
+
+	if freq, err := cache.Frequency("key1"); err != nil || freq != 1 {
+		t.Error("key1 frequency should be 1, got", freq, err)
+	}
+	if freq, err := cache.Frequency("key2"); err != nil || freq != 3 {
+		t.Error("key2 frequency should be 3, got", freq, err)
+	}
+
+	// key1 has the lowest LFU frequency despite its higher access count
+	cache.Add("key3", 0, "value3")
+	if cache.Exists("key1") {
+		t.Error("key1 should be evicted (lower LFU frequency)")
+	}
+	if !cache.Exists("key2") {
+		t.Error("key2 should still exist (higher LFU frequency)")
+	}
+
+	// Every key must live in exactly one frequency bucket
+	members := 0
+	for _, node := range cache.frequencies {
+		members += node.items.Len()
+	}
+	if members != cache.Count() {
+		t.Error("Frequency lists out of sync with cache size:", members, cache.Count())
+	}
+}
+
+func TestLFUEvictExpiredFirst(t *testing.T) {
+	cache := NewLFUCache("testLFUEvictExpired", 2)
+
+	cache.Add("key1", 0, "value1")
+	item2 := cache.Add("key2", 50*time.Millisecond, "value2")
+
+	expired := false
+	item2.SetAboutToExpireCallback(func(key interface{}) {
+		expired = true
+	})
+
+	// key2 is used far more often, but will have expired by the time we evict
+	for i := 0; i < 5; i++ {
+		cache.Value("key2")
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	cache.Add("key3", 0, "value3")
+	if cache.Exists("key2") {
+		t.Error("key2 should be evicted first (expired)")
+	}
+	if !cache.Exists("key1") {
+		t.Error("key1 should still exist (not expired)")
+	}
+	if !expired {
+		t.Error("AboutToExpire callback not triggered for expired item")
+	}
+
+	// Without expired items we fall back to the least frequently used one
+	cache.Value("key3")
+	cache.Add("key4", 0, "value4")
+	if cache.Exists("key1") {
+		t.Error("key1 should be evicted (lower frequency)")
+	}
+}
+
+func TestLFUEvictExpiredKeepAlive(t *testing.T) {
+	cache := NewLFUCache("testLFUEvictExpiredKeepAlive", 2)
+
+	item1 := cache.Add("key1", 100*time.Millisecond, "value1")
+	cache.Value("key1")
+	cache.Value("key1")
+	cache.Add("key2", 0, "value2")
+
+	// Keep key1 alive behind the cache's back
+	time.Sleep(60 * time.Millisecond)
+	item1.KeepAlive()
+	time.Sleep(60 * time.Millisecond)
+
+	cache.Add("key3", 0, "value3")
+	if !cache.Exists("key1") {
+		t.Error("key1 should not be treated as expired after KeepAlive")
+	}
+	if cache.Exists("key2") {
+		t.Error("key2 should be evicted (lower frequency)")
+	}
+}
@@ -0,0 +1,240 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"container/list"
+)
+
+// LIRS block states.
+const (
+	lirsLIR = iota
+	lirsResidentHIR
+	lirsNonResidentHIR
+)
+
+// lirsEntry tracks a key's state and its positions in the LIRS stack, the
+// resident-HIR queue and the list of non-resident keys.
+type lirsEntry struct {
+	key    interface{}
+	status int
+
+	stackElement    *list.Element
+	queueElement    *list.Element
+	nonResidentElem *list.Element
+}
+
+// LIRSPolicy implements the Low Inter-reference Recency Set eviction policy.
+// Items with a low inter-reference recency (LIR) make up most of the cache
+// and are never evicted directly; a small budget of resident high
+// inter-reference recency (HIR) items absorbs one-off accesses. This copes
+// much better than LRU with weak-locality workloads such as loops and scans.
+type LIRSPolicy struct {
+	// Maximum number of LIR items, and of non-resident HIR keys remembered.
+	lirCapacity         int
+	nonResidentCapacity int
+	lirCount            int
+
+	// The LIRS stack, most recent at the front. Its bottom is always LIR.
+	stack *list.List
+	// Resident HIR items, next victim at the front.
+	queue *list.List
+	// Non-resident HIR keys, oldest at the front.
+	nonResident *list.List
+
+	entries map[interface{}]*lirsEntry
+}
+
+// NewLIRSPolicy creates a LIRS policy for a cache with the given capacity.
+// Parameter hirRatio determines which share of the capacity is reserved for
+// resident HIR items; the original paper suggests 0.01. At least one slot is
+// always reserved.
+func NewLIRSPolicy(capacity int, hirRatio float64) *LIRSPolicy {
+	hirCapacity := int(float64(capacity) * hirRatio)
+	if hirCapacity < 1 {
+		hirCapacity = 1
+	}
+	lirCapacity := capacity - hirCapacity
+	if lirCapacity < 1 {
+		lirCapacity = 1
+	}
+
+	return &LIRSPolicy{
+		lirCapacity:         lirCapacity,
+		nonResidentCapacity: capacity,
+		stack:               list.New(),
+		queue:               list.New(),
+		nonResident:         list.New(),
+		entries:             make(map[interface{}]*lirsEntry),
+	}
+}
+
+// Add handles a miss: during warm-up new items become LIR; afterwards they
+// become LIR only if their key is still on the stack (i.e. their
+// inter-reference recency is low), and resident HIR otherwise.
+func (p *LIRSPolicy) Add(item *CacheItem) {
+	entry, exists := p.entries[item.key]
+	if exists && entry.status != lirsNonResidentHIR {
+		p.Access(item)
+		return
+	}
+
+	if !exists {
+		entry = &lirsEntry{key: item.key}
+		p.entries[item.key] = entry
+		if p.lirCount < p.lirCapacity {
+			entry.status = lirsLIR
+			entry.stackElement = p.stack.PushFront(entry)
+			p.lirCount++
+			return
+		}
+
+		entry.status = lirsResidentHIR
+		entry.stackElement = p.stack.PushFront(entry)
+		entry.queueElement = p.queue.PushBack(entry)
+		return
+	}
+
+	// A non-resident HIR key is still on the stack: promote it.
+	p.nonResident.Remove(entry.nonResidentElem)
+	entry.nonResidentElem = nil
+	p.stack.MoveToFront(entry.stackElement)
+	entry.status = lirsLIR
+	p.lirCount++
+	p.demoteBottom()
+}
+
+// Access handles a hit, promoting resident HIR items that are still on the
+// stack to LIR.
+func (p *LIRSPolicy) Access(item *CacheItem) {
+	entry, exists := p.entries[item.key]
+	if !exists || entry.status == lirsNonResidentHIR {
+		return
+	}
+
+	if entry.status == lirsLIR {
+		p.stack.MoveToFront(entry.stackElement)
+		p.prune()
+		return
+	}
+
+	if entry.stackElement != nil {
+		p.stack.MoveToFront(entry.stackElement)
+		p.queue.Remove(entry.queueElement)
+		entry.queueElement = nil
+		entry.status = lirsLIR
+		p.lirCount++
+		p.demoteBottom()
+		return
+	}
+
+	entry.stackElement = p.stack.PushFront(entry)
+	p.queue.MoveToBack(entry.queueElement)
+}
+
+// Remove forgets an item entirely.
+func (p *LIRSPolicy) Remove(item *CacheItem) {
+	entry, exists := p.entries[item.key]
+	if !exists || entry.status == lirsNonResidentHIR {
+		return
+	}
+	p.forget(entry)
+	p.prune()
+}
+
+// Evict drops the oldest resident HIR item. Its key stays on the stack as a
+// non-resident HIR entry, so a quick re-reference can promote it to LIR.
+func (p *LIRSPolicy) Evict() (interface{}, bool) {
+	front := p.queue.Front()
+	if front == nil {
+		// No resident HIR items, fall back to the bottom LIR item.
+		back := p.stack.Back()
+		if back == nil {
+			return nil, false
+		}
+		entry := back.Value.(*lirsEntry)
+		p.forget(entry)
+		p.prune()
+		return entry.key, true
+	}
+
+	entry := p.queue.Remove(front).(*lirsEntry)
+	entry.queueElement = nil
+	if entry.stackElement == nil {
+		delete(p.entries, entry.key)
+		return entry.key, true
+	}
+
+	entry.status = lirsNonResidentHIR
+	entry.nonResidentElem = p.nonResident.PushBack(entry)
+	for p.nonResident.Len() > p.nonResidentCapacity {
+		p.forget(p.nonResident.Front().Value.(*lirsEntry))
+	}
+	return entry.key, true
+}
+
+// Reset drops all bookkeeping.
+func (p *LIRSPolicy) Reset() {
+	p.stack.Init()
+	p.queue.Init()
+	p.nonResident.Init()
+	p.lirCount = 0
+	p.entries = make(map[interface{}]*lirsEntry)
+}
+
+// demoteBottom turns the LIR item at the bottom of the stack into a resident
+// HIR item once there are too many LIR items.
+func (p *LIRSPolicy) demoteBottom() {
+	if p.lirCount <= p.lirCapacity {
+		return
+	}
+
+	back := p.stack.Back()
+	entry := back.Value.(*lirsEntry)
+	p.stack.Remove(back)
+	entry.stackElement = nil
+	entry.status = lirsResidentHIR
+	entry.queueElement = p.queue.PushBack(entry)
+	p.lirCount--
+	p.prune()
+}
+
+// prune removes HIR entries from the bottom of the stack, so that it always
+// ends with a LIR item.
+func (p *LIRSPolicy) prune() {
+	for back := p.stack.Back(); back != nil; back = p.stack.Back() {
+		entry := back.Value.(*lirsEntry)
+		if entry.status == lirsLIR {
+			return
+		}
+
+		p.stack.Remove(back)
+		entry.stackElement = nil
+		if entry.status == lirsNonResidentHIR {
+			p.nonResident.Remove(entry.nonResidentElem)
+			delete(p.entries, entry.key)
+		}
+	}
+}
+
+// forget removes an entry from all structures.
+func (p *LIRSPolicy) forget(entry *lirsEntry) {
+	if entry.stackElement != nil {
+		p.stack.Remove(entry.stackElement)
+	}
+	if entry.queueElement != nil {
+		p.queue.Remove(entry.queueElement)
+	}
+	if entry.nonResidentElem != nil {
+		p.nonResident.Remove(entry.nonResidentElem)
+	}
+	if entry.status == lirsLIR {
+		p.lirCount--
+	}
+	delete(p.entries, entry.key)
+}
@@ -0,0 +1,83 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+)
+
+func TestLIRSLoop(t *testing.T) {
+	cache := NewBoundedCache("testLIRS", 10, NewLIRSPolicy(10, 0.1))
+
+	misses := 0
+	cache.SetDataLoader(func(key interface{}, args ...interface{}) *CacheItem {
+		misses++
+		return NewCacheItem(key, 0, key)
+	})
+
+	// Looping over slightly more keys than fit defeats LRU entirely, while
+	// LIRS keeps most of the loop resident
+	for round := 0; round < 20; round++ {
+		for i := 0; i < 11; i++ {
+			if _, err := cache.Value(i); err != nil {
+				t.Error("Error retrieving value", err)
+			}
+		}
+	}
+
+	if cache.Count() != 10 {
+		t.Error("Cache should be filled to capacity, got", cache.Count())
+	}
+	if misses > 11+20*2 {
+		t.Error("Too many misses for a looping access pattern:", misses)
+	}
+}
+
+func TestLIRSPromotion(t *testing.T) {
+	p := NewLIRSPolicy(3, 0.34)
+
+	a := NewCacheItem("a", 0, nil)
+	b := NewCacheItem("b", 0, nil)
+	c := NewCacheItem("c", 0, nil)
+	d := NewCacheItem("d", 0, nil)
+
+	// a and b fill the LIR set, c becomes resident HIR
+	p.Add(a)
+	p.Add(b)
+	p.Add(c)
+	if p.entries["c"].status != lirsResidentHIR {
+		t.Error("c should be a resident HIR item")
+	}
+
+	// Evicting c keeps it on the stack as non-resident HIR
+	if key, _ := p.Evict(); key != "c" {
+		t.Error("Expected c to be evicted, got", key)
+	}
+	if p.entries["c"].status != lirsNonResidentHIR {
+		t.Error("c should be remembered as non-resident HIR")
+	}
+
+	// Re-adding c promotes it to LIR and demotes the stack bottom (a)
+	p.Add(c)
+	if p.entries["c"].status != lirsLIR || p.entries["a"].status != lirsResidentHIR {
+		t.Error("c should be promoted and a demoted")
+	}
+
+	// d is new and becomes resident HIR; a is the next victim
+	p.Add(d)
+	if key, _ := p.Evict(); key != "a" {
+		t.Error("Expected a to be evicted, got", key)
+	}
+
+	p.Remove(b)
+	p.Remove(c)
+	p.Remove(d)
+	if p.lirCount != 0 || p.queue.Len() != 0 {
+		t.Error("Bookkeeping should be empty after removing all items")
+	}
+}
@@ -0,0 +1,158 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// cacheList is the data of a list item.
+type cacheList struct {
+	sync.Mutex
+
+	// Oldest value first, so pushing appends.
+	values []interface{}
+	// Maximum number of values, 0 means unlimited.
+	maxLen int
+}
+
+// MarshalJSON exports a list as its values, newest first, e.g. for Export.
+func (l *cacheList) MarshalJSON() ([]byte, error) {
+	l.Lock()
+	defer l.Unlock()
+	return json.Marshal(l.rangeInternal(0, len(l.values)-1))
+}
+
+// rangeInternal returns the values from index start to end, newest first.
+// Careful: do not run this method unless the list-mutex is locked!
+func (l *cacheList) rangeInternal(start, end int) []interface{} {
+	values := make([]interface{}, 0, end-start+1)
+	for i := start; i <= end; i++ {
+		values = append(values, l.values[len(l.values)-1-i])
+	}
+	return values
+}
+
+// AddList adds an empty list to the cache, replacing any existing item with
+// the same key. Parameter lifeSpan works just like for Add; pushing to and
+// popping from the list counts as an access. Once the list holds maxLen
+// values, pushing a new one drops the oldest; 0 means unlimited.
+//
+// Lists get modified in place, so pushing to and popping from them isn't
+// recorded in mutation logs and automatic snapshots.
+func (table *CacheTable) AddList(key interface{}, lifeSpan time.Duration, maxLen int) *CacheItem {
+	return table.Add(key, lifeSpan, &cacheList{maxLen: maxLen})
+}
+
+// PushToList adds a value to the front of a list and returns the list's new
+// length. If the key doesn't exist, a list with the table's default lifespan
+// and no maximum length gets added, see AddList.
+func (table *CacheTable) PushToList(key interface{}, v interface{}) (int, error) {
+	item := table.valueOrAdd(key, func() interface{} {
+		return &cacheList{}
+	})
+
+	l, ok := item.Data().(*cacheList)
+	if !ok {
+		return 0, ErrNotAList
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	l.values = append(l.values, v)
+	if l.maxLen > 0 && len(l.values) > l.maxLen {
+		l.values[0] = nil
+		l.values = l.values[1:]
+	}
+	return len(l.values), nil
+}
+
+// valueOrAdd returns an item and marks it to be kept alive, or adds it with
+// the table's default lifespan and the data returned by newData if the key
+// doesn't exist.
+func (table *CacheTable) valueOrAdd(key interface{}, newData func() interface{}) *CacheItem {
+	table.touch()
+	table.Lock()
+	item, ok := table.items[key]
+	if ok {
+		table.Unlock()
+		table.keepAlive(item)
+		return item
+	}
+
+	item = table.newItem(key, 0, newData())
+	table.addInternal(item)
+	return item
+}
+
+// PopFromList removes and returns the value at the front of a list, i.e. the
+// one pushed last. Empty lists stay in the cache until they expire or get
+// deleted.
+func (table *CacheTable) PopFromList(key interface{}) (interface{}, error) {
+	table.RLock()
+	item, ok := table.items[key]
+	table.RUnlock()
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	table.keepAlive(item)
+
+	l, ok := item.Data().(*cacheList)
+	if !ok {
+		return nil, ErrNotAList
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	if len(l.values) == 0 {
+		return nil, ErrEmptyList
+	}
+	last := len(l.values) - 1
+	v := l.values[last]
+	l.values[last] = nil
+	l.values = l.values[:last]
+	return v, nil
+}
+
+// ListRange returns the values of a list from index start to end, both
+// inclusive, newest first. Negative indexes count from the end of the list,
+// so ListRange(key, 0, -1) returns all values. The lookup works just like
+// Value, including the data-loader.
+func (table *CacheTable) ListRange(key interface{}, start, end int) ([]interface{}, error) {
+	item, err := table.Value(key)
+	if err != nil {
+		return nil, err
+	}
+
+	l, ok := item.Data().(*cacheList)
+	if !ok {
+		return nil, ErrNotAList
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	n := len(l.values)
+	if start < 0 {
+		start += n
+	}
+	if end < 0 {
+		end += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end >= n {
+		end = n - 1
+	}
+	if start > end {
+		return []interface{}{}, nil
+	}
+	return l.rangeInternal(start, end), nil
+}
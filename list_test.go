@@ -0,0 +1,92 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestList(t *testing.T) {
+	table := Cache("testList")
+	table.AddList("recent", 0, 3)
+
+	for i, expected := range []int{1, 2, 3, 3, 3} {
+		if n, err := table.PushToList("recent", i); err != nil || n != expected {
+			t.Error("Unexpected length after push", n, err)
+		}
+	}
+
+	values, err := table.ListRange("recent", 0, -1)
+	if err != nil || fmt.Sprint(values) != "[4 3 2]" {
+		t.Error("Expected the newest values, got", values, err)
+	}
+	values, _ = table.ListRange("recent", 1, 10)
+	if fmt.Sprint(values) != "[3 2]" {
+		t.Error("Expected the range to get clamped, got", values)
+	}
+	values, _ = table.ListRange("recent", -1, -1)
+	if fmt.Sprint(values) != "[2]" {
+		t.Error("Expected the oldest value, got", values)
+	}
+	values, _ = table.ListRange("recent", 2, 1)
+	if len(values) != 0 {
+		t.Error("Expected an empty range, got", values)
+	}
+
+	for _, expected := range []int{4, 3, 2} {
+		if v, err := table.PopFromList("recent"); err != nil || v != expected {
+			t.Error("Expected to pop", expected, "got", v, err)
+		}
+	}
+	if _, err := table.PopFromList("recent"); err != ErrEmptyList {
+		t.Error("Expected ErrEmptyList, got", err)
+	}
+
+	if _, err := table.PopFromList("missing"); err != ErrKeyNotFound {
+		t.Error("Expected ErrKeyNotFound, got", err)
+	}
+	table.Add(k, 0, v)
+	if _, err := table.PushToList(k, 1); err != ErrNotAList {
+		t.Error("Expected ErrNotAList, got", err)
+	}
+}
+
+func TestListCreatedOnPush(t *testing.T) {
+	table, _ := CacheWithOptions("testListCreatedOnPush", WithDefaultLifeSpan(100*time.Millisecond))
+	for i := 0; i < 100; i++ {
+		table.PushToList("unbounded", i)
+	}
+
+	values, _ := table.ListRange("unbounded", 0, -1)
+	if len(values) != 100 || values[0] != 99 {
+		t.Error("Expected all pushed values, got", len(values))
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if table.Exists("unbounded") {
+		t.Error("List should expire with the default lifespan")
+	}
+}
+
+func TestListExport(t *testing.T) {
+	table := Cache("testListExport")
+	table.PushToList("l", "a")
+	table.PushToList("l", "b")
+
+	var buf bytes.Buffer
+	if err := table.Export(&buf); err != nil {
+		t.Fatal("Error exporting table", err)
+	}
+	if !strings.Contains(buf.String(), `["b","a"]`) {
+		t.Error("Lists should get exported as their values, got", buf.String())
+	}
+}
@@ -0,0 +1,30 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"time"
+)
+
+// LoadCost returns how long the data-loader took to load the item, 0 if the
+// item wasn't loaded, e.g. because it got added explicitly.
+func (item *CacheItem) LoadCost() time.Duration {
+	// immutable
+	return item.loadCost
+}
+
+// WithCostAwareEviction makes a table with a capacity or budget evict items
+// based on how much loading time they save: an item's access count gets
+// weighted by its load cost, so expensive-to-recompute items are kept longer
+// than cheap ones that are accessed just as often. Items that weren't loaded
+// via the data-loader have no cost and get evicted first, see SampleCostLFU.
+func WithCostAwareEviction() Option {
+	return func(o *cacheOptions) {
+		o.costAware = true
+	}
+}
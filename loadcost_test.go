@@ -0,0 +1,73 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadCost(t *testing.T) {
+	table := Cache("testLoadCost")
+	table.SetDataLoader(func(key interface{}, args ...interface{}) *CacheItem {
+		time.Sleep(10 * time.Millisecond)
+		return NewCacheItem(key, 0, v)
+	})
+
+	table.Value(k)
+	// The stored copy of the loaded item carries its cost.
+	item, err := table.Value(k)
+	if err != nil {
+		t.Error("Error loading item", err)
+		return
+	}
+	if item.LoadCost() < 10*time.Millisecond {
+		t.Error("Expected a load cost of at least 10ms, got", item.LoadCost())
+	}
+	if item := table.Add("added", 0, v); item.LoadCost() != 0 {
+		t.Error("Expected added items to have no load cost, got", item.LoadCost())
+	}
+}
+
+func TestCostAwareEviction(t *testing.T) {
+	table, _ := CacheWithOptions("testCostAwareEviction", WithCapacity(50), WithCostAwareEviction())
+	table.SetDataLoader(func(key interface{}, args ...interface{}) *CacheItem {
+		time.Sleep(time.Millisecond)
+		return NewCacheItem(key, 0, v)
+	})
+
+	if _, err := table.Value("expensive"); err != nil {
+		t.Error("Error loading item", err)
+	}
+	// Cheap items accessed more often still get evicted first.
+	for i := 0; i < 100; i++ {
+		key := i
+		table.Add(key, 0, v)
+		table.Value(key)
+		table.Value(key)
+	}
+	if !table.Exists("expensive") {
+		t.Error("Expensive item got evicted in favor of cheap ones")
+	}
+}
+
+func TestSampleCostLFU(t *testing.T) {
+	p := NewSamplingPolicy(10, SampleCostLFU)
+	cheap := NewCacheItem("cheap", 0, v)
+	expensive := NewCacheItem("expensive", 0, v)
+	expensive.loadCost = time.Second
+
+	p.Add(expensive)
+	p.Add(cheap)
+	for i := 0; i < 10; i++ {
+		p.Access(cheap)
+	}
+	if key, _ := p.Evict(); key != "cheap" {
+		t.Error("Expected the cheap item to get evicted, got", key)
+	}
+}
@@ -0,0 +1,202 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"context"
+	"time"
+)
+
+// LoadOptions are caller-supplied options forwarded to the loader, e.g. a
+// tenant or a consistency level the backend should be queried with.
+type LoadOptions map[string]interface{}
+
+// LoadRequest describes an item the loader is asked to load, see SetLoader.
+type LoadRequest struct {
+	// The key of the item.
+	Key interface{}
+	// The context of the lookup. Never nil.
+	Context context.Context
+	// The options passed to Load, nil if none.
+	Options LoadOptions
+
+	// Arguments passed to Value, forwarded to loaders configured via
+	// SetDataLoader only.
+	args []interface{}
+}
+
+// loadCall is a load in progress, shared by all callers loading the same key
+// with the same options at the same time.
+type loadCall struct {
+	req  LoadRequest
+	done chan struct{}
+	item *CacheItem
+	// How many callers are still waiting for the load, and how to cancel it
+	// once none is left. Guarded by the table's loadMutex.
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// detachedContext carries the values of its parent, but is never done, so a
+// shared load isn't canceled along with the caller that started it.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (c detachedContext) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (c detachedContext) Done() <-chan struct{}             { return nil }
+func (c detachedContext) Err() error                        { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+// SetLoader configures a loader callback, which will be called when trying to
+// access a non-existing key. It replaces the data-loader configured via
+// SetDataLoader: arguments passed to Value don't reach it, use Load to pass
+// options instead.
+func (table *CacheTable) SetLoader(f func(req *LoadRequest) *CacheItem) {
+	table.Lock()
+	defer table.Unlock()
+	table.loadData = f
+}
+
+// Load returns an item from the cache, just like Value, but passes the given
+// context and options on to the loader. Concurrent calls loading the same key
+// share a single call of the loader, as long as they pass equal options:
+// both sets hold the same names with equal values, values that can't be
+// compared never being equal. Callers passing different options don't join,
+// but load the key themselves, the item loaded last ending up cached.
+//
+// The shared loader's context carries the values of the first caller's
+// context, but only gets canceled once all callers waiting for it gave up.
+// Callers return early with their context's error if it gets done, while the
+// load continues for the others. Once the last caller gave up, loaders
+// respecting their context stop, and nothing gets cached.
+func (table *CacheTable) Load(ctx context.Context, key interface{}, opts LoadOptions) (*CacheItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return table.value(LoadRequest{Key: key, Context: ctx, Options: opts}, true)
+}
+
+// dataLoader adapts a data-loader configured via SetDataLoader to a loader.
+func dataLoader(f func(interface{}, ...interface{}) *CacheItem) func(*LoadRequest) *CacheItem {
+	if f == nil {
+		return nil
+	}
+	return func(req *LoadRequest) *CacheItem {
+		return f(req.Key, req.args...)
+	}
+}
+
+// loadShared loads an item, sharing the loader's call with concurrent callers
+// loading the same key with equal options.
+func (table *CacheTable) loadShared(req LoadRequest, loadData func(*LoadRequest) *CacheItem) (*CacheItem, error) {
+	table.loadMutex.Lock()
+	call, ok := table.loadCalls[req.Key]
+	if ok && (!equalLoadOptions(call.req.Options, req.Options) || !equalLoadArgs(call.req.args, req.args)) {
+		// Options differ, so the shared result may not fit this caller.
+		table.loadMutex.Unlock()
+		return table.load(req, loadData)
+	}
+	if ok {
+		call.waiters++
+		table.loadMutex.Unlock()
+		return table.awaitLoad(req.Context, call)
+	}
+
+	ctx, cancel := context.WithCancel(detachedContext{parent: req.Context})
+	call = &loadCall{req: req, done: make(chan struct{}), waiters: 1, cancel: cancel}
+	call.req.Context = ctx
+	if table.loadCalls == nil {
+		table.loadCalls = make(map[interface{}]*loadCall)
+	}
+	table.loadCalls[req.Key] = call
+	table.loadMutex.Unlock()
+
+	go func() {
+		call.item, _ = table.load(call.req, loadData)
+
+		table.loadMutex.Lock()
+		delete(table.loadCalls, call.req.Key)
+		table.loadMutex.Unlock()
+		cancel()
+		close(call.done)
+	}()
+
+	return table.awaitLoad(req.Context, call)
+}
+
+// awaitLoad waits for a shared load to finish, or for the context to be done.
+func (table *CacheTable) awaitLoad(ctx context.Context, call *loadCall) (*CacheItem, error) {
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		table.loadMutex.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			call.cancel()
+		}
+		table.loadMutex.Unlock()
+		return nil, ctx.Err()
+	}
+
+	if call.item == nil {
+		return nil, ErrKeyNotFoundOrLoadable
+	}
+	return call.item, nil
+}
+
+// load calls the loader and caches its result.
+func (table *CacheTable) load(req LoadRequest, loadData func(*LoadRequest) *CacheItem) (*CacheItem, error) {
+	start := time.Now()
+	item := loadData(&req)
+	if item == nil {
+		return nil, ErrKeyNotFoundOrLoadable
+	}
+
+	table.addLoaded(req.Key, item, time.Since(start))
+	return item, nil
+}
+
+// equalLoadOptions returns whether two sets of load options are equal.
+func equalLoadOptions(a, b LoadOptions) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, v := range a {
+		w, ok := b[name]
+		if !ok || !equalLoadOption(v, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// equalLoadArgs returns whether two callers passed equal arguments to
+// ValueCtx, arguments that can't be compared never being equal.
+func equalLoadArgs(a, b []interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !equalLoadOption(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// equalLoadOption returns whether two option values are equal, values that
+// can't be compared never being equal.
+func equalLoadOption(v, w interface{}) (equal bool) {
+	defer func() {
+		if recover() != nil {
+			equal = false
+		}
+	}()
+	return v == w
+}
@@ -0,0 +1,150 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type loaderTestKey struct{}
+
+func TestLoad(t *testing.T) {
+	table, _ := CacheWithOptions("testLoad")
+	table.Flush()
+
+	var got LoadRequest
+	table.SetLoader(func(req *LoadRequest) *CacheItem {
+		got = *req
+		return NewCacheItem(req.Key, 0, req.Options["tenant"])
+	})
+
+	ctx := context.WithValue(context.Background(), loaderTestKey{}, "traced")
+	p, err := table.Load(ctx, k, LoadOptions{"tenant": "acme"})
+	if err != nil || p.Data() != "acme" {
+		t.Error("Error loading item", err)
+	}
+	if got.Key != k || got.Options["tenant"] != "acme" {
+		t.Error("Loader got an unexpected request", got)
+	}
+	if got.Context.Value(loaderTestKey{}) != "traced" {
+		t.Error("Loader's context should carry the caller's values")
+	}
+	if p, err := table.Value(k); err != nil || p.Data() != "acme" {
+		t.Error("Loaded item should be cached", err)
+	}
+
+	// Value passes neither options nor its arguments to typed loaders.
+	if p, err := table.Value("other", "ignored"); err != nil || p.Data() != nil {
+		t.Error("Value should load items via the loader", err)
+	}
+	if got.Options != nil || got.Context == nil {
+		t.Error("Value should pass no options and a background context", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := table.Load(ctx, "canceled", nil); err != context.Canceled {
+		t.Error("Expected context.Canceled, got", err)
+	}
+}
+
+func TestLoadShared(t *testing.T) {
+	table, _ := CacheWithOptions("testLoadShared")
+	table.Flush()
+
+	var loads int32
+	release := make(chan struct{})
+	table.SetLoader(func(req *LoadRequest) *CacheItem {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return NewCacheItem(req.Key, 0, req.Options["v"])
+	})
+
+	var wg sync.WaitGroup
+	load := func(key string, opts LoadOptions) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := table.Load(context.Background(), key, opts); err != nil {
+				t.Error("Error loading item", err)
+			}
+		}()
+	}
+	for i := 0; i < 5; i++ {
+		load("equal", LoadOptions{"v": 1})
+	}
+	for atomic.LoadInt32(&loads) < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	// Joins the load in progress, unless its options differ.
+	load("equal", LoadOptions{"v": 1})
+	load("equal", LoadOptions{"v": 2})
+	for atomic.LoadInt32(&loads) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&loads); n != 2 {
+		t.Error("Expected callers with equal options to share a load, got", n, "loads")
+	}
+}
+
+func TestLoadCanceled(t *testing.T) {
+	table, _ := CacheWithOptions("testLoadCanceled")
+	table.Flush()
+
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	table.SetLoader(func(req *LoadRequest) *CacheItem {
+		close(started)
+		<-req.Context.Done()
+		close(canceled)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := table.Load(ctx, k, nil)
+		done <- err
+	}()
+	<-started
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Error("Expected context.Canceled, got", err)
+	}
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("Loader's context should be canceled once no caller is waiting")
+	}
+}
+
+func TestEqualLoadOptions(t *testing.T) {
+	if !equalLoadOptions(nil, LoadOptions{}) {
+		t.Error("Empty options should be equal")
+	}
+	if !equalLoadOptions(LoadOptions{"a": 1}, LoadOptions{"a": 1}) {
+		t.Error("Options with equal values should be equal")
+	}
+	if equalLoadOptions(LoadOptions{"a": 1}, LoadOptions{"b": 1}) {
+		t.Error("Options with different names should differ")
+	}
+	if equalLoadOptions(LoadOptions{"a": []int{1}}, LoadOptions{"a": []int{1}}) {
+		t.Error("Options that can't be compared should differ")
+	}
+	if equalLoadOptions(LoadOptions{"a": [1]interface{}{[]int{1}}}, LoadOptions{"a": [1]interface{}{[]int{1}}}) {
+		t.Error("Options holding values that can't be compared should differ")
+	}
+}
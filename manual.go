@@ -0,0 +1,92 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WithManualTicks makes a table start no background goroutines and follow
+// the time passed to Tick instead of the wall clock, so it behaves
+// deterministically in simulations and property-based tests. Expired items
+// only get removed, and stale or expired items only get refreshed or
+// revalidated, when Tick gets called. Until the first call, the table's time
+// is the time it got created at. Features that run in the background by
+// design, e.g. EnableAutoSnapshot or EnableEvents, still do so once enabled.
+func WithManualTicks() Option {
+	return func(o *cacheOptions) {
+		o.manualTicks = true
+	}
+}
+
+// Tick advances the time of a table created with WithManualTicks to now,
+// removes the items that expired by then, and runs the refreshes and
+// revalidations that became due, synchronously and in order. It does nothing
+// for other tables.
+func (table *CacheTable) Tick(now time.Time) {
+	if !table.options.manualTicks {
+		return
+	}
+
+	atomic.StoreInt64(&table.clock.now, now.UnixNano())
+	table.checkExpiry()
+
+	for {
+		table.Lock()
+		pending := table.pending
+		table.pending = nil
+		table.Unlock()
+		if len(pending) == 0 {
+			return
+		}
+		for _, f := range pending {
+			f()
+		}
+	}
+}
+
+// now returns the table's current time.
+func (table *CacheTable) now() time.Time {
+	if table.options.manualTicks {
+		return time.Unix(0, table.clock.nanos())
+	}
+	return time.Now()
+}
+
+// stamp sets an item's creation and access times to the table's current
+// time, if it doesn't follow the wall clock.
+func (table *CacheTable) stamp(item *CacheItem) {
+	if !table.options.manualTicks {
+		return
+	}
+	now := table.now()
+	item.createdOn = now
+	item.setAccessedOn(now)
+}
+
+// spawn runs f in the background, or on the next Tick.
+func (table *CacheTable) spawn(f func()) {
+	if !table.options.manualTicks {
+		go f()
+		return
+	}
+	table.Lock()
+	defer table.Unlock()
+	table.pending = append(table.pending, f)
+}
+
+// spawnInternal runs f in the background, or on the next Tick.
+// Careful: do not run this method unless the table-mutex is locked!
+func (table *CacheTable) spawnInternal(f func()) {
+	if !table.options.manualTicks {
+		go f()
+		return
+	}
+	table.pending = append(table.pending, f)
+}
@@ -0,0 +1,69 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+	"time"
+)
+
+func TestManualTicks(t *testing.T) {
+	table := newCacheTable("testManualTicks", newCacheOptions(WithManualTicks()))
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	table.Tick(start)
+
+	item := table.Add(k, 10*time.Millisecond, v)
+	if !item.CreatedOn().Equal(start) || !item.AccessedOn().Equal(start) {
+		t.Error("Expected item to be timestamped with the table's time, got", item.CreatedOn())
+	}
+
+	// No background expiration, however long we wait.
+	time.Sleep(30 * time.Millisecond)
+	if !table.Exists(k) {
+		t.Error("Item expired without a tick")
+	}
+
+	table.Tick(start.Add(5 * time.Millisecond))
+	if _, err := table.Value(k); err != nil {
+		t.Error("Item expired before its lifespan was exceeded")
+	}
+	if !item.AccessedOn().Equal(start.Add(5 * time.Millisecond)) {
+		t.Error("Access wasn't timestamped with the table's time, got", item.AccessedOn())
+	}
+	table.Tick(start.Add(20 * time.Millisecond))
+	if table.Exists(k) {
+		t.Error("Item didn't expire on tick")
+	}
+}
+
+func TestManualTicksRefresh(t *testing.T) {
+	table := newCacheTable("testManualTicksRefresh", newCacheOptions(WithManualTicks()))
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	table.Tick(start)
+
+	loads := 0
+	table.SetDataLoader(func(key interface{}, args ...interface{}) *CacheItem {
+		loads++
+		return NewCacheItem(key, 0, loads)
+	})
+	table.AddWithSoftLifeSpan(k, time.Minute, 0, 0)
+
+	table.Tick(start.Add(2 * time.Minute))
+	if r, _ := table.Value(k); r.Data() != 0 {
+		t.Error("Expected the stale item to be served")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if loads != 0 {
+		t.Error("Stale item got refreshed without a tick")
+	}
+
+	table.Tick(start.Add(2 * time.Minute))
+	if r, _ := table.Value(k); r.Data() != 1 || loads != 1 {
+		t.Error("Expected the stale item to be refreshed on tick")
+	}
+}
@@ -0,0 +1,94 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"context"
+)
+
+// Map is a facade over a cache table with the methods of sync.Map, see
+// CacheTable.AsMap. It's safe for concurrent use.
+type Map struct {
+	table *CacheTable
+}
+
+// AsMap returns a live view of the table with the methods of sync.Map, easing
+// the migration of code written against sync.Map. Stored values get the
+// table's default lifespan, see WithDefaultLifeSpan, and expire just like
+// items added via Add.
+func (table *CacheTable) AsMap() *Map {
+	return &Map{table: table}
+}
+
+// Load returns the value stored for a key, and whether one was found. The
+// lookup works just like Value, including the data-loader.
+func (m *Map) Load(key interface{}) (value interface{}, ok bool) {
+	item, err := m.table.Value(key)
+	if err != nil {
+		return nil, false
+	}
+	return item.Data(), true
+}
+
+// Store sets the value for a key.
+func (m *Map) Store(key, value interface{}) {
+	m.table.Replace(key, 0, value)
+}
+
+// LoadOrStore returns the existing value for a key, if any. Otherwise it
+// stores and returns the given value. Parameter loaded reports whether the
+// value was loaded.
+func (m *Map) LoadOrStore(key, value interface{}) (actual interface{}, loaded bool) {
+	table := m.table
+
+	table.Lock()
+	if item, ok := table.items[key]; ok {
+		table.Unlock()
+		table.keepAlive(item)
+		table.stats.hit()
+		return item.Data(), true
+	}
+
+	table.addInternal(table.newItem(key, 0, value))
+	table.audit(context.Background(), auditOpAdd, key)
+	return value, false
+}
+
+// LoadAndDelete deletes the value for a key, returning the previous value if
+// any. Parameter loaded reports whether the key was present.
+func (m *Map) LoadAndDelete(key interface{}) (value interface{}, loaded bool) {
+	item, err := m.table.Delete(key)
+	if err != nil {
+		return nil, false
+	}
+	return item.Data(), true
+}
+
+// Delete deletes the value for a key.
+func (m *Map) Delete(key interface{}) {
+	m.table.Delete(key)
+}
+
+// Range calls f for each key and value, in no particular order, until f
+// returns false. Unlike Foreach, it doesn't hold the table's lock while
+// calling f, so f may modify the table; it sees the items present when Range
+// was called.
+func (m *Map) Range(f func(key, value interface{}) bool) {
+	m.table.RLock()
+	items := make([]*CacheItem, 0, len(m.table.items))
+	for _, item := range m.table.items {
+		items = append(items, item)
+	}
+	m.table.RUnlock()
+
+	for _, item := range items {
+		if !f(item.key, item.Data()) {
+			return
+		}
+	}
+}
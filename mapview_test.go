@@ -0,0 +1,58 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMap(t *testing.T) {
+	table, _ := CacheWithOptions("testMap", WithDefaultLifeSpan(100*time.Millisecond))
+	m := table.AsMap()
+
+	m.Store("a", 1)
+	if value, ok := m.Load("a"); !ok || value != 1 {
+		t.Error("Expected to load stored value, got", value)
+	}
+	if _, ok := m.Load("missing"); ok {
+		t.Error("Missing key should not be loaded")
+	}
+
+	if actual, loaded := m.LoadOrStore("a", 2); !loaded || actual != 1 {
+		t.Error("Expected existing value to be loaded, got", actual)
+	}
+	if actual, loaded := m.LoadOrStore("b", 2); loaded || actual != 2 {
+		t.Error("Expected new value to be stored, got", actual)
+	}
+
+	n := 0
+	m.Range(func(key, value interface{}) bool {
+		// Modifying the table while ranging must not deadlock.
+		m.Delete(key)
+		n++
+		return true
+	})
+	if n != 2 || table.Count() != 0 {
+		t.Error("Expected to range over and delete 2 items, got", n, table.Count())
+	}
+
+	m.Store("c", 3)
+	if value, loaded := m.LoadAndDelete("c"); !loaded || value != 3 {
+		t.Error("Expected to load deleted value, got", value)
+	}
+	if _, loaded := m.LoadAndDelete("c"); loaded {
+		t.Error("Deleted key should not be loaded")
+	}
+
+	m.Store("d", 4)
+	time.Sleep(200 * time.Millisecond)
+	if _, ok := m.Load("d"); ok {
+		t.Error("Stored values should expire with the default lifespan")
+	}
+}
@@ -0,0 +1,186 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"time"
+)
+
+// Every mapped arena file starts with a header: the magic, the slab size and
+// the number of slabs. The slabs follow, holding one record per value: the
+// record's state, the lengths of key and value, the item's creation time and
+// lifespan, then key and value.
+const (
+	arenaFileMagic       = "c2garena"
+	arenaFileHeaderSize  = 16
+	arenaHeaderSize      = 25
+	arenaRecordEnd       = 0
+	arenaRecordLive      = 1
+	arenaRecordDead      = 2
+	arenaRecordAnonymous = 3
+)
+
+// MappedArena is a file holding the slabs of a byte arena, see
+// OpenMappedArena.
+type MappedArena struct {
+	file  *os.File
+	data  []byte
+	arena *byteArena
+}
+
+// OpenMappedArena opens the file at path to hold the slabs of a byte arena,
+// creating it with the given number of slabs of slabSize bytes if it doesn't
+// exist yet. The file gets mapped into memory, so its slabs don't count
+// against the Go heap, and tables can be far larger than the available RAM.
+// See WithMappedArena to use it.
+//
+// Existing files must have been created with the same slab size and number
+// of slabs, or ErrArenaMismatch is returned. Their items with string keys get
+// recovered by the table using the arena, so a restarted process doesn't have
+// to warm up its cache again. Mapping files isn't supported on all platforms,
+// ErrMmapUnsupported is returned there.
+func OpenMappedArena(path string, slabSize, slabs int) (*MappedArena, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	size := arenaFileHeaderSize + slabSize*slabs
+	created := fi.Size() == 0
+	if created {
+		err = f.Truncate(int64(size))
+	} else if fi.Size() != int64(size) {
+		err = ErrArenaMismatch
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	data, err := mmapFile(f, size)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	header := data[:arenaFileHeaderSize]
+	if created {
+		copy(header, arenaFileMagic)
+		binary.LittleEndian.PutUint32(header[8:], uint32(slabSize))
+		binary.LittleEndian.PutUint32(header[12:], uint32(slabs))
+	} else if !bytes.Equal(header[:8], []byte(arenaFileMagic)) ||
+		binary.LittleEndian.Uint32(header[8:]) != uint32(slabSize) ||
+		binary.LittleEndian.Uint32(header[12:]) != uint32(slabs) {
+		munmap(data)
+		f.Close()
+		return nil, ErrArenaMismatch
+	}
+
+	m := &MappedArena{file: f, data: data, arena: newByteArena(slabSize)}
+	m.arena.mapping = m
+	for i := 0; i < slabs; i++ {
+		offset := arenaFileHeaderSize + i*slabSize
+		m.arena.slabs = append(m.arena.slabs, data[offset:offset+slabSize:offset+slabSize])
+		m.arena.live = append(m.arena.live, 0)
+	}
+	return m, nil
+}
+
+// WithMappedArena stores byte slice values in the slabs of the given mapped
+// arena, just like WithByteArena, and recovers the items it holds. Values
+// that don't fit into the arena anymore are stored as usual. An arena may
+// only be used by a single table, which closes it when it gets closed, see
+// CacheTable.Close: unlike deleting all items, that keeps them in the file.
+func WithMappedArena(m *MappedArena) Option {
+	return func(o *cacheOptions) {
+		o.mappedArena = m
+	}
+}
+
+// Close unmaps and closes the arena's file. Closing the table using it
+// already does so.
+func (m *MappedArena) Close() error {
+	m.arena.Lock()
+	defer m.arena.Unlock()
+
+	if m.data == nil {
+		return nil
+	}
+	m.arena.detached = true
+	err := munmap(m.data)
+	m.data = nil
+	if cerr := m.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// writeArenaRecord writes the header and key of an item's record, and
+// returns their size. Items without a string key can't be recovered.
+func writeArenaRecord(b []byte, key string, keyed bool, item *CacheItem, length int) int {
+	b[0] = arenaRecordLive
+	if !keyed {
+		b[0] = arenaRecordAnonymous
+	}
+	binary.LittleEndian.PutUint32(b[1:], uint32(len(key)))
+	binary.LittleEndian.PutUint32(b[5:], uint32(length))
+	binary.LittleEndian.PutUint64(b[9:], uint64(item.createdOn.UnixNano()))
+	binary.LittleEndian.PutUint64(b[17:], uint64(item.lifeSpan))
+	copy(b[arenaHeaderSize:], key)
+	return arenaHeaderSize + len(key)
+}
+
+// recover returns items for the records left in the arena's slabs, and
+// rebuilds the bookkeeping of the space they take up. Records that can't be
+// recovered get dropped.
+func (a *byteArena) recover() []*CacheItem {
+	a.Lock()
+	defer a.Unlock()
+
+	var items []*CacheItem
+	for i, slab := range a.slabs {
+		for offset := 0; offset+arenaHeaderSize <= len(slab) && slab[offset] != arenaRecordEnd; {
+			b := slab[offset:]
+			keyLength := int(binary.LittleEndian.Uint32(b[1:]))
+			length := int(binary.LittleEndian.Uint32(b[5:]))
+			size := arenaHeaderSize + keyLength + length
+			if keyLength < 0 || length < 0 || size > len(b) {
+				// Garbage, e.g. written by a crashing process.
+				break
+			}
+
+			switch b[0] {
+			case arenaRecordLive:
+				key := string(b[arenaHeaderSize : arenaHeaderSize+keyLength])
+				lifeSpan := time.Duration(binary.LittleEndian.Uint64(b[17:]))
+				item := NewCacheItem(key, lifeSpan, nil)
+				item.createdOn = time.Unix(0, int64(binary.LittleEndian.Uint64(b[9:])))
+				item.source = SourceRestore
+				item.arena = a
+				item.span = arenaSpan{slab: i, offset: offset + arenaHeaderSize + keyLength, length: length, size: size}
+				items = append(items, item)
+				a.live[i] += size
+				a.values++
+			case arenaRecordAnonymous:
+				b[0] = arenaRecordDead
+			}
+			offset += size
+		}
+		if a.live[i] == 0 {
+			a.free = append(a.free, i)
+		}
+	}
+	return items
+}
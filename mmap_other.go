@@ -0,0 +1,26 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"os"
+)
+
+// mmapFile maps the first size bytes of a file into memory, which isn't
+// supported on this platform.
+func mmapFile(f *os.File, size int) ([]byte, error) {
+	return nil, ErrMmapUnsupported
+}
+
+// munmap unmaps memory mapped by mmapFile.
+func munmap(b []byte) error {
+	return ErrMmapUnsupported
+}
@@ -0,0 +1,78 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMappedArena(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache2go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "table.arena")
+
+	m, err := OpenMappedArena(path, 64, 3)
+	if err == ErrMmapUnsupported {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := newCacheTable("testMappedArena", newCacheOptions(WithMappedArena(m)))
+	table.Add("kept", time.Hour, []byte("value"))
+	table.Add("deleted", 0, []byte("gone"))
+	table.Add(42, 0, []byte("no string key"))
+	table.Add("large", 0, make([]byte, 64))
+	table.Delete("deleted")
+
+	if p, _ := table.Value("kept"); string(p.Data().([]byte)) != "value" {
+		t.Error("Expected mapped values to be readable")
+	}
+	if s, _ := table.ArenaStats(); s.Values != 2 {
+		t.Error("Expected 2 values in the arena, got", s.Values)
+	}
+	if err := table.Close(); err != nil {
+		t.Error("Error closing table", err)
+	}
+
+	if _, err := OpenMappedArena(path, 32, 6); err != ErrArenaMismatch {
+		t.Error("Expected ErrArenaMismatch, got", err)
+	}
+	m, err = OpenMappedArena(path, 64, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table = newCacheTable("testMappedArena", newCacheOptions(WithMappedArena(m)))
+	defer table.Close()
+
+	if table.Count() != 1 {
+		t.Error("Expected only the kept item to be recovered, got", table.Count())
+	}
+	p, err := table.Value("kept")
+	if err != nil || string(p.Data().([]byte)) != "value" || p.LifeSpan() != time.Hour || p.Source() != SourceRestore {
+		t.Error("Expected the kept item to be recovered", err)
+	}
+	if s, _ := table.ArenaStats(); s.Values != 1 {
+		t.Error("Expected 1 value in the arena, got", s.Values)
+	}
+
+	// Full arenas store values as usual.
+	for i := 0; i < 10; i++ {
+		table.Add(i, 0, []byte("0123456789"))
+	}
+	if p, _ := table.Value(9); string(p.Data().([]byte)) != "0123456789" {
+		t.Error("Expected values to be stored once the arena is full")
+	}
+}
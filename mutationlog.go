@@ -0,0 +1,488 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Operations recorded in a mutation log.
+const (
+	logOpSet    = "set"
+	logOpDelete = "del"
+	logOpFlush  = "flush"
+)
+
+// logSegmentPrefix starts the line opening a new segment of an encrypted log,
+// followed by the segment's id, see logCipher.
+const logSegmentPrefix = "#segment "
+
+// MutationLogOptions configures a table's mutation log.
+type MutationLogOptions struct {
+	// Decodes the keys and values when replaying the log, see ImportDecoder.
+	// If nil, they get unmarshaled into generic JSON types.
+	Decoder ImportDecoder
+	// How often the log gets compacted in the background, 0 disables
+	// compaction.
+	CompactInterval time.Duration
+	// Whether to sync the log to disk after every mutation. This survives
+	// power loss, not just crashes, but makes every mutation much slower.
+	Sync bool
+	// Encrypts the log's records with the provided keys, nil disables
+	// encryption. Records are bound to their position in the log, so
+	// reordering, replaying or splicing them fails the replay.
+	Keys KeyProvider
+}
+
+// logRecord is a line of a mutation log.
+type logRecord struct {
+	Op string `json:"op"`
+	exportedItem
+}
+
+// mutationLog appends a table's mutations to a file.
+type mutationLog struct {
+	sync.Mutex
+
+	path string
+	opts MutationLogOptions
+	file *os.File
+	// Size of the file, i.e. where the next record gets written.
+	offset int64
+	// Encrypts the records of the current segment, nil if unencrypted.
+	cipher *logCipher
+	// Records written since the last compaction.
+	records int
+	// Whether the log is being compacted, and the records written
+	// meanwhile.
+	compacting bool
+	pending    []logRecord
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// EnableMutationLog makes the table durable: it first replays the log at the
+// given path, restoring the table's items after a crash or restart, then
+// rewrites it to hold all of the table's items, including the ones added
+// before enabling the log, and from then on appends every addition, removal
+// and flush to it. The log only grows, so it's periodically rewritten in the
+// background to just hold the table's current items, if configured. Note
+// that accesses aren't logged, so replayed items with a lifespan may expire
+// earlier than they would have.
+func (table *CacheTable) EnableMutationLog(path string, opts MutationLogOptions) error {
+	if opts.Decoder == nil {
+		opts.Decoder = decodeJSON
+	}
+	if err := table.DisableMutationLog(); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	offset, err := table.replayLog(file, opts)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	// Never continue a segment, as its nonces may have been used by records
+	// lost in a crash.
+	c, n, err := startSegment(file, opts.Keys)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	l := &mutationLog{
+		path:   path,
+		opts:   opts,
+		file:   file,
+		offset: offset + n,
+		cipher: c,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	table.Lock()
+	table.mutationLog = l
+	table.Unlock()
+
+	if opts.CompactInterval > 0 {
+		go table.compactLog(l)
+	} else {
+		close(l.done)
+	}
+	if err := table.compact(l); err != nil {
+		table.DisableMutationLog()
+		return err
+	}
+	table.log("Enabled mutation log", path, "for table", table.name)
+
+	return nil
+}
+
+// DisableMutationLog stops logging the table's mutations.
+func (table *CacheTable) DisableMutationLog() error {
+	table.Lock()
+	l := table.mutationLog
+	table.mutationLog = nil
+	table.Unlock()
+
+	if l == nil {
+		return nil
+	}
+	return l.close()
+}
+
+// replayLog applies the records in the log to the table. An incomplete last
+// record, e.g. due to a crash while writing it, gets truncated, leaving the
+// file positioned for appending new records. Returns the file's size.
+func (table *CacheTable) replayLog(file *os.File, opts MutationLogOptions) (int64, error) {
+	r := bufio.NewReader(file)
+	var offset int64
+	var c *logCipher
+	now := time.Now()
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// Drop the partially written record, if any.
+			if err := file.Truncate(offset); err != nil {
+				return 0, err
+			}
+			_, err := file.Seek(offset, io.SeekStart)
+			return offset, err
+		} else if err != nil {
+			return 0, err
+		}
+		start := offset
+		offset += int64(len(line))
+
+		if opts.Keys != nil {
+			if bytes.HasPrefix(line, []byte(logSegmentPrefix)) {
+				segment, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(line[len(logSegmentPrefix):])))
+				if err != nil {
+					return 0, ErrInvalidCiphertext
+				}
+				c = newLogCipher(opts.Keys, segment)
+				continue
+			}
+			if c == nil {
+				// Records must belong to a segment.
+				return 0, ErrInvalidCiphertext
+			}
+		}
+
+		rec, err := decodeRecord(line, c, start)
+		if err != nil {
+			return 0, err
+		}
+		if err := table.applyRecord(rec, opts.Decoder, now); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// startSegment writes the line opening a new segment of an encrypted log,
+// returning the segment's cipher, nil if unencrypted, and the line's length.
+func startSegment(w io.Writer, keys KeyProvider) (*logCipher, int64, error) {
+	if keys == nil {
+		return nil, 0, nil
+	}
+	c, err := newLogSegment(keys)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	line := logSegmentPrefix + base64.StdEncoding.EncodeToString(c.segment) + "\n"
+	if _, err := io.WriteString(w, line); err != nil {
+		return nil, 0, err
+	}
+	return c, int64(len(line)), nil
+}
+
+// applyRecord applies a logged mutation to the table.
+func (table *CacheTable) applyRecord(rec logRecord, decode ImportDecoder, now time.Time) error {
+	switch rec.Op {
+	case logOpSet:
+		if rec.dropped(table.restorePolicy, now) {
+			// Still apply it, so it replaces any previous item.
+			key, _, err := decode(rec.Key, rec.Value)
+			if err != nil {
+				return err
+			}
+			table.Delete(key)
+			return nil
+		}
+		item, err := rec.restore(decode, table.restorePolicy, now)
+		if err != nil {
+			return err
+		}
+		table.Lock()
+		table.addInternal(item)
+	case logOpDelete:
+		key, _, err := decode(rec.Key, rec.Value)
+		if err != nil {
+			return err
+		}
+		table.Delete(key)
+	case logOpFlush:
+		table.Flush()
+	}
+
+	return nil
+}
+
+// logMutation appends a record to the table's mutation log, if any.
+// Careful: do not run this method unless the table-mutex is locked!
+func (table *CacheTable) logMutation(op string, item *CacheItem, key interface{}) {
+	l := table.mutationLog
+	if l == nil {
+		return
+	}
+
+	rec := logRecord{Op: op}
+	var err error
+	switch {
+	case item != nil:
+		rec.exportedItem, err = exportItem(item, nil)
+	case key != nil:
+		rec.Key, err = json.Marshal(key)
+		rec.Value = json.RawMessage("null")
+	}
+	if err == nil {
+		err = l.write(rec)
+	}
+	if err != nil {
+		table.log("Error writing mutation log of table", table.name, ":", err)
+	}
+}
+
+// encodeRecord returns a record's line in the log, written at the given
+// offset, encrypted if a cipher is provided.
+func encodeRecord(rec logRecord, c *logCipher, offset int64) ([]byte, error) {
+	b, err := json.Marshal(rec)
+	if err != nil || c == nil {
+		return append(b, '\n'), err
+	}
+
+	b, err = c.seal(b, offset)
+	if err != nil {
+		return nil, err
+	}
+	line := make([]byte, base64.StdEncoding.EncodedLen(len(b)), base64.StdEncoding.EncodedLen(len(b))+1)
+	base64.StdEncoding.Encode(line, b)
+	return append(line, '\n'), nil
+}
+
+// decodeRecord parses a line written by encodeRecord.
+func decodeRecord(line []byte, c *logCipher, offset int64) (logRecord, error) {
+	var rec logRecord
+	if c != nil {
+		b := make([]byte, base64.StdEncoding.DecodedLen(len(line)))
+		n, err := base64.StdEncoding.Decode(b, bytes.TrimSpace(line))
+		if err != nil {
+			return rec, ErrInvalidCiphertext
+		}
+		if line, err = c.open(b[:n], offset); err != nil {
+			return rec, err
+		}
+	}
+
+	err := json.Unmarshal(line, &rec)
+	return rec, err
+}
+
+// write appends a record to the log.
+func (l *mutationLog) write(rec logRecord) error {
+	l.Lock()
+	defer l.Unlock()
+	b, err := encodeRecord(rec, l.cipher, l.offset)
+	if err != nil {
+		return err
+	}
+	n, err := l.file.Write(b)
+	l.offset += int64(n)
+	if err != nil {
+		return err
+	}
+	l.records++
+	if l.compacting {
+		l.pending = append(l.pending, rec)
+	}
+	if l.opts.Sync {
+		return l.file.Sync()
+	}
+	return nil
+}
+
+// close stops the compaction and closes the log file.
+func (l *mutationLog) close() error {
+	select {
+	case <-l.stop:
+	default:
+		close(l.stop)
+	}
+	<-l.done
+
+	l.Lock()
+	defer l.Unlock()
+	return l.file.Close()
+}
+
+// compactLog periodically compacts the log until it gets closed.
+func (table *CacheTable) compactLog(l *mutationLog) {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.opts.CompactInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.Lock()
+			changed := l.records > 0
+			l.Unlock()
+			if !changed {
+				continue
+			}
+			if err := table.compact(l); err != nil {
+				table.log("Error compacting mutation log of table", table.name, ":", err)
+			}
+		}
+	}
+}
+
+// compact rewrites the log to just hold the table's current items. They get
+// written and synced to a new file without blocking writers, which keep
+// appending to the old file meanwhile. Their records also get buffered, to be
+// appended to the new file before it replaces the old one.
+func (table *CacheTable) compact(l *mutationLog) error {
+	// Lock the log before releasing the table, so mutations applied after
+	// taking the snapshot get buffered.
+	table.RLock()
+	l.Lock()
+	if l.compacting {
+		l.Unlock()
+		table.RUnlock()
+		return nil
+	}
+	items := make([]*CacheItem, 0, len(table.items))
+	for _, item := range table.items {
+		items = append(items, item)
+	}
+	records := l.records
+	l.records = 0
+	l.compacting = true
+	l.Unlock()
+	table.RUnlock()
+
+	tmp := l.path + ".tmp"
+	file, c, offset, err := writeCompacted(tmp, items, l.opts.Keys)
+
+	// Append what got written meanwhile, and swap the files.
+	l.Lock()
+	defer l.Unlock()
+	pending := l.pending
+	l.compacting = false
+	l.pending = nil
+	if err == nil {
+		select {
+		case <-l.stop:
+			// Closed meanwhile, so leave the log alone.
+			file.Close()
+			os.Remove(tmp)
+			return nil
+		default:
+		}
+		offset, err = writeRecords(file, c, offset, pending)
+		if err == nil && len(pending) > 0 {
+			err = file.Sync()
+		}
+		if err == nil {
+			err = os.Rename(tmp, l.path)
+		}
+		if err != nil {
+			file.Close()
+			os.Remove(tmp)
+		}
+	}
+	if err != nil {
+		l.records += records
+		return err
+	}
+
+	l.file.Close()
+	l.file = file
+	l.offset = offset
+	l.cipher = c
+	table.log("Compacted mutation log of table", table.name, "to", len(items), "items")
+
+	return nil
+}
+
+// writeCompacted writes the items to a new log file at path, in a segment of
+// its own if keys are provided, and syncs it. Returns the file, the segment's
+// cipher and the file's size.
+func writeCompacted(path string, items []*CacheItem, keys KeyProvider) (*os.File, *logCipher, int64, error) {
+	recs := make([]logRecord, len(items))
+	for i, item := range items {
+		var err error
+		recs[i].Op = logOpSet
+		if recs[i].exportedItem, err = exportItem(item, nil); err != nil {
+			return nil, nil, 0, err
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	w := bufio.NewWriter(file)
+	c, offset, err := startSegment(w, keys)
+	if err == nil {
+		offset, err = writeRecords(w, c, offset, recs)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(path)
+		return nil, nil, 0, err
+	}
+	return file, c, offset, nil
+}
+
+// writeRecords writes records starting at the given offset in the log, and
+// returns the offset following them.
+func writeRecords(w io.Writer, c *logCipher, offset int64, recs []logRecord) (int64, error) {
+	for _, rec := range recs {
+		b, err := encodeRecord(rec, c, offset)
+		if err != nil {
+			return offset, err
+		}
+		if _, err := w.Write(b); err != nil {
+			return offset, err
+		}
+		offset += int64(len(b))
+	}
+	return offset, nil
+}
@@ -0,0 +1,155 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func tempLogPath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "cache2go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "table.log"), func() { os.RemoveAll(dir) }
+}
+
+func TestMutationLogReplay(t *testing.T) {
+	path, cleanup := tempLogPath(t)
+	defer cleanup()
+
+	table := Cache("testMutationLog")
+	if err := table.EnableMutationLog(path, MutationLogOptions{}); err != nil {
+		t.Error("Error enabling mutation log", err)
+	}
+	table.Add("a", 0, "1")
+	table.Add("b", 0, "2")
+	table.Add("a", 0, "3")
+	table.Delete("b")
+	table.Add("c", 50*time.Millisecond, "4")
+	table.DisableMutationLog()
+
+	// Simulate a crash while writing a record
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	f.WriteString(`{"op":"set","key":"d"`)
+	f.Close()
+
+	time.Sleep(100 * time.Millisecond)
+	restored := Cache("testMutationLogRestored")
+	if err := restored.EnableMutationLog(path, MutationLogOptions{}); err != nil {
+		t.Error("Error replaying mutation log", err)
+	}
+	defer restored.DisableMutationLog()
+	if p, err := restored.Value("a"); err != nil || p.Data() != "3" {
+		t.Error("Error retrieving replayed item", p, err)
+	}
+	if restored.Exists("b") || restored.Exists("c") || restored.Exists("d") {
+		t.Error("Deleted, expired and incomplete items should not be replayed")
+	}
+
+	// New records get appended after the truncated one
+	restored.Add("e", 0, "5")
+	b, _ := ioutil.ReadFile(path)
+	if !strings.HasSuffix(string(b), "\n") || strings.Contains(string(b), `"key":"d"`) {
+		t.Error("Incomplete record should have been truncated:", string(b))
+	}
+}
+
+func TestMutationLogExistingItems(t *testing.T) {
+	path, cleanup := tempLogPath(t)
+	defer cleanup()
+
+	table := Cache("testMutationLogExistingItems")
+	table.Flush()
+	table.Add("a", 0, "1")
+	table.EnableMutationLog(path, MutationLogOptions{})
+	table.Add("b", 0, "2")
+	table.DisableMutationLog()
+
+	restored := Cache("testMutationLogExistingItemsRestored")
+	restored.Flush()
+	restored.EnableMutationLog(path, MutationLogOptions{})
+	defer restored.DisableMutationLog()
+	if restored.Count() != 2 || !restored.Exists("a") {
+		t.Error("Items added before enabling the log should be replayed, got", restored.Count())
+	}
+}
+
+func TestMutationLogCompactionConcurrentWrites(t *testing.T) {
+	path, cleanup := tempLogPath(t)
+	defer cleanup()
+
+	table := Cache("testMutationLogCompactionConcurrent")
+	table.Flush()
+	table.EnableMutationLog(path, MutationLogOptions{})
+	for i := 0; i < 100; i++ {
+		table.Add(i, 0, i)
+	}
+
+	// Writes during compactions end up in the compacted log.
+	table.RLock()
+	l := table.mutationLog
+	table.RUnlock()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			if err := table.compact(l); err != nil {
+				t.Error("Error compacting log", err)
+			}
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		table.Delete(i)
+		table.Add(100+i, 0, i)
+	}
+	<-done
+	table.DisableMutationLog()
+
+	restored := Cache("testMutationLogCompactionConcurrentRestored")
+	restored.Flush()
+	restored.EnableMutationLog(path, MutationLogOptions{})
+	defer restored.DisableMutationLog()
+	if restored.Count() != 100 || restored.Exists(float64(0)) || !restored.Exists(float64(199)) {
+		t.Error("Expected the latest 100 items to be replayed, got", restored.Count())
+	}
+}
+
+func TestMutationLogCompaction(t *testing.T) {
+	path, cleanup := tempLogPath(t)
+	defer cleanup()
+
+	table := Cache("testMutationLogCompaction")
+	table.EnableMutationLog(path, MutationLogOptions{CompactInterval: 20 * time.Millisecond})
+	defer table.DisableMutationLog()
+	for i := 0; i < 10; i++ {
+		table.Add(k, 0, i)
+	}
+	table.Flush()
+	table.Add("a", 0, "1")
+	table.Add("b", 0, "2")
+
+	time.Sleep(100 * time.Millisecond)
+	b, _ := ioutil.ReadFile(path)
+	if lines := strings.Count(string(b), "\n"); lines != 2 {
+		t.Error("Compacted log should only hold the current items, got", lines, "records")
+	}
+
+	table.Add("c", 0, "3")
+	restored := Cache("testMutationLogCompactionRestored")
+	restored.EnableMutationLog(path, MutationLogOptions{})
+	defer restored.DisableMutationLog()
+	if restored.Count() != 3 {
+		t.Error("Expected 3 replayed items, got", restored.Count())
+	}
+}
@@ -0,0 +1,243 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NATSSink is an EventSink publishing events as JSON to a subject of a NATS
+// server. It speaks just enough of the NATS protocol to publish, connecting
+// lazily and reconnecting after errors. Connections get upgraded to TLS if
+// the server requires it. As the protocol doesn't acknowledge publishes,
+// errors the server reports for them get returned by the next Publish.
+type NATSSink struct {
+	// Address of the NATS server, e.g. "localhost:4222".
+	Addr string
+	// Subject the events get published to.
+	Subject string
+	// Token or user and password to authenticate with, if required.
+	Token    string
+	User     string
+	Password string
+	// Timeout for connecting and writing, 10 seconds if 0.
+	Timeout time.Duration
+	// TLS configuration used if the server requires TLS. If nil, the
+	// server's certificate gets verified against the system's roots.
+	TLSConfig *tls.Config
+
+	mutex sync.Mutex
+	conn  net.Conn
+	w     *bufio.Writer
+	// Error the server reported since the last publish.
+	err error
+}
+
+// natsInfo is the part of a NATS server's INFO we care about.
+type natsInfo struct {
+	TLSRequired bool `json:"tls_required"`
+}
+
+// NewNATSSink creates a sink publishing to the given subject.
+func NewNATSSink(addr, subject string) *NATSSink {
+	return &NATSSink{Addr: addr, Subject: subject}
+}
+
+// Publish publishes an event.
+func (s *NATSSink) Publish(e Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.err; err != nil {
+		s.err = nil
+		return err
+	}
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return err
+		}
+	}
+
+	s.conn.SetWriteDeadline(time.Now().Add(s.timeout()))
+	fmt.Fprintf(s.w, "PUB %s %d\r\n", s.Subject, len(payload))
+	s.w.Write(payload)
+	s.w.WriteString("\r\n")
+	if err := s.w.Flush(); err != nil {
+		s.closeConn()
+		return err
+	}
+	return nil
+}
+
+// Close closes the connection to the server.
+func (s *NATSSink) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	return s.closeConn()
+}
+
+func (s *NATSSink) timeout() time.Duration {
+	if s.Timeout > 0 {
+		return s.Timeout
+	}
+	return 10 * time.Second
+}
+
+// connect dials the server and introduces the client, waiting for the
+// server to accept it.
+// Careful: do not run this method unless the sink-mutex is locked!
+func (s *NATSSink) connect() error {
+	conn, err := net.DialTimeout("tcp", s.Addr, s.timeout())
+	if err != nil {
+		return err
+	}
+
+	// The server greets with its INFO.
+	conn.SetDeadline(time.Now().Add(s.timeout()))
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	var info natsInfo
+	if !strings.HasPrefix(line, "INFO ") || json.Unmarshal([]byte(line[len("INFO "):]), &info) != nil {
+		conn.Close()
+		return fmt.Errorf("Unexpected greeting from NATS server: %q", line)
+	}
+	if info.TLSRequired {
+		if conn, err = s.upgrade(conn); err != nil {
+			return err
+		}
+		r = bufio.NewReader(conn)
+	}
+
+	options, _ := json.Marshal(map[string]interface{}{
+		"verbose":    false,
+		"pedantic":   false,
+		"name":       "cache2go",
+		"lang":       "go",
+		"auth_token": s.Token,
+		"user":       s.User,
+		"pass":       s.Password,
+	})
+	w := bufio.NewWriter(conn)
+	fmt.Fprintf(w, "CONNECT %s\r\nPING\r\n", options)
+	if err := w.Flush(); err != nil {
+		conn.Close()
+		return err
+	}
+
+	// The server answers the ping once it accepted the connection, or
+	// reports why it didn't.
+	line, err = r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if err := natsError(line); err != nil {
+		conn.Close()
+		return err
+	}
+	if !strings.HasPrefix(line, "PONG") {
+		conn.Close()
+		return fmt.Errorf("Unexpected reply from NATS server: %q", line)
+	}
+	conn.SetDeadline(time.Time{})
+
+	s.conn = conn
+	s.w = w
+	go s.serve(conn, r)
+	return nil
+}
+
+// upgrade performs the TLS handshake on a connection.
+func (s *NATSSink) upgrade(conn net.Conn) (net.Conn, error) {
+	config := s.TLSConfig
+	if config == nil {
+		config = &tls.Config{}
+	}
+	if config.ServerName == "" {
+		config = config.Clone()
+		config.ServerName, _, _ = net.SplitHostPort(s.Addr)
+	}
+
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// natsError returns the error a -ERR line from the server reports, or nil.
+func natsError(line string) error {
+	if !strings.HasPrefix(line, "-ERR") {
+		return nil
+	}
+	return fmt.Errorf("NATS server error: %s", strings.Trim(strings.TrimSpace(line[len("-ERR"):]), "'"))
+}
+
+// serve answers the server's pings, which keep the connection alive, and
+// records the errors it reports, until the connection gets closed.
+func (s *NATSSink) serve(conn net.Conn, r *bufio.Reader) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			break
+		}
+		if err := natsError(line); err != nil {
+			s.mutex.Lock()
+			if s.conn == conn {
+				s.err = err
+			}
+			s.mutex.Unlock()
+			continue
+		}
+		if !strings.HasPrefix(line, "PING") {
+			continue
+		}
+
+		s.mutex.Lock()
+		if s.conn == conn {
+			conn.SetWriteDeadline(time.Now().Add(s.timeout()))
+			s.w.WriteString("PONG\r\n")
+			s.w.Flush()
+		}
+		s.mutex.Unlock()
+	}
+
+	s.mutex.Lock()
+	if s.conn == conn {
+		s.closeConn()
+	}
+	s.mutex.Unlock()
+}
+
+// closeConn closes the connection, so the next publish reconnects.
+// Careful: do not run this method unless the sink-mutex is locked!
+func (s *NATSSink) closeConn() error {
+	err := s.conn.Close()
+	s.conn = nil
+	s.w = nil
+	return err
+}
@@ -0,0 +1,187 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveNATS accepts a single connection, greeting it with the given INFO
+// and upgrading it to TLS if config is set. It answers the client's pings,
+// reports the lines it receives followed by the payloads of publishes, and
+// replies to publishes with reply.
+func serveNATS(l net.Listener, info string, config *tls.Config, reply string, received chan<- string) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "INFO %s\r\n", info)
+	if config != nil {
+		conn = tls.Server(conn, config)
+		defer conn.Close()
+	}
+
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		if strings.HasPrefix(line, "PING") {
+			fmt.Fprint(conn, "PONG\r\n")
+			continue
+		}
+		received <- strings.TrimSpace(line)
+		var subject string
+		var n int
+		if _, err := fmt.Sscanf(line, "PUB %s %d", &subject, &n); err == nil {
+			payload := make([]byte, n+2)
+			io.ReadFull(r, payload)
+			received <- string(payload[:n])
+			fmt.Fprint(conn, reply)
+		}
+	}
+}
+
+func TestNATSSink(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	received := make(chan string, 10)
+	// Make sure pings get answered.
+	go serveNATS(l, `{"server_id":"test"}`, nil, "PING\r\n", received)
+
+	sink := NewNATSSink(l.Addr().String(), "cache.events")
+	sink.Token = "secret"
+	defer sink.Close()
+	if err := sink.Publish(Event{Table: "t", Type: EventAdd, Key: "k"}); err != nil {
+		t.Fatal("Error publishing", err)
+	}
+
+	if connect := <-received; !strings.HasPrefix(connect, "CONNECT ") || !strings.Contains(connect, `"auth_token":"secret"`) {
+		t.Error("Unexpected CONNECT", connect)
+	}
+	if pub := <-received; !strings.HasPrefix(pub, "PUB cache.events ") {
+		t.Error("Unexpected PUB", pub)
+	}
+	var e Event
+	if err := json.Unmarshal([]byte(<-received), &e); err != nil || e.Type != EventAdd || e.Key != "k" {
+		t.Errorf("Unexpected event %+v: %v", e, err)
+	}
+	if pong := <-received; pong != "PONG" {
+		t.Error("Expected PONG, got", pong)
+	}
+}
+
+func TestNATSSinkTLS(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	received := make(chan string, 10)
+	config := &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	go serveNATS(l, `{"server_id":"test","tls_required":true}`, config, "", received)
+
+	sink := NewNATSSink(l.Addr().String(), "cache.events")
+	sink.TLSConfig = &tls.Config{RootCAs: roots}
+	defer sink.Close()
+	if err := sink.Publish(Event{Table: "t", Type: EventAdd, Key: "k"}); err != nil {
+		t.Fatal("Error publishing", err)
+	}
+	if connect := <-received; !strings.HasPrefix(connect, "CONNECT ") {
+		t.Error("Unexpected CONNECT", connect)
+	}
+}
+
+func TestNATSSinkErrors(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	received := make(chan string, 10)
+	go serveNATS(l, `{"server_id":"test"}`, nil, "-ERR 'Permissions Violation for Publish to cache.events'\r\n", received)
+
+	sink := NewNATSSink(l.Addr().String(), "cache.events")
+	defer sink.Close()
+	if err := sink.Publish(Event{Table: "t", Type: EventAdd, Key: "k"}); err != nil {
+		t.Fatal("Error publishing", err)
+	}
+	<-received
+	<-received
+	<-received
+
+	// The error gets reported by the next publish.
+	var err2 error
+	for i := 0; i < 100 && err2 == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+		err2 = sink.Publish(Event{Table: "t", Type: EventAdd, Key: "k"})
+	}
+	if err2 == nil || !strings.Contains(err2.Error(), "Permissions Violation") {
+		t.Error("Expected the server's error, got", err2)
+	}
+
+	// Servers rejecting the connection fail the publish right away.
+	l2, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l2.Close()
+	go func() {
+		conn, err := l2.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprint(conn, "INFO {\"server_id\":\"test\",\"auth_required\":true}\r\n")
+		bufio.NewReader(conn).ReadString('\n')
+		fmt.Fprint(conn, "-ERR 'Authorization Violation'\r\n")
+	}()
+	sink2 := NewNATSSink(l2.Addr().String(), "cache.events")
+	defer sink2.Close()
+	if err := sink2.Publish(Event{Table: "t", Type: EventAdd, Key: "k"}); err == nil || !strings.Contains(err.Error(), "Authorization Violation") {
+		t.Error("Expected the connection to be rejected, got", err)
+	}
+}
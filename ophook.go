@@ -0,0 +1,67 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"time"
+)
+
+// Op is a table operation reported to the operation hook, see SetOpHook.
+type Op int
+
+const (
+	// OpAdd is a call to Add or AddContext.
+	OpAdd Op = iota
+	// OpValue is a call to Value, including ones via ValueCtx.
+	OpValue
+	// OpDelete is a call to Delete or DeleteContext.
+	OpDelete
+)
+
+// String returns the operation's name.
+func (op Op) String() string {
+	switch op {
+	case OpAdd:
+		return "add"
+	case OpValue:
+		return "value"
+	case OpDelete:
+		return "delete"
+	}
+	return "unknown"
+}
+
+// opHook is the operation hook of a table.
+type opHook struct {
+	f func(op Op, key interface{}, d time.Duration)
+}
+
+// SetOpHook configures a callback, which will be called after every Add,
+// Value and Delete with the operation's key and how long it took, including
+// running the data-loader and other callbacks, e.g. to feed a tracing or
+// profiling system. It gets called synchronously, so it needs to be fast.
+// Pass nil to remove the hook.
+func (table *CacheTable) SetOpHook(f func(op Op, key interface{}, d time.Duration)) {
+	var h *opHook
+	if f != nil {
+		h = &opHook{f: f}
+	}
+	table.opHook.Store(h)
+}
+
+// loadOpHook returns the operation hook, or nil if none is set. It doesn't
+// need the table-mutex, so it's cheap to call on every operation.
+func (table *CacheTable) loadOpHook() *opHook {
+	h, _ := table.opHook.Load().(*opHook)
+	return h
+}
+
+// observe reports an operation that started at the given time.
+func (h *opHook) observe(op Op, key interface{}, start time.Time) {
+	h.f(op, key, time.Since(start))
+}
@@ -0,0 +1,56 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOpHook(t *testing.T) {
+	table := Cache("testOpHook")
+	table.SetDataLoader(func(key interface{}, args ...interface{}) *CacheItem {
+		time.Sleep(10 * time.Millisecond)
+		return NewCacheItem(key, 0, "loaded")
+	})
+	defer table.SetDataLoader(nil)
+
+	type observed struct {
+		op  Op
+		key interface{}
+		d   time.Duration
+	}
+	var ops []observed
+	table.SetOpHook(func(op Op, key interface{}, d time.Duration) {
+		ops = append(ops, observed{op, key, d})
+	})
+
+	table.Add("a", 0, "value")
+	table.Value("b")
+	table.Delete("a")
+	table.Delete("missing")
+
+	expected := []observed{{OpAdd, "a", 0}, {OpValue, "b", 0}, {OpDelete, "a", 0}, {OpDelete, "missing", 0}}
+	if len(ops) != len(expected) {
+		t.Fatal("Expected", len(expected), "operations, got", ops)
+	}
+	for i, e := range expected {
+		if ops[i].op != e.op || ops[i].key != e.key {
+			t.Errorf("Expected %s %v, got %s %v", e.op, e.key, ops[i].op, ops[i].key)
+		}
+	}
+	if ops[1].d < 10*time.Millisecond {
+		t.Error("Expected the duration to include loading the item, got", ops[1].d)
+	}
+
+	table.SetOpHook(nil)
+	table.Value("a")
+	if len(ops) != len(expected) {
+		t.Error("Removed hook shouldn't get called")
+	}
+}
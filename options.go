@@ -0,0 +1,189 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"log"
+	"math/rand"
+	"time"
+)
+
+// Option configures a cache table when it gets created.
+type Option func(*cacheOptions)
+
+// cacheOptions holds the configuration assembled from Options.
+type cacheOptions struct {
+	// Lifespan used when adding items with a lifespan of 0.
+	lifeSpan time.Duration
+	// Soft lifespan used when adding items without one.
+	softLifeSpan time.Duration
+	// Maximum number of items, 0 means unlimited.
+	capacity int
+	// Whether usage statistics get collected.
+	stats bool
+	// The logger used for the table.
+	logger *log.Logger
+	// Capacity limit shared with other tables, and the table's weight.
+	budget       *SharedBudget
+	budgetWeight int
+	// Faults injected for testing, nil if none.
+	faults *FaultInjector
+	// How to restore items' lifespans.
+	restorePolicy RestorePolicy
+	// How long deleted keys are tombstoned, and whether adding them in the
+	// meantime gets rejected.
+	tombstoneWindow     time.Duration
+	rejectResurrections bool
+	// Maximum number of expired items deleted, and time spent deleting
+	// them, per lock acquisition.
+	expiryBatchSize  int
+	expiryBatchPause time.Duration
+	// Resolution of the clock timestamping accesses, 0 for time.Now.
+	clockResolution time.Duration
+	// Whether evictions take items' load costs into account.
+	costAware bool
+	// Number of new items kept on probation, 0 if not scan-resistant.
+	probationSize int
+	// Whether values are treated as immutable, and how often they get
+	// audited for modifications, see WithStrictMode.
+	strict        bool
+	auditInterval time.Duration
+	// Source of random decisions, nil for a time-seeded one, and the share
+	// by which lifespans get randomly shortened.
+	randSource     rand.Source
+	lifeSpanJitter float64
+	// Whether background work only happens on calls to Tick.
+	manualTicks bool
+	// Heap size beyond which items get reclaimed, 0 if they're not held
+	// like soft references.
+	softHeapLimit uint64
+	// Size of the slabs holding byte slice values, 0 if they're stored as
+	// usual, see WithByteArena.
+	arenaSlabSize int
+	// Arena backed by a file holding byte slice values, nil if none, see
+	// WithMappedArena.
+	mappedArena *MappedArena
+	// Pool deduplicating strings, nil if none, and the maximum length of
+	// string values added to it, see WithStringInterning.
+	internPool        *InternPool
+	internMaxValueLen int
+}
+
+// WithDefaultLifeSpan makes items added with a lifespan of 0 expire after the
+// given duration instead of living forever.
+func WithDefaultLifeSpan(lifeSpan time.Duration) Option {
+	return func(o *cacheOptions) {
+		o.lifeSpan = lifeSpan
+	}
+}
+
+// WithSoftLifeSpan makes items added without a soft lifespan turn stale after
+// the given duration, see CacheTable.AddWithSoftLifeSpan.
+func WithSoftLifeSpan(softLifeSpan time.Duration) Option {
+	return func(o *cacheOptions) {
+		o.softLifeSpan = softLifeSpan
+	}
+}
+
+// WithCapacity limits the number of items in a table. Once it's full, adding
+// an item evicts an approximately least recently used one.
+func WithCapacity(capacity int) Option {
+	return func(o *cacheOptions) {
+		o.capacity = capacity
+	}
+}
+
+// WithStats enables collecting usage statistics, see CacheTable.Stats.
+func WithStats() Option {
+	return func(o *cacheOptions) {
+		o.stats = true
+	}
+}
+
+// WithLogger sets the logger to be used by a table.
+func WithLogger(logger *log.Logger) Option {
+	return func(o *cacheOptions) {
+		o.logger = logger
+	}
+}
+
+// WithBudget makes a table share the given budget with other tables. Tables
+// with a higher weight get a proportionally larger share of the budget before
+// their items get evicted in favor of other tables' items.
+func WithBudget(budget *SharedBudget, weight int) Option {
+	return func(o *cacheOptions) {
+		o.budget = budget
+		o.budgetWeight = weight
+	}
+}
+
+// Defaults of WithExpiryBatch.
+const (
+	defaultExpiryBatchSize  = 1000
+	defaultExpiryBatchPause = time.Millisecond
+)
+
+// WithExpiryBatch bounds how long expiration checks block other users of a
+// table when many items expire at once: after deleting maxDeletions items,
+// or after spending maxPause doing so, the table gets unlocked before the
+// next batch, so concurrent reads don't stall. Defaults to 1000 deletions
+// and 1ms.
+func WithExpiryBatch(maxDeletions int, maxPause time.Duration) Option {
+	return func(o *cacheOptions) {
+		o.expiryBatchSize = maxDeletions
+		o.expiryBatchPause = maxPause
+	}
+}
+
+// RestorePolicy determines the remaining lifespans of items restored from
+// exports, snapshots and mutation logs.
+type RestorePolicy int
+
+const (
+	// RestoreDrop keeps the items' absolute expiry times, and drops items
+	// that expired in the meantime.
+	RestoreDrop RestorePolicy = iota
+	// RestoreKeep keeps all items, restarting their full lifespans.
+	RestoreKeep
+	// RestoreExtend keeps the absolute expiry times of items that are still
+	// alive, and restarts the full lifespans of items that expired in the
+	// meantime, e.g. to serve a warm cache while refreshing it.
+	RestoreExtend
+)
+
+// WithRestorePolicy sets how to restore items' lifespans, RestoreDrop by
+// default.
+func WithRestorePolicy(policy RestorePolicy) Option {
+	return func(o *cacheOptions) {
+		o.restorePolicy = policy
+	}
+}
+
+// newCacheOptions assembles the configuration from a list of Options.
+func newCacheOptions(opts ...Option) cacheOptions {
+	var o cacheOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// equal returns whether two sets of options configure a table the same way.
+func (o cacheOptions) equal(p cacheOptions) bool {
+	return o.normalized() == p.normalized()
+}
+
+// normalized returns a copy of the options without the ones that don't
+// affect what a table holds: the logger, and the random source, which may not
+// even be comparable. All other options take part in comparisons, so new ones
+// get covered automatically.
+func (o cacheOptions) normalized() cacheOptions {
+	o.logger = nil
+	o.randSource = nil
+	return o
+}
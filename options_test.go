@@ -0,0 +1,177 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// countingWriter counts the writes to it, safe for concurrent use.
+type countingWriter struct {
+	writes int32
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	atomic.AddInt32(&w.writes, 1)
+	return len(p), nil
+}
+
+func TestCacheDefaults(t *testing.T) {
+	out := new(countingWriter)
+	SetCacheDefaults(
+		WithDefaultLifeSpan(50*time.Millisecond),
+		WithCapacity(2),
+		WithStats(),
+		WithLogger(log.New(out, "cache2go: ", log.Ldate|log.Ltime)),
+	)
+	defer SetCacheDefaults()
+
+	table := Cache("testCacheDefaults")
+	table.Add("key1", 0, "value1")
+	table.Add("key2", time.Minute, "value2")
+	table.Value("key1")
+	table.Value("missing")
+
+	// The table inherits the default capacity
+	table.Add("key3", time.Minute, "value3")
+	if table.Count() != 2 {
+		t.Error("Table should be limited to the default capacity, got", table.Count())
+	}
+
+	stats := table.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Added != 3 || stats.Evicted != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if stats.HitRatio() != 0.5 {
+		t.Error("Expected a hit ratio of 0.5, got", stats.HitRatio())
+	}
+	if atomic.LoadInt32(&out.writes) == 0 {
+		t.Error("Table should log to the default logger")
+	}
+
+	// Tables created after resetting the defaults are unaffected
+	SetCacheDefaults()
+	plain := Cache("testCacheDefaultsPlain")
+	item := plain.Add("key1", 0, "value1")
+	if item.LifeSpan() != 0 || plain.Stats() != (CacheStats{}) {
+		t.Error("Table should not inherit reset defaults")
+	}
+}
+
+func TestCacheDefaultLifeSpan(t *testing.T) {
+	SetCacheDefaults(WithDefaultLifeSpan(50 * time.Millisecond))
+	table := Cache("testCacheDefaultLifeSpan")
+	SetCacheDefaults()
+
+	item := table.Add("key1", 0, "value1")
+	if item.LifeSpan() != 50*time.Millisecond {
+		t.Error("Item should inherit the default lifespan")
+	}
+	table.NotFoundAdd("key2", 0, "value2")
+
+	time.Sleep(100 * time.Millisecond)
+	if table.Exists("key1") || table.Exists("key2") {
+		t.Error("Items should expire after the default lifespan")
+	}
+}
+
+func TestCacheWithOptions(t *testing.T) {
+	table, err := CacheWithOptions("testCacheWithOptions", WithCapacity(10), WithStats())
+	if err != nil {
+		t.Error("Error creating table with options", err)
+	}
+	if Cache("testCacheWithOptions") != table {
+		t.Error("Cache should return the table created with options")
+	}
+
+	// Asking again with the same options returns the same table
+	again, err := CacheWithOptions("testCacheWithOptions", WithStats(), WithCapacity(10))
+	if err != nil || again != table {
+		t.Error("Expected the existing table without an error", err)
+	}
+
+	// Different options are reported, instead of silently sharing the table
+	other, err := CacheWithOptions("testCacheWithOptions", WithCapacity(1000))
+	if err != ErrCacheOptionsMismatch {
+		t.Error("Expected ErrCacheOptionsMismatch, got", err)
+	}
+	if other != table {
+		t.Error("The existing table should be returned along with the error")
+	}
+
+	// Options that can't be compared don't matter, and don't panic.
+	if _, err := CacheWithOptions("testCacheWithOptions", WithCapacity(10), WithStats(),
+		WithLogger(log.New(ioutil.Discard, "", 0)), WithRandSource(uncomparableSource{})); err != nil {
+		t.Error("Expected the logger and random source to be ignored, got", err)
+	}
+}
+
+func TestCacheOptionsEqualCoversAllFields(t *testing.T) {
+	// Options deliberately left out of comparisons, see normalized.
+	ignored := map[string]bool{"logger": true, "randSource": true}
+
+	typ := reflect.TypeOf(cacheOptions{})
+	for i := 0; i < typ.NumField(); i++ {
+		var o cacheOptions
+		f := reflect.ValueOf(&o).Elem().Field(i)
+		f = reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem()
+		switch f.Kind() {
+		case reflect.Bool:
+			f.SetBool(true)
+		case reflect.Int, reflect.Int64:
+			f.SetInt(1)
+		case reflect.Uint64:
+			f.SetUint(1)
+		case reflect.Float64:
+			f.SetFloat(1)
+		case reflect.Ptr:
+			f.Set(reflect.New(f.Type().Elem()))
+		case reflect.Interface:
+			f.Set(reflect.ValueOf(rand.NewSource(1)))
+		default:
+			t.Error("Don't know how to set option", typ.Field(i).Name, "of kind", f.Kind())
+			continue
+		}
+
+		name := typ.Field(i).Name
+		if equal := o.equal(cacheOptions{}); equal != ignored[name] {
+			t.Error("Option", name, "isn't compared as expected, equal:", equal)
+		}
+	}
+}
+
+// uncomparableSource is a random source that panics when compared.
+type uncomparableSource struct {
+	seeds []int64
+}
+
+func (s uncomparableSource) Int63() int64    { return 4 }
+func (s uncomparableSource) Seed(seed int64) {}
+
+func TestLFUCacheRegistry(t *testing.T) {
+	c, err := LFUCacheWithCapacity("testLFUCacheRegistry", 10)
+	if err != nil {
+		t.Error("Error creating LFU cache", err)
+	}
+	if again, err := LFUCacheWithCapacity("testLFUCacheRegistry", 10); err != nil || again != c {
+		t.Error("Expected the existing LFU cache without an error", err)
+	}
+	if other, err := LFUCacheWithCapacity("testLFUCacheRegistry", 20); err != ErrCacheOptionsMismatch || other != c {
+		t.Error("Expected the existing LFU cache with ErrCacheOptionsMismatch, got", err)
+	}
+	if named := LFUCacheNamed("testLFUCacheRegistry", 20); named != c {
+		t.Error("Expected LFUCacheNamed to return the existing LFU cache")
+	}
+}
@@ -0,0 +1,78 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"time"
+)
+
+// SetParent chains the table in front of a parent cache: on a miss, the table
+// consults its parent before its own data-loader, and keeps a copy of the
+// parent's item expiring along with it. Small per-goroutine or per-shard
+// tables can thus sit in front of one big shared table, taking the load of
+// hot keys off it. A parent that's a table itself passes Load's context and
+// options on to its own parent or data-loader, so the whole chain shares a
+// single load of a missing key. Items added to or deleted from the table
+// don't reach its parent. Pass nil to stop consulting the parent.
+//
+// Returns ErrParentCycle if the table would become its own ancestor.
+func (table *CacheTable) SetParent(parent Cacher) error {
+	for p := parent; p != nil; {
+		if p == Cacher(table) {
+			return ErrParentCycle
+		}
+		t, ok := p.(*CacheTable)
+		if !ok {
+			break
+		}
+		p = t.Parent()
+	}
+
+	table.Lock()
+	defer table.Unlock()
+	table.parent = parent
+	return nil
+}
+
+// Parent returns the table's parent cache, nil if none.
+func (table *CacheTable) Parent() Cacher {
+	table.RLock()
+	defer table.RUnlock()
+	return table.parent
+}
+
+// valueFromParent looks up an item missing in the table in its parent, and
+// adds a copy of it to the table, which expires along with the parent's item.
+// Items that already outlived their lifespan are treated as missing.
+func (table *CacheTable) valueFromParent(parent Cacher, req LoadRequest, shared bool) (*CacheItem, error) {
+	var p *CacheItem
+	var err error
+	if t, ok := parent.(*CacheTable); ok {
+		p, err = t.value(req, shared)
+	} else {
+		p, err = parent.Value(req.Key, req.args...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lifeSpan := p.LifeSpan()
+	if lifeSpan > 0 {
+		lifeSpan -= time.Since(p.AccessedOn())
+		if lifeSpan <= 0 {
+			return nil, ErrKeyNotFound
+		}
+	}
+
+	item := table.newItem(req.Key, lifeSpan, p.Data())
+	item.source = SourceParent
+
+	table.Lock()
+	table.addInternal(item)
+	return item, nil
+}
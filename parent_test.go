@@ -0,0 +1,101 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParent(t *testing.T) {
+	root, _ := CacheWithOptions("testParentRoot")
+	root.Flush()
+	child, _ := CacheWithOptions("testParentChild")
+	child.Flush()
+	if err := child.SetParent(root); err != nil {
+		t.Error("Error setting parent", err)
+	}
+
+	loads := 0
+	root.SetLoader(func(req *LoadRequest) *CacheItem {
+		loads++
+		return NewCacheItem(req.Key, time.Minute, req.Options["v"])
+	})
+	child.SetDataLoader(func(key interface{}, args ...interface{}) *CacheItem {
+		t.Error("Child's data-loader should not run if the parent has the item")
+		return nil
+	})
+
+	root.Add("shared", time.Minute, "root")
+	p, err := child.Value("shared")
+	if err != nil || p.Data() != "root" || p.Source() != SourceParent || p.LifeSpan() > time.Minute || p.LifeSpan() < 59*time.Second {
+		t.Error("Child should copy the parent's item on a miss", err)
+	}
+	if !child.Exists("shared") {
+		t.Error("Child should keep the parent's item")
+	}
+
+	// Load's options pass through the chain to the parent's loader.
+	p, err = child.Load(context.Background(), "loaded", LoadOptions{"v": 42})
+	if err != nil || p.Data() != 42 || loads != 1 || !root.Exists("loaded") {
+		t.Error("Parent should load missing items", err, loads)
+	}
+
+	child.Add("local", 0, "child")
+	child.Delete("shared")
+	if root.Exists("local") || !root.Exists("shared") {
+		t.Error("Child's writes should not reach the parent")
+	}
+
+	if err := root.SetParent(child); err != ErrParentCycle {
+		t.Error("Expected ErrParentCycle, got", err)
+	}
+	if err := child.SetParent(child); err != ErrParentCycle {
+		t.Error("Expected ErrParentCycle, got", err)
+	}
+
+	child.SetParent(nil)
+	child.SetDataLoader(nil)
+	if _, err := child.Value("shared"); err != ErrKeyNotFound {
+		t.Error("Child without parent should miss, got", err)
+	}
+}
+
+// agedParent is a parent cache whose items were last accessed a while ago.
+type agedParent struct {
+	*CacheTable
+	age time.Duration
+}
+
+func (p agedParent) Value(key interface{}, args ...interface{}) (*CacheItem, error) {
+	item, err := p.CacheTable.Value(key, args...)
+	if err == nil {
+		item.setAccessedOn(time.Now().Add(-p.age))
+	}
+	return item, err
+}
+
+func TestParentRemainingLifeSpan(t *testing.T) {
+	root, _ := CacheWithOptions("testParentRemainingLifeSpanRoot")
+	root.Flush()
+	root.Add("aging", time.Minute, "root")
+	child, _ := CacheWithOptions("testParentRemainingLifeSpanChild")
+	child.Flush()
+
+	child.SetParent(agedParent{root, 40 * time.Second})
+	if p, err := child.Value("aging"); err != nil || p.LifeSpan() > 20*time.Second {
+		t.Error("Child's copy should expire along with the parent's item", p.LifeSpan(), err)
+	}
+
+	child.Delete("aging")
+	child.SetParent(agedParent{root, 2 * time.Minute})
+	if _, err := child.Value("aging"); err != ErrKeyNotFound || child.Exists("aging") {
+		t.Error("Child shouldn't copy expired items, got", err)
+	}
+}
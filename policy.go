@@ -0,0 +1,26 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+// EvictionPolicy decides which item gets evicted from a BoundedCache once it
+// reached its capacity. Policies keep their own bookkeeping and don't need to
+// be safe for concurrent use: the cache serializes all calls.
+type EvictionPolicy interface {
+	// Add is called after a new item was inserted into the cache.
+	Add(item *CacheItem)
+	// Access is called every time an existing item was read or replaced.
+	Access(item *CacheItem)
+	// Remove is called when an item left the cache without being evicted by
+	// the policy, e.g. because it got deleted.
+	Remove(item *CacheItem)
+	// Evict picks the next victim, drops it from the policy's bookkeeping and
+	// returns its key. It returns false if the policy tracks no items.
+	Evict() (interface{}, bool)
+	// Reset drops all bookkeeping.
+	Reset()
+}
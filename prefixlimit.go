@@ -0,0 +1,172 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"sort"
+	"strings"
+)
+
+// Number of keys sampled when evicting an item with a limited prefix.
+const prefixLimitSamples = 5
+
+// prefixLimit tracks the keys starting with a limited prefix.
+type prefixLimit struct {
+	prefix string
+	limit  int
+
+	// The matching keys, and their positions in the key slice.
+	keys    []string
+	indexes map[string]int
+}
+
+// add starts tracking a key.
+func (l *prefixLimit) add(key string) {
+	if _, ok := l.indexes[key]; ok {
+		return
+	}
+	l.indexes[key] = len(l.keys)
+	l.keys = append(l.keys, key)
+}
+
+// remove stops tracking a key, swapping it with the last key.
+func (l *prefixLimit) remove(key string) {
+	i, ok := l.indexes[key]
+	if !ok {
+		return
+	}
+	last := l.keys[len(l.keys)-1]
+	l.keys[i] = last
+	l.indexes[last] = i
+	l.keys = l.keys[:len(l.keys)-1]
+	delete(l.indexes, key)
+}
+
+// prefixLimitsByLength sorts limits by descending prefix length, so nested
+// prefixes make room before the prefixes containing them.
+type prefixLimitsByLength []*prefixLimit
+
+func (p prefixLimitsByLength) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p prefixLimitsByLength) Len() int           { return len(p) }
+func (p prefixLimitsByLength) Less(i, j int) bool { return len(p[i].prefix) > len(p[j].prefix) }
+
+// reset stops tracking all keys.
+func (l *prefixLimit) reset() {
+	l.keys = nil
+	l.indexes = make(map[string]int)
+}
+
+// LimitPrefix limits the number of items with string keys starting with the
+// given prefix, e.g. LimitPrefix("resize:", 10000), so keys of one feature
+// can't crowd out everybody else's in a shared table. Adding a key beyond the
+// limit evicts the approximately least recently used item with the same
+// prefix, independently of the table's capacity. Limits of nested prefixes
+// all apply. A limit of 0 removes the prefix's limit.
+func (table *CacheTable) LimitPrefix(prefix string, limit int) {
+	table.Lock()
+	defer table.Unlock()
+
+	for i, l := range table.prefixLimits {
+		if l.prefix != prefix {
+			continue
+		}
+		if limit <= 0 {
+			table.prefixLimits = append(table.prefixLimits[:i:i], table.prefixLimits[i+1:]...)
+			return
+		}
+		l.limit = limit
+		table.evictPrefixInternal(l, 0)
+		return
+	}
+	if limit <= 0 {
+		return
+	}
+
+	l := &prefixLimit{
+		prefix:  prefix,
+		limit:   limit,
+		indexes: make(map[string]int),
+	}
+	for key := range table.items {
+		if s, ok := key.(string); ok && strings.HasPrefix(s, prefix) {
+			l.add(s)
+		}
+	}
+	// Copy the limits, as they may be iterated while the table is unlocked.
+	n := len(table.prefixLimits)
+	table.prefixLimits = append(table.prefixLimits[:n:n], l)
+	sort.Stable(prefixLimitsByLength(table.prefixLimits))
+	table.evictPrefixInternal(l, 0)
+}
+
+// evictPrefixesInternal makes room for a new key in the limits of all of its
+// prefixes.
+// Careful: do not run this method unless the table-mutex is locked!
+func (table *CacheTable) evictPrefixesInternal(key interface{}) {
+	s, ok := key.(string)
+	if !ok {
+		return
+	}
+	for _, l := range table.prefixLimits {
+		if strings.HasPrefix(s, l.prefix) {
+			table.evictPrefixInternal(l, 1)
+		}
+	}
+}
+
+// evictPrefixInternal removes the least recently used of a few sampled items
+// with the limited prefix, until there's room for the given number of new
+// ones.
+// Careful: do not run this method unless the table-mutex is locked!
+func (table *CacheTable) evictPrefixInternal(l *prefixLimit, room int) {
+	for len(l.keys) > 0 && len(l.keys)+room > l.limit {
+		var victim string
+		var victimItem *CacheItem
+		for i := 0; i < prefixLimitSamples; i++ {
+			key := l.keys[table.rand.Intn(len(l.keys))]
+			item, ok := table.items[key]
+			if !ok {
+				// Not stored anymore, e.g. got evicted while unlocked.
+				l.remove(key)
+				victimItem = nil
+				break
+			}
+			if victimItem == nil || item.AccessedOn().Before(victimItem.AccessedOn()) {
+				victim, victimItem = key, item
+			}
+		}
+		if victimItem == nil {
+			continue
+		}
+
+		table.log("Evicting item with key", victim, "exceeding the limit of prefix", l.prefix, "in table", table.name)
+		if _, err := table.deleteInternal(victim, RemovalEvicted); err == nil {
+			table.stats.evict()
+		}
+	}
+}
+
+// trackPrefixesInternal starts or stops counting a key toward the limits of
+// its prefixes.
+// Careful: do not run this method unless the table-mutex is locked!
+func (table *CacheTable) trackPrefixesInternal(key interface{}, added bool) {
+	s, ok := key.(string)
+	if !ok {
+		return
+	}
+	for _, l := range table.prefixLimits {
+		if !strings.HasPrefix(s, l.prefix) {
+			continue
+		}
+		if added {
+			l.add(s)
+		} else {
+			l.remove(s)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// countPrefix returns the number of items with string keys starting with the
+// given prefix.
+func countPrefix(table *CacheTable, prefix string) int {
+	n := 0
+	table.Foreach(func(key interface{}, item *CacheItem) {
+		if s, ok := key.(string); ok && strings.HasPrefix(s, prefix) {
+			n++
+		}
+	})
+	return n
+}
+
+func TestLimitPrefix(t *testing.T) {
+	table, _ := CacheWithOptions("testLimitPrefix", WithStats())
+	table.Flush()
+	table.LimitPrefix("resize:", 10)
+	evicted := table.Stats().Evicted
+
+	for i := 0; i < 5; i++ {
+		table.Add("user:"+strconv.Itoa(i), 0, i)
+	}
+	table.Add(42, 0, "not a string")
+	for i := 0; i < 100; i++ {
+		table.Add("resize:"+strconv.Itoa(i), 0, i)
+	}
+
+	if n := countPrefix(table, "resize:"); n != 10 {
+		t.Error("Expected 10 items with the limited prefix, got", n)
+	}
+	if n := countPrefix(table, "user:"); n != 5 {
+		t.Error("Items with other prefixes shouldn't get evicted, got", n)
+	}
+	if !table.Exists(42) {
+		t.Error("Items with non-string keys shouldn't get evicted")
+	}
+	if !table.Exists("resize:99") {
+		t.Error("The most recently added item should be kept")
+	}
+	if stats := table.Stats(); stats.Evicted-evicted != 90 {
+		t.Error("Expected 90 evictions, got", stats.Evicted-evicted)
+	}
+
+	// Replacing items doesn't count toward the limit.
+	table.Add("resize:99", 0, "replaced")
+	if n := countPrefix(table, "resize:"); n != 10 {
+		t.Error("Expected 10 items after replacing one, got", n)
+	}
+
+	// Deleted and flushed items make room.
+	table.Delete("resize:99")
+	table.Add("resize:new", 0, true)
+	if n := countPrefix(table, "resize:"); n != 10 || table.Stats().Evicted-evicted != 90 {
+		t.Error("Deleted items should make room, got", n, "items")
+	}
+	table.Flush()
+	for i := 0; i < 10; i++ {
+		table.Add("resize:"+strconv.Itoa(i), 0, i)
+	}
+	if n := countPrefix(table, "resize:"); n != 10 || table.Stats().Evicted-evicted != 90 {
+		t.Error("Flushed items should make room, got", n, "items")
+	}
+}
+
+func TestLimitPrefixExisting(t *testing.T) {
+	table := Cache("testLimitPrefixExisting")
+	table.Flush()
+	for i := 0; i < 20; i++ {
+		table.Add("thumb:"+strconv.Itoa(i), 0, i)
+	}
+
+	// Lowering a limit evicts existing items right away.
+	table.LimitPrefix("thumb:", 15)
+	if n := countPrefix(table, "thumb:"); n != 15 {
+		t.Error("Expected 15 items after setting the limit, got", n)
+	}
+	table.LimitPrefix("thumb:", 5)
+	if n := countPrefix(table, "thumb:"); n != 5 {
+		t.Error("Expected 5 items after lowering the limit, got", n)
+	}
+
+	// Nested prefixes both apply.
+	table.LimitPrefix("thumb:small:", 2)
+	for i := 0; i < 10; i++ {
+		table.Add("thumb:small:"+strconv.Itoa(i), 0, i)
+	}
+	if n := countPrefix(table, "thumb:small:"); n != 2 {
+		t.Error("Expected 2 items with the nested prefix, got", n)
+	}
+	if n := countPrefix(table, "thumb:"); n != 5 {
+		t.Error("Expected 5 items with the outer prefix, got", n)
+	}
+
+	// Removing the limit stops evicting.
+	table.LimitPrefix("thumb:", 0)
+	table.LimitPrefix("thumb:small:", 0)
+	for i := 0; i < 10; i++ {
+		table.Add("thumb:big:"+strconv.Itoa(i), 0, i)
+	}
+	if n := countPrefix(table, "thumb:"); n != 15 {
+		t.Error("Expected 15 items after removing the limits, got", n)
+	}
+}
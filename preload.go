@@ -0,0 +1,76 @@
+//go:build go1.16
+// +build go1.16
+
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Codec decodes seed data, see PreloadFS.
+type Codec interface {
+	// Decode returns the key/value pairs held by a file's data.
+	Decode(data []byte) (map[interface{}]interface{}, error)
+}
+
+// JSONCodec decodes seed data holding a JSON object, using its member names
+// as keys.
+type JSONCodec struct{}
+
+// Decode returns the members of the JSON object held by data.
+func (JSONCodec) Decode(data []byte) (map[interface{}]interface{}, error) {
+	var members map[string]interface{}
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, err
+	}
+
+	pairs := make(map[interface{}]interface{}, len(members))
+	for k, v := range members {
+		pairs[k] = v
+	}
+	return pairs, nil
+}
+
+// PreloadFS populates cache tables with the seed data held by the files of
+// fsys matching the glob pattern, see fs.Glob, e.g. embedded via go:embed
+// for static lookup tables. Each file gets decoded with the given codec and
+// its pairs get added to the table named like the file, without directory
+// and extension: "seed/countries.json" populates Cache("countries"). Items
+// get added with a lifespan of 0, so the tables' lifespan rules or default
+// lifespans apply, and a data-loader can refresh them once they expire.
+func PreloadFS(fsys fs.FS, glob string, decoder Codec) error {
+	names, err := fs.Glob(fsys, glob)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		pairs, err := decoder.Decode(data)
+		if err != nil {
+			return fmt.Errorf("Decoding %s: %v", name, err)
+		}
+
+		base := path.Base(name)
+		table := Cache(strings.TrimSuffix(base, path.Ext(base)))
+		for k, v := range pairs {
+			table.Add(k, 0, v)
+		}
+		table.log("Preloaded", len(pairs), "items from", name, "into table", table.name)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+//go:build go1.16
+// +build go1.16
+
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestPreloadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"seed/testPreloadCountries.json":  {Data: []byte(`{"de": "Germany", "fr": "France"}`)},
+		"seed/testPreloadCurrencies.json": {Data: []byte(`{"eur": "Euro"}`)},
+		"seed/README.md":                  {Data: []byte(`not seed data`)},
+	}
+	Cache("testPreloadCountries").Flush()
+	Cache("testPreloadCurrencies").Flush()
+
+	if err := PreloadFS(fsys, "seed/*.json", JSONCodec{}); err != nil {
+		t.Fatal("Error preloading seed data", err)
+	}
+	if p, err := Cache("testPreloadCountries").Value("de"); err != nil || p.Data() != "Germany" {
+		t.Error("Expected seed data to be preloaded", err)
+	}
+	if n := Cache("testPreloadCountries").Count(); n != 2 {
+		t.Error("Expected 2 countries, got", n)
+	}
+	if n := Cache("testPreloadCurrencies").Count(); n != 1 {
+		t.Error("Expected 1 currency, got", n)
+	}
+
+	if err := PreloadFS(fsys, "seed/*.md", JSONCodec{}); err == nil {
+		t.Error("Expected an error decoding invalid seed data")
+	}
+}
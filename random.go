@@ -0,0 +1,77 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"math/rand"
+	"time"
+)
+
+// RandomPolicy evicts a random item, keeping no metadata apart from the set
+// of keys.
+type RandomPolicy struct {
+	keys    []interface{}
+	indices map[interface{}]int
+	rand    *rand.Rand
+}
+
+// NewRandomPolicy creates a random-replacement eviction policy.
+func NewRandomPolicy() *RandomPolicy {
+	return &RandomPolicy{
+		indices: make(map[interface{}]int),
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Add starts tracking a new item.
+func (p *RandomPolicy) Add(item *CacheItem) {
+	if _, exists := p.indices[item.key]; exists {
+		return
+	}
+	p.indices[item.key] = len(p.keys)
+	p.keys = append(p.keys, item.key)
+}
+
+// Access does nothing: random replacement doesn't care about accesses.
+func (p *RandomPolicy) Access(item *CacheItem) {}
+
+// Remove stops tracking an item.
+func (p *RandomPolicy) Remove(item *CacheItem) {
+	if index, exists := p.indices[item.key]; exists {
+		p.remove(index)
+	}
+}
+
+// Evict drops a random item.
+func (p *RandomPolicy) Evict() (interface{}, bool) {
+	if len(p.keys) == 0 {
+		return nil, false
+	}
+
+	index := p.rand.Intn(len(p.keys))
+	key := p.keys[index]
+	p.remove(index)
+	return key, true
+}
+
+// Reset drops all bookkeeping.
+func (p *RandomPolicy) Reset() {
+	p.keys = nil
+	p.indices = make(map[interface{}]int)
+}
+
+// remove swaps the key at index with the last key and shrinks the key slice.
+func (p *RandomPolicy) remove(index int) {
+	key := p.keys[index]
+	last := len(p.keys) - 1
+	p.keys[index] = p.keys[last]
+	p.indices[p.keys[index]] = index
+	p.keys[last] = nil
+	p.keys = p.keys[:last]
+	delete(p.indices, key)
+}
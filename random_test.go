@@ -0,0 +1,44 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+)
+
+func TestRandomEviction(t *testing.T) {
+	cache := NewBoundedCache("testRandom", 10, NewRandomPolicy())
+
+	evicted := make(map[interface{}]bool)
+	cache.SetAboutToDeleteItemCallback(func(item *CacheItem) {
+		evicted[item.Key()] = true
+	})
+
+	for i := 0; i < 100; i++ {
+		cache.Add(i, 0, i)
+	}
+
+	if cache.Count() != 10 {
+		t.Error("Cache should be filled to capacity")
+	}
+	if len(evicted) != 90 {
+		t.Error("Expected 90 distinct evictions, got", len(evicted))
+	}
+	cache.Foreach(func(key interface{}, item *CacheItem) {
+		if evicted[key] {
+			t.Error("Evicted key still in cache:", key)
+		}
+	})
+
+	p := NewRandomPolicy()
+	p.Add(NewCacheItem("a", 0, nil))
+	p.Remove(NewCacheItem("a", 0, nil))
+	if _, ok := p.Evict(); ok {
+		t.Error("Empty policy should not return a victim")
+	}
+}
@@ -0,0 +1,83 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// lockedSource makes a random source safe for concurrent use.
+type lockedSource struct {
+	sync.Mutex
+	src rand.Source
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.Lock()
+	defer s.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.Lock()
+	defer s.Unlock()
+	s.src.Seed(seed)
+}
+
+// newTableRand returns the random number generator of a table, using the
+// given source, or a time-seeded one if nil.
+func newTableRand(src rand.Source) *rand.Rand {
+	if src == nil {
+		src = rand.NewSource(time.Now().UnixNano())
+	}
+	return rand.New(&lockedSource{src: src})
+}
+
+// WithRandSource makes a table draw all its random decisions from the given
+// source: the items sampled for eviction, including by prefix limits, and
+// lifespan jitter. The same seed and the same sequence of operations then
+// result in the same behavior, e.g. to reproduce it in tests and
+// simulations. The source doesn't need to be safe for concurrent use, but
+// mustn't be shared with other tables.
+func WithRandSource(src rand.Source) Option {
+	return func(o *cacheOptions) {
+		o.randSource = src
+	}
+}
+
+// WithLifeSpanJitter shortens the lifespans of added items by a random share
+// of up to jitter, between 0 and 1, so items added at the same time don't
+// all expire at once and cause a thundering herd of reloads, see
+// ExpiryForecast.
+func WithLifeSpanJitter(jitter float64) Option {
+	return func(o *cacheOptions) {
+		o.lifeSpanJitter = jitter
+	}
+}
+
+// jitter applies the table's lifespan jitter to a lifespan.
+func (table *CacheTable) jitter(lifeSpan time.Duration) time.Duration {
+	if table.options.lifeSpanJitter <= 0 || lifeSpan <= 0 {
+		return lifeSpan
+	}
+	return lifeSpan - time.Duration(table.rand.Float64()*table.options.lifeSpanJitter*float64(lifeSpan))
+}
+
+// SetRandSource makes the policy sample candidates using the given source,
+// e.g. a seeded one for reproducible evictions.
+func (p *SamplingPolicy) SetRandSource(src rand.Source) {
+	p.rand = rand.New(src)
+}
+
+// SetRandSource makes the policy pick victims using the given source, e.g. a
+// seeded one for reproducible evictions.
+func (p *RandomPolicy) SetRandSource(src rand.Source) {
+	p.rand = rand.New(src)
+}
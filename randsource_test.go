@@ -0,0 +1,75 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRandSource(t *testing.T) {
+	// The same seed results in the same evictions.
+	var survivors [2][]bool
+	for run := 0; run < 2; run++ {
+		table := newCacheTable("testRandSource", newCacheOptions(WithCapacity(10), WithRandSource(rand.NewSource(42))))
+		for i := 0; i < 50; i++ {
+			table.Add(strconv.Itoa(i), 0, v)
+		}
+		for i := 0; i < 50; i++ {
+			survivors[run] = append(survivors[run], table.Exists(strconv.Itoa(i)))
+		}
+	}
+	for i := range survivors[0] {
+		if survivors[0][i] != survivors[1][i] {
+			t.Error("Evictions differ with the same random source")
+			break
+		}
+	}
+}
+
+func TestLifeSpanJitter(t *testing.T) {
+	table := newCacheTable("testLifeSpanJitter", newCacheOptions(WithLifeSpanJitter(0.5), WithRandSource(rand.NewSource(42))))
+
+	distinct := make(map[time.Duration]bool)
+	for i := 0; i < 10; i++ {
+		item := table.newItem(k, time.Hour, v)
+		if item.LifeSpan() > time.Hour || item.LifeSpan() < 30*time.Minute {
+			t.Error("Jittered lifespan out of range", item.LifeSpan())
+		}
+		distinct[item.LifeSpan()] = true
+	}
+	if len(distinct) < 2 {
+		t.Error("Lifespans didn't get jittered")
+	}
+	if item := table.newItem(k, 0, v); item.LifeSpan() != 0 {
+		t.Error("Jitter applied to an unlimited lifespan", item.LifeSpan())
+	}
+}
+
+func TestRandomPolicyRandSource(t *testing.T) {
+	var victims [2][]interface{}
+	for run := 0; run < 2; run++ {
+		p := NewRandomPolicy()
+		p.SetRandSource(rand.NewSource(42))
+		for i := 0; i < 20; i++ {
+			p.Add(NewCacheItem(i, 0, v))
+		}
+		for i := 0; i < 5; i++ {
+			key, _ := p.Evict()
+			victims[run] = append(victims[run], key)
+		}
+	}
+	for i := range victims[0] {
+		if victims[0][i] != victims[1][i] {
+			t.Error("Victims differ with the same random source")
+			break
+		}
+	}
+}
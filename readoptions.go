@@ -0,0 +1,56 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"context"
+)
+
+// ReadOptions are directives for a single lookup, see ValueWithOptions.
+type ReadOptions struct {
+	// Skip the cache: load the item via the data-loader without looking it
+	// up or storing it, like SetBypass does for the whole table.
+	Bypass bool
+	// Force a reload: load the item via the data-loader even if it's cached,
+	// and store the result. If loading fails, the cached item is kept.
+	Refresh bool
+}
+
+// ValueWithOptions returns an item from the cache, just like Value, but
+// applies the given directives to this lookup only, e.g. for admin tooling or
+// cache-busting query parameters. Neither directive counts as a hit or a
+// miss. Without a data-loader there's nothing to load, so Bypass returns
+// ErrKeyNotFound and Refresh behaves just like Value.
+func (table *CacheTable) ValueWithOptions(key interface{}, opts ReadOptions, args ...interface{}) (*CacheItem, error) {
+	table.touch()
+	if !opts.Bypass && !opts.Refresh {
+		return table.Value(key, args...)
+	}
+
+	table.faults.delay()
+	table.RLock()
+	loadData := table.faults.loader(table.loadData)
+	table.RUnlock()
+
+	if loadData == nil {
+		if opts.Bypass {
+			return nil, ErrKeyNotFound
+		}
+		return table.Value(key, args...)
+	}
+
+	req := LoadRequest{Key: key, Context: context.Background(), args: args}
+	if !opts.Bypass {
+		return table.load(req, loadData)
+	}
+	item := loadData(&req)
+	if item == nil {
+		return nil, ErrKeyNotFoundOrLoadable
+	}
+	return item, nil
+}
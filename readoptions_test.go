@@ -0,0 +1,65 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+)
+
+func TestValueWithOptions(t *testing.T) {
+	table, _ := CacheWithOptions("testValueWithOptions", WithStats())
+	table.Add(k, 0, "cached")
+
+	if _, err := table.ValueWithOptions(k, ReadOptions{Bypass: true}); err != ErrKeyNotFound {
+		t.Error("Expected ErrKeyNotFound without a data-loader, got", err)
+	}
+	if p, err := table.ValueWithOptions(k, ReadOptions{Refresh: true}); err != nil || p.Data() != "cached" {
+		t.Error("Refresh without a data-loader should return the cached item", err)
+	}
+
+	loads := 0
+	table.SetDataLoader(func(key interface{}, args ...interface{}) *CacheItem {
+		loads++
+		if key == "unloadable" {
+			return nil
+		}
+		return NewCacheItem(key, 0, "loaded")
+	})
+
+	if p, _ := table.ValueWithOptions(k, ReadOptions{}); p.Data() != "cached" {
+		t.Error("Lookups without directives should be served from the cache")
+	}
+	if p, _ := table.ValueWithOptions(k, ReadOptions{Bypass: true}); p.Data() != "loaded" {
+		t.Error("Bypassing lookups should be served by the data-loader")
+	}
+	if p, _ := table.Value(k); p.Data() != "cached" {
+		t.Error("Bypassing lookups should not store their result")
+	}
+
+	if p, _ := table.ValueWithOptions(k, ReadOptions{Refresh: true}); p.Data() != "loaded" {
+		t.Error("Refreshing lookups should be served by the data-loader")
+	}
+	if p, _ := table.Value(k); p.Data() != "loaded" {
+		t.Error("Refreshing lookups should store their result")
+	}
+	if loads != 2 {
+		t.Error("Expected 2 loads, got", loads)
+	}
+
+	table.Add("unloadable", 0, "cached")
+	if _, err := table.ValueWithOptions("unloadable", ReadOptions{Refresh: true}); err != ErrKeyNotFoundOrLoadable {
+		t.Error("Expected ErrKeyNotFoundOrLoadable, got", err)
+	}
+	if p, _ := table.Value("unloadable"); p.Data() != "cached" {
+		t.Error("Failed refreshes should keep the cached item")
+	}
+
+	if stats := table.Stats(); stats.Hits != 5 || stats.Misses != 0 {
+		t.Errorf("Directives should not count as hits or misses, got %+v", stats)
+	}
+}
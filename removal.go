@@ -0,0 +1,81 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"sync/atomic"
+)
+
+// RemovalReason describes why an item was removed from the cache.
+type RemovalReason int
+
+const (
+	// RemovalDeleted means the item was removed via Delete.
+	RemovalDeleted RemovalReason = iota
+	// RemovalExpired means the item exceeded its lifespan.
+	RemovalExpired
+	// RemovalEvicted means the item was removed to make room for others.
+	RemovalEvicted
+	// RemovalReplaced means another item was added with the same key.
+	RemovalReplaced
+	// RemovalFlushed means the whole cache was flushed.
+	RemovalFlushed
+	// RemovalReclaimed means the item was removed under memory pressure,
+	// see WithSoftReferences.
+	RemovalReclaimed
+)
+
+// String returns a human readable name of the removal reason.
+func (r RemovalReason) String() string {
+	switch r {
+	case RemovalDeleted:
+		return "deleted"
+	case RemovalExpired:
+		return "expired"
+	case RemovalEvicted:
+		return "evicted"
+	case RemovalReplaced:
+		return "replaced"
+	case RemovalFlushed:
+		return "flushed"
+	case RemovalReclaimed:
+		return "reclaimed"
+	}
+	return "unknown"
+}
+
+// claimRemoval marks the item as removed from the cache, and returns whether
+// it wasn't already. Racing removals, e.g. a delete whose callbacks are still
+// running while the key gets replaced, thus notify the item's listener and
+// drop the cache's reference only once: whoever claims the removal does.
+// Careful: do not run this method unless the table-mutex is locked!
+func (item *CacheItem) claimRemoval() bool {
+	return atomic.CompareAndSwapUint32(&item.removing, 0, 1)
+}
+
+// removed triggers the callbacks of an item about to be removed from the
+// cache, and drops the cache's reference, releasing the item's data unless
+// there are still readers holding it. The caller must have claimed the
+// removal, see claimRemoval.
+// Careful: do not run this method while holding the table-mutex!
+func (item *CacheItem) removed(reason RemovalReason, aboutToDeleteItem []func(*CacheItem)) {
+	for _, callback := range aboutToDeleteItem {
+		callback(item)
+	}
+	if item.removalListener != nil {
+		item.removalListener(item, reason)
+	}
+
+	item.RLock()
+	for _, callback := range item.aboutToExpire {
+		callback(item.key)
+	}
+	item.RUnlock()
+
+	item.Release()
+}
@@ -0,0 +1,141 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAddWithListener(t *testing.T) {
+	table, _ := CacheWithOptions("testAddWithListener", WithCapacity(1))
+
+	var mutex sync.Mutex
+	reasons := make(map[interface{}]RemovalReason)
+	listener := func(item *CacheItem, reason RemovalReason) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if _, ok := reasons[item.Key()]; ok {
+			t.Error("Listener called more than once for", item.Key())
+		}
+		reasons[item.Key()] = reason
+	}
+
+	table.AddWithListener("deleted", 0, v, listener)
+	table.Delete("deleted")
+
+	table.AddWithListener("expired", 50*time.Millisecond, v, listener)
+	time.Sleep(100 * time.Millisecond)
+
+	table.AddWithListener("replaced", 0, v, listener)
+	table.Add("replaced", 0, v)
+
+	table.AddWithListener("evicted", 0, v, listener)
+	table.Add("other", 0, v)
+
+	table.AddWithListener("flushed", 0, v, listener)
+	table.Flush()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	expected := map[interface{}]RemovalReason{
+		"deleted":  RemovalDeleted,
+		"expired":  RemovalExpired,
+		"replaced": RemovalReplaced,
+		"evicted":  RemovalEvicted,
+		"flushed":  RemovalFlushed,
+	}
+	for key, reason := range expected {
+		if reasons[key] != reason {
+			t.Errorf("Expected %v to be %s, got %s", key, reason, reasons[key])
+		}
+	}
+}
+
+func TestListenerRacingReAdd(t *testing.T) {
+	table := newCacheTable("testListenerRacingReAdd", newCacheOptions())
+
+	var calls int32
+	res := &testResource{}
+	table.AddWithListener("k", 0, res, func(item *CacheItem, reason RemovalReason) {
+		atomic.AddInt32(&calls, 1)
+	})
+
+	// Re-add and delete the key while the delete's callbacks still run.
+	table.SetAboutToDeleteItemCallback(func(item *CacheItem) {
+		if item.Data() == res {
+			table.Add("k", 0, "successor")
+			table.Delete("k")
+		}
+	})
+	if _, err := table.Delete("k"); err != nil {
+		t.Error("Error deleting item", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Error("Expected the listener to be called once, got", n)
+	}
+	if n := res.Released(); n != 1 {
+		t.Error("Expected the value to be released once, got", n)
+	}
+}
+
+func TestListenerConcurrentDeleteReAdd(t *testing.T) {
+	table := newCacheTable("testListenerConcurrentDeleteReAdd", newCacheOptions())
+
+	var mutex sync.Mutex
+	calls := make(map[*CacheItem]int)
+	listener := func(item *CacheItem, reason RemovalReason) {
+		mutex.Lock()
+		calls[item]++
+		mutex.Unlock()
+	}
+	var resources []*testResource
+	for i := 0; i < 200; i++ {
+		res := &testResource{}
+		resources = append(resources, res)
+		table.AddWithListener("k", 0, res, listener)
+	}
+	table.AddWithListener("k", 0, &testResource{}, listener)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				table.Delete("k")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				res := &testResource{}
+				mutex.Lock()
+				resources = append(resources, res)
+				mutex.Unlock()
+				table.AddWithListener("k", 0, res, listener)
+			}
+		}()
+	}
+	wg.Wait()
+	table.Flush()
+
+	for item, n := range calls {
+		if n != 1 {
+			t.Error("Expected the listener of each item to be called once, got", n, "for", item.Data())
+		}
+	}
+	for _, res := range resources {
+		if n := res.Released(); n != 1 {
+			t.Error("Expected each value to be released once, got", n)
+			break
+		}
+	}
+}
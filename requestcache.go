@@ -0,0 +1,187 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"sync"
+	"time"
+)
+
+// RequestCache is a short-lived overlay on top of a shared cache, see
+// NewRequestCache.
+type RequestCache struct {
+	sync.RWMutex
+
+	// The shared cache reads fall through to.
+	parent Cacher
+	// Items added during the request.
+	items map[interface{}]*CacheItem
+	// Keys deleted during the request, hiding the parent's items.
+	deleted map[interface{}]bool
+	// Whether the overlay got flushed, hiding all of the parent's items.
+	flushed bool
+
+	// Usage statistics.
+	stats statsCounter
+}
+
+// NewRequestCache creates an overlay for a single request: reads fall through
+// to parent, while items added or deleted via the overlay only affect the
+// overlay itself. Half-computed values the request stores along the way thus
+// never reach the shared cache, and simply get dropped along with the overlay
+// at the end of the request.
+//
+// Items added to the overlay don't expire, as it's not meant to outlive the
+// request. Loading a missing key still uses and fills the parent, since loaded
+// values are complete.
+func NewRequestCache(parent Cacher) *RequestCache {
+	return &RequestCache{
+		parent:  parent,
+		items:   make(map[interface{}]*CacheItem),
+		deleted: make(map[interface{}]bool),
+	}
+}
+
+// Parent returns the cache the overlay reads through to.
+func (cache *RequestCache) Parent() Cacher {
+	return cache.parent
+}
+
+// hidden returns whether the parent's item with the given key is hidden.
+// Careful: do not run this method unless the cache-mutex is locked!
+func (cache *RequestCache) hidden(key interface{}) bool {
+	return cache.flushed || cache.deleted[key]
+}
+
+// Add adds a key/value pair to the overlay, leaving the parent untouched.
+func (cache *RequestCache) Add(key interface{}, lifeSpan time.Duration, data interface{}) *CacheItem {
+	item := NewCacheItem(key, lifeSpan, data)
+
+	cache.Lock()
+	defer cache.Unlock()
+	cache.items[key] = item
+	delete(cache.deleted, key)
+	cache.stats.add()
+	return item
+}
+
+// Value returns an item added to the overlay, or else the parent's item,
+// unless the overlay deleted it. You can pass additional arguments to the
+// parent's DataLoader callback function.
+func (cache *RequestCache) Value(key interface{}, args ...interface{}) (*CacheItem, error) {
+	cache.RLock()
+	item, exists := cache.items[key]
+	hidden := cache.hidden(key)
+	cache.RUnlock()
+
+	if exists {
+		item.KeepAlive()
+		cache.stats.hit()
+		return item, nil
+	}
+	if hidden {
+		cache.stats.miss()
+		return nil, ErrKeyNotFound
+	}
+
+	item, err := cache.parent.Value(key, args...)
+	if err != nil {
+		cache.stats.miss()
+		return nil, err
+	}
+	cache.stats.hit()
+	return item, nil
+}
+
+// Delete removes an item from the overlay, hiding the parent's item with the
+// same key for the rest of the request without deleting it.
+func (cache *RequestCache) Delete(key interface{}) (*CacheItem, error) {
+	cache.Lock()
+	defer cache.Unlock()
+
+	item, exists := cache.items[key]
+	if !exists && !cache.hidden(key) {
+		item, exists = peek(cache.parent, key)
+	}
+	if !exists {
+		return nil, ErrKeyNotFound
+	}
+
+	delete(cache.items, key)
+	if !cache.flushed {
+		cache.deleted[key] = true
+	}
+	cache.stats.delete()
+	return item, nil
+}
+
+// Exists returns whether an item is visible through the overlay.
+func (cache *RequestCache) Exists(key interface{}) bool {
+	cache.RLock()
+	defer cache.RUnlock()
+
+	if _, exists := cache.items[key]; exists {
+		return true
+	}
+	return !cache.hidden(key) && cache.parent.Exists(key)
+}
+
+// Count returns how many items are visible through the overlay.
+func (cache *RequestCache) Count() int {
+	count := 0
+	cache.Foreach(func(key interface{}, item *CacheItem) {
+		count++
+	})
+	return count
+}
+
+// Foreach iterates over all items visible through the overlay, its own ones
+// first.
+func (cache *RequestCache) Foreach(trans func(key interface{}, item *CacheItem)) {
+	cache.RLock()
+	defer cache.RUnlock()
+
+	for k, v := range cache.items {
+		trans(k, v)
+	}
+	if cache.flushed {
+		return
+	}
+	cache.parent.Foreach(func(k interface{}, v *CacheItem) {
+		if _, shadowed := cache.items[k]; !shadowed && !cache.deleted[k] {
+			trans(k, v)
+		}
+	})
+}
+
+// Flush removes all items from the overlay and hides all of the parent's
+// items for the rest of the request, without flushing the parent.
+func (cache *RequestCache) Flush() {
+	cache.Lock()
+	defer cache.Unlock()
+
+	cache.items = make(map[interface{}]*CacheItem)
+	cache.deleted = make(map[interface{}]bool)
+	cache.flushed = true
+}
+
+// Discard drops everything the request added or deleted, making the parent's
+// items visible again.
+func (cache *RequestCache) Discard() {
+	cache.Lock()
+	defer cache.Unlock()
+
+	cache.items = make(map[interface{}]*CacheItem)
+	cache.deleted = make(map[interface{}]bool)
+	cache.flushed = false
+}
+
+// Stats returns the usage statistics of the overlay.
+func (cache *RequestCache) Stats() CacheStats {
+	return cache.stats.snapshot()
+}
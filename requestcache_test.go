@@ -0,0 +1,59 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+)
+
+func TestRequestCache(t *testing.T) {
+	parent, _ := CacheWithOptions("testRequestCache")
+	parent.Flush()
+	parent.Add("shared", 0, "parent")
+	parent.Add("gone", 0, "parent")
+
+	rc := NewRequestCache(parent)
+	rc.Add("local", 0, "partial")
+	rc.Add("shared", 0, "overridden")
+	if _, err := rc.Delete("gone"); err != nil {
+		t.Error("Error deleting parent's item via overlay", err)
+	}
+
+	if p, err := rc.Value("shared"); err != nil || p.Data() != "overridden" {
+		t.Error("Overlay should prefer its own items", err)
+	}
+	if _, err := rc.Value("gone"); err != ErrKeyNotFound {
+		t.Error("Overlay should hide deleted items, got", err)
+	}
+	if rc.Count() != 2 || !rc.Exists("local") || rc.Exists("gone") {
+		t.Error("Unexpected items visible through overlay", rc.Count())
+	}
+
+	// The parent is untouched.
+	if parent.Count() != 2 || parent.Exists("local") {
+		t.Error("Overlay writes should not reach the parent")
+	}
+	if p, err := parent.Value("shared"); err != nil || p.Data() != "parent" {
+		t.Error("Parent's item should be unchanged", err)
+	}
+
+	// Items added to the parent later show through.
+	parent.Add("later", 0, "parent")
+	if p, err := rc.Value("later"); err != nil || p.Data() != "parent" {
+		t.Error("Overlay should read through to the parent", err)
+	}
+
+	rc.Flush()
+	if rc.Count() != 0 || rc.Exists("shared") || parent.Count() != 3 {
+		t.Error("Flushing the overlay should hide the parent's items only")
+	}
+	rc.Discard()
+	if rc.Count() != 3 || rc.Exists("local") {
+		t.Error("Discarding should drop the request's writes", rc.Count())
+	}
+}
@@ -0,0 +1,84 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"time"
+)
+
+// Validator holds the metadata a data source needs to tell whether a cached
+// value is still up to date, like an HTTP ETag or Last-Modified date.
+type Validator struct {
+	ETag         string
+	LastModified time.Time
+}
+
+// isZero returns whether the validator carries no metadata at all.
+func (v Validator) isZero() bool {
+	return v.ETag == "" && v.LastModified.IsZero()
+}
+
+// SetValidator attaches revalidation metadata to the item, see
+// CacheTable.SetRevalidator.
+func (item *CacheItem) SetValidator(v Validator) {
+	item.Lock()
+	defer item.Unlock()
+	item.validator = v
+}
+
+// Validator returns the item's revalidation metadata.
+func (item *CacheItem) Validator() Validator {
+	item.RLock()
+	defer item.RUnlock()
+	return item.validator
+}
+
+// SetRevalidator configures a callback, which will be called when an item
+// carrying a Validator exceeds its lifespan. Instead of dropping the item
+// right away, the callback gets passed the item's key and metadata and can
+// either answer "not modified" by returning true, which keeps the item for
+// another lifespan without transferring its value again, or return a fresh
+// item replacing the stale one. If it returns neither, the item expires.
+// The stale item keeps being served while the callback runs.
+func (table *CacheTable) SetRevalidator(f func(key interface{}, validator Validator) (*CacheItem, bool)) {
+	table.Lock()
+	defer table.Unlock()
+	table.revalidate = f
+}
+
+// revalidateItem runs the revalidator for an expired item and applies its
+// answer.
+func (table *CacheTable) revalidateItem(key interface{}, item *CacheItem, revalidate func(interface{}, Validator) (*CacheItem, bool)) {
+	fresh, notModified := revalidate(key, item.Validator())
+
+	switch {
+	case notModified:
+		item.Lock()
+		item.setAccessedOn(time.Now())
+		item.revalidating = false
+		item.Unlock()
+
+		table.log("Revalidated item with key", key, "in table", table.name)
+		table.expirationCheck()
+
+	case fresh != nil:
+		table.Lock()
+		fresh.key = key
+		fresh.source = SourceRevalidator
+		table.addInternal(fresh)
+
+	default:
+		table.Lock()
+		if table.items[key] == item {
+			if _, err := table.deleteInternal(key, RemovalExpired); err == nil {
+				table.stats.expire()
+			}
+		}
+		table.Unlock()
+	}
+}
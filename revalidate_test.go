@@ -0,0 +1,56 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRevalidator(t *testing.T) {
+	table := Cache("testRevalidator")
+
+	var calls int32
+	table.SetRevalidator(func(key interface{}, validator Validator) (*CacheItem, bool) {
+		atomic.AddInt32(&calls, 1)
+		switch key {
+		case "unchanged":
+			return nil, validator.ETag == "v1"
+		case "changed":
+			item := NewCacheItem(key, time.Minute, "new value")
+			item.SetValidator(Validator{ETag: "v2"})
+			return item, false
+		}
+		return nil, false
+	})
+
+	for _, key := range []string{"unchanged", "changed", "gone"} {
+		item := table.Add(key, 50*time.Millisecond, "old value")
+		item.SetValidator(Validator{ETag: "v1"})
+	}
+	table.Add("plain", 50*time.Millisecond, "old value")
+
+	time.Sleep(120 * time.Millisecond)
+
+	if atomic.LoadInt32(&calls) < 3 {
+		t.Error("Expected every item with a validator to be revalidated, got", atomic.LoadInt32(&calls))
+	}
+	if p, err := table.Value("unchanged"); err != nil || p.Data().(string) != "old value" {
+		t.Error("Not modified item should be kept", err)
+	}
+	if p, err := table.Value("changed"); err != nil || p.Data().(string) != "new value" || p.Validator().ETag != "v2" {
+		t.Error("Changed item should be replaced", err)
+	}
+	if table.Exists("gone") {
+		t.Error("Item should expire if the revalidator has no answer")
+	}
+	if table.Exists("plain") {
+		t.Error("Items without validator should expire as usual")
+	}
+}
@@ -0,0 +1,118 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"time"
+)
+
+// UnaryCache caches the responses of idempotent unary RPCs, e.g. of a gRPC
+// server, see NewUnaryCache.
+type UnaryCache struct {
+	table *CacheTable
+	// Lifespan of the responses per full method name.
+	lifeSpans map[string]time.Duration
+	// Returns the scope responses may be shared in.
+	scope func(ctx context.Context) (string, bool)
+	// Encodes requests for hashing.
+	encode func(req interface{}) ([]byte, error)
+}
+
+// UnaryCacheOption configures a UnaryCache.
+type UnaryCacheOption func(*UnaryCache)
+
+// WithRequestEncoder makes the cache hash requests encoded by encode, e.g.
+// proto.Marshal with deterministic output, instead of their JSON encoding.
+func WithRequestEncoder(encode func(req interface{}) ([]byte, error)) UnaryCacheOption {
+	return func(c *UnaryCache) {
+		c.encode = encode
+	}
+}
+
+// unaryKey identifies a response by its method, the scope it's shared in and
+// the request.
+type unaryKey struct {
+	method string
+	scope  string
+	req    [sha256.Size]byte
+}
+
+// NewUnaryCache returns a cache for the responses of the given methods, keyed
+// by their full names, e.g. "/users.Users/GetUser", storing them in table for
+// the configured lifespans. Responses of other methods don't get cached.
+//
+// Responses are only shared by calls in the same scope, returned by scope
+// from the call's context, e.g. the authenticated user or tenant, or an empty
+// string for responses that are the same for everyone. Calls get passed on
+// uncached if scope returns false, e.g. for unauthenticated callers, or if
+// it's nil.
+//
+// It doesn't depend on a gRPC implementation. To use it as a gRPC unary
+// server interceptor, pass its Intercept method on:
+//
+//	grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+//		return c.Intercept(ctx, info.FullMethod, req, handler)
+//	})
+func NewUnaryCache(table *CacheTable, lifeSpans map[string]time.Duration, scope func(ctx context.Context) (string, bool), opts ...UnaryCacheOption) *UnaryCache {
+	c := &UnaryCache{
+		table:     table,
+		lifeSpans: make(map[string]time.Duration, len(lifeSpans)),
+		scope:     scope,
+		encode:    json.Marshal,
+	}
+	for method, lifeSpan := range lifeSpans {
+		c.lifeSpans[method] = lifeSpan
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Intercept returns the cached response to the request of the given method,
+// or calls handler and caches its response. Concurrent calls missing the same
+// response wait for the first one's handler instead of calling their own.
+// Failed calls don't get cached. Responses are shared by all callers in the
+// same scope, so neither they nor the handler may modify them once returned.
+func (c *UnaryCache) Intercept(ctx context.Context, method string, req interface{}, handler func(ctx context.Context, req interface{}) (interface{}, error)) (interface{}, error) {
+	lifeSpan, ok := c.lifeSpans[method]
+	if !ok || c.scope == nil {
+		return handler(ctx, req)
+	}
+	scope, ok := c.scope(ctx)
+	if !ok {
+		return handler(ctx, req)
+	}
+	b, err := c.encode(req)
+	if err != nil {
+		// Requests we can't tell apart mustn't share responses.
+		c.table.log("Not caching response of", method, "to unencodable request:", err)
+		return handler(ctx, req)
+	}
+
+	key := unaryKey{method: method, scope: scope, req: sha256.Sum256(b)}
+	if item, err := c.table.Value(key); err == nil {
+		return item.Data(), nil
+	}
+
+	unlock := c.table.KeyLock(key)
+	defer unlock()
+	// Another call may have cached the response while we were waiting.
+	if item, err := c.table.Value(key); err == nil {
+		return item.Data(), nil
+	}
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	c.table.Add(key, lifeSpan, resp)
+	return resp, nil
+}
@@ -0,0 +1,133 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testUserRequest struct {
+	ID int
+}
+
+type testCallerKey struct{}
+
+// testCallerScope scopes responses to the caller stored in the context.
+func testCallerScope(ctx context.Context) (string, bool) {
+	caller, ok := ctx.Value(testCallerKey{}).(string)
+	return caller, ok
+}
+
+func TestUnaryCache(t *testing.T) {
+	table, _ := CacheWithOptions("testUnaryCache")
+	table.Flush()
+
+	calls := 0
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		if req.(testUserRequest).ID < 0 {
+			return nil, errors.New("invalid ID")
+		}
+		return req.(testUserRequest).ID * 10, nil
+	}
+	c := NewUnaryCache(table, map[string]time.Duration{"/users.Users/GetUser": time.Minute}, testCallerScope)
+	ctx := context.WithValue(context.Background(), testCallerKey{}, "alice")
+
+	for i := 0; i < 2; i++ {
+		for _, id := range []int{1, 2} {
+			resp, err := c.Intercept(ctx, "/users.Users/GetUser", testUserRequest{id}, handler)
+			if err != nil || resp != id*10 {
+				t.Error("Unexpected response", resp, err)
+			}
+		}
+	}
+	if calls != 2 {
+		t.Error("Expected responses to be cached per request, got", calls, "calls")
+	}
+
+	for i := 0; i < 2; i++ {
+		c.Intercept(ctx, "/users.Users/DeleteUser", testUserRequest{1}, handler)
+		if _, err := c.Intercept(ctx, "/users.Users/GetUser", testUserRequest{-1}, handler); err == nil {
+			t.Error("Expected the handler's error")
+		}
+	}
+	if calls != 6 {
+		t.Error("Expected other methods and errors not to be cached, got", calls, "calls")
+	}
+}
+
+func TestUnaryCacheScope(t *testing.T) {
+	table, _ := CacheWithOptions("testUnaryCacheScope")
+	table.Flush()
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		caller, _ := testCallerScope(ctx)
+		return "profile of " + caller, nil
+	}
+	ttls := map[string]time.Duration{"/users.Users/GetProfile": time.Minute}
+	c := NewUnaryCache(table, ttls, testCallerScope)
+
+	alice := context.WithValue(context.Background(), testCallerKey{}, "alice")
+	bob := context.WithValue(context.Background(), testCallerKey{}, "bob")
+	c.Intercept(alice, "/users.Users/GetProfile", testUserRequest{}, handler)
+	if resp, _ := c.Intercept(bob, "/users.Users/GetProfile", testUserRequest{}, handler); resp != "profile of bob" {
+		t.Error("Responses should not be shared between callers, got", resp)
+	}
+
+	// Calls without a scope aren't cached.
+	c.Intercept(context.Background(), "/users.Users/GetProfile", testUserRequest{}, handler)
+	if table.Count() != 2 {
+		t.Error("Expected 2 cached responses, got", table.Count())
+	}
+	c = NewUnaryCache(table, ttls, nil)
+	c.Intercept(alice, "/users.Users/GetProfile", testUserRequest{ID: 1}, handler)
+	if table.Count() != 2 {
+		t.Error("Responses should not be cached without a scope function")
+	}
+}
+
+func TestUnaryCacheConcurrentMisses(t *testing.T) {
+	table, _ := CacheWithOptions("testUnaryCacheConcurrentMisses")
+	table.Flush()
+
+	var calls int32
+	release := make(chan struct{})
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "response", nil
+	}
+	c := NewUnaryCache(table, map[string]time.Duration{"/users.Users/GetUser": time.Minute}, testCallerScope)
+	ctx := context.WithValue(context.Background(), testCallerKey{}, "alice")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if resp, err := c.Intercept(ctx, "/users.Users/GetUser", testUserRequest{1}, handler); err != nil || resp != "response" {
+				t.Error("Unexpected response", resp, err)
+			}
+		}()
+	}
+	for atomic.LoadInt32(&calls) < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Error("Concurrent misses should share a call, got", n, "calls")
+	}
+}
@@ -0,0 +1,165 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Sink is a SnapshotSink storing snapshots as objects in an S3 bucket. It
+// works with any S3-compatible object storage, like MinIO or Ceph, as it
+// addresses buckets path-style and signs requests with AWS Signature V4.
+type S3Sink struct {
+	// The storage's base URL, e.g. https://s3.eu-west-1.amazonaws.com.
+	Endpoint string
+	// The region the bucket is located in, e.g. eu-west-1.
+	Region string
+	// The bucket to store snapshots in.
+	Bucket string
+	// Prepended to the snapshot names to form the object keys.
+	Prefix string
+	// Credentials used to sign the requests.
+	AccessKeyID     string
+	SecretAccessKey string
+	// The client used to send the requests, http.DefaultClient if nil.
+	Client *http.Client
+}
+
+// Save uploads the snapshot.
+func (s *S3Sink) Save(name string, r io.Reader) error {
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do("PUT", name, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Saving snapshot %s failed: %s", name, resp.Status)
+	}
+	return nil
+}
+
+// Load downloads the snapshot.
+func (s *S3Sink) Load(name string) (io.ReadCloser, error) {
+	resp, err := s.do("GET", name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrSnapshotNotFound
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("Loading snapshot %s failed: %s", name, resp.Status)
+	}
+}
+
+// do sends a signed request for the snapshot's object.
+func (s *S3Sink) do(method, name string, body []byte) (*http.Response, error) {
+	u := strings.TrimRight(s.Endpoint, "/") + "/" + url.PathEscape(s.Bucket)
+	for _, segment := range strings.Split(s.Prefix+name, "/") {
+		u += "/" + url.PathEscape(segment)
+	}
+
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	signV4(req, body, s.AccessKeyID, s.SecretAccessKey, s.Region, time.Now())
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// signV4 signs a request for S3 with AWS Signature Version 4, covering the
+// host and all headers set on the request.
+func signV4(req *http.Request, body []byte, accessKeyID, secretAccessKey, region string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders bytes.Buffer
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func sha256Hex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,182 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"math/rand"
+	"time"
+)
+
+// SamplingMode determines how SamplingPolicy ranks sampled candidates.
+type SamplingMode int
+
+const (
+	// SampleLRU evicts the sampled item that has been idle the longest.
+	SampleLRU SamplingMode = iota
+	// SampleLFU evicts the sampled item that has been accessed least often.
+	SampleLFU
+	// SampleCostLFU evicts the sampled item whose accesses saved the least
+	// loading time, weighting its access count by its load cost, see
+	// CacheItem.LoadCost. Items that weren't loaded get evicted first.
+	SampleCostLFU
+)
+
+// samplingEntry is the per-key metadata kept by SamplingPolicy.
+type samplingEntry struct {
+	key  interface{}
+	item *CacheItem
+	// Logical time of the last access.
+	lastAccess uint64
+	frequency  uint32
+	// Position in the policy's key slice.
+	index int
+}
+
+// SamplingPolicy implements approximate LRU/LFU eviction the way Redis does:
+// instead of maintaining ordered structures, it picks a handful of random
+// items on eviction and drops the best candidate among them. It keeps only a
+// few words of metadata per item and does no list manipulation on access.
+type SamplingPolicy struct {
+	samples int
+	mode    SamplingMode
+
+	// Whether candidates get ranked by their items' own access metadata,
+	// which doesn't need accesses to be reported.
+	itemRanked bool
+
+	clock   uint64
+	keys    []*samplingEntry
+	entries map[interface{}]*samplingEntry
+	rand    *rand.Rand
+}
+
+// NewSamplingPolicy creates a sampling eviction policy that looks at the given
+// number of random items per eviction; Redis defaults to 5. More samples
+// approximate exact LRU/LFU more closely at the cost of slower evictions.
+func NewSamplingPolicy(samples int, mode SamplingMode) *SamplingPolicy {
+	if samples < 1 {
+		samples = 1
+	}
+
+	return &SamplingPolicy{
+		samples: samples,
+		mode:    mode,
+		entries: make(map[interface{}]*samplingEntry),
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// newItemSamplingPolicy creates a sampling eviction policy that ranks
+// candidates by their items' access timestamps and counts, so Access doesn't
+// need to be called on cache hits. Tables use it to serve hits without
+// locking the policy.
+func newItemSamplingPolicy(samples int, mode SamplingMode) *SamplingPolicy {
+	p := NewSamplingPolicy(samples, mode)
+	p.itemRanked = true
+	return p
+}
+
+// Add starts tracking a new item.
+func (p *SamplingPolicy) Add(item *CacheItem) {
+	if _, exists := p.entries[item.key]; exists {
+		p.Access(item)
+		return
+	}
+
+	p.clock++
+	entry := &samplingEntry{
+		key:        item.key,
+		item:       item,
+		lastAccess: p.clock,
+		frequency:  1,
+		index:      len(p.keys),
+	}
+	p.keys = append(p.keys, entry)
+	p.entries[item.key] = entry
+}
+
+// Access updates an item's access time and frequency.
+func (p *SamplingPolicy) Access(item *CacheItem) {
+	entry, exists := p.entries[item.key]
+	if !exists {
+		return
+	}
+
+	p.clock++
+	entry.lastAccess = p.clock
+	entry.frequency++
+}
+
+// Remove stops tracking an item.
+func (p *SamplingPolicy) Remove(item *CacheItem) {
+	if entry, exists := p.entries[item.key]; exists {
+		p.remove(entry)
+	}
+}
+
+// Evict samples random items and drops the best candidate among them.
+func (p *SamplingPolicy) Evict() (interface{}, bool) {
+	if len(p.keys) == 0 {
+		return nil, false
+	}
+
+	var victim *samplingEntry
+	for i := 0; i < p.samples; i++ {
+		candidate := p.keys[p.rand.Intn(len(p.keys))]
+		if victim == nil || p.better(candidate, victim) {
+			victim = candidate
+		}
+	}
+
+	p.remove(victim)
+	return victim.key, true
+}
+
+// Reset drops all bookkeeping.
+func (p *SamplingPolicy) Reset() {
+	p.keys = nil
+	p.entries = make(map[interface{}]*samplingEntry)
+}
+
+// better returns whether a is a better eviction candidate than b.
+func (p *SamplingPolicy) better(a, b *samplingEntry) bool {
+	if p.mode == SampleCostLFU {
+		if ac, bc := p.savedCost(a), p.savedCost(b); ac != bc {
+			return ac < bc
+		}
+	}
+	if p.itemRanked {
+		if p.mode == SampleLFU && a.item.AccessCount() != b.item.AccessCount() {
+			return a.item.AccessCount() < b.item.AccessCount()
+		}
+		return a.item.AccessedOn().Before(b.item.AccessedOn())
+	}
+	if p.mode == SampleLFU && a.frequency != b.frequency {
+		return a.frequency < b.frequency
+	}
+	return a.lastAccess < b.lastAccess
+}
+
+// savedCost returns how much loading time an entry's accesses saved.
+func (p *SamplingPolicy) savedCost(entry *samplingEntry) float64 {
+	frequency := float64(entry.frequency)
+	if p.itemRanked {
+		frequency = float64(entry.item.AccessCount() + 1)
+	}
+	return frequency * float64(entry.item.LoadCost())
+}
+
+// remove swaps an entry with the last key and shrinks the key slice.
+func (p *SamplingPolicy) remove(entry *samplingEntry) {
+	last := p.keys[len(p.keys)-1]
+	p.keys[entry.index] = last
+	last.index = entry.index
+	p.keys[len(p.keys)-1] = nil
+	p.keys = p.keys[:len(p.keys)-1]
+	delete(p.entries, entry.key)
+}
@@ -0,0 +1,76 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+)
+
+func TestSamplingLRU(t *testing.T) {
+	cache := NewBoundedCache("testSamplingLRU", 100, NewSamplingPolicy(10, SampleLRU))
+
+	for i := 0; i < 100; i++ {
+		cache.Add(i, 0, i)
+	}
+	// Keep the upper half of the keys busy
+	for i := 50; i < 100; i++ {
+		cache.Value(i)
+	}
+
+	for i := 100; i < 125; i++ {
+		cache.Add(i, 0, i)
+	}
+
+	// Sampling is approximate, but recently used keys should mostly survive
+	survivors := 0
+	for i := 50; i < 100; i++ {
+		if cache.Exists(i) {
+			survivors++
+		}
+	}
+	if survivors < 45 {
+		t.Error("Too many recently used items got evicted, survivors:", survivors)
+	}
+	if cache.Count() != 100 {
+		t.Error("Cache should be filled to capacity")
+	}
+}
+
+func TestSamplingLFU(t *testing.T) {
+	p := NewSamplingPolicy(5, SampleLFU)
+
+	hot := NewCacheItem("hot", 0, nil)
+	cold := NewCacheItem("cold", 0, nil)
+	p.Add(hot)
+	p.Add(cold)
+	p.Access(hot)
+
+	// With a single candidate left, sampling always finds it
+	p.Remove(hot)
+	if key, ok := p.Evict(); !ok || key != "cold" {
+		t.Error("Expected cold to be evicted, got", key)
+	}
+	if _, ok := p.Evict(); ok {
+		t.Error("Empty policy should not return a victim")
+	}
+
+	// Among two candidates, 5 samples pick the colder one most of the time
+	coldEvictions := 0
+	for i := 0; i < 100; i++ {
+		p.Add(hot)
+		p.Add(cold)
+		p.Access(hot)
+		if key, _ := p.Evict(); key == "cold" {
+			coldEvictions++
+		}
+		p.Reset()
+	}
+	if coldEvictions < 80 {
+		t.Error("Sampling LFU should prefer the less frequently used item:", coldEvictions)
+	}
+}
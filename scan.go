@@ -0,0 +1,82 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"container/list"
+)
+
+// probation is the segment new items of a scan-resistant table start in,
+// until they get accessed again.
+type probation struct {
+	size int
+	// Items on probation, oldest first. Items that got promoted or removed
+	// from the table are only dropped once they reach the front.
+	items *list.List
+}
+
+func newProbation(size int) *probation {
+	return &probation{
+		size:  size,
+		items: list.New(),
+	}
+}
+
+// WithScanResistance makes a table with a capacity keep new items on
+// probation: up to size of them are held in a separate segment, and only get
+// promoted to the main store once they're accessed again. While the segment
+// is full, making room evicts the oldest item on probation rather than one
+// from the main store, so one-off bulk scans, e.g. exports or crawlers, don't
+// wipe out the working set. Items on probation that drop out of the full
+// segment without getting evicted join the main store.
+func WithScanResistance(size int) Option {
+	return func(o *cacheOptions) {
+		o.probationSize = size
+	}
+}
+
+// probateInternal puts a new item on probation, if the table is
+// scan-resistant.
+// Careful: do not run this method unless the table-mutex is locked!
+func (table *CacheTable) probateInternal(item *CacheItem) {
+	p := table.probation
+	if p == nil {
+		return
+	}
+
+	p.items.PushBack(item)
+	for p.items.Len() > p.size {
+		p.items.Remove(p.items.Front())
+	}
+}
+
+// probationVictimInternal picks the oldest item still on probation for
+// eviction, once the segment is full.
+// Careful: do not run this method unless the table-mutex is locked!
+func (table *CacheTable) probationVictimInternal() (interface{}, bool) {
+	p := table.probation
+	if p == nil {
+		return nil, false
+	}
+
+	for e := p.items.Front(); e != nil; e = p.items.Front() {
+		item := e.Value.(*CacheItem)
+		if table.items[item.key] != item || item.AccessCount() > 0 {
+			// Removed from the table, or promoted to the main store.
+			p.items.Remove(e)
+			continue
+		}
+		if p.items.Len() < p.size {
+			return nil, false
+		}
+
+		p.items.Remove(e)
+		return item.key, true
+	}
+	return nil, false
+}
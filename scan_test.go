@@ -0,0 +1,52 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestScanResistance(t *testing.T) {
+	table, _ := CacheWithOptions("testScanResistance", WithCapacity(20), WithScanResistance(5))
+
+	// The working set gets accessed again, promoting it to the main store.
+	for i := 0; i < 15; i++ {
+		key := "hot" + strconv.Itoa(i)
+		table.Add(key, 0, v)
+		table.Value(key)
+	}
+
+	// A scan adds many items that are never accessed again.
+	for i := 0; i < 1000; i++ {
+		table.Add("scan"+strconv.Itoa(i), 0, v)
+	}
+
+	if table.Count() != 20 {
+		t.Error("Expected 20 items, got", table.Count())
+	}
+	for i := 0; i < 15; i++ {
+		if !table.Exists("hot" + strconv.Itoa(i)) {
+			t.Error("Scan evicted working set item", i)
+		}
+	}
+	for i := 995; i < 1000; i++ {
+		if !table.Exists("scan" + strconv.Itoa(i)) {
+			t.Error("Expected the most recent scanned item", i, "to be on probation")
+		}
+	}
+
+	// Items on probation get promoted once accessed again.
+	table.Value("scan999")
+	for i := 1000; i < 1010; i++ {
+		table.Add("scan"+strconv.Itoa(i), 0, v)
+	}
+	if !table.Exists("scan999") {
+		t.Error("Promoted item got evicted from probation")
+	}
+}
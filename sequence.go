@@ -0,0 +1,64 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"context"
+	"time"
+)
+
+// Seq returns the item's sequence number, 0 if none, see CacheTable.AddSeq.
+func (item *CacheItem) Seq() uint64 {
+	// immutable
+	return item.seq
+}
+
+// AddSeq adds a key/value pair to the cache, just like Add, tagged with a
+// sequence number, e.g. the version or commit position of the value in the
+// source of truth. The item is dropped as stale if the cached item or an
+// invalidation of the key carries a higher sequence number, see Invalidate.
+// Returns whether the item was added.
+func (table *CacheTable) AddSeq(key interface{}, seq uint64, lifeSpan time.Duration, data interface{}) (*CacheItem, bool) {
+	item := table.newItem(key, lifeSpan, data)
+	item.seq = seq
+
+	table.Lock()
+	if !table.addInternal(item) {
+		return item, false
+	}
+
+	table.audit(context.Background(), auditOpAdd, key)
+	return item, true
+}
+
+// Invalidate deletes an item, just like Delete, as of the given sequence
+// number, e.g. one received along with an invalidation message from other
+// instances. Adds of the key carrying a sequence number up to seq get dropped
+// as stale afterwards, so a slow writer can't resurrect the invalidated
+// value. This requires the table to keep tombstones, see WithTombstones; the
+// window should exceed how long adds may be in flight.
+func (table *CacheTable) Invalidate(key interface{}, seq uint64) {
+	table.Lock()
+	_, err := table.deleteTombstoned(key, seq)
+	table.Unlock()
+	if err != nil {
+		return
+	}
+
+	table.stats.delete()
+	table.audit(context.Background(), auditOpDelete, key)
+}
+
+// SetSequencer configures a callback returning the sequence number of items
+// being added without one, e.g. from a version field of their value, so
+// plain Add and the data-loader get the same protection as AddSeq.
+func (table *CacheTable) SetSequencer(f func(item *CacheItem) uint64) {
+	table.Lock()
+	defer table.Unlock()
+	table.sequencer = f
+}
@@ -0,0 +1,96 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAddSeq(t *testing.T) {
+	table, _ := CacheWithOptions("testAddSeq", WithTombstones(time.Minute, false))
+	dropped := 0
+	table.SetResurrectedItemCallback(func(item *CacheItem) {
+		dropped++
+	})
+
+	if _, ok := table.AddSeq(k, 5, 0, "v5"); !ok {
+		t.Error("Expected item to be added")
+	}
+	if _, ok := table.AddSeq(k, 3, 0, "v3"); ok {
+		t.Error("Items older than the cached one should be dropped")
+	}
+
+	table.Invalidate(k, 7)
+	if table.Exists(k) {
+		t.Error("Invalidated item should be deleted")
+	}
+	if _, ok := table.AddSeq(k, 6, 0, "v6"); ok {
+		t.Error("Items older than the invalidation should be dropped")
+	}
+	if dropped != 2 {
+		t.Error("Expected 2 dropped items, got", dropped)
+	}
+
+	// An invalidation arriving out of order doesn't lower the bar.
+	table.Invalidate(k, 4)
+	if _, ok := table.AddSeq(k, 7, 0, "v7"); ok {
+		t.Error("Items as old as the invalidation should be dropped")
+	}
+	if item, ok := table.AddSeq(k, 8, 0, "v8"); !ok || item.Seq() != 8 {
+		t.Error("Items newer than the invalidation should be added")
+	}
+	if table.Tombstoned(k) {
+		t.Error("Newer items should clear the tombstone")
+	}
+	if dropped != 3 {
+		t.Error("Newer items should not count as resurrections, got", dropped)
+	}
+}
+
+func TestInvalidateRacingAdd(t *testing.T) {
+	table, _ := CacheWithOptions("testInvalidateRacingAdd", WithTombstones(time.Minute, false))
+	table.Flush()
+	table.AddSeq(k, 5, 0, "v5")
+
+	// A slow writer's add arriving while the removal callbacks run.
+	var added bool
+	table.SetAboutToDeleteItemCallback(func(item *CacheItem) {
+		_, added = table.AddSeq(k, 6, 0, "v6")
+	})
+	table.Invalidate(k, 7)
+	table.RemoveAboutToDeleteItemCallback()
+
+	if added || table.Exists(k) {
+		t.Error("Adds older than a running invalidation should be dropped")
+	}
+}
+
+func TestSequencer(t *testing.T) {
+	type versioned struct {
+		version uint64
+	}
+
+	table, _ := CacheWithOptions("testSequencer", WithTombstones(time.Minute, false))
+	table.SetSequencer(func(item *CacheItem) uint64 {
+		if v, ok := item.Data().(versioned); ok {
+			return v.version
+		}
+		return 0
+	})
+
+	table.Invalidate(k, 10)
+	table.Add(k, 0, versioned{9})
+	if table.Exists(k) {
+		t.Error("Stale item should be dropped")
+	}
+	table.Add(k, 0, versioned{11})
+	if item, err := table.Value(k); err != nil || item.Seq() != 11 {
+		t.Error("Item should be added with its sequence number", err)
+	}
+}
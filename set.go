@@ -0,0 +1,145 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// cacheSet is the data of a set item.
+type cacheSet struct {
+	sync.RWMutex
+
+	members map[interface{}]struct{}
+}
+
+func newCacheSet() *cacheSet {
+	return &cacheSet{members: make(map[interface{}]struct{})}
+}
+
+// MarshalJSON exports a set as its members, in no particular order, e.g. for
+// Export.
+func (s *cacheSet) MarshalJSON() ([]byte, error) {
+	s.RLock()
+	defer s.RUnlock()
+	return json.Marshal(s.membersInternal())
+}
+
+// membersInternal returns the members in no particular order.
+// Careful: do not run this method unless the set-mutex is locked!
+func (s *cacheSet) membersInternal() []interface{} {
+	members := make([]interface{}, 0, len(s.members))
+	for member := range s.members {
+		members = append(members, member)
+	}
+	return members
+}
+
+// AddSet adds an empty set to the cache, replacing any existing item with the
+// same key. Parameter lifeSpan works just like for Add; adding and removing
+// members counts as an access. Members need to be usable as map keys.
+//
+// Sets get modified in place, so adding and removing members isn't recorded
+// in mutation logs and automatic snapshots.
+func (table *CacheTable) AddSet(key interface{}, lifeSpan time.Duration) *CacheItem {
+	return table.Add(key, lifeSpan, newCacheSet())
+}
+
+// SAdd adds a member to a set and returns whether it wasn't a member before.
+// If the key doesn't exist, a set with the table's default lifespan gets
+// added, see AddSet.
+func (table *CacheTable) SAdd(key interface{}, member interface{}) (bool, error) {
+	item := table.valueOrAdd(key, func() interface{} {
+		return newCacheSet()
+	})
+
+	s, ok := item.Data().(*cacheSet)
+	if !ok {
+		return false, ErrNotASet
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if _, exists := s.members[member]; exists {
+		return false, nil
+	}
+	s.members[member] = struct{}{}
+	return true, nil
+}
+
+// SRem removes a member from a set and returns whether it was a member.
+// Empty sets stay in the cache until they expire or get deleted.
+func (table *CacheTable) SRem(key interface{}, member interface{}) (bool, error) {
+	table.RLock()
+	item, ok := table.items[key]
+	table.RUnlock()
+	if !ok {
+		return false, ErrKeyNotFound
+	}
+	table.keepAlive(item)
+
+	s, ok := item.Data().(*cacheSet)
+	if !ok {
+		return false, ErrNotASet
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if _, exists := s.members[member]; !exists {
+		return false, nil
+	}
+	delete(s.members, member)
+	return true, nil
+}
+
+// SMembers returns the members of a set, in no particular order. The lookup
+// works just like Value, including the data-loader.
+func (table *CacheTable) SMembers(key interface{}) ([]interface{}, error) {
+	s, err := table.set(key)
+	if err != nil {
+		return nil, err
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+	return s.membersInternal(), nil
+}
+
+// SIsMember returns whether a value is a member of a set. A missing set
+// counts as an empty one. The lookup works just like Value, including the
+// data-loader.
+func (table *CacheTable) SIsMember(key interface{}, member interface{}) (bool, error) {
+	s, err := table.set(key)
+	if err == ErrKeyNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+	_, exists := s.members[member]
+	return exists, nil
+}
+
+// set looks up a set via Value.
+func (table *CacheTable) set(key interface{}) (*cacheSet, error) {
+	item, err := table.Value(key)
+	if err != nil {
+		return nil, err
+	}
+
+	s, ok := item.Data().(*cacheSet)
+	if !ok {
+		return nil, ErrNotASet
+	}
+	return s, nil
+}
@@ -0,0 +1,77 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSet(t *testing.T) {
+	table := Cache("testSet")
+
+	if added, err := table.SAdd("notified", 1); !added || err != nil {
+		t.Error("Expected new member to get added", err)
+	}
+	if added, _ := table.SAdd("notified", 1); added {
+		t.Error("Existing member should not get added again")
+	}
+	table.SAdd("notified", 2)
+
+	if ok, _ := table.SIsMember("notified", 2); !ok {
+		t.Error("Expected 2 to be a member")
+	}
+	if ok, _ := table.SIsMember("notified", 3); ok {
+		t.Error("Expected 3 not to be a member")
+	}
+	if ok, err := table.SIsMember("missing", 3); ok || err != nil {
+		t.Error("Missing sets should be empty", err)
+	}
+
+	members, err := table.SMembers("notified")
+	if err != nil || len(members) != 2 {
+		t.Error("Expected 2 members, got", members, err)
+	}
+
+	if removed, _ := table.SRem("notified", 1); !removed {
+		t.Error("Expected member to get removed")
+	}
+	if removed, _ := table.SRem("notified", 1); removed {
+		t.Error("Removed member should not get removed again")
+	}
+	if _, err := table.SRem("missing", 1); err != ErrKeyNotFound {
+		t.Error("Expected ErrKeyNotFound, got", err)
+	}
+
+	table.Add(k, 0, v)
+	if _, err := table.SAdd(k, 1); err != ErrNotASet {
+		t.Error("Expected ErrNotASet, got", err)
+	}
+	if _, err := table.SMembers(k); err != ErrNotASet {
+		t.Error("Expected ErrNotASet, got", err)
+	}
+}
+
+func TestSetLifeSpan(t *testing.T) {
+	table := Cache("testSetLifeSpan")
+	table.AddSet("today", 100*time.Millisecond)
+	table.SAdd("today", "alice")
+
+	var buf bytes.Buffer
+	table.Export(&buf)
+	if !strings.Contains(buf.String(), `["alice"]`) {
+		t.Error("Sets should get exported as their members, got", buf.String())
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if ok, _ := table.SIsMember("today", "alice"); ok {
+		t.Error("Set should have expired")
+	}
+}
@@ -0,0 +1,35 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+// SetShadow makes the table feed a shadow cache with the same operations it
+// performs: lookups, additions, deletions, expirations and flushes. The
+// shadow cache only tracks keys, never data, and doesn't affect what the
+// table returns. Its Stats then tell the hit ratio the table would have had
+// with the shadow cache's capacity and eviction policy, which allows trying
+// out a different configuration in production without risk. Note that the
+// shadow cache shouldn't have a data-loader set. Pass nil to stop shadowing.
+func (table *CacheTable) SetShadow(shadow *BoundedCache) {
+	table.Lock()
+	defer table.Unlock()
+	table.shadow = shadow
+}
+
+// Shadow returns the table's shadow cache, nil if none.
+func (table *CacheTable) Shadow() *BoundedCache {
+	table.RLock()
+	defer table.RUnlock()
+	return table.shadow
+}
+
+// shadowValue feeds a lookup to the shadow cache, if any.
+func (table *CacheTable) shadowValue(key interface{}) {
+	if shadow := table.Shadow(); shadow != nil {
+		shadow.Value(key)
+	}
+}
@@ -0,0 +1,60 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+)
+
+func TestShadow(t *testing.T) {
+	table := Cache("testShadow")
+	shadow := NewBoundedCache("testShadowShadow", 2, NewFIFOPolicy())
+	table.SetShadow(shadow)
+	if table.Shadow() != shadow {
+		t.Error("Shadow cache should be set")
+	}
+
+	for i := 0; i < 4; i++ {
+		table.Add(i, 0, i)
+	}
+	// The table holds all items, the shadow cache only the last two keys
+	for i := 0; i < 4; i++ {
+		if _, err := table.Value(i); err != nil {
+			t.Error("Table should serve all items")
+		}
+	}
+
+	stats := shadow.Stats()
+	if stats.Hits != 2 || stats.Misses != 2 {
+		t.Error("Smaller shadow cache should miss the evicted keys:", stats)
+	}
+	if shadow.Count() != 2 {
+		t.Error("Shadow cache should be filled to its capacity")
+	}
+	shadow.Foreach(func(key interface{}, item *CacheItem) {
+		if item.Data() != nil {
+			t.Error("Shadow cache should not hold any data")
+		}
+	})
+
+	// Deletions and flushes get mirrored too
+	table.Delete(3)
+	if shadow.Exists(3) {
+		t.Error("Deleted key should be gone from the shadow cache")
+	}
+	table.Flush()
+	if shadow.Count() != 0 {
+		t.Error("Flushing the table should flush the shadow cache")
+	}
+
+	table.SetShadow(nil)
+	table.Add(k, 0, v)
+	if shadow.Exists(k) {
+		t.Error("Removed shadow cache should not be fed anymore")
+	}
+}
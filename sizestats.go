@@ -0,0 +1,132 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// DefaultSizeBounds are the bucket bounds used by SizeHistogram if none are
+// given, in bytes.
+var DefaultSizeBounds = []int{
+	64,
+	1 << 10,
+	16 << 10,
+	256 << 10,
+	1 << 20,
+}
+
+// SizeHistogram describes the distribution of the encoded sizes of a table's
+// values.
+type SizeHistogram struct {
+	// The buckets' upper bounds in bytes, in ascending order.
+	Bounds []int
+	// Number of items per bucket: Counts[i] holds the items with a size of
+	// up to Bounds[i], but larger than Bounds[i-1]. The last count holds the
+	// items larger than the last bound.
+	Counts []int
+	// Total size of the items per bucket, in bytes.
+	Bytes []int64
+	// Number of items whose values couldn't be encoded.
+	Unencodable int
+}
+
+// TypeUsage is the number and total encoded size of a table's values of a
+// specific type, see TypeBreakdown.
+type TypeUsage struct {
+	// The Go type, e.g. "*main.User".
+	Type  string
+	Count int
+	// Total size in bytes of the values that could be encoded.
+	Bytes int64
+}
+
+// typeUsagesBySize sorts type usages by descending size.
+type typeUsagesBySize []TypeUsage
+
+func (p typeUsagesBySize) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p typeUsagesBySize) Len() int           { return len(p) }
+func (p typeUsagesBySize) Less(i, j int) bool { return p[i].Bytes > p[j].Bytes }
+
+// encodedSize returns the size of a value: the length of strings and byte
+// slices, and the length of the JSON encoding of anything else.
+func encodedSize(v interface{}) (int, bool) {
+	switch v := v.(type) {
+	case string:
+		return len(v), true
+	case []byte:
+		return len(v), true
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return 0, false
+	}
+	return len(b), true
+}
+
+// SizeHistogram returns the distribution of the encoded sizes of the table's
+// values, bucketed by the given ascending bounds in bytes, or
+// DefaultSizeBounds if none are given. Values are measured as strings, byte
+// slices or JSON, so the sizes approximate their memory usage. Encoding
+// happens on a frozen view of the table, see Freeze, so it doesn't block the
+// table's users.
+func (table *CacheTable) SizeHistogram(bounds ...int) SizeHistogram {
+	if len(bounds) == 0 {
+		bounds = DefaultSizeBounds
+	}
+
+	h := SizeHistogram{
+		Bounds: bounds,
+		Counts: make([]int, len(bounds)+1),
+		Bytes:  make([]int64, len(bounds)+1),
+	}
+	table.Freeze().Foreach(func(key interface{}, item *CacheItem) {
+		size, ok := encodedSize(item.Data())
+		if !ok {
+			h.Unencodable++
+			return
+		}
+		i := 0
+		for i < len(bounds) && size > bounds[i] {
+			i++
+		}
+		h.Counts[i]++
+		h.Bytes[i] += int64(size)
+	})
+
+	return h
+}
+
+// TypeBreakdown returns the number and total encoded size of the table's
+// values per Go type, largest first, e.g. to spot accidentally cached huge
+// structs. Sizes are measured just like by SizeHistogram.
+func (table *CacheTable) TypeBreakdown() []TypeUsage {
+	usages := make(map[string]*TypeUsage)
+	table.Freeze().Foreach(func(key interface{}, item *CacheItem) {
+		t := fmt.Sprintf("%T", item.Data())
+		u, ok := usages[t]
+		if !ok {
+			u = &TypeUsage{Type: t}
+			usages[t] = u
+		}
+		u.Count++
+		if size, ok := encodedSize(item.Data()); ok {
+			u.Bytes += int64(size)
+		}
+	})
+
+	breakdown := make([]TypeUsage, 0, len(usages))
+	for _, u := range usages {
+		breakdown = append(breakdown, *u)
+	}
+	sort.Sort(typeUsagesBySize(breakdown))
+
+	return breakdown
+}
@@ -0,0 +1,69 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"strings"
+	"testing"
+)
+
+type sizeStatsValue struct {
+	Blob string
+}
+
+func TestSizeHistogram(t *testing.T) {
+	table := Cache("testSizeHistogram")
+	table.Flush()
+	table.Add(1, 0, "small")
+	table.Add(2, 0, make([]byte, 1000))
+	table.Add(3, 0, sizeStatsValue{strings.Repeat("x", 2000)})
+	table.Add(4, 0, strings.Repeat("x", 2<<20))
+	table.Add(5, 0, make(chan int))
+
+	h := table.SizeHistogram()
+	expected := []int{1, 1, 1, 0, 0, 1}
+	if h.Unencodable != 1 || len(h.Counts) != len(expected) {
+		t.Fatalf("Unexpected histogram %+v", h)
+	}
+	for i, c := range expected {
+		if h.Counts[i] != c {
+			t.Errorf("Unexpected count in bucket %d: %+v", i, h)
+		}
+	}
+	if h.Bytes[0] != 5 || h.Bytes[1] != 1000 || h.Bytes[5] != 2<<20 {
+		t.Errorf("Unexpected sizes %+v", h.Bytes)
+	}
+
+	h = table.SizeHistogram(100)
+	if h.Counts[0] != 1 || h.Counts[1] != 3 {
+		t.Errorf("Unexpected histogram with custom bounds %+v", h)
+	}
+}
+
+func TestTypeBreakdown(t *testing.T) {
+	table := Cache("testTypeBreakdown")
+	table.Flush()
+	table.Add(1, 0, "a")
+	table.Add(2, 0, "bc")
+	table.Add(3, 0, &sizeStatsValue{strings.Repeat("x", 100)})
+	table.Add(4, 0, 42)
+
+	breakdown := table.TypeBreakdown()
+	if len(breakdown) != 3 {
+		t.Fatal("Expected 3 types, got", breakdown)
+	}
+	if u := breakdown[0]; u.Type != "*cache2go.sizeStatsValue" || u.Count != 1 || u.Bytes != 111 {
+		t.Error("Expected the largest type first, got", u)
+	}
+	if u := breakdown[1]; u.Type != "string" || u.Count != 2 || u.Bytes != 3 {
+		t.Error("Expected strings second, got", u)
+	}
+	if u := breakdown[2]; u.Type != "int" || u.Count != 1 || u.Bytes != 2 {
+		t.Error("Expected ints last, got", u)
+	}
+}
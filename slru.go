@@ -0,0 +1,131 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"container/list"
+)
+
+// slruEntry tracks in which segment a key currently lives.
+type slruEntry struct {
+	element   *list.Element
+	protected bool
+}
+
+// SLRUPolicy implements the segmented LRU eviction policy. New items enter a
+// probationary segment and only get promoted to the protected segment when
+// they are accessed again, so one-off scans can't flush the working set.
+type SLRUPolicy struct {
+	// Maximum number of items in the protected segment.
+	protectedCapacity int
+
+	// Both segments, most recently used items at the front.
+	probation *list.List
+	protected *list.List
+	entries   map[interface{}]*slruEntry
+}
+
+// NewSLRUPolicy creates a segmented LRU policy for a cache with the given
+// capacity. Parameter protectedRatio determines which share of the capacity
+// (between 0 and 1) is reserved for the protected segment; 0.8 is a common
+// choice.
+func NewSLRUPolicy(capacity int, protectedRatio float64) *SLRUPolicy {
+	if protectedRatio < 0 {
+		protectedRatio = 0
+	}
+	if protectedRatio > 1 {
+		protectedRatio = 1
+	}
+
+	return &SLRUPolicy{
+		protectedCapacity: int(float64(capacity) * protectedRatio),
+		probation:         list.New(),
+		protected:         list.New(),
+		entries:           make(map[interface{}]*slruEntry),
+	}
+}
+
+// Add puts a new item at the front of the probationary segment.
+func (p *SLRUPolicy) Add(item *CacheItem) {
+	if _, exists := p.entries[item.key]; exists {
+		p.Access(item)
+		return
+	}
+
+	p.entries[item.key] = &slruEntry{
+		element: p.probation.PushFront(item.key),
+	}
+}
+
+// Access promotes probationary items to the protected segment and refreshes
+// the recency of protected ones.
+func (p *SLRUPolicy) Access(item *CacheItem) {
+	entry, exists := p.entries[item.key]
+	if !exists {
+		return
+	}
+	if entry.protected {
+		p.protected.MoveToFront(entry.element)
+		return
+	}
+	if p.protectedCapacity == 0 {
+		p.probation.MoveToFront(entry.element)
+		return
+	}
+
+	p.probation.Remove(entry.element)
+	entry.element = p.protected.PushFront(item.key)
+	entry.protected = true
+
+	// Demote the least recently used protected item if the segment overflows.
+	if p.protected.Len() > p.protectedCapacity {
+		back := p.protected.Back()
+		key := p.protected.Remove(back)
+		demoted := p.entries[key]
+		demoted.element = p.probation.PushFront(key)
+		demoted.protected = false
+	}
+}
+
+// Remove drops an item from whichever segment it lives in.
+func (p *SLRUPolicy) Remove(item *CacheItem) {
+	entry, exists := p.entries[item.key]
+	if !exists {
+		return
+	}
+	if entry.protected {
+		p.protected.Remove(entry.element)
+	} else {
+		p.probation.Remove(entry.element)
+	}
+	delete(p.entries, item.key)
+}
+
+// Evict picks the least recently used probationary item, falling back to the
+// protected segment once the probationary one is empty.
+func (p *SLRUPolicy) Evict() (interface{}, bool) {
+	segment := p.probation
+	if segment.Len() == 0 {
+		segment = p.protected
+	}
+	back := segment.Back()
+	if back == nil {
+		return nil, false
+	}
+
+	key := segment.Remove(back)
+	delete(p.entries, key)
+	return key, true
+}
+
+// Reset drops all bookkeeping.
+func (p *SLRUPolicy) Reset() {
+	p.probation.Init()
+	p.protected.Init()
+	p.entries = make(map[interface{}]*slruEntry)
+}
@@ -0,0 +1,62 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+)
+
+func TestSLRUScanResistance(t *testing.T) {
+	cache := NewBoundedCache("testSLRU", 4, NewSLRUPolicy(4, 0.5))
+
+	// Build a small working set that gets accessed twice
+	cache.Add("hot1", 0, "value")
+	cache.Add("hot2", 0, "value")
+	cache.Value("hot1")
+	cache.Value("hot2")
+
+	// A one-off scan must not push the working set out
+	for i := 0; i < 100; i++ {
+		cache.Add(i, 0, i)
+	}
+
+	if !cache.Exists("hot1") || !cache.Exists("hot2") {
+		t.Error("Protected items should survive a scan")
+	}
+	if cache.Count() != 4 {
+		t.Error("Cache should be filled to capacity")
+	}
+}
+
+func TestSLRUDemotion(t *testing.T) {
+	p := NewSLRUPolicy(3, 0.34)
+
+	a := NewCacheItem("a", 0, nil)
+	b := NewCacheItem("b", 0, nil)
+	c := NewCacheItem("c", 0, nil)
+	p.Add(a)
+	p.Add(b)
+	p.Add(c)
+
+	// Protected segment holds a single item: promoting b demotes a
+	p.Access(a)
+	p.Access(b)
+
+	if key, _ := p.Evict(); key != "c" {
+		t.Error("Expected c to be evicted first, got", key)
+	}
+	if key, _ := p.Evict(); key != "a" {
+		t.Error("Expected demoted a to be evicted next, got", key)
+	}
+	if key, _ := p.Evict(); key != "b" {
+		t.Error("Expected protected b to be evicted last, got", key)
+	}
+	if _, ok := p.Evict(); ok {
+		t.Error("Empty policy should not return a victim")
+	}
+}
@@ -0,0 +1,107 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// SnapshotSink stores table snapshots, e.g. off-host, so a new instance can
+// restore them for a warm start.
+type SnapshotSink interface {
+	// Save stores a snapshot under the given name, replacing any previous
+	// snapshot of that name.
+	Save(name string, r io.Reader) error
+	// Load returns the snapshot stored under the given name, or
+	// ErrSnapshotNotFound.
+	Load(name string) (io.ReadCloser, error)
+}
+
+// SaveSnapshot exports the table's items (see Export) to the sink, naming the
+// snapshot after the table.
+func (table *CacheTable) SaveSnapshot(sink SnapshotSink) error {
+	var buf bytes.Buffer
+	if err := table.Export(&buf); err != nil {
+		return err
+	}
+
+	table.log("Saving snapshot of table", table.name, "with", buf.Len(), "bytes")
+	return sink.Save(table.name, &buf)
+}
+
+// RestoreSnapshot imports the table's snapshot from the sink (see
+// ImportWith), returning how many items got restored. Changes saved since
+// the snapshot by EnableAutoSnapshot get applied as well.
+func (table *CacheTable) RestoreSnapshot(sink SnapshotSink, decode ImportDecoder) (int, error) {
+	r, err := sink.Load(table.name)
+	if err != nil {
+		return 0, err
+	}
+	n, err := table.ImportWith(r, decode)
+	r.Close()
+	if err != nil {
+		return n, err
+	}
+
+	r, err = sink.Load(deltaName(table.name))
+	if err == ErrSnapshotNotFound {
+		return n, nil
+	} else if err != nil {
+		return n, err
+	}
+	defer r.Close()
+
+	return n, table.applyDelta(r, decode)
+}
+
+// FileSink is a SnapshotSink storing snapshots as files in a directory.
+type FileSink struct {
+	dir string
+}
+
+// NewFileSink creates a sink storing snapshots in the given directory.
+func NewFileSink(dir string) *FileSink {
+	return &FileSink{dir: dir}
+}
+
+// Save writes the snapshot to a file. The file gets replaced atomically, so
+// a crash never leaves a partially written snapshot behind.
+func (s *FileSink) Save(name string, r io.Reader) error {
+	f, err := ioutil.TempFile(s.dir, name+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), s.path(name))
+}
+
+// Load opens the snapshot's file.
+func (s *FileSink) Load(name string) (io.ReadCloser, error) {
+	f, err := os.Open(s.path(name))
+	if os.IsNotExist(err) {
+		return nil, ErrSnapshotNotFound
+	}
+	return f, err
+}
+
+func (s *FileSink) path(name string) string {
+	return filepath.Join(s.dir, name+".snapshot")
+}
@@ -0,0 +1,41 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache2go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sink := NewFileSink(dir)
+
+	table := Cache("testFileSink")
+	if _, err := table.RestoreSnapshot(sink, nil); err != ErrSnapshotNotFound {
+		t.Error("Expected missing snapshot, got", err)
+	}
+
+	table.Add(k, 0, v)
+	if err := table.SaveSnapshot(sink); err != nil {
+		t.Error("Error saving snapshot", err)
+	}
+	table.Flush()
+
+	if n, err := table.RestoreSnapshot(sink, nil); err != nil || n != 1 {
+		t.Error("Error restoring snapshot", n, err)
+	}
+	if p, err := table.Value(k); err != nil || p.Data() != v {
+		t.Error("Error retrieving restored item", p, err)
+	}
+}
@@ -0,0 +1,189 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// WithSoftReferences holds the table's items like soft references: after
+// every garbage collection that leaves more than heapLimit bytes allocated on
+// the heap, the least recently accessed items get reclaimed, even before they
+// expire. The further the heap exceeds the limit, the larger the share of
+// items reclaimed: a heap twice the limit reclaims half of them. Their
+// removal reason is RemovalReclaimed, and lookups of them miss, so a
+// data-loader repopulates them on their next access. See
+// SetReclaimedItemCallback to get notified.
+func WithSoftReferences(heapLimit uint64) Option {
+	return func(o *cacheOptions) {
+		o.softHeapLimit = heapLimit
+	}
+}
+
+var (
+	// Tables holding items like soft references, and whether garbage
+	// collections are being watched for them, guarded by softRefsMutex.
+	softRefTables   = make(map[*CacheTable]bool)
+	softRefWatching bool
+	softRefsMutex   sync.Mutex
+)
+
+// gcSentinel is an object left for the garbage collector, whose finalizer
+// notices garbage collections. It holds a pointer, so it doesn't get
+// allocated alongside other objects, which would delay its finalization.
+type gcSentinel struct {
+	_ *gcSentinel
+}
+
+// watchSoftRefs makes the table reclaim items after garbage collections. All
+// tables share a single watcher, so the heap gets measured once per garbage
+// collection, no matter how many tables there are.
+func watchSoftRefs(table *CacheTable) {
+	softRefsMutex.Lock()
+	defer softRefsMutex.Unlock()
+	softRefTables[table] = true
+	if !softRefWatching {
+		softRefWatching = true
+		watchGC()
+	}
+}
+
+// unwatchSoftRefs stops reclaiming the table's items.
+func unwatchSoftRefs(table *CacheTable) {
+	softRefsMutex.Lock()
+	defer softRefsMutex.Unlock()
+	delete(softRefTables, table)
+}
+
+// watchGC calls collected after the next garbage collection, and again after
+// every following one, as long as there are tables to reclaim items of.
+func watchGC() {
+	runtime.SetFinalizer(&gcSentinel{}, func(*gcSentinel) {
+		if collected() {
+			watchGC()
+		}
+	})
+}
+
+// collected measures the heap after a garbage collection, and lets the tables
+// exceeding their limits reclaim items. Returns false once there are no tables
+// left to watch garbage collections for.
+func collected() bool {
+	softRefsMutex.Lock()
+	if len(softRefTables) == 0 {
+		softRefWatching = false
+		softRefsMutex.Unlock()
+		return false
+	}
+	tables := make([]*CacheTable, 0, len(softRefTables))
+	for table := range softRefTables {
+		tables = append(tables, table)
+	}
+	softRefsMutex.Unlock()
+
+	heap := heapAlloc()
+	for _, table := range tables {
+		if heap > table.options.softHeapLimit {
+			table.collected(heap)
+		}
+	}
+	return true
+}
+
+// collected reclaims a share of the table's items as large as the share of
+// the heap exceeding the table's limit. Runs on the finalizer goroutine, so
+// the actual work happens in the background, at most once at a time.
+func (table *CacheTable) collected(heap uint64) {
+	if !atomic.CompareAndSwapUint32(&table.reclaiming, 0, 1) {
+		return
+	}
+
+	share := float64(heap-table.options.softHeapLimit) / float64(heap)
+	table.spawn(func() {
+		defer atomic.StoreUint32(&table.reclaiming, 0)
+		table.log("Heap of", heap, "bytes exceeds the soft reference limit of table", table.name)
+		table.reclaim(share)
+	})
+}
+
+// Reclaim removes the least recently accessed half of the table's items, just
+// like WithSoftReferences does when the heap is twice the limit, and returns
+// how many items got reclaimed. Use it to react to other signals of memory
+// pressure.
+func (table *CacheTable) Reclaim() int {
+	return table.reclaim(0.5)
+}
+
+// reclaim removes the given share of the table's items, the least recently
+// accessed ones, at least one, and returns how many items got reclaimed.
+func (table *CacheTable) reclaim(share float64) int {
+	table.touch()
+	table.Lock()
+
+	items := make(cacheItemsByAccess, 0, len(table.items))
+	for _, item := range table.items {
+		items = append(items, item)
+	}
+	sort.Sort(items)
+	n := int(math.Ceil(share * float64(len(items))))
+	if n > len(items) {
+		n = len(items)
+	}
+
+	var reclaimed []*CacheItem
+	for _, item := range items[:n] {
+		// Skip items replaced while callbacks of others ran.
+		if table.items[item.key] != item {
+			continue
+		}
+		if r, err := table.deleteInternal(item.key, RemovalReclaimed); err == nil {
+			table.stats.evict()
+			reclaimed = append(reclaimed, r)
+		}
+	}
+	reclaimedItem := table.reclaimedItem
+	table.log("Reclaimed", len(reclaimed), "items of table", table.name)
+	table.Unlock()
+
+	for _, item := range reclaimed {
+		for _, callback := range reclaimedItem {
+			callback(item)
+		}
+	}
+	return len(reclaimed)
+}
+
+// SetReclaimedItemCallback configures a callback, which will be called every
+// time an item got reclaimed, see WithSoftReferences, e.g. to reload hot
+// items right away instead of on their next access.
+func (table *CacheTable) SetReclaimedItemCallback(f func(*CacheItem)) {
+	if len(table.reclaimedItem) > 0 {
+		table.RemoveReclaimedItemCallbacks()
+	}
+	table.Lock()
+	defer table.Unlock()
+	table.reclaimedItem = append(table.reclaimedItem, f)
+}
+
+// AddReclaimedItemCallback appends a new callback to the reclaimed item queue.
+func (table *CacheTable) AddReclaimedItemCallback(f func(*CacheItem)) {
+	table.Lock()
+	defer table.Unlock()
+	table.reclaimedItem = append(table.reclaimedItem, f)
+}
+
+// RemoveReclaimedItemCallbacks empties the reclaimed item callback queue.
+func (table *CacheTable) RemoveReclaimedItemCallbacks() {
+	table.Lock()
+	defer table.Unlock()
+	table.reclaimedItem = nil
+}
@@ -0,0 +1,103 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestReclaim(t *testing.T) {
+	table, _ := CacheWithOptions("testReclaim")
+	table.Flush()
+
+	var reasons []RemovalReason
+	var reclaimed []interface{}
+	table.SetReclaimedItemCallback(func(item *CacheItem) {
+		reclaimed = append(reclaimed, item.Key())
+	})
+	for i := 0; i < 5; i++ {
+		item := table.AddWithListener(i, 0, v, func(item *CacheItem, reason RemovalReason) {
+			reasons = append(reasons, reason)
+		})
+		item.setAccessedOn(time.Now().Add(time.Duration(i) * time.Second))
+	}
+
+	if n := table.Reclaim(); n != 3 {
+		t.Error("Expected half of the items to be reclaimed, got", n)
+	}
+	for i, key := range []interface{}{0, 1, 2} {
+		if table.Exists(key) || len(reclaimed) != 3 || reclaimed[i] != key {
+			t.Error("Expected the least recently accessed items to be reclaimed, got", reclaimed)
+		}
+	}
+	for _, reason := range reasons {
+		if reason != RemovalReclaimed {
+			t.Error("Expected removal reason reclaimed, got", reason)
+		}
+	}
+	if table.Count() != 2 {
+		t.Error("Expected the most recently accessed items to be kept")
+	}
+}
+
+func TestWithSoftReferences(t *testing.T) {
+	table, _ := CacheWithOptions("testWithSoftReferences", WithSoftReferences(1))
+	table.Flush()
+
+	for i := 0; i < 4; i++ {
+		table.Add(i, 0, v)
+	}
+	for start := time.Now(); table.Count() == 4 && time.Since(start) < time.Second; {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if table.Count() == 4 {
+		t.Error("Expected items to be reclaimed once the heap exceeds the limit")
+	}
+}
+
+func TestReclaimShare(t *testing.T) {
+	table, _ := CacheWithOptions("testReclaimShare")
+	table.Flush()
+	for i := 0; i < 8; i++ {
+		table.Add(i, 0, v)
+	}
+
+	if n := table.reclaim(0.2); n != 2 || table.Count() != 6 {
+		t.Error("Expected a share of the items to be reclaimed, got", n)
+	}
+	if n := table.reclaim(0.01); n != 1 {
+		t.Error("Expected at least one item to be reclaimed, got", n)
+	}
+	if n := table.reclaim(2); n != 5 || table.Count() != 0 {
+		t.Error("Expected all items to be reclaimed, got", n)
+	}
+}
+
+func TestSoftReferencesSharedWatcher(t *testing.T) {
+	a := newCacheTable("testSoftReferencesSharedA", newCacheOptions(WithSoftReferences(1<<62)))
+	defer a.Close()
+	b := newCacheTable("testSoftReferencesSharedB", newCacheOptions(WithSoftReferences(1<<62)))
+
+	softRefsMutex.Lock()
+	watched := softRefTables[a] && softRefTables[b] && softRefWatching
+	softRefsMutex.Unlock()
+	if !watched {
+		t.Error("Expected both tables to be watched")
+	}
+
+	b.Close()
+	softRefsMutex.Lock()
+	watched = softRefTables[b]
+	softRefsMutex.Unlock()
+	if watched {
+		t.Error("Closed tables should not be watched anymore")
+	}
+}
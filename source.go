@@ -0,0 +1,70 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"time"
+)
+
+// ItemSource describes where an item's value came from.
+type ItemSource int
+
+const (
+	// SourceAdd means the item was added explicitly, e.g. via Add.
+	SourceAdd ItemSource = iota
+	// SourceLoader means the item was returned by the data-loader, including
+	// refreshes of stale items.
+	SourceLoader
+	// SourceRevalidator means the item was returned by the revalidator.
+	SourceRevalidator
+	// SourceRestore means the item was restored from an export, a snapshot,
+	// a mutation log or a mapped arena.
+	SourceRestore
+	// SourceParent means the item was copied from the parent cache on a
+	// miss, see SetParent.
+	SourceParent
+)
+
+// String returns a human readable name of the item source.
+func (s ItemSource) String() string {
+	switch s {
+	case SourceAdd:
+		return "add"
+	case SourceLoader:
+		return "loader"
+	case SourceRevalidator:
+		return "revalidator"
+	case SourceRestore:
+		return "restore"
+	case SourceParent:
+		return "parent"
+	}
+	return "unknown"
+}
+
+// Source returns where the item's value came from, e.g. to find out whether
+// a stale value was restored from a snapshot or returned by the data-loader.
+func (item *CacheItem) Source() ItemSource {
+	// immutable
+	return item.source
+}
+
+// addLoaded adds a copy of an item returned by the data-loader, which took
+// the given time to load it. The copy gets the table's defaults, like any
+// added item, unless the loaded item sets its own lifespans.
+func (table *CacheTable) addLoaded(key interface{}, loaded *CacheItem, cost time.Duration) {
+	item := table.newItem(key, loaded.LifeSpan(), loaded.Data())
+	if loaded.softLifeSpan > 0 {
+		item.softLifeSpan = loaded.softLifeSpan
+	}
+	item.source = SourceLoader
+	item.loadCost = cost
+
+	table.Lock()
+	table.addInternal(item)
+}
@@ -0,0 +1,51 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestItemSource(t *testing.T) {
+	table, _ := CacheWithOptions("testItemSource", WithStats())
+	table.SetDataLoader(func(key interface{}, args ...interface{}) *CacheItem {
+		return NewCacheItem(key, 0, "loaded")
+	})
+
+	if item := table.Add("added", 0, v); item.Source() != SourceAdd {
+		t.Error("Expected source add, got", item.Source())
+	}
+	table.Value("loaded")
+	if item, _ := peek(table, "loaded"); item.Source() != SourceLoader {
+		t.Error("Expected source loader, got", item.Source())
+	}
+
+	var buf bytes.Buffer
+	table.Export(&buf)
+	restored, _ := CacheWithOptions("testItemSourceRestored", WithStats())
+	restored.Import(&buf)
+	restored.Foreach(func(key interface{}, item *CacheItem) {
+		if item.Source() != SourceRestore {
+			t.Error("Expected source restore for", key, "got", item.Source())
+		}
+	})
+
+	if stats := table.Stats(); stats.Added != 2 || stats.Loaded != 1 || stats.Restored != 0 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if stats := restored.Stats(); stats.Added != 2 || stats.Loaded != 0 || stats.Restored != 2 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+
+	for s, name := range map[ItemSource]string{SourceAdd: "add", SourceLoader: "loader", SourceRevalidator: "revalidator", SourceRestore: "restore", 42: "unknown"} {
+		if s.String() != name {
+			t.Error("Expected", name, "got", s.String())
+		}
+	}
+}
@@ -0,0 +1,148 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// SQLLoader loads items by querying a database, e.g. a row by its ID, see
+// NewSQLLoader.
+type SQLLoader struct {
+	db    *sql.DB
+	query string
+	scan  func(row *sql.Row) (interface{}, error)
+	// Returns the query's arguments for a request, nil for just the key.
+	args func(req *LoadRequest) []interface{}
+	// How long a query may take, 0 for as long as the request's context
+	// allows.
+	timeout time.Duration
+	// Callback method triggered when a query fails.
+	failed func(key interface{}, err error)
+
+	// The prepared query, nil until it got prepared.
+	stmt      *sql.Stmt
+	stmtMutex sync.Mutex
+}
+
+// SQLLoaderOption configures an SQLLoader.
+type SQLLoaderOption func(*SQLLoader)
+
+// WithSQLArgs makes the loader query the database with the arguments args
+// returns for a request, e.g. fields of a composite key, instead of the key
+// itself.
+func WithSQLArgs(args func(req *LoadRequest) []interface{}) SQLLoaderOption {
+	return func(l *SQLLoader) {
+		l.args = args
+	}
+}
+
+// WithSQLTimeout cancels queries taking longer than the given duration.
+func WithSQLTimeout(timeout time.Duration) SQLLoaderOption {
+	return func(l *SQLLoader) {
+		l.timeout = timeout
+	}
+}
+
+// WithSQLErrorCallback configures a callback, which will be called every time
+// a query fails, other than by not returning a row, e.g. to log it. The
+// lookup fails with ErrKeyNotFoundOrLoadable either way.
+func WithSQLErrorCallback(f func(key interface{}, err error)) SQLLoaderOption {
+	return func(l *SQLLoader) {
+		l.failed = f
+	}
+}
+
+// NewSQLLoader returns a loader querying db for the keys being looked up,
+// for use with SetLoader. The query selects a single row, taking the key as
+// its only argument, e.g. "SELECT name, email FROM users WHERE id = ?", with
+// the placeholder syntax of the database's driver. Parameter scan turns the
+// row into the value to cache. Keys without a row fail to load.
+//
+// The query gets prepared on first use and the statement reused for all
+// keys, and runs with the lookup's context, see Load.
+func NewSQLLoader(db *sql.DB, query string, scan func(row *sql.Row) (interface{}, error), opts ...SQLLoaderOption) *SQLLoader {
+	l := &SQLLoader{
+		db:    db,
+		query: query,
+		scan:  scan,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Load loads the requested item from the database, returning nil if that
+// fails. Pass it to SetLoader.
+func (l *SQLLoader) Load(req *LoadRequest) *CacheItem {
+	ctx := req.Context
+	if l.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.timeout)
+		defer cancel()
+	}
+
+	stmt, err := l.prepare(ctx)
+	if err != nil {
+		l.fail(req.Key, err)
+		return nil
+	}
+	args := []interface{}{req.Key}
+	if l.args != nil {
+		args = l.args(req)
+	}
+
+	v, err := l.scan(stmt.QueryRowContext(ctx, args...))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			l.fail(req.Key, err)
+		}
+		return nil
+	}
+	return NewCacheItem(req.Key, 0, v)
+}
+
+// Close releases the prepared query.
+func (l *SQLLoader) Close() error {
+	l.stmtMutex.Lock()
+	defer l.stmtMutex.Unlock()
+
+	if l.stmt == nil {
+		return nil
+	}
+	err := l.stmt.Close()
+	l.stmt = nil
+	return err
+}
+
+// prepare returns the prepared query, preparing it unless that happened
+// already.
+func (l *SQLLoader) prepare(ctx context.Context) (*sql.Stmt, error) {
+	l.stmtMutex.Lock()
+	defer l.stmtMutex.Unlock()
+
+	if l.stmt == nil {
+		stmt, err := l.db.PrepareContext(ctx, l.query)
+		if err != nil {
+			return nil, err
+		}
+		l.stmt = stmt
+	}
+	return l.stmt, nil
+}
+
+// fail reports a failed query.
+func (l *SQLLoader) fail(key interface{}, err error) {
+	if l.failed != nil {
+		l.failed(key, err)
+	}
+}
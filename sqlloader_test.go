@@ -0,0 +1,125 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testDriver serves queries from a map of users by ID, counting how often
+// queries got prepared.
+type testDriver struct {
+	users    map[int64]string
+	prepared int32
+}
+
+type testConn struct{ d *testDriver }
+type testStmt struct{ d *testDriver }
+type testRows struct{ names []string }
+
+var sqlTestDriver = &testDriver{users: map[int64]string{1: "alice", 2: "bob"}}
+
+func init() {
+	sql.Register("cache2gotest", sqlTestDriver)
+}
+
+func (d *testDriver) Open(name string) (driver.Conn, error) { return testConn{d}, nil }
+
+func (c testConn) Prepare(query string) (driver.Stmt, error) {
+	atomic.AddInt32(&c.d.prepared, 1)
+	return testStmt{c.d}, nil
+}
+func (c testConn) Close() error              { return nil }
+func (c testConn) Begin() (driver.Tx, error) { return nil, errors.New("Not supported") }
+
+func (s testStmt) Close() error  { return nil }
+func (s testStmt) NumInput() int { return 1 }
+func (s testStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("Not supported")
+}
+func (s testStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if args[0].Value.(int64) < 0 {
+		// Negative IDs take forever.
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return s.Query([]driver.Value{args[0].Value})
+}
+func (s testStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if name, ok := s.d.users[args[0].(int64)]; ok {
+		return &testRows{names: []string{name}}, nil
+	}
+	return &testRows{}, nil
+}
+
+func (r *testRows) Columns() []string { return []string{"name"} }
+func (r *testRows) Close() error      { return nil }
+func (r *testRows) Next(dest []driver.Value) error {
+	if len(r.names) == 0 {
+		return io.EOF
+	}
+	dest[0] = r.names[0]
+	r.names = r.names[1:]
+	return nil
+}
+
+func TestSQLLoader(t *testing.T) {
+	db, err := sql.Open("cache2gotest", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var failures int32
+	loader := NewSQLLoader(db, "SELECT name FROM users WHERE id = ?", func(row *sql.Row) (interface{}, error) {
+		var name string
+		err := row.Scan(&name)
+		return name, err
+	}, WithSQLTimeout(10*time.Millisecond), WithSQLErrorCallback(func(key interface{}, err error) {
+		atomic.AddInt32(&failures, 1)
+	}))
+	defer loader.Close()
+
+	table, _ := CacheWithOptions("testSQLLoader")
+	table.Flush()
+	table.SetLoader(loader.Load)
+
+	prepared := atomic.LoadInt32(&sqlTestDriver.prepared)
+	for _, id := range []int{1, 2} {
+		if _, err := table.Value(id); err != nil {
+			t.Error("Error loading user", id, err)
+		}
+	}
+	if p, _ := table.Value(1); p.Data() != "alice" {
+		t.Error("Expected user 1 to be alice, got", p.Data())
+	}
+	if n := atomic.LoadInt32(&sqlTestDriver.prepared) - prepared; n != 1 {
+		t.Error("Expected the query to be prepared once, got", n)
+	}
+
+	if _, err := table.Value(3); err != ErrKeyNotFoundOrLoadable {
+		t.Error("Expected ErrKeyNotFoundOrLoadable for missing rows, got", err)
+	}
+	if n := atomic.LoadInt32(&failures); n != 0 {
+		t.Error("Expected missing rows not to be reported as failures, got", n)
+	}
+
+	if _, err := table.Load(context.Background(), -1, nil); err != ErrKeyNotFoundOrLoadable {
+		t.Error("Expected ErrKeyNotFoundOrLoadable for slow queries, got", err)
+	}
+	if n := atomic.LoadInt32(&failures); n != 1 {
+		t.Error("Expected timeouts to be reported as failures, got", n)
+	}
+}
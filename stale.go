@@ -0,0 +1,80 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"context"
+	"time"
+)
+
+// SoftLifeSpan returns after which time period since its creation the item
+// is considered stale, 0 meaning never.
+func (item *CacheItem) SoftLifeSpan() time.Duration {
+	// immutable
+	return item.softLifeSpan
+}
+
+// IsStale returns whether the item outlived its soft lifespan. Stale items
+// are still served, but get refreshed in the background.
+func (item *CacheItem) IsStale() bool {
+	return item.softLifeSpan > 0 && time.Since(item.createdOn) >= item.softLifeSpan
+}
+
+// isStale returns whether an item is stale as of the table's current time.
+func (table *CacheTable) isStale(item *CacheItem) bool {
+	return item.softLifeSpan > 0 && table.now().Sub(item.createdOn) >= item.softLifeSpan
+}
+
+// AddWithSoftLifeSpan adds a key/value pair to the cache with two lifespans.
+// Parameter softLifeSpan determines after which time period since its
+// creation the item turns stale: it's still served by Value, which then
+// refreshes it in the background via the data-loader. Parameter lifeSpan is
+// the hard limit, just like for Add: once it's exceeded, the item gets
+// removed and further lookups miss.
+func (table *CacheTable) AddWithSoftLifeSpan(key interface{}, softLifeSpan, lifeSpan time.Duration, data interface{}) *CacheItem {
+	item := table.newItem(key, lifeSpan, data)
+	if softLifeSpan > 0 {
+		item.softLifeSpan = softLifeSpan
+	}
+
+	table.Lock()
+	table.addInternal(item)
+
+	table.audit(context.Background(), auditOpAdd, key)
+	return item
+}
+
+// refreshItem reloads a stale item in the background, unless it's already
+// being refreshed: the refresh is shared by all callers, and loads the item
+// as requested by the caller who started it. Its context carries the values
+// of the caller's, but doesn't get canceled along with it.
+func (table *CacheTable) refreshItem(item *CacheItem, req LoadRequest, loadData func(*LoadRequest) *CacheItem) {
+	key := req.Key
+	req.Context = detachedContext{parent: req.Context}
+	item.Lock()
+	if item.refreshing {
+		item.Unlock()
+		return
+	}
+	item.refreshing = true
+	item.Unlock()
+
+	table.spawn(func() {
+		start := time.Now()
+		fresh := loadData(&req)
+		if fresh == nil {
+			item.Lock()
+			item.refreshing = false
+			item.Unlock()
+			return
+		}
+
+		table.log("Refreshed stale item with key", key, "in table", table.name)
+		table.addLoaded(key, fresh, time.Since(start))
+	})
+}
@@ -0,0 +1,104 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSoftLifeSpan(t *testing.T) {
+	table := Cache("testSoftLifeSpan")
+	var loads int32
+	table.SetDataLoader(func(key interface{}, args ...interface{}) *CacheItem {
+		n := atomic.AddInt32(&loads, 1)
+		return NewCacheItem(key, 0, n)
+	})
+
+	table.AddWithSoftLifeSpan(k, 50*time.Millisecond, 500*time.Millisecond, v)
+	p, err := table.Value(k)
+	if err != nil || p.Data() != v || p.IsStale() {
+		t.Error("Error retrieving fresh item")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	// Stale items are still served, but trigger a refresh
+	p, err = table.Value(k)
+	if err != nil || p.Data() != v || !p.IsStale() {
+		t.Error("Stale item should still be served")
+	}
+	table.Value(k)
+
+	time.Sleep(50 * time.Millisecond)
+	if atomic.LoadInt32(&loads) != 1 {
+		t.Error("Stale item should have been refreshed exactly once")
+	}
+	p, err = table.Value(k)
+	if err != nil || p.Data() != int32(1) {
+		t.Error("Error retrieving refreshed item")
+	}
+	// The refreshed item inherited the soft lifespan of the table, i.e. none
+	if p.IsStale() || p.SoftLifeSpan() != 0 {
+		t.Error("Refreshed item should not be stale")
+	}
+}
+
+func TestHardLifeSpan(t *testing.T) {
+	table := Cache("testHardLifeSpan")
+	table.AddWithSoftLifeSpan(k, 50*time.Millisecond, 100*time.Millisecond, v)
+
+	time.Sleep(75 * time.Millisecond)
+	if p, err := table.Value(k); err != nil || !p.IsStale() {
+		t.Error("Stale item should be served without a data-loader")
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if _, err := table.Value(k); err == nil {
+		t.Error("Item should be gone after its hard lifespan")
+	}
+}
+
+func TestDefaultSoftLifeSpan(t *testing.T) {
+	table, _ := CacheWithOptions("testDefaultSoftLifeSpan", WithSoftLifeSpan(time.Minute))
+	if p := table.Add(k, 0, v); p.SoftLifeSpan() != time.Minute {
+		t.Error("Item should inherit the table's soft lifespan")
+	}
+	if p := table.AddWithSoftLifeSpan(k, time.Second, 0, v); p.SoftLifeSpan() != time.Second {
+		t.Error("Explicit soft lifespan should override the table's")
+	}
+}
+
+func TestRefreshedItemDefaults(t *testing.T) {
+	table, _ := CacheWithOptions("testRefreshedItemDefaults", WithDefaultLifeSpan(time.Hour))
+	table.Flush()
+	refreshed := make(chan error, 1)
+	table.SetLoader(func(req *LoadRequest) *CacheItem {
+		// Wait for the caller to be done with its context.
+		time.Sleep(20 * time.Millisecond)
+		refreshed <- req.Context.Err()
+		return NewCacheItem(req.Key, 0, "fresh")
+	})
+
+	table.AddWithSoftLifeSpan(k, time.Millisecond, 0, v)
+	time.Sleep(5 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	if p, err := table.Load(ctx, k, nil); err != nil || p.Data() != v {
+		t.Error("Stale item should still be served", err)
+	}
+	cancel()
+
+	if err := <-refreshed; err != nil {
+		t.Error("Refresh should outlive the caller's context, got", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if p, err := table.Value(k); err != nil || p.Data() != "fresh" || p.LifeSpan() != time.Hour {
+		t.Error("Refreshed item should get the table's default lifespan", err)
+	}
+}
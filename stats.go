@@ -0,0 +1,137 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"sync/atomic"
+)
+
+// CacheStats holds the usage statistics of a cache.
+type CacheStats struct {
+	// Lookups that found an item in the cache.
+	Hits int64
+	// Lookups that didn't find an item in the cache, including those that
+	// got served by the data-loader afterwards.
+	Misses int64
+	// Items added to the cache.
+	Added int64
+	// Items added via the data-loader, included in Added.
+	Loaded int64
+	// Items restored from exports, snapshots and mutation logs, included in
+	// Added.
+	Restored int64
+	// Items removed via Delete.
+	Deleted int64
+	// Items removed because their lifespan was exceeded.
+	Expired int64
+	// Items removed to make room for new ones.
+	Evicted int64
+}
+
+// HitRatio returns the share of lookups that found an item in the cache.
+func (s CacheStats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// add returns the sum of two sets of statistics.
+func (s CacheStats) add(o CacheStats) CacheStats {
+	return CacheStats{
+		Hits:     s.Hits + o.Hits,
+		Misses:   s.Misses + o.Misses,
+		Added:    s.Added + o.Added,
+		Loaded:   s.Loaded + o.Loaded,
+		Restored: s.Restored + o.Restored,
+		Deleted:  s.Deleted + o.Deleted,
+		Expired:  s.Expired + o.Expired,
+		Evicted:  s.Evicted + o.Evicted,
+	}
+}
+
+// statsCounter collects usage statistics. All methods are safe to call on a
+// nil counter, which is how disabled statistics are represented.
+type statsCounter struct {
+	hits     int64
+	misses   int64
+	added    int64
+	loaded   int64
+	restored int64
+	deleted  int64
+	expired  int64
+	evicted  int64
+}
+
+func (s *statsCounter) hit() {
+	if s != nil {
+		atomic.AddInt64(&s.hits, 1)
+	}
+}
+
+func (s *statsCounter) miss() {
+	if s != nil {
+		atomic.AddInt64(&s.misses, 1)
+	}
+}
+
+func (s *statsCounter) add() {
+	if s != nil {
+		atomic.AddInt64(&s.added, 1)
+	}
+}
+
+// addFrom counts an item added from the given source.
+func (s *statsCounter) addFrom(source ItemSource) {
+	if s == nil {
+		return
+	}
+	atomic.AddInt64(&s.added, 1)
+	switch source {
+	case SourceLoader:
+		atomic.AddInt64(&s.loaded, 1)
+	case SourceRestore:
+		atomic.AddInt64(&s.restored, 1)
+	}
+}
+
+func (s *statsCounter) delete() {
+	if s != nil {
+		atomic.AddInt64(&s.deleted, 1)
+	}
+}
+
+func (s *statsCounter) expire() {
+	if s != nil {
+		atomic.AddInt64(&s.expired, 1)
+	}
+}
+
+func (s *statsCounter) evict() {
+	if s != nil {
+		atomic.AddInt64(&s.evicted, 1)
+	}
+}
+
+// snapshot returns the current counter values.
+func (s *statsCounter) snapshot() CacheStats {
+	if s == nil {
+		return CacheStats{}
+	}
+	return CacheStats{
+		Hits:     atomic.LoadInt64(&s.hits),
+		Misses:   atomic.LoadInt64(&s.misses),
+		Added:    atomic.LoadInt64(&s.added),
+		Loaded:   atomic.LoadInt64(&s.loaded),
+		Restored: atomic.LoadInt64(&s.restored),
+		Deleted:  atomic.LoadInt64(&s.deleted),
+		Expired:  atomic.LoadInt64(&s.expired),
+		Evicted:  atomic.LoadInt64(&s.evicted),
+	}
+}
@@ -0,0 +1,50 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+)
+
+var (
+	_ Cacher = &CacheTable{}
+	_ Cacher = &LFUCache{}
+	_ Cacher = &BoundedCache{}
+	_ Cacher = &RequestCache{}
+)
+
+func TestForeachCacheAndAggregateStats(t *testing.T) {
+	before := AggregateStats()
+
+	table, _ := CacheWithOptions("testAggregateStats", WithStats())
+	table.Add("key1", 0, "value1")
+	table.Value("key1")
+	table.Value("missing")
+
+	lfu := NewLFUCache("testAggregateStatsLFU", 2)
+	mutex.Lock()
+	lfuCaches["testAggregateStatsLFU"] = lfu
+	mutex.Unlock()
+	lfu.Add("key1", 0, "value1")
+	lfu.Value("key1")
+	lfu.Delete("key1")
+
+	found := make(map[string]Cacher)
+	ForeachCache(func(name string, c Cacher) {
+		found[name] = c
+	})
+	if found["testAggregateStats"] != table || found["testAggregateStatsLFU"] != lfu {
+		t.Error("ForeachCache should visit all tables and LFU caches")
+	}
+
+	after := AggregateStats()
+	if after.Hits-before.Hits != 2 || after.Misses-before.Misses != 1 ||
+		after.Added-before.Added != 2 || after.Deleted-before.Deleted != 1 {
+		t.Errorf("Unexpected aggregate stats: %+v (before %+v)", after, before)
+	}
+}
@@ -0,0 +1,146 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"time"
+)
+
+// WithStrictMode treats cached values as immutable, to catch bugs that would
+// otherwise show up as mysterious data corruption. Adding an item with a key
+// that's already cached gets rejected, unless it's added via Replace; items
+// from the data-loader and restored items may still replace cached ones.
+// If auditInterval is greater than 0, values get hashed when added, and
+// rehashed in the background at the given interval, until the table gets
+// closed, to detect values modified in place through shared pointers.
+// Violations get logged and reported to the strict violation callbacks, see
+// SetStrictViolationCallback.
+func WithStrictMode(auditInterval time.Duration) Option {
+	return func(o *cacheOptions) {
+		o.strict = true
+		o.auditInterval = auditInterval
+	}
+}
+
+// Replace adds a key/value pair to the cache, just like Add, but may replace
+// an item with the same key in strict mode, see WithStrictMode.
+func (table *CacheTable) Replace(key interface{}, lifeSpan time.Duration, data interface{}) *CacheItem {
+	item := table.newItem(key, lifeSpan, data)
+	item.replace = true
+
+	table.Lock()
+	table.addInternal(item)
+
+	table.audit(context.Background(), auditOpAdd, key)
+	return item
+}
+
+// SetStrictViolationCallback configures a callback, which will be called
+// every time strict mode catches an add overwriting a cached item, with
+// ErrKeyExists, or a value modified in place, with ErrValueMutated.
+func (table *CacheTable) SetStrictViolationCallback(f func(item *CacheItem, err error)) {
+	if len(table.strictViolation) > 0 {
+		table.RemoveStrictViolationCallbacks()
+	}
+	table.Lock()
+	defer table.Unlock()
+	table.strictViolation = append(table.strictViolation, f)
+}
+
+// AddStrictViolationCallback appends a new callback to the strict violation
+// queue.
+func (table *CacheTable) AddStrictViolationCallback(f func(item *CacheItem, err error)) {
+	table.Lock()
+	defer table.Unlock()
+	table.strictViolation = append(table.strictViolation, f)
+}
+
+// RemoveStrictViolationCallbacks empties the strict violation callback queue.
+func (table *CacheTable) RemoveStrictViolationCallbacks() {
+	table.Lock()
+	defer table.Unlock()
+	table.strictViolation = nil
+}
+
+// rejectOverwriteInternal returns whether strict mode rejects adding an item,
+// as it would overwrite a cached one.
+// Careful: do not run this method unless the table-mutex is locked!
+func (table *CacheTable) rejectOverwriteInternal(item *CacheItem) bool {
+	if !table.options.strict || item.replace || item.source != SourceAdd {
+		return false
+	}
+	_, ok := table.items[item.key]
+	return ok
+}
+
+// AuditValues rehashes the values of a table in strict mode with an audit
+// interval, and returns the keys of the items whose values got modified in
+// place since they were added. The violations get reported to the strict
+// violation callbacks as well.
+func (table *CacheTable) AuditValues() []interface{} {
+	table.RLock()
+	var mutated []*CacheItem
+	for _, item := range table.items {
+		if item.valueHash == 0 {
+			continue
+		}
+		if h, ok := valueHash(item.Data()); ok && h != item.valueHash {
+			mutated = append(mutated, item)
+		}
+	}
+	strictViolation := table.strictViolation
+	table.RUnlock()
+
+	keys := make([]interface{}, 0, len(mutated))
+	for _, item := range mutated {
+		table.log("Value of key", item.key, "in table", table.name, "was modified in place")
+		for _, callback := range strictViolation {
+			callback(item, ErrValueMutated)
+		}
+		keys = append(keys, item.key)
+	}
+	return keys
+}
+
+// auditValues periodically audits the table's values until stop is closed.
+func (table *CacheTable) auditValues(interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			table.AuditValues()
+		}
+	}
+}
+
+// valueHash returns a hash of a value's contents. Strings and values that
+// can't be encoded don't get hashed, as they either can't be modified or
+// can't be checked.
+func valueHash(v interface{}) (uint64, bool) {
+	h := fnv.New64a()
+	switch v := v.(type) {
+	case string:
+		return 0, false
+	case []byte:
+		h.Write(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return 0, false
+		}
+		h.Write(b)
+	}
+	// 0 means not hashed.
+	return h.Sum64() | 1, true
+}
@@ -0,0 +1,78 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStrictMode(t *testing.T) {
+	table, _ := CacheWithOptions("testStrictMode", WithStrictMode(0))
+	table.Flush()
+
+	var violations []error
+	table.SetStrictViolationCallback(func(item *CacheItem, err error) {
+		violations = append(violations, err)
+	})
+
+	table.Add(k, 0, v)
+	table.Add(k, 0, v+"overwritten")
+	if r, _ := table.Value(k); r.Data() != v {
+		t.Error("Strict mode allowed overwriting a cached item")
+	}
+	if len(violations) != 1 || violations[0] != ErrKeyExists {
+		t.Error("Expected an ErrKeyExists violation, got", violations)
+	}
+
+	table.Replace(k, 0, v+"replaced")
+	if r, _ := table.Value(k); r.Data() != v+"replaced" {
+		t.Error("Replace didn't replace the cached item")
+	}
+
+	err := table.Tx(func(tx *CacheTx) error {
+		tx.Add(k, 0, v)
+		return nil
+	})
+	if err != ErrKeyExists {
+		t.Error("Expected ErrKeyExists from transaction, got", err)
+	}
+}
+
+func TestStrictModeAudit(t *testing.T) {
+	table, _ := CacheWithOptions("testStrictModeAudit", WithStrictMode(time.Hour))
+	table.Flush()
+
+	mutated := make(chan interface{}, 1)
+	table.SetStrictViolationCallback(func(item *CacheItem, err error) {
+		if err == ErrValueMutated {
+			mutated <- item.Key()
+		}
+	})
+
+	shared := map[string]int{"a": 1}
+	table.Add("shared", 0, shared)
+	table.Add("untouched", 0, []int{1, 2})
+	if keys := table.AuditValues(); len(keys) != 0 {
+		t.Error("Expected no modified values, got", keys)
+	}
+
+	shared["a"] = 2
+	keys := table.AuditValues()
+	if len(keys) != 1 || keys[0] != "shared" {
+		t.Error("Expected the shared value to be reported, got", keys)
+	}
+	select {
+	case key := <-mutated:
+		if key != "shared" {
+			t.Error("Expected violation callback for the shared value, got", key)
+		}
+	default:
+		t.Error("Violation callback didn't get called")
+	}
+}
@@ -0,0 +1,149 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"time"
+)
+
+// tombstone records the deletion of a key.
+type tombstone struct {
+	deletedOn time.Time
+	// The invalidation's sequence number, 0 if none, see Invalidate.
+	seq uint64
+}
+
+// Tombstones are pruned once there are twice as many as after the last
+// pruning, but at least this many.
+const minTombstonePrune = 64
+
+// WithTombstones makes Delete keep a tombstone for the given window, during
+// which adding an item with the same key counts as resurrecting it: racing
+// writers may be about to re-add a value that was just invalidated. The
+// resurrected item callbacks get notified of such adds, see
+// SetResurrectedItemCallback. If reject is set, the items are dropped
+// instead of getting added.
+func WithTombstones(window time.Duration, reject bool) Option {
+	return func(o *cacheOptions) {
+		o.tombstoneWindow = window
+		o.rejectResurrections = reject
+	}
+}
+
+// SetResurrectedItemCallback configures a callback, which will be called
+// every time an item with a tombstoned key gets added or rejected, see
+// WithTombstones, or gets dropped as stale, see AddSeq.
+func (table *CacheTable) SetResurrectedItemCallback(f func(*CacheItem)) {
+	if len(table.resurrectedItem) > 0 {
+		table.RemoveResurrectedItemCallbacks()
+	}
+	table.Lock()
+	defer table.Unlock()
+	table.resurrectedItem = append(table.resurrectedItem, f)
+}
+
+// AddResurrectedItemCallback appends a new callback to the resurrectedItem
+// queue
+func (table *CacheTable) AddResurrectedItemCallback(f func(*CacheItem)) {
+	table.Lock()
+	defer table.Unlock()
+	table.resurrectedItem = append(table.resurrectedItem, f)
+}
+
+// RemoveResurrectedItemCallbacks empties the resurrected item callback queue
+func (table *CacheTable) RemoveResurrectedItemCallbacks() {
+	table.Lock()
+	defer table.Unlock()
+	table.resurrectedItem = nil
+}
+
+// Tombstoned returns whether the key was deleted within the tombstone window,
+// see WithTombstones.
+func (table *CacheTable) Tombstoned(key interface{}) bool {
+	table.RLock()
+	defer table.RUnlock()
+	t, ok := table.tombstones[key]
+	return ok && time.Since(t.deletedOn) < table.options.tombstoneWindow
+}
+
+// tombstone records the deletion of a key, by an invalidation with the given
+// sequence number, or 0.
+// Careful: do not run this method unless the table-mutex is locked!
+func (table *CacheTable) tombstone(key interface{}, seq uint64) {
+	if table.options.tombstoneWindow <= 0 {
+		return
+	}
+	if table.tombstones == nil {
+		table.tombstones = make(map[interface{}]tombstone)
+	}
+
+	now := time.Now()
+	if t, ok := table.tombstones[key]; ok && t.seq > seq {
+		// Don't forget about a later invalidation.
+		seq = t.seq
+	}
+	table.tombstones[key] = tombstone{deletedOn: now, seq: seq}
+	if len(table.tombstones) >= 2*table.tombstonesPruned && len(table.tombstones) >= minTombstonePrune {
+		for k, t := range table.tombstones {
+			if now.Sub(t.deletedOn) >= table.options.tombstoneWindow {
+				delete(table.tombstones, k)
+			}
+		}
+		table.tombstonesPruned = len(table.tombstones)
+	}
+}
+
+// deleteTombstoned deletes an item, recording a tombstone of its key with the
+// given sequence number before the removal callbacks unlock the table-mutex,
+// so stale adds racing them already get rejected. Keys that aren't cached get
+// tombstoned too, as they may be about to get re-added, vetoed deletes don't.
+// Careful: do not run this method unless the table-mutex is locked!
+func (table *CacheTable) deleteTombstoned(key interface{}, seq uint64) (*CacheItem, error) {
+	if table.options.tombstoneWindow <= 0 {
+		return table.deleteInternal(key, RemovalDeleted)
+	}
+
+	r, err := table.deleteInternalFunc(key, RemovalDeleted, func() {
+		table.tombstone(key, seq)
+	})
+	if err == ErrKeyNotFound {
+		table.tombstone(key, seq)
+	}
+	return r, err
+}
+
+// admit decides whether an item with the given sequence number may be added,
+// without changing the table. It returns whether the item is stale, i.e.
+// carries a sequence number not newer than that of the cached item or of an
+// invalidation of its key, and whether adding it resurrects a tombstoned key.
+// Once the item gets added, its tombstone must be removed, see admitted.
+// Careful: do not run this method unless the table-mutex is locked!
+func (table *CacheTable) admit(item *CacheItem, seq uint64) (stale, resurrected bool) {
+	if seq > 0 {
+		if old, ok := table.items[item.key]; ok && seq < old.seq {
+			return true, false
+		}
+	}
+
+	t, ok := table.tombstones[item.key]
+	if !ok || time.Since(t.deletedOn) >= table.options.tombstoneWindow {
+		return false, false
+	}
+	if seq > 0 && t.seq > 0 {
+		// Newer than the invalidation, so it's no resurrection.
+		return seq <= t.seq, false
+	}
+	return false, true
+}
+
+// admitted removes the tombstone of an item's key once admit allowed adding
+// it.
+// Careful: do not run this method unless the table-mutex is locked!
+func (table *CacheTable) admitted(item *CacheItem) {
+	delete(table.tombstones, item.key)
+}
@@ -0,0 +1,84 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTombstones(t *testing.T) {
+	table, _ := CacheWithOptions("testTombstones", WithTombstones(100*time.Millisecond, false))
+	var resurrected []interface{}
+	table.SetResurrectedItemCallback(func(item *CacheItem) {
+		resurrected = append(resurrected, item.Key())
+	})
+
+	table.Add(k, 0, v)
+	table.Delete(k)
+	table.Delete("never cached")
+	if !table.Tombstoned(k) || !table.Tombstoned("never cached") {
+		t.Error("Deleted keys should be tombstoned")
+	}
+
+	table.Add(k, 0, v)
+	if !table.Exists(k) || len(resurrected) != 1 {
+		t.Error("Resurrections should be added and flagged", resurrected)
+	}
+	if table.Tombstoned(k) {
+		t.Error("Resurrected keys should no longer be tombstoned")
+	}
+	table.Add(k, 0, v)
+	if len(resurrected) != 1 {
+		t.Error("Adding a live key should not count as resurrection")
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	table.Add("never cached", 0, v)
+	if len(resurrected) != 1 || table.Tombstoned("never cached") {
+		t.Error("Tombstones should end after the window")
+	}
+}
+
+func TestTombstonesReject(t *testing.T) {
+	table, _ := CacheWithOptions("testTombstonesReject", WithTombstones(time.Minute, true))
+	rejected := 0
+	table.SetResurrectedItemCallback(func(item *CacheItem) {
+		rejected++
+	})
+
+	table.Add(k, 0, v)
+	table.Delete(k)
+	table.Add(k, 0, v)
+	table.Add(k, 0, v)
+	if table.Exists(k) || rejected != 2 {
+		t.Error("Resurrections should be rejected", rejected)
+	}
+
+	table.Add("other", 0, v)
+	if !table.Exists("other") {
+		t.Error("Keys without tombstone should be added")
+	}
+}
+
+func TestTombstonesPrune(t *testing.T) {
+	table, _ := CacheWithOptions("testTombstonesPrune", WithTombstones(time.Millisecond, false))
+	for i := 0; i < 1000; i++ {
+		table.Delete(i)
+		if i%100 == 0 {
+			time.Sleep(2 * time.Millisecond)
+		}
+	}
+
+	table.RLock()
+	n := len(table.tombstones)
+	table.RUnlock()
+	if n >= 1000 {
+		t.Error("Expired tombstones should get pruned, got", n)
+	}
+}
@@ -0,0 +1,103 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// ttlPrefixRule is the lifespan of keys with a prefix, see SetTTLRule.
+type ttlPrefixRule struct {
+	prefix   string
+	lifeSpan time.Duration
+}
+
+// ttlPrefixRulesByLength sorts rules by descending prefix length, so the
+// most specific rule matching a key comes first.
+type ttlPrefixRulesByLength []ttlPrefixRule
+
+func (r ttlPrefixRulesByLength) Len() int           { return len(r) }
+func (r ttlPrefixRulesByLength) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r ttlPrefixRulesByLength) Less(i, j int) bool { return len(r[i].prefix) > len(r[j].prefix) }
+
+// ttlRules determine the lifespans of items added with a lifespan of 0. They
+// never get modified once stored in a table, so they can be read without the
+// table-mutex.
+type ttlRules struct {
+	f        func(key interface{}) time.Duration
+	prefixes []ttlPrefixRule
+}
+
+// SetTTLRule makes items whose keys start with the given prefix expire after
+// the given lifespan, if they get added with a lifespan of 0. The rule with
+// the longest matching prefix applies; keys matching none get the table's
+// default lifespan, see WithDefaultLifeSpan. A lifespan of 0 removes the
+// prefix's rule.
+func (table *CacheTable) SetTTLRule(prefix string, lifeSpan time.Duration) {
+	table.Lock()
+	defer table.Unlock()
+
+	old := table.loadTTLRules()
+	rules := &ttlRules{}
+	if old != nil {
+		rules.f = old.f
+		for _, r := range old.prefixes {
+			if r.prefix != prefix {
+				rules.prefixes = append(rules.prefixes, r)
+			}
+		}
+	}
+	if lifeSpan > 0 {
+		rules.prefixes = append(rules.prefixes, ttlPrefixRule{prefix: prefix, lifeSpan: lifeSpan})
+		sort.Stable(ttlPrefixRulesByLength(rules.prefixes))
+	}
+	table.ttlRules.Store(rules)
+}
+
+// SetTTLFunc configures a callback returning the lifespan of items added with
+// a lifespan of 0, e.g. based on their key's type. It takes precedence over
+// the prefix rules, unless it returns 0. Pass nil to remove it.
+func (table *CacheTable) SetTTLFunc(f func(key interface{}) time.Duration) {
+	table.Lock()
+	defer table.Unlock()
+
+	rules := &ttlRules{f: f}
+	if old := table.loadTTLRules(); old != nil {
+		rules.prefixes = old.prefixes
+	}
+	table.ttlRules.Store(rules)
+}
+
+// loadTTLRules returns the table's lifespan rules, or nil if none are set.
+func (table *CacheTable) loadTTLRules() *ttlRules {
+	r, _ := table.ttlRules.Load().(*ttlRules)
+	return r
+}
+
+// lifeSpan returns the lifespan of a key according to the rules, 0 if none
+// applies.
+func (r *ttlRules) lifeSpan(key interface{}) time.Duration {
+	if r.f != nil {
+		if lifeSpan := r.f(key); lifeSpan > 0 {
+			return lifeSpan
+		}
+	}
+
+	s, ok := key.(string)
+	if !ok {
+		return 0
+	}
+	for _, rule := range r.prefixes {
+		if strings.HasPrefix(s, rule.prefix) {
+			return rule.lifeSpan
+		}
+	}
+	return 0
+}
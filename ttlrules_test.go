@@ -0,0 +1,52 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTTLRules(t *testing.T) {
+	table, _ := CacheWithOptions("testTTLRules", WithDefaultLifeSpan(time.Hour))
+	table.SetTTLRule("session:", time.Minute)
+	table.SetTTLRule("session:admin:", time.Second)
+
+	lifeSpans := map[string]time.Duration{
+		"session:1":       time.Minute,
+		"session:admin:1": time.Second,
+		"user:1":          time.Hour,
+	}
+	for key, lifeSpan := range lifeSpans {
+		if item := table.Add(key, 0, v); item.LifeSpan() != lifeSpan {
+			t.Error("Expected lifespan", lifeSpan, "for key", key, "got", item.LifeSpan())
+		}
+	}
+	if item := table.Add("session:2", 5*time.Minute, v); item.LifeSpan() != 5*time.Minute {
+		t.Error("Rule overrode explicit lifespan", item.LifeSpan())
+	}
+
+	table.SetTTLFunc(func(key interface{}) time.Duration {
+		if _, ok := key.(int); ok {
+			return 2 * time.Minute
+		}
+		return 0
+	})
+	if item := table.Add(1, 0, v); item.LifeSpan() != 2*time.Minute {
+		t.Error("Expected lifespan from TTL func, got", item.LifeSpan())
+	}
+	if item := table.Add("session:3", 0, v); item.LifeSpan() != time.Minute {
+		t.Error("Expected TTL func returning 0 to fall back to rules, got", item.LifeSpan())
+	}
+
+	table.SetTTLRule("session:", 0)
+	table.SetTTLFunc(nil)
+	if item := table.Add("session:4", 0, v); item.LifeSpan() != time.Hour {
+		t.Error("Expected removed rule to fall back to default, got", item.LifeSpan())
+	}
+}
@@ -0,0 +1,105 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"time"
+)
+
+// DefaultTTLBounds are the bucket bounds used by TTLHistogram if none are
+// given.
+var DefaultTTLBounds = []time.Duration{
+	time.Second,
+	10 * time.Second,
+	time.Minute,
+	10 * time.Minute,
+	time.Hour,
+}
+
+// TTLHistogram describes the distribution of the remaining lifespans of a
+// table's items.
+type TTLHistogram struct {
+	// The buckets' upper bounds, in ascending order.
+	Bounds []time.Duration
+	// Number of items per bucket: Counts[i] holds the items expiring within
+	// Bounds[i], but not within Bounds[i-1]. The last count holds the items
+	// expiring after the last bound.
+	Counts []int
+	// Number of items that never expire.
+	NoExpiry int
+}
+
+// remainingLifeSpans calls f with the remaining lifespan of every item that
+// expires. The remaining lifespans assume the items don't get accessed
+// anymore, as accessing an item extends its lifespan.
+func (table *CacheTable) remainingLifeSpans(f func(remaining time.Duration)) (noExpiry int) {
+	table.RLock()
+	defer table.RUnlock()
+
+	now := time.Now()
+	for _, item := range table.items {
+		item.RLock()
+		lifeSpan := item.lifeSpan
+		accessedOn := item.AccessedOn()
+		item.RUnlock()
+
+		if lifeSpan == 0 {
+			noExpiry++
+			continue
+		}
+		remaining := lifeSpan - now.Sub(accessedOn)
+		if remaining < 0 {
+			remaining = 0
+		}
+		f(remaining)
+	}
+
+	return noExpiry
+}
+
+// TTLHistogram returns the distribution of the remaining lifespans of the
+// table's items, bucketed by the given ascending bounds, or DefaultTTLBounds
+// if none are given.
+func (table *CacheTable) TTLHistogram(bounds ...time.Duration) TTLHistogram {
+	if len(bounds) == 0 {
+		bounds = DefaultTTLBounds
+	}
+
+	h := TTLHistogram{
+		Bounds: bounds,
+		Counts: make([]int, len(bounds)+1),
+	}
+	h.NoExpiry = table.remainingLifeSpans(func(remaining time.Duration) {
+		i := 0
+		for i < len(bounds) && remaining > bounds[i] {
+			i++
+		}
+		h.Counts[i]++
+	})
+
+	return h
+}
+
+// ExpiryForecast returns how many items will expire in each of the next n
+// intervals, unless they get accessed in the meantime. Spikes indicate many
+// items expiring at once, which may cause a thundering herd of refreshes;
+// adding some jitter to the lifespans smoothes them out.
+func (table *CacheTable) ExpiryForecast(interval time.Duration, n int) []int {
+	forecast := make([]int, n)
+	if interval <= 0 {
+		return forecast
+	}
+
+	table.remainingLifeSpans(func(remaining time.Duration) {
+		if i := int(remaining / interval); i < n {
+			forecast[i]++
+		}
+	})
+
+	return forecast
+}
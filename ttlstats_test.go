@@ -0,0 +1,53 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTTLHistogram(t *testing.T) {
+	table := Cache("testTTLHistogram")
+	table.Add(1, 0, v)
+	table.Add(2, 5*time.Second, v)
+	table.Add(3, 30*time.Second, v)
+	table.Add(4, 45*time.Second, v)
+	table.Add(5, 2*time.Hour, v)
+
+	h := table.TTLHistogram()
+	expected := []int{0, 1, 2, 0, 0, 1}
+	if h.NoExpiry != 1 || len(h.Counts) != len(expected) {
+		t.Errorf("Unexpected histogram %+v", h)
+		return
+	}
+	for i, c := range expected {
+		if h.Counts[i] != c {
+			t.Errorf("Unexpected count in bucket %d: %+v", i, h)
+		}
+	}
+
+	h = table.TTLHistogram(time.Minute)
+	if h.Counts[0] != 3 || h.Counts[1] != 1 {
+		t.Errorf("Unexpected histogram with custom bounds %+v", h)
+	}
+}
+
+func TestExpiryForecast(t *testing.T) {
+	table := Cache("testExpiryForecast")
+	table.Add(1, 0, v)
+	table.Add(2, 5*time.Second, v)
+	table.Add(3, 15*time.Second, v)
+	table.Add(4, 18*time.Second, v)
+	table.Add(5, time.Hour, v)
+
+	forecast := table.ExpiryForecast(10*time.Second, 3)
+	if len(forecast) != 3 || forecast[0] != 1 || forecast[1] != 2 || forecast[2] != 0 {
+		t.Error("Unexpected forecast", forecast)
+	}
+}
@@ -0,0 +1,144 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"container/list"
+)
+
+// twoQueueEntry tracks in which queue a resident key currently lives.
+type twoQueueEntry struct {
+	element *list.Element
+	hot     bool
+}
+
+// TwoQueuePolicy implements the 2Q eviction policy. New items enter the A1in
+// FIFO queue. Keys evicted from A1in are remembered in the A1out ghost queue,
+// and only items that get re-added while still remembered there make it into
+// the Am LRU queue. One-time scans therefore never touch Am.
+type TwoQueuePolicy struct {
+	// Maximum number of items in A1in and keys remembered in A1out.
+	inCapacity    int
+	ghostCapacity int
+
+	// Resident queues, most recent items at the front.
+	in  *list.List
+	hot *list.List
+	// Ghost queue holding only keys, most recent at the front.
+	ghost *list.List
+
+	entries map[interface{}]*twoQueueEntry
+	ghosts  map[interface{}]*list.Element
+}
+
+// NewTwoQueuePolicy creates a 2Q policy for a cache with the given capacity.
+// Parameter inRatio determines which share of the capacity is used for the
+// A1in queue, ghostRatio how many evicted keys (relative to the capacity) get
+// remembered in A1out. The original paper suggests 0.25 and 0.5.
+func NewTwoQueuePolicy(capacity int, inRatio, ghostRatio float64) *TwoQueuePolicy {
+	inCapacity := int(float64(capacity) * inRatio)
+	if inCapacity < 1 {
+		inCapacity = 1
+	}
+
+	return &TwoQueuePolicy{
+		inCapacity:    inCapacity,
+		ghostCapacity: int(float64(capacity) * ghostRatio),
+		in:            list.New(),
+		hot:           list.New(),
+		ghost:         list.New(),
+		entries:       make(map[interface{}]*twoQueueEntry),
+		ghosts:        make(map[interface{}]*list.Element),
+	}
+}
+
+// Add puts a new item into Am if its key was recently evicted from A1in, and
+// into A1in otherwise.
+func (p *TwoQueuePolicy) Add(item *CacheItem) {
+	if _, exists := p.entries[item.key]; exists {
+		p.Access(item)
+		return
+	}
+
+	if element, exists := p.ghosts[item.key]; exists {
+		p.ghost.Remove(element)
+		delete(p.ghosts, item.key)
+		p.entries[item.key] = &twoQueueEntry{
+			element: p.hot.PushFront(item.key),
+			hot:     true,
+		}
+		return
+	}
+
+	p.entries[item.key] = &twoQueueEntry{
+		element: p.in.PushFront(item.key),
+	}
+}
+
+// Access refreshes the recency of items in Am. Accesses to items in A1in are
+// deliberately ignored, as they're usually correlated references.
+func (p *TwoQueuePolicy) Access(item *CacheItem) {
+	if entry, exists := p.entries[item.key]; exists && entry.hot {
+		p.hot.MoveToFront(entry.element)
+	}
+}
+
+// Remove drops a resident item without remembering it in A1out.
+func (p *TwoQueuePolicy) Remove(item *CacheItem) {
+	entry, exists := p.entries[item.key]
+	if !exists {
+		return
+	}
+	if entry.hot {
+		p.hot.Remove(entry.element)
+	} else {
+		p.in.Remove(entry.element)
+	}
+	delete(p.entries, item.key)
+}
+
+// Evict reclaims the oldest A1in item once A1in exceeds its share (or Am is
+// empty), remembering its key in A1out. Otherwise it evicts the least
+// recently used item from Am.
+func (p *TwoQueuePolicy) Evict() (interface{}, bool) {
+	if p.in.Len() > 0 && (p.in.Len() >= p.inCapacity || p.hot.Len() == 0) {
+		key := p.in.Remove(p.in.Back())
+		delete(p.entries, key)
+		p.remember(key)
+		return key, true
+	}
+
+	back := p.hot.Back()
+	if back == nil {
+		return nil, false
+	}
+	key := p.hot.Remove(back)
+	delete(p.entries, key)
+	return key, true
+}
+
+// Reset drops all bookkeeping, including remembered keys.
+func (p *TwoQueuePolicy) Reset() {
+	p.in.Init()
+	p.hot.Init()
+	p.ghost.Init()
+	p.entries = make(map[interface{}]*twoQueueEntry)
+	p.ghosts = make(map[interface{}]*list.Element)
+}
+
+// remember adds a key to A1out, forgetting the oldest keys if it's full.
+func (p *TwoQueuePolicy) remember(key interface{}) {
+	if p.ghostCapacity == 0 {
+		return
+	}
+
+	p.ghosts[key] = p.ghost.PushFront(key)
+	for p.ghost.Len() > p.ghostCapacity {
+		delete(p.ghosts, p.ghost.Remove(p.ghost.Back()))
+	}
+}
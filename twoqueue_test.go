@@ -0,0 +1,71 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+)
+
+func TestTwoQueuePromotion(t *testing.T) {
+	p := NewTwoQueuePolicy(4, 0.25, 0.5)
+
+	a := NewCacheItem("a", 0, nil)
+	b := NewCacheItem("b", 0, nil)
+	p.Add(a)
+	p.Add(b)
+
+	// a leaves A1in first and gets remembered in A1out
+	if key, _ := p.Evict(); key != "a" {
+		t.Error("Expected a to be evicted first, got", key)
+	}
+
+	// Re-adding a remembered key puts it into Am
+	p.Add(a)
+	if entry := p.entries["a"]; entry == nil || !entry.hot {
+		t.Error("Re-added key should be placed in Am")
+	}
+	if _, exists := p.ghosts["a"]; exists {
+		t.Error("Promoted key should no longer be remembered in A1out")
+	}
+
+	// A1in is drained before Am
+	if key, _ := p.Evict(); key != "b" {
+		t.Error("Expected b to be evicted from A1in, got", key)
+	}
+	if key, _ := p.Evict(); key != "a" {
+		t.Error("Expected a to be evicted from Am, got", key)
+	}
+	if _, ok := p.Evict(); ok {
+		t.Error("Empty policy should not return a victim")
+	}
+}
+
+func TestTwoQueueScanResistance(t *testing.T) {
+	cache := NewBoundedCache("testTwoQueue", 8, NewTwoQueuePolicy(8, 0.25, 0.5))
+
+	// Get two keys into Am by having them evicted from and re-added to A1in
+	cache.Add("hot1", 0, "value")
+	cache.Add("hot2", 0, "value")
+	for i := 0; i < 8; i++ {
+		cache.Add(i, 0, i)
+	}
+	cache.Add("hot1", 0, "value")
+	cache.Add("hot2", 0, "value")
+
+	// A long one-off scan only churns through A1in
+	for i := 100; i < 1000; i++ {
+		cache.Add(i, 0, i)
+	}
+
+	if !cache.Exists("hot1") || !cache.Exists("hot2") {
+		t.Error("Items in Am should survive a scan")
+	}
+	if cache.Count() != 8 {
+		t.Error("Cache should be filled to capacity")
+	}
+}
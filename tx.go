@@ -0,0 +1,294 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"context"
+	"time"
+)
+
+// CacheTx collects the writes of a transaction, see CacheTable.Tx.
+type CacheTx struct {
+	table *CacheTable
+
+	// Pending writes by key, nil for deletions, and the keys in the order
+	// they were first written.
+	writes map[interface{}]*CacheItem
+	keys   []interface{}
+	// Generations of the items read by optimistic transactions, 0 for keys
+	// that weren't cached, nil for other transactions.
+	reads map[interface{}]uint64
+}
+
+// Tx runs f, then applies the writes it made through the transaction all at
+// once: other users of the table either see none or all of them. If f returns
+// an error, the writes get discarded and the error is returned. The whole
+// transaction gets discarded as well, returning ErrTxRejected, if the
+// sequencer or tombstones reject any of its items, see AddSeq and
+// WithTombstones, ErrKeyExists, if strict mode rejects overwriting an item,
+// see WithStrictMode, or ErrDeleteVetoed, if a delete veto callback objects
+// to deleting one, see SetDeleteVetoCallback.
+// Callbacks of the added and deleted items run after the writes got applied.
+// Transactions don't isolate reads: the items read may get changed by others
+// before the transaction gets applied.
+func (table *CacheTable) Tx(f func(tx *CacheTx) error) error {
+	tx := &CacheTx{
+		table:  table,
+		writes: make(map[interface{}]*CacheItem),
+	}
+	if err := f(tx); err != nil {
+		return err
+	}
+	return table.commit(tx)
+}
+
+// OptimisticTx runs a transaction just like Tx, additionally recording the
+// generation of every item read through it, see CacheItem.Generation. If any
+// of the keys read got added, replaced or deleted by the time the
+// transaction gets applied, it gets discarded and ErrConflict is returned, so
+// the caller can retry it based on the current values.
+func (table *CacheTable) OptimisticTx(f func(tx *CacheTx) error) error {
+	tx := &CacheTx{
+		table:  table,
+		writes: make(map[interface{}]*CacheItem),
+		reads:  make(map[interface{}]uint64),
+	}
+	if err := f(tx); err != nil {
+		return err
+	}
+	return table.commit(tx)
+}
+
+// Value returns an item from the cache, just like CacheTable.Value, taking the
+// transaction's own writes into account.
+func (tx *CacheTx) Value(key interface{}, args ...interface{}) (*CacheItem, error) {
+	if item, ok := tx.writes[key]; ok {
+		if item == nil {
+			return nil, ErrKeyNotFound
+		}
+		return item, nil
+	}
+
+	item, err := tx.table.Value(key, args...)
+	if tx.reads != nil {
+		if _, ok := tx.reads[key]; !ok {
+			var generation uint64
+			if err == nil {
+				generation = item.Generation()
+			}
+			tx.reads[key] = generation
+		}
+	}
+	return item, err
+}
+
+// Add adds a key/value pair to the cache once the transaction gets applied,
+// see CacheTable.Add.
+func (tx *CacheTx) Add(key interface{}, lifeSpan time.Duration, data interface{}) *CacheItem {
+	item := tx.table.newItem(key, lifeSpan, data)
+	tx.write(key, item)
+	return item
+}
+
+// Replace adds a key/value pair to the cache once the transaction gets
+// applied, see CacheTable.Replace.
+func (tx *CacheTx) Replace(key interface{}, lifeSpan time.Duration, data interface{}) *CacheItem {
+	item := tx.Add(key, lifeSpan, data)
+	item.replace = true
+	return item
+}
+
+// Delete deletes an item from the cache once the transaction gets applied,
+// see CacheTable.Delete. Deleting keys that aren't cached is a no-op.
+func (tx *CacheTx) Delete(key interface{}) {
+	tx.write(key, nil)
+}
+
+// write records a pending write, nil for deletions.
+func (tx *CacheTx) write(key interface{}, item *CacheItem) {
+	if _, ok := tx.writes[key]; !ok {
+		tx.keys = append(tx.keys, key)
+	}
+	tx.writes[key] = item
+}
+
+// commit applies a transaction's writes without unlocking the table in
+// between, then runs their callbacks.
+func (table *CacheTable) commit(tx *CacheTx) error {
+	table.touch()
+	table.Lock()
+
+	// The vetoes unlock the table, so check for conflicts afterwards.
+	if err := table.vetoDeletesInternal(tx); err != nil {
+		table.Unlock()
+		return err
+	}
+	for key, generation := range tx.reads {
+		var current uint64
+		if r, ok := table.items[key]; ok {
+			current = r.Generation()
+		}
+		if current != generation {
+			table.Unlock()
+			return ErrConflict
+		}
+	}
+
+	// Check all items first, so a rejected one discards the whole transaction
+	// before any of them changed the table.
+	for _, key := range tx.keys {
+		if item := tx.writes[key]; item != nil && table.rejectOverwriteInternal(item) {
+			table.Unlock()
+			table.log("Rejecting transaction overwriting key", key, "in table", table.name)
+			return ErrKeyExists
+		}
+	}
+	// Items without a sequence number of their own come last, so the
+	// sequencer only gets asked once everything else got admitted.
+	var resurrected []*CacheItem
+	seqs := make(map[interface{}]uint64)
+	for _, sequenced := range []bool{false, true} {
+		for _, key := range tx.keys {
+			item := tx.writes[key]
+			if item == nil || (item.seq == 0 && table.sequencer != nil) != sequenced {
+				continue
+			}
+			seq := item.seq
+			if sequenced {
+				seq = table.sequencer(item)
+			}
+			seqs[key] = seq
+			stale, res := table.admit(item, seq)
+			if stale || (res && table.options.rejectResurrections) {
+				table.Unlock()
+				table.log("Rejecting transaction with stale or tombstoned key", key, "in table", table.name)
+				return ErrTxRejected
+			}
+			if res {
+				resurrected = append(resurrected, item)
+			}
+		}
+	}
+
+	var added, deleted, replaced []*CacheItem
+	for _, key := range tx.keys {
+		item := tx.writes[key]
+		if item == nil {
+			if r, ok := table.items[key]; ok {
+				table.unlinkInternal(key, r, RemovalDeleted)
+				if r.claimRemoval() {
+					deleted = append(deleted, r)
+				}
+			}
+			table.tombstone(key, 0)
+			continue
+		}
+
+		item.seq = seqs[key]
+		table.admitted(item)
+		if table.shadow != nil {
+			table.shadow.Add(item.key, item.lifeSpan, nil)
+		}
+		if table.bypass {
+			table.stats.addFrom(item.source)
+			continue
+		}
+		if old, ok := table.storeInternal(item); ok && old.claimRemoval() {
+			replaced = append(replaced, old)
+		}
+		added = append(added, item)
+	}
+
+	// Evictions may unlock the table, so they only happen once all writes
+	// got applied.
+	table.evictInternal(0)
+	for _, l := range table.prefixLimits {
+		table.evictPrefixInternal(l, 0)
+	}
+
+	// Cache values so we don't keep blocking the mutex.
+	expDur := table.cleanupInterval
+	addedItem := table.addedItem
+	aboutToDeleteItem := table.aboutToDeleteItem
+	resurrectedItem := table.resurrectedItem
+	table.Unlock()
+
+	table.enforceLimits()
+
+	for _, r := range deleted {
+		r.removed(RemovalDeleted, aboutToDeleteItem)
+		table.stats.delete()
+		table.audit(context.Background(), auditOpDelete, r.key)
+	}
+	for _, old := range replaced {
+		if old.removalListener != nil {
+			old.removalListener(old, RemovalReplaced)
+		}
+		old.Release()
+	}
+	for _, item := range resurrected {
+		for _, callback := range resurrectedItem {
+			callback(item)
+		}
+	}
+	check := false
+	for _, item := range added {
+		for _, callback := range addedItem {
+			callback(item)
+		}
+		table.audit(context.Background(), auditOpAdd, item.key)
+		// If we haven't set up any expiration check timer or found a more
+		// imminent item.
+		if item.lifeSpan > 0 && (expDur == 0 || item.lifeSpan < expDur) {
+			check = true
+		}
+	}
+	if check {
+		table.expirationCheck()
+	}
+
+	return nil
+}
+
+// vetoDeletesInternal runs the delete veto callbacks for the items a
+// transaction deletes, unlocking the table meanwhile. It repeats for items
+// added in the meantime, until the vetoes agreed to delete all items cached
+// at those keys, or returns ErrDeleteVetoed once one of them objects.
+// Careful: do not run this method unless the table-mutex is locked!
+func (table *CacheTable) vetoDeletesInternal(tx *CacheTx) error {
+	vetted := make(map[*CacheItem]bool)
+	for {
+		var pending []*CacheItem
+		for _, key := range tx.keys {
+			if tx.writes[key] != nil {
+				continue
+			}
+			if r, ok := table.items[key]; ok && !vetted[r] {
+				pending = append(pending, r)
+			}
+		}
+		if len(pending) == 0 || len(table.deleteVeto) == 0 {
+			return nil
+		}
+
+		// Cache value so we don't keep blocking the mutex.
+		deleteVeto := table.deleteVeto
+		table.Unlock()
+		for _, r := range pending {
+			for _, callback := range deleteVeto {
+				if !callback(r) {
+					table.Lock()
+					table.log("Transaction deleting item with key", r.key, "from table", table.name, "was vetoed")
+					return ErrDeleteVetoed
+				}
+			}
+			vetted[r] = true
+		}
+		table.Lock()
+	}
+}
@@ -0,0 +1,256 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTx(t *testing.T) {
+	table := Cache("testTx")
+	table.Add("a", 0, 1)
+	table.Add("b", 0, 2)
+
+	added := 0
+	deleted := 0
+	table.SetAddedItemCallback(func(item *CacheItem) {
+		added++
+	})
+	table.SetAboutToDeleteItemCallback(func(item *CacheItem) {
+		deleted++
+	})
+
+	err := table.Tx(func(tx *CacheTx) error {
+		a, err := tx.Value("a")
+		if err != nil {
+			return err
+		}
+		tx.Add("c", 0, a.Data().(int)+10)
+		tx.Delete("a")
+		if _, err := tx.Value("a"); err != ErrKeyNotFound {
+			t.Error("Transaction doesn't see its own deletion")
+		}
+		if c, err := tx.Value("c"); err != nil || c.Data().(int) != 11 {
+			t.Error("Transaction doesn't see its own add")
+		}
+		if table.Exists("c") {
+			t.Error("Add got applied before the transaction finished")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error("Error applying transaction", err)
+	}
+	if table.Exists("a") || !table.Exists("b") || !table.Exists("c") {
+		t.Error("Transaction didn't get applied correctly")
+	}
+	if added != 1 || deleted != 1 {
+		t.Error("Expected 1 added and 1 deleted callback, got", added, "and", deleted)
+	}
+
+	errAbort := errors.New("abort")
+	err = table.Tx(func(tx *CacheTx) error {
+		tx.Add("d", 0, 4)
+		tx.Delete("b")
+		return errAbort
+	})
+	if err != errAbort {
+		t.Error("Expected the transaction's error, got", err)
+	}
+	if table.Exists("d") || !table.Exists("b") {
+		t.Error("Failed transaction got applied")
+	}
+}
+
+func TestTxRejected(t *testing.T) {
+	table, _ := CacheWithOptions("testTxRejected", WithTombstones(time.Minute, true))
+	table.Add("a", 0, 1)
+	table.Delete("a")
+
+	err := table.Tx(func(tx *CacheTx) error {
+		tx.Add("b", 0, 2)
+		tx.Add("a", 0, 1)
+		return nil
+	})
+	if err != ErrTxRejected {
+		t.Error("Expected ErrTxRejected, got", err)
+	}
+	if table.Exists("a") || table.Exists("b") {
+		t.Error("Rejected transaction got applied")
+	}
+}
+
+func TestTxRejectedKeepsTombstones(t *testing.T) {
+	table, _ := CacheWithOptions("testTxRejectedKeepsTombstones", WithTombstones(time.Minute, false))
+	table.Flush()
+	table.Add("a", 0, 1)
+	table.Delete("a")
+	table.AddSeq("b", 5, 0, 1)
+
+	err := table.Tx(func(tx *CacheTx) error {
+		tx.Add("a", 0, 2)
+		tx.Add("b", 0, 2).seq = 1
+		return nil
+	})
+	if err != ErrTxRejected {
+		t.Error("Expected ErrTxRejected, got", err)
+	}
+	if !table.Tombstoned("a") {
+		t.Error("Rejected transaction removed a tombstone")
+	}
+}
+
+func TestTxAtomic(t *testing.T) {
+	table := Cache("testTxAtomic")
+	table.Add("a", 0, 0)
+	table.Add("b", 0, 0)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= 1000; i++ {
+			n := i
+			table.Tx(func(tx *CacheTx) error {
+				tx.Add("a", 0, n)
+				tx.Add("b", 0, n)
+				return nil
+			})
+		}
+	}()
+
+	for i := 0; i < 1000; i++ {
+		values := make(map[interface{}]int)
+		table.Foreach(func(key interface{}, item *CacheItem) {
+			values[key] = item.Data().(int)
+		})
+		if values["a"] != values["b"] {
+			t.Error("Saw a partially applied transaction:", values)
+			break
+		}
+	}
+	wg.Wait()
+}
+
+func TestTxCapacity(t *testing.T) {
+	table, _ := CacheWithOptions("testTxCapacity", WithCapacity(2))
+	table.Add("a", 0, 1)
+	table.Add("b", 0, 2)
+
+	table.Tx(func(tx *CacheTx) error {
+		tx.Add("c", 0, 3)
+		tx.Add("d", 0, 4)
+		return nil
+	})
+	if table.Count() != 2 {
+		t.Error("Expected the table to be trimmed to 2 items, got", table.Count())
+	}
+}
+
+func TestOptimisticTx(t *testing.T) {
+	table := Cache("testOptimisticTx")
+	table.Add("a", 0, 1)
+
+	increment := func(concurrent func()) error {
+		return table.OptimisticTx(func(tx *CacheTx) error {
+			a, err := tx.Value("a")
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Value("b"); err != ErrKeyNotFound {
+				t.Error("Expected ErrKeyNotFound, got", err)
+			}
+			if concurrent != nil {
+				concurrent()
+			}
+			tx.Add("a", 0, a.Data().(int)+1)
+			return nil
+		})
+	}
+
+	if err := increment(nil); err != nil {
+		t.Error("Error applying transaction", err)
+	}
+	if err := increment(func() { table.Add("a", 0, 10) }); err != ErrConflict {
+		t.Error("Expected ErrConflict after replacing a read key, got", err)
+	}
+	if err := increment(func() { table.Add("b", 0, 10) }); err != ErrConflict {
+		t.Error("Expected ErrConflict after adding a read key, got", err)
+	}
+	table.Delete("b")
+	if err := increment(func() { table.Add("c", 0, 10) }); err != nil {
+		t.Error("Unrelated change caused a conflict", err)
+	}
+
+	a, _ := table.Value("a")
+	if a.Data().(int) != 11 {
+		t.Error("Expected a to be 11, got", a.Data())
+	}
+}
+
+func TestTxDeleteVetoed(t *testing.T) {
+	table, _ := CacheWithOptions("testTxDeleteVetoed")
+	table.Add("busy", 0, 1)
+	table.Add("idle", 0, 2)
+	table.SetDeleteVetoCallback(func(item *CacheItem) bool {
+		return item.Key() != "busy"
+	})
+	deleted := 0
+	table.SetAboutToDeleteItemCallback(func(item *CacheItem) {
+		deleted++
+	})
+
+	err := table.Tx(func(tx *CacheTx) error {
+		tx.Add("new", 0, 3)
+		tx.Delete("idle")
+		tx.Delete("busy")
+		return nil
+	})
+	if err != ErrDeleteVetoed {
+		t.Error("Expected ErrDeleteVetoed, got", err)
+	}
+	if table.Exists("new") || !table.Exists("idle") || !table.Exists("busy") || deleted != 0 {
+		t.Error("Vetoed transaction got applied")
+	}
+
+	err = table.Tx(func(tx *CacheTx) error {
+		tx.Delete("idle")
+		return nil
+	})
+	if err != nil {
+		t.Error("Error applying transaction", err)
+	}
+	if table.Exists("idle") || deleted != 1 {
+		t.Error("Transaction didn't delete the item agreed on")
+	}
+}
+
+func TestTxRejectedKeepsSequence(t *testing.T) {
+	table, _ := CacheWithOptions("testTxRejectedKeepsSequence", WithStrictMode(0))
+	var seq uint64
+	table.SetSequencer(func(item *CacheItem) uint64 {
+		seq++
+		return seq
+	})
+	table.Add("a", 0, 1)
+
+	err := table.Tx(func(tx *CacheTx) error {
+		tx.Add("b", 0, 2)
+		tx.Add("a", 0, 2)
+		return nil
+	})
+	if err != ErrKeyExists {
+		t.Error("Expected ErrKeyExists, got", err)
+	}
+	if seq != 1 {
+		t.Error("Rejected transaction used up sequence numbers, expected 1, got", seq)
+	}
+}
@@ -0,0 +1,40 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+// SetDeleteVetoCallback configures a callback, which will be called every
+// time an item is about to be deleted explicitly, e.g. via Delete or
+// Invalidate, before the about to delete item callbacks. Returning false
+// vetoes the delete: the item stays cached and ErrDeleteVetoed is returned.
+// This allows keeping items while something else still references them, e.g.
+// a background job. Expiration, eviction, replacing and flushing items can't
+// be vetoed. A vetoed delete within a transaction discards the whole
+// transaction, see CacheTable.Tx.
+func (table *CacheTable) SetDeleteVetoCallback(f func(*CacheItem) bool) {
+	if len(table.deleteVeto) > 0 {
+		table.RemoveDeleteVetoCallbacks()
+	}
+	table.Lock()
+	defer table.Unlock()
+	table.deleteVeto = append(table.deleteVeto, f)
+}
+
+// AddDeleteVetoCallback appends a new callback to the delete veto queue. The
+// item is only deleted if all of them agree.
+func (table *CacheTable) AddDeleteVetoCallback(f func(*CacheItem) bool) {
+	table.Lock()
+	defer table.Unlock()
+	table.deleteVeto = append(table.deleteVeto, f)
+}
+
+// RemoveDeleteVetoCallbacks empties the delete veto callback queue.
+func (table *CacheTable) RemoveDeleteVetoCallbacks() {
+	table.Lock()
+	defer table.Unlock()
+	table.deleteVeto = nil
+}
@@ -0,0 +1,55 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"testing"
+)
+
+func TestDeleteVeto(t *testing.T) {
+	table, _ := CacheWithOptions("testDeleteVeto")
+	table.Flush()
+
+	referenced := map[interface{}]bool{"busy": true}
+	table.SetDeleteVetoCallback(func(item *CacheItem) bool {
+		return !referenced[item.Key()]
+	})
+	deleted := 0
+	table.SetAboutToDeleteItemCallback(func(item *CacheItem) {
+		deleted++
+	})
+	defer table.RemoveAboutToDeleteItemCallback()
+	defer table.RemoveDeleteVetoCallbacks()
+
+	table.Add("busy", 0, v)
+	table.Add("idle", 0, v)
+
+	if _, err := table.Delete("busy"); err != ErrDeleteVetoed {
+		t.Error("Expected ErrDeleteVetoed, got", err)
+	}
+	if !table.Exists("busy") || deleted != 0 {
+		t.Error("Vetoed items should be kept without triggering callbacks")
+	}
+	if _, err := table.Delete("idle"); err != nil || table.Exists("idle") || deleted != 1 {
+		t.Error("Items not vetoed should be deleted", err)
+	}
+
+	referenced["busy"] = false
+	if _, err := table.Delete("busy"); err != nil || table.Exists("busy") {
+		t.Error("Items should be deleted once the veto is lifted", err)
+	}
+
+	// Only explicit deletes can be vetoed.
+	referenced["busy"] = true
+	table.Add("busy", 0, v)
+	table.Add("busy", 0, v)
+	table.Flush()
+	if table.Exists("busy") {
+		t.Error("Flushing items should not be vetoed")
+	}
+}
@@ -0,0 +1,223 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// EventEvictionStorm is the type of events sent by a WebhookSink once the
+// evictions exceed its threshold.
+const EventEvictionStorm = "evictionStorm"
+
+// WebhookSink is an EventSink notifying a webhook of the events worth
+// alerting on: flushes, deletions of specific keys and eviction storms. Use
+// it with EnableEvents.
+type WebhookSink struct {
+	// The URL the notifications get posted to.
+	URL string
+	// Renders a batch of events, a []Event, into the request body; a JSON
+	// array of the events if nil.
+	Template *template.Template
+	// The body's content type, "application/json" if empty.
+	ContentType string
+
+	// Whether to notify of flushes.
+	Flushes bool
+	// Whether to notify of the deletion of a key, nil for none.
+	KeyDeleted func(key interface{}) bool
+	// Notifies once more than StormEvictions items got evicted within
+	// StormWindow, 0 disables it.
+	StormEvictions int
+	StormWindow    time.Duration
+
+	// Notifications get batched for BatchInterval, or until BatchSize of them
+	// are waiting, if set. A BatchInterval of 0 sends them right away.
+	BatchSize     int
+	BatchInterval time.Duration
+	// How often to retry failed requests, waiting RetryBackoff before the
+	// first retry and doubling it for every further one.
+	Retries      int
+	RetryBackoff time.Duration
+	// The client used to send the requests, http.DefaultClient if nil.
+	Client *http.Client
+
+	mutex sync.Mutex
+	batch []Event
+	timer *time.Timer
+	// Error of the last batch sent in the background.
+	err error
+	// Times of the recent evictions, and of the last storm notification.
+	evictions []time.Time
+	stormedOn time.Time
+	// Keeps batches in order, even if one gets sent in the background.
+	sending sync.Mutex
+}
+
+// NewWebhookSink creates a sink posting notifications to the given URL.
+// Configure what to notify of via its fields.
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{URL: url}
+}
+
+// Publish batches an event worth notifying of, and sends the batch once it's
+// full. Returns the error of sending the batch, or of a batch previously sent
+// in the background.
+func (s *WebhookSink) Publish(e Event) error {
+	s.mutex.Lock()
+	notify := s.notable(e)
+	if notify == nil {
+		err := s.err
+		s.err = nil
+		s.mutex.Unlock()
+		return err
+	}
+
+	s.batch = append(s.batch, *notify)
+	if s.BatchInterval > 0 && (s.BatchSize <= 0 || len(s.batch) < s.BatchSize) {
+		if s.timer == nil {
+			s.timer = time.AfterFunc(s.BatchInterval, s.flushInBackground)
+		}
+		err := s.err
+		s.err = nil
+		s.mutex.Unlock()
+		return err
+	}
+	batch := s.takeBatch()
+	s.mutex.Unlock()
+
+	return s.send(batch)
+}
+
+// Close sends the waiting notifications, if any.
+func (s *WebhookSink) Close() error {
+	s.mutex.Lock()
+	batch := s.takeBatch()
+	s.mutex.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+	return s.send(batch)
+}
+
+// notable returns the notification for an event, or nil if it isn't worth
+// one.
+// Careful: do not run this method unless the sink-mutex is locked!
+func (s *WebhookSink) notable(e Event) *Event {
+	switch e.Type {
+	case EventFlush:
+		if s.Flushes {
+			return &e
+		}
+	case EventDelete:
+		if s.KeyDeleted != nil && s.KeyDeleted(e.Key) {
+			return &e
+		}
+	case EventEvict:
+		if s.StormEvictions <= 0 {
+			return nil
+		}
+
+		// Forget evictions that left the window.
+		s.evictions = append(s.evictions, e.Time)
+		i := 0
+		for i < len(s.evictions) && e.Time.Sub(s.evictions[i]) > s.StormWindow {
+			i++
+		}
+		s.evictions = s.evictions[i:]
+
+		if len(s.evictions) > s.StormEvictions && e.Time.Sub(s.stormedOn) > s.StormWindow {
+			s.stormedOn = e.Time
+			return &Event{Time: e.Time, Table: e.Table, Type: EventEvictionStorm}
+		}
+	}
+	return nil
+}
+
+// takeBatch returns the waiting notifications and stops the batch timer.
+// Careful: do not run this method unless the sink-mutex is locked!
+func (s *WebhookSink) takeBatch() []Event {
+	batch := s.batch
+	s.batch = nil
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	return batch
+}
+
+// flushInBackground sends the waiting notifications once the batch interval
+// passed.
+func (s *WebhookSink) flushInBackground() {
+	s.mutex.Lock()
+	batch := s.takeBatch()
+	s.mutex.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+
+	if err := s.send(batch); err != nil {
+		s.mutex.Lock()
+		s.err = err
+		s.mutex.Unlock()
+	}
+}
+
+// send posts a batch of notifications, retrying if configured.
+func (s *WebhookSink) send(batch []Event) error {
+	var body bytes.Buffer
+	if s.Template != nil {
+		if err := s.Template.Execute(&body, batch); err != nil {
+			return err
+		}
+	} else if err := json.NewEncoder(&body).Encode(batch); err != nil {
+		return err
+	}
+	contentType := s.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	s.sending.Lock()
+	defer s.sending.Unlock()
+
+	backoff := s.RetryBackoff
+	var err error
+	for attempt := 0; attempt <= s.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		var resp *http.Response
+		resp, err = client.Post(s.URL, contentType, bytes.NewReader(body.Bytes()))
+		if err != nil {
+			continue
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode < 300 {
+			return nil
+		}
+		err = fmt.Errorf("Webhook %s responded with %s", s.URL, resp.Status)
+	}
+	return err
+}
@@ -0,0 +1,104 @@
+/*
+ * Simple caching library with expiration capabilities
+ *     Copyright (c) 2013-2017, Christian Muehlhaeuser <muesli@gmail.com>
+ *
+ *   For license see LICENSE.txt
+ */
+
+package cache2go
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func TestWebhookSink(t *testing.T) {
+	var mutex sync.Mutex
+	var batches [][]Event
+	failures := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var batch []Event
+		json.NewDecoder(r.Body).Decode(&batch)
+		batches = append(batches, batch)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL)
+	sink.Flushes = true
+	sink.KeyDeleted = func(key interface{}) bool {
+		return key == "important"
+	}
+	sink.StormEvictions = 5
+	sink.StormWindow = time.Minute
+	sink.BatchSize = 2
+	sink.BatchInterval = time.Minute
+	sink.Retries = 1
+
+	table, _ := CacheWithOptions("testWebhookSink", WithCapacity(1))
+	table.EnableEvents(sink, 100)
+	table.Add("important", 0, v)
+	table.Delete("important")
+	for i := 0; i < 10; i++ {
+		table.Add(i, 0, v)
+	}
+	table.Flush()
+	table.DisableEvents()
+	if err := sink.Close(); err != nil {
+		t.Error("Error sending last batch", err)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(batches) != 2 || len(batches[0]) != 2 || len(batches[1]) != 1 {
+		t.Fatal("Expected a full and a partial batch, got", batches)
+	}
+	for i, eventType := range []string{EventDelete, EventEvictionStorm} {
+		if batches[0][i].Type != eventType {
+			t.Error("Expected", eventType, "got", batches[0][i].Type)
+		}
+	}
+	if batches[1][0].Type != EventFlush {
+		t.Error("Expected flush, got", batches[1][0].Type)
+	}
+}
+
+func TestWebhookSinkTemplate(t *testing.T) {
+	bodies := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies <- r.Header.Get("Content-Type") + " " + string(b)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL)
+	sink.Flushes = true
+	sink.Template = template.Must(template.New("").Parse(`{{range .}}{{.Table}} got {{.Type}}ed{{end}}`))
+	sink.ContentType = "text/plain"
+	sink.BatchInterval = 10 * time.Millisecond
+
+	if err := sink.Publish(Event{Table: "t", Type: EventFlush}); err != nil {
+		t.Error("Error publishing", err)
+	}
+	select {
+	case body := <-bodies:
+		if !strings.HasPrefix(body, "text/plain t got flushed") {
+			t.Error("Unexpected body", body)
+		}
+	case <-time.After(time.Second):
+		t.Error("Batch should be sent after the interval")
+	}
+}